@@ -66,6 +66,7 @@ export default {
     prev: 'Previous',
     createTime: 'Create Time',
     rerun: 'Rerun',
+    resume: 'Resume',
     keyword: 'Keyword',
     function: 'Function',
     historyRecord: 'History',
@@ -203,6 +204,8 @@ export default {
     fileTitle: 'File list',
     bindDatasource: 'Add source file',
     endImport: 'Stop Import',
+    invalidAnswers: 'Unparsable / invalid answers',
+    droppedBlocks: 'Dropped blocks',
     prop: 'Prop',
     mapping: 'CSV Index',
     edgeText: 'Edge',
@@ -558,6 +561,18 @@ export default {
     importGraphSpace: 'Import Graph Space',
     exportNGQLFilePath: 'Export NGQL File Path',
     attachPrompt: 'Attach Prompt',
+    aliases: 'Aliases',
+    aliasesTip: 'One entity per line, the canonical name followed by its aliases, e.g. "Apple Inc.: Apple, AAPL". The aliases are merged into the canonical name.',
+    resolveEntities: 'Resolve Entities',
+    resolveEntitiesTip: 'Ask the LLM to merge the names referring to the same entity after the extraction, which consumes extra tokens.',
+    chunkStrategy: 'Chunk Strategy',
+    chunkStrategyTip: 'How the text is split into the blocks sent to the LLM. By tokens splits at the heading, paragraph and sentence boundaries, and each block starts with the headings above it.',
+    chunkByTokens: 'By Tokens',
+    chunkByLines: 'By Lines',
+    chunkSize: 'Chunk Size',
+    chunkSizeTip: 'The tokens of a block estimated for the model. Half of the max text length left by the prompt by default.',
+    chunkOverlap: 'Chunk Overlap',
+    chunkOverlapTip: 'The tokens of the sentences repeated from the end of the previous block in the same section, at most half of the chunk size.',
     next: 'Next',
     url: 'URL',
     previous: 'Previous',
@@ -66,6 +66,7 @@ export default {
     prev: '上一步',
     createTime: '创建时间',
     rerun: '重新导入',
+    resume: '继续导入',
     keyword: '关键字',
     function: '函数',
     historyRecord: '历史记录',
@@ -197,6 +198,8 @@ export default {
     fileTitle: '文件列表',
     bindDatasource: '添加导入文件',
     endImport: '终止导入',
+    invalidAnswers: '解析失败 / 校验失败的回答',
+    droppedBlocks: '丢弃的文本块',
     prop: '属性',
     mapping: '对应列标',
     edgeText: '边',
@@ -538,6 +541,18 @@ export default {
     importGraphSpace: '导入图空间',
     exportNGQLFilePath: '导出 NGQL 文件路径',
     attachPrompt: '附加提示',
+    aliases: '别名',
+    aliasesTip: '每行一个实体，规范名称后接其别名，例如 "Apple Inc.: Apple, AAPL"。别名会被合并到规范名称。',
+    resolveEntities: '实体消歧',
+    resolveEntitiesTip: '抽取完成后由大模型合并指向同一实体的名称，会消耗额外的 token。',
+    chunkStrategy: '分块策略',
+    chunkStrategyTip: '文本切分为发送给大模型的块的方式。按 token 切分会在标题、段落和句子边界处切分，且每个块以其上方的标题开头。',
+    chunkByTokens: '按 token',
+    chunkByLines: '按行',
+    chunkSize: '块大小',
+    chunkSizeTip: '按模型估算的每块 token 数。默认为最大文本长度扣除提示词后的一半。',
+    chunkOverlap: '块重叠',
+    chunkOverlapTip: '从同一章节的上一块末尾重复的句子 token 数，最多为块大小的一半。',
     next: '下一步',
     previous: '上一步',
     start: '开始',
@@ -26,9 +26,18 @@ const service = {
   importData: (params, config?) => {
     return post('/api/import-tasks')(params, config);
   },
+  validateImportTask: (params, config?) => {
+    return post('/api/import-tasks/validate')(params, config);
+  },
+  suggestImportMapping: (params, config?) => {
+    return post('/api/import-tasks/suggest-mapping')(params, config);
+  },
   stopImportTask: (id: string, config?) => {
     return get(`/api/import-tasks/${id}/stop`)(undefined, config);
   },
+  retryImportTask: (id: string, config?) => {
+    return post(`/api/import-tasks/${id}/retry`)(undefined, config);
+  },
   saveTaskDraft: (params, config?) => {
     return post('/api/import-tasks/draft')(params, config);
   },
@@ -61,9 +70,36 @@ const service = {
   updateFileConfig: (params?, config?) => {
     return post('/api/files/update')(params, config);
   },
+  createFileFolder: (params: { path: string }, config?) => {
+    return post('/api/files/folders')(params, config);
+  },
+  moveFile: (params: { from: string; to: string }, config?) => {
+    return post('/api/files/move')(params, config);
+  },
   uploadFiles: (params?, config?) => {
     return post('/api/files')(params, { ...config, headers: { 'Content-Type': 'multipart/form-data' } });
   },
+  // chunked uploads of the large files, a chunk is sent at the received offset
+  initFileUpload: (params: { name: string; size: number; withHeader?: boolean; delimiter?: string }, config?) => {
+    return post('/api/files/uploads')(params, config);
+  },
+  getFileUpload: (id: string) => {
+    return get(`/api/files/uploads/${id}`)();
+  },
+  uploadFileChunk: (params: { id: string; offset: number; chunk: Blob }, config?) => {
+    const { id, offset, chunk } = params;
+    return put(`/api/files/uploads/${id}?offset=${offset}`)(chunk as any, {
+      ...config,
+      headers: { 'Content-Type': 'application/octet-stream' },
+    });
+  },
+  completeFileUpload: (params: { id: string; checksum: string }, config?) => {
+    const { id, checksum } = params;
+    return post(`/api/files/uploads/${id}/complete`)({ checksum }, config);
+  },
+  abortFileUpload: (id: string) => {
+    return _delete(`/api/files/uploads/${id}`)();
+  },
   initSketch: (params, config?) => {
     return post(`/api/sketches/sketch`)(params, config);
   },
@@ -122,6 +158,38 @@ const service = {
     const { id, ...restParams } = params;
     return get(`/api/datasources/${id}/file-preview`)(restParams, config);
   },
+  previewQuery: (params: { id: string; query: string; limit?: number }, config?) => {
+    const { id, ...restParams } = params;
+    return post(`/api/datasources/${id}/query-preview`)(restParams, config);
+  },
+
+  // notify
+  getNotifyChannelList: (params?, config?) => {
+    return get('/api/notify-channels')(params, config);
+  },
+  addNotifyChannel: (params, config?) => {
+    return post('/api/notify-channels')(params, config);
+  },
+  updateNotifyChannel: (params, config?) => {
+    const { id, ...restParams } = params;
+    return post(`/api/notify-channels/${id}`)(restParams, config);
+  },
+  deleteNotifyChannel: (id: string, config?) => {
+    return _delete(`/api/notify-channels/${id}`)(undefined, config);
+  },
+  testNotifyChannel: (id: string, config?) => {
+    return post(`/api/notify-channels/${id}/test`)(undefined, config);
+  },
+  getNotifyDeliveryList: (params?, config?) => {
+    return get('/api/notify-deliveries')(params, config);
+  },
+  // storage
+  getStorageUsage: (config?) => {
+    return get('/api/storage/usage')(undefined, config);
+  },
+  purgeStorage: (config?) => {
+    return post('/api/storage/purge')(undefined, config);
+  },
 };
 
 export const updateService = (partService: any) => {
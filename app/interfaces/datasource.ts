@@ -1,6 +1,11 @@
 export enum IDatasourceType {
   'S3' = 's3',
   'SFTP' = 'sftp',
+  'FTP' = 'ftp',
+  'HDFS' = 'hdfs',
+  'Azure' = 'azure',
+  'GCS' = 'gcs',
+  'SQL' = 'sql',
   'Local' = 'local',
 }
 export enum ES3Platform {
@@ -30,6 +35,44 @@ export interface IDatasourceItem {
     host: string;
     port: number;
     username: string;
-    password: string;
+    password?: string;
+    privateKey?: string;
+    passphrase?: string;
+    hostKey?: string;
+    knownHosts?: string;
+  };
+  ftpConfig?: {
+    host: string;
+    port: number;
+    username?: string;
+    password?: string;
+    tls?: 'explicit' | 'implicit';
+    insecureSkipVerify?: boolean;
+  };
+  hdfsConfig?: {
+    endpoint: string;
+    address?: string;
+    user: string;
+    auth?: 'simple' | 'kerberos';
+    realm?: string;
+    kdc?: string;
+    spn?: string;
+    password?: string;
+  };
+  azureConfig?: {
+    endpoint?: string;
+    accountName: string;
+    container: string;
+    accountKey?: string;
+  };
+  gcsConfig?: {
+    endpoint?: string;
+    bucket: string;
+    credentials?: string;
+  };
+  sqlConfig?: {
+    driver: 'mysql' | 'postgres' | 'sqlite';
+    dsn: string;
+    password?: string;
   };
 }
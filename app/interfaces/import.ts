@@ -9,6 +9,7 @@ export enum ITaskStatus {
   'Aborted' = 'Failed',
   'Pending' = 'Pending',
   'Draft' = 'Draft',
+  'Queued' = 'Queued',
 }
 
 export interface ITaskStats {
@@ -38,6 +39,12 @@ export interface ITaskItem {
   stats: ITaskStats;
   rawConfig: string;
   llmJob?: ILLMJob;
+  priority: number;
+  startAt: number;
+  cron: string;
+  queuePosition: number;
+  parentId?: string;
+  notifyChannelIds?: string[];
 }
 
 export interface ILLMJob {
@@ -58,6 +65,9 @@ export interface ILLMJob {
     failed_reason: string;
     prompt_tokens: number;
     completion_tokens: number;
+    parse_failures?: number;
+    validation_failures?: number;
+    failed_blocks?: number;
   };
   update_time: string;
   create_time: string;
@@ -0,0 +1,42 @@
+export enum INotifyChannelType {
+  'Webhook' = 'webhook',
+  'SMTP' = 'smtp',
+}
+
+export type INotifyChannelAdd = Omit<INotifyChannelItem, 'id' | 'createTime'>;
+export type INotifyChannelUpdate = Omit<INotifyChannelItem, 'createTime'>;
+
+export interface INotifyChannelItem {
+  id: string;
+  name: string;
+  type: INotifyChannelType;
+  isDefault: boolean;
+  createTime: number;
+  webhookConfig?: {
+    url: string;
+    headers?: Record<string, string>;
+    secret?: string;
+  };
+  smtpConfig?: {
+    host: string;
+    port: number;
+    username?: string;
+    password?: string;
+    from: string;
+    to: string[];
+  };
+}
+
+export interface INotifyDelivery {
+  id: number;
+  channelId: string;
+  jobKind: 'import_task' | 'llm_job';
+  jobId: string;
+  event: 'finished' | 'failed' | 'stopped';
+  status: 'pending' | 'success' | 'failed';
+  attempts: number;
+  lastError: string;
+  payload: string;
+  createTime: number;
+  updateTime: number;
+}
@@ -1,6 +1,6 @@
 import { useStore } from '@app/stores';
 import { useI18n } from '@vesoft-inc/i18n';
-import { Button, Form, Input, Modal, Radio, Select, Tooltip, message } from 'antd';
+import { Button, Form, Input, InputNumber, Modal, Radio, Select, Switch, Tooltip, message } from 'antd';
 import { observer } from 'mobx-react-lite';
 import Icon from '@app/components/Icon';
 import { useEffect, useMemo, useState } from 'react';
@@ -30,6 +30,8 @@ const Create = observer((props: { visible: boolean; onCancel: () => void }) => {
     form.setFieldsValue({
       type: 'file',
       userPrompt: '',
+      chunkStrategy: 'tokens',
+      chunkOverlap: 100,
     });
     setTokens(null);
   }, [props.visible]);
@@ -162,6 +164,45 @@ const Create = observer((props: { visible: boolean; onCancel: () => void }) => {
         <Form.Item label={intl.get('llm.attachPrompt')} name="userPrompt">
           <Input.TextArea />
         </Form.Item>
+        <Form.Item label={intl.get('llm.aliases')} name="aliases" tooltip={intl.get('llm.aliasesTip')}>
+          <Input.TextArea placeholder="Apple Inc.: Apple, AAPL" />
+        </Form.Item>
+        <Form.Item
+          label={intl.get('llm.resolveEntities')}
+          name="resolveEntities"
+          valuePropName="checked"
+          tooltip={intl.get('llm.resolveEntitiesTip')}
+        >
+          <Switch />
+        </Form.Item>
+        <Form.Item
+          label={intl.get('llm.chunkStrategy')}
+          name="chunkStrategy"
+          tooltip={intl.get('llm.chunkStrategyTip')}
+        >
+          <Radio.Group>
+            <Radio.Button value="tokens">{intl.get('llm.chunkByTokens')}</Radio.Button>
+            <Radio.Button value="lines">{intl.get('llm.chunkByLines')}</Radio.Button>
+          </Radio.Group>
+        </Form.Item>
+        <Form.Item noStyle shouldUpdate={(prev, cur) => prev.chunkStrategy !== cur.chunkStrategy}>
+          {({ getFieldValue }) =>
+            getFieldValue('chunkStrategy') === 'tokens' && (
+              <>
+                <Form.Item label={intl.get('llm.chunkSize')} name="chunkSize" tooltip={intl.get('llm.chunkSizeTip')}>
+                  <InputNumber min={64} placeholder="auto" />
+                </Form.Item>
+                <Form.Item
+                  label={intl.get('llm.chunkOverlap')}
+                  name="chunkOverlap"
+                  tooltip={intl.get('llm.chunkOverlapTip')}
+                >
+                  <InputNumber min={0} />
+                </Form.Item>
+              </>
+            )
+          }
+        </Form.Item>
       </Form>
 
       <Form layout="vertical" style={{ display: step === 1 ? 'block' : 'none' }}>
@@ -42,8 +42,8 @@ const FileSelect = observer((props: IFileSelect) => {
   const getLocalFiles = useCallback(async () => {
     const files = await getFiles();
     setState({
-      // filter csv file
-      directory: files.filter((file) => file.name.endsWith('.csv')),
+      // filter csv file, the compressed csv files and the csv files in the archives
+      directory: files.filter((file) => /\.csv(\.gz|\.gzip|\.bz2)?$/i.test(file.name)),
       path: '/',
       loading: false,
       activeId: IDatasourceType.Local,
@@ -64,9 +64,10 @@ const FileSelect = observer((props: IFileSelect) => {
   }, []);
   const handleSelectFile = useCallback(
     async (item) => {
-      if (item.type !== 'directory') return;
+      // the archives are browsed like the directories
+      if (item.type !== 'directory' && item.type !== 'archive') return;
       setState({ loading: true });
-      const newPath = `${path === '/' ? '' : path}${item.name}${item.type === 'directory' ? '/' : ''}`;
+      const newPath = `${path === '/' ? '' : path}${item.name}/`;
       getDatasourceDirectory(activeId, newPath);
     },
     [path],
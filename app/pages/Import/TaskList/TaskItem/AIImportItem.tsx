@@ -54,6 +54,7 @@ const AIImportItem = observer((props: IProps) => {
   } = props;
   const { intl } = useI18n();
   const [rerunLoading, setRerunLoading] = React.useState(false);
+  const [resumeLoading, setResumeLoading] = React.useState(false);
 
   const progressStatus = llmStatusMap[llmJob.status];
 
@@ -83,6 +84,15 @@ const AIImportItem = observer((props: IProps) => {
     }
     setRerunLoading(false);
   };
+  const handleResume = async () => {
+    setResumeLoading(true);
+    const res = await post('/api/llm/import/job/resume')({ jobId: llmJob.job_id });
+    if (res.code === 0) {
+      antMsg.success(intl.get('common.success'));
+      props.onRefresh();
+    }
+    setResumeLoading(false);
+  };
 
   return (
     <>
@@ -107,6 +117,13 @@ const AIImportItem = observer((props: IProps) => {
                   prompt tokens:{llmJob.process?.prompt_tokens || '-'}/ completion tokens:{' '}
                   {llmJob.process?.completion_tokens || '-'}
                 </span>
+                {!!(llmJob.process?.parse_failures || llmJob.process?.validation_failures) && (
+                  <span>
+                    {intl.get('import.invalidAnswers')}: {llmJob.process.parse_failures || 0} /{' '}
+                    {llmJob.process.validation_failures || 0} / {intl.get('import.droppedBlocks')}:{' '}
+                    {llmJob.process.failed_blocks || 0}
+                  </span>
+                )}
               </>
             )}
           </div>
@@ -197,6 +214,13 @@ const AIImportItem = observer((props: IProps) => {
 
             {!loadingStatus.includes(llmJob.status) && (
               <>
+                {[ILLMStatus.Failed, ILLMStatus.Cancel].includes(llmJob.status) && (
+                  <Button className="primaryBtn" loading={resumeLoading} onClick={handleResume}>
+                    <Tooltip title={intl.get('common.resume')}>
+                      <Icon type="icon-studio-btn-return" />
+                    </Tooltip>
+                  </Button>
+                )}
                 <Button className="primaryBtn" loading={rerunLoading} onClick={handleRerun}>
                   <Tooltip title={intl.get('common.rerun')}>
                     <Icon type="icon-studio-btn-play" />
@@ -62,6 +62,8 @@ const TaskItem = (props: IProps) => {
   const [progressStatus, setStatus] = useState<'success' | 'active' | 'normal' | 'exception' | undefined>(undefined);
   const [extraMsg, setExtraMsg] = useState('');
   const { processedBytes, totalBytes, failedProcessed } = stats || {};
+  // the size of a streamed source is unknown before it is read to the end
+  const sizeKnown = totalBytes > 0 && totalBytes >= processedBytes;
   const time = useRef('');
   const timeoutId = useRef<number>(null);
   const [rerunLoading, setRerunLoading] = useState(false);
@@ -214,8 +216,8 @@ const TaskItem = (props: IProps) => {
               <div className={styles.moreInfo}>
                 {processedBytes > 0 && (
                   <span>
-                    {status !== ITaskStatus.Finished && `${getFileSize(processedBytes)} / `}
-                    {getFileSize(totalBytes)}{' '}
+                    {status !== ITaskStatus.Finished && sizeKnown && `${getFileSize(processedBytes)} / `}
+                    {getFileSize(sizeKnown ? totalBytes : processedBytes)}{' '}
                   </span>
                 )}
                 {!isDraft && <span>{time.current}</span>}
@@ -225,7 +227,9 @@ const TaskItem = (props: IProps) => {
               <Progress
                 format={(percent) => `${percent}%`}
                 status={progressStatus}
-                percent={status !== ITaskStatus.Finished ? floor((processedBytes / totalBytes) * 100, 2) : 100}
+                percent={
+                  status === ITaskStatus.Finished ? 100 : sizeKnown ? floor((processedBytes / totalBytes) * 100, 2) : 0
+                }
                 strokeColor={progressStatus && COLOR_MAP[progressStatus]}
               />
             )}
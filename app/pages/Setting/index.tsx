@@ -110,6 +110,8 @@ const Setting = observer(() => {
                     <Select defaultValue="openai" style={{ width: 120 }}>
                       <Select.Option value="openai">OpenAI</Select.Option>
                       <Select.Option value="qwen">Aliyun</Select.Option>
+                      <Select.Option value="azure">Azure OpenAI</Select.Option>
+                      <Select.Option value="ollama">Ollama</Select.Option>
                     </Select>
                   </Form.Item>
                   <Form.Item label="URL" name="url" required={true}>
@@ -121,9 +123,42 @@ const Setting = observer(() => {
                   <Form.Item label="model" name="model">
                     <Input />
                   </Form.Item>
+                  <Form.Item noStyle shouldUpdate={(prev, cur) => prev.apiType !== cur.apiType}>
+                    {({ getFieldValue }) =>
+                      getFieldValue('apiType') === 'azure' && (
+                        <>
+                          <Form.Item label="deployment" name="deployment">
+                            <Input />
+                          </Form.Item>
+                          <Form.Item label="api-version" name="apiVersion">
+                            <Input placeholder="2024-06-01" />
+                          </Form.Item>
+                        </>
+                      )
+                    }
+                  </Form.Item>
                   <Form.Item label={intl.get('setting.maxTextLength')} name="maxContextLength" required={true}>
                     <InputNumber min={0} />
                   </Form.Item>
+                  <Form.Item label="concurrency" name="concurrency">
+                    <InputNumber min={1} max={32} placeholder="1" />
+                  </Form.Item>
+                  <Form.Item label="requests per minute" name="rpm">
+                    <InputNumber min={0} />
+                  </Form.Item>
+                  <Form.Item label="tokens per minute" name="tpm">
+                    <InputNumber min={0} />
+                  </Form.Item>
+                  <Form.Item label="response format" name="responseFormat">
+                    <Select placeholder="json_object" style={{ width: 160 }} allowClear={true}>
+                      <Select.Option value="json_schema">JSON schema</Select.Option>
+                      <Select.Option value="json_object">JSON object</Select.Option>
+                      <Select.Option value="none">None</Select.Option>
+                    </Select>
+                  </Form.Item>
+                  <Form.Item label="repairs" name="repairs">
+                    <InputNumber min={0} max={5} placeholder="2" />
+                  </Form.Item>
                   <Form.Item label="" required={true}>
                     <Button onClick={onSubmitLLMForm} type="primary">
                       {intl.get('setting.verify')}
@@ -20,8 +20,11 @@ import (
 
 type Config struct {
 	LLMJob struct {
-		Space string
-		File  string
+		Space         string
+		File          string
+		ChunkStrategy string `json:",default=lines"`
+		ChunkSize     int    `json:",default=0"`
+		ChunkOverlap  int    `json:",default=0"`
 	}
 	Auth struct {
 		Address  string
@@ -55,9 +58,12 @@ func main() {
 		CacheNodes: make(map[string]llm.Node),
 		CacheEdges: make(map[string]map[string]llm.Edge),
 		LLMJob: &db.LLMJob{
-			JobID: fmt.Sprintf("%d", time.Now().UnixNano()),
-			Space: c.LLMJob.Space,
-			File:  c.LLMJob.File,
+			JobID:         fmt.Sprintf("%d", time.Now().UnixNano()),
+			Space:         c.LLMJob.Space,
+			File:          c.LLMJob.File,
+			ChunkStrategy: c.LLMJob.ChunkStrategy,
+			ChunkSize:     c.LLMJob.ChunkSize,
+			ChunkOverlap:  c.LLMJob.ChunkOverlap,
 		},
 		AuthData: &auth.AuthData{
 			Address:  c.Auth.Address,
@@ -172,6 +178,7 @@ func RunFileJob(llmJob *llm.ImportJob) {
 		llmJob.SetJobFailed(err)
 		return
 	}
+	llmJob.ResolveEntities()
 	llmJob.Process.Ratio = 0.8
 
 	fileName := filepath.Base(llmJob.LLMJob.File)
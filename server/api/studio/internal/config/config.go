@@ -22,9 +22,9 @@ var PromptTemplate = `As a knowledge graph AI importer, your task is to extract
     Return the results directly, without explain and comment. The results should be in the following JSON format:
     {
       "nodes":[{ "name":string,"type":string,"props":object }],
-      "edges":[{ "src":string,"dst":string,"edgeType":string,"props":object }]
+      "edges":[{ "src":string,"srcType":string,"dst":string,"dstType":string,"edgeType":string,"props":object }]
     }
-    The name of the nodes should be an actual object and a noun.
+    The name of the nodes should be an actual object and a noun. The srcType and the dstType of an edge are the types of its src and dst nodes.
     Result:`
 
 func GetConfig() *Config {
@@ -46,6 +46,9 @@ type Config struct {
 	File        struct {
 		UploadDir string
 		TasksDir  string
+		// The dir of the sqlite databases the sql datasources can read besides the files uploaded by the user.
+		// The database of studio is never read.
+		SqliteDir string `json:",optional"`
 	} `json:",optional"`
 
 	WebSocket struct {
@@ -81,6 +84,54 @@ type Config struct {
 		MaxIdleConns              int    `json:",default=10"`
 	}
 
+	Import struct {
+		// The maximum number of import tasks running at the same time, 0 means no limit.
+		Concurrency int `json:",default=5"`
+		// The maximum number of import tasks running at the same time in one graph space, 0 means no limit.
+		SpaceConcurrency int `json:",default=2"`
+	} `json:",optional"`
+
+	Lease struct {
+		// The time (second) a claimed job stays owned by an instance without a heartbeat.
+		// Once expired, another instance takes the job over.
+		TTL int64 `json:",default=30"`
+		// The interval (second) at which an instance renews the leases it owns.
+		HeartbeatInterval int64 `json:",default=10"`
+	} `json:",optional"`
+
+	Notify struct {
+		// The external url of studio, used to link the job logs in the notifications.
+		BaseURL string `json:",optional"`
+		// The maximum retries of a failed delivery.
+		Retries int `json:",default=3"`
+		// The wait time (second) before the first retry, doubled for each next retry.
+		RetryInterval int64 `json:",default=10"`
+		// The timeout (second) of a delivery.
+		Timeout int64 `json:",default=10"`
+	} `json:",optional"`
+
+	Storage struct {
+		// The maximum bytes of the uploaded files, the import task dirs and the llm job files of a user, 0 means no limit.
+		UserQuota int64 `json:",default=0"`
+		// The maximum bytes of all the files of the instance, 0 means no limit.
+		InstanceQuota int64 `json:",default=0"`
+		// The uploaded files unmodified for more days are purged, 0 means never.
+		UploadRetentionDays int `json:",default=0"`
+		// The import tasks finished for more days are purged with their dirs, 0 means never.
+		TaskRetentionDays int `json:",default=0"`
+		// The oldest finished import tasks are purged while the task dirs take more bytes, 0 means no limit.
+		TaskRetentionBytes int64 `json:",default=0"`
+		// The llm jobs finished for more days are purged with their files, 0 means never.
+		LLMRetentionDays int `json:",default=0"`
+		// The oldest finished llm jobs are purged while their files take more bytes, 0 means no limit.
+		LLMRetentionBytes int64 `json:",default=0"`
+		// The interval (second) at which the janitor purges the expired files.
+		JanitorInterval int64 `json:",default=3600"`
+		// The users who can see the storage usage of all the users and purge the expired files.
+		// Each one is host:port/user of the graph the user signs in, such as 127.0.0.1:9669/root.
+		Admins []string `json:",optional"`
+	} `json:",optional"`
+
 	LLM struct {
 		GQLPath        string `json:",default=./data/llm"`
 		GQLBatchSize   int    `json:",default=100"`
@@ -135,6 +186,27 @@ func (c *Config) Complete() {
 	if c.LLM.MaxBlockSize == 0 {
 		c.LLM.MaxBlockSize = 1024 * 1024 * 1024
 	}
+	if c.Lease.TTL <= 0 {
+		c.Lease.TTL = 30
+	}
+	if c.Lease.HeartbeatInterval <= 0 || c.Lease.HeartbeatInterval >= c.Lease.TTL {
+		c.Lease.HeartbeatInterval = c.Lease.TTL / 3
+		if c.Lease.HeartbeatInterval == 0 {
+			c.Lease.HeartbeatInterval = 1
+		}
+	}
+	if c.Notify.Retries < 0 {
+		c.Notify.Retries = 0
+	}
+	if c.Notify.RetryInterval <= 0 {
+		c.Notify.RetryInterval = 10
+	}
+	if c.Notify.Timeout <= 0 {
+		c.Notify.Timeout = 10
+	}
+	if c.Storage.JanitorInterval <= 0 {
+		c.Storage.JanitorInterval = 3600
+	}
 	if c.LLM.PromptTemplate == "" {
 		c.LLM.PromptTemplate = PromptTemplate
 	}
@@ -11,8 +11,10 @@ import (
 	health "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/handler/health"
 	importtask "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/handler/importtask"
 	llm "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/handler/llm"
+	notify "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/handler/notify"
 	schema "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/handler/schema"
 	sketches "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/handler/sketches"
+	storage "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/handler/storage"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
 
 	"github.com/zeromicro/go-zero/rest"
@@ -83,6 +85,41 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/api/files/update",
 				Handler: file.FileConfigUpdateHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/files/folders",
+				Handler: file.FileFolderCreateHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/files/move",
+				Handler: file.FileMoveHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/files/uploads",
+				Handler: file.FileUploadInitHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/api/files/uploads/:id",
+				Handler: file.GetFileUploadHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPut,
+				Path:    "/api/files/uploads/:id",
+				Handler: file.FileUploadChunkHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/files/uploads/:id/complete",
+				Handler: file.FileUploadCompleteHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodDelete,
+				Path:    "/api/files/uploads/:id",
+				Handler: file.FileUploadAbortHandler(serverCtx),
+			},
 		},
 	)
 
@@ -93,6 +130,16 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/api/import-tasks",
 				Handler: importtask.CreateImportTaskHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/import-tasks/validate",
+				Handler: importtask.ValidateImportTaskHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/import-tasks/suggest-mapping",
+				Handler: importtask.SuggestImportMappingHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodPost,
 				Path:    "/api/import-tasks/draft",
@@ -133,6 +180,11 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/api/import-tasks/:id/stop",
 				Handler: importtask.StopImportTaskHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/import-tasks/:id/retry",
+				Handler: importtask.RetryImportTaskHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodGet,
 				Path:    "/api/import-tasks/:id/download-logs",
@@ -253,6 +305,11 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/api/datasources/:id/file-preview",
 				Handler: datasource.DatasourcePreviewFileHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/datasources/:id/query-preview",
+				Handler: datasource.DatasourcePreviewQueryHandler(serverCtx),
+			},
 		},
 	)
 
@@ -303,6 +360,61 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/api/llm/import/ngql",
 				Handler: llm.DownloadLLMImportNgqlHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/llm/text2ngql",
+				Handler: llm.LLMText2NGQLHandler(serverCtx),
+			},
+		},
+	)
+
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/notify-channels",
+				Handler: notify.NotifyChannelAddHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/notify-channels/:id",
+				Handler: notify.NotifyChannelUpdateHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodDelete,
+				Path:    "/api/notify-channels/:id",
+				Handler: notify.NotifyChannelRemoveHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/api/notify-channels",
+				Handler: notify.NotifyChannelListHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/notify-channels/:id/test",
+				Handler: notify.NotifyChannelTestHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/api/notify-deliveries",
+				Handler: notify.NotifyDeliveryListHandler(serverCtx),
+			},
+		},
+	)
+
+	server.AddRoutes(
+		[]rest.Route{
+			{
+				Method:  http.MethodGet,
+				Path:    "/api/storage/usage",
+				Handler: storage.StorageUsageHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/api/storage/purge",
+				Handler: storage.StoragePurgeHandler(serverCtx),
+			},
 		},
 	)
 }
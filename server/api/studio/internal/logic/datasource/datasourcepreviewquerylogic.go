@@ -0,0 +1,29 @@
+package datasource
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type DatasourcePreviewQueryLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewDatasourcePreviewQueryLogic(ctx context.Context, svcCtx *svc.ServiceContext) *DatasourcePreviewQueryLogic {
+	return &DatasourcePreviewQueryLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *DatasourcePreviewQueryLogic) DatasourcePreviewQuery(req types.DatasourcePreviewQueryRequest) (resp *types.DatasourcePreviewQueryData, err error) {
+	return service.NewDatasourceService(l.ctx, l.svcCtx).PreviewQuery(req)
+}
@@ -0,0 +1,29 @@
+package file
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type FileFolderCreateLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewFileFolderCreateLogic(ctx context.Context, svcCtx *svc.ServiceContext) *FileFolderCreateLogic {
+	return &FileFolderCreateLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *FileFolderCreateLogic) FileFolderCreate(req types.FileFolderCreateRequest) error {
+	return service.NewFileService(l.ctx, l.svcCtx).FileFolderCreate(req)
+}
@@ -0,0 +1,29 @@
+package file
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type FileMoveLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewFileMoveLogic(ctx context.Context, svcCtx *svc.ServiceContext) *FileMoveLogic {
+	return &FileMoveLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *FileMoveLogic) FileMove(req types.FileMoveRequest) error {
+	return service.NewFileService(l.ctx, l.svcCtx).FileMove(req)
+}
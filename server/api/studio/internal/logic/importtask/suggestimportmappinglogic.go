@@ -0,0 +1,29 @@
+package importtask
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SuggestImportMappingLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSuggestImportMappingLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SuggestImportMappingLogic {
+	return &SuggestImportMappingLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *SuggestImportMappingLogic) SuggestImportMapping(req types.SuggestImportMappingRequest) (resp *types.SuggestImportMappingData, err error) {
+	return service.NewImportService(l.ctx, l.svcCtx).SuggestImportMapping(&req)
+}
@@ -0,0 +1,29 @@
+package importtask
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ValidateImportTaskLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewValidateImportTaskLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ValidateImportTaskLogic {
+	return &ValidateImportTaskLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *ValidateImportTaskLogic) ValidateImportTask(req types.CreateImportTaskRequest) (resp *types.ValidateImportTaskData, err error) {
+	return service.NewImportService(l.ctx, l.svcCtx).ValidateImportTask(&req)
+}
@@ -0,0 +1,29 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service/llm"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type LLMText2NGQLLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewLLMText2NGQLLogic(ctx context.Context, svcCtx *svc.ServiceContext) LLMText2NGQLLogic {
+	return LLMText2NGQLLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *LLMText2NGQLLogic) LLMText2NGQL(req types.LLMText2NGQLRequest) (resp *types.LLMResponse, err error) {
+	return llm.NewLLMService(l.ctx, l.svcCtx).Text2NGQL(&req)
+}
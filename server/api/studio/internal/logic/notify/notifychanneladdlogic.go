@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type NotifyChannelAddLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewNotifyChannelAddLogic(ctx context.Context, svcCtx *svc.ServiceContext) *NotifyChannelAddLogic {
+	return &NotifyChannelAddLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *NotifyChannelAddLogic) NotifyChannelAdd(req types.NotifyChannelAddRequest) (resp *types.NotifyChannelAddData, err error) {
+	return service.NewNotifyService(l.ctx, l.svcCtx).AddChannel(req)
+}
@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type NotifyChannelListLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewNotifyChannelListLogic(ctx context.Context, svcCtx *svc.ServiceContext) *NotifyChannelListLogic {
+	return &NotifyChannelListLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *NotifyChannelListLogic) NotifyChannelList() (resp *types.NotifyChannelListData, err error) {
+	return service.NewNotifyService(l.ctx, l.svcCtx).ListChannels()
+}
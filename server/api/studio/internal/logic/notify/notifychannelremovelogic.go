@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type NotifyChannelRemoveLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewNotifyChannelRemoveLogic(ctx context.Context, svcCtx *svc.ServiceContext) *NotifyChannelRemoveLogic {
+	return &NotifyChannelRemoveLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *NotifyChannelRemoveLogic) NotifyChannelRemove(req types.NotifyChannelRemoveRequest) error {
+	return service.NewNotifyService(l.ctx, l.svcCtx).RemoveChannel(req)
+}
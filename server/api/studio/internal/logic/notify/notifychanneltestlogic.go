@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type NotifyChannelTestLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewNotifyChannelTestLogic(ctx context.Context, svcCtx *svc.ServiceContext) *NotifyChannelTestLogic {
+	return &NotifyChannelTestLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *NotifyChannelTestLogic) NotifyChannelTest(req types.NotifyChannelTestRequest) error {
+	return service.NewNotifyService(l.ctx, l.svcCtx).TestChannel(req)
+}
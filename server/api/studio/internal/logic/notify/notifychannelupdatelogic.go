@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type NotifyChannelUpdateLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewNotifyChannelUpdateLogic(ctx context.Context, svcCtx *svc.ServiceContext) *NotifyChannelUpdateLogic {
+	return &NotifyChannelUpdateLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *NotifyChannelUpdateLogic) NotifyChannelUpdate(req types.NotifyChannelUpdateRequest) error {
+	return service.NewNotifyService(l.ctx, l.svcCtx).UpdateChannel(req)
+}
@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type NotifyDeliveryListLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewNotifyDeliveryListLogic(ctx context.Context, svcCtx *svc.ServiceContext) *NotifyDeliveryListLogic {
+	return &NotifyDeliveryListLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *NotifyDeliveryListLogic) NotifyDeliveryList(req types.NotifyDeliveryListRequest) (resp *types.NotifyDeliveryListData, err error) {
+	return service.NewNotifyService(l.ctx, l.svcCtx).ListDeliveries(req)
+}
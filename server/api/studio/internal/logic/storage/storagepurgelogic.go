@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type StoragePurgeLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewStoragePurgeLogic(ctx context.Context, svcCtx *svc.ServiceContext) *StoragePurgeLogic {
+	return &StoragePurgeLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *StoragePurgeLogic) StoragePurge() (resp *types.StoragePurgeData, err error) {
+	return service.NewStorageService(l.ctx, l.svcCtx).Purge()
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type StorageUsageLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewStorageUsageLogic(ctx context.Context, svcCtx *svc.ServiceContext) *StorageUsageLogic {
+	return &StorageUsageLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *StorageUsageLogic) StorageUsage() (resp *types.StorageUsageData, err error) {
+	return service.NewStorageService(l.ctx, l.svcCtx).Usage()
+}
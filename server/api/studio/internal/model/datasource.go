@@ -13,7 +13,7 @@ type Datasource struct {
 	Type       string    `gorm:"column:type;type:varchar(128);not null"`
 	Platform   string    `gorm:"column:platform;type:varchar(128);not null"`
 	Config     string    `gorm:"column:config;type:text;not null"`
-	Secret     string    `gorm:"column:secret;type:varchar(128);not null"`
+	Secret     string    `gorm:"column:secret;type:text;not null"`
 	Host       string    `gorm:"column:host;type:varchar(128);not null"`
 	Username   string    `gorm:"column:username;type:varchar(128);not null"`
 	CreateTime time.Time `gorm:"column:create_time;type:datetime;autoCreateTime"`
@@ -120,8 +120,12 @@ func InitDB(config *config.Config, db *gorm.DB) {
 			&SchemaSnapshot{},
 			&Favorite{},
 			&File{},
+			&FileUpload{},
 			&LLMConfig{},
 			&LLMJob{},
+			&JobLease{},
+			&NotifyChannel{},
+			&NotifyDelivery{},
 		)
 		if err != nil {
 			zap.L().Fatal(fmt.Sprintf("init taskInfo table fail: %s", err))
@@ -4,10 +4,25 @@ import (
 	"time"
 )
 
+// File is the config of a file uploaded by a user, Name is the path of the file in the namespace of the user
 type File struct {
 	ID         int       `gorm:"column:id;primaryKey;autoIncrement"`
 	BID        string    `gorm:"column:b_id;not null;type:char(32);uniqueIndex;comment:file id"`
-	Name       string    `gorm:"column:name;type:varchar(128);unique"`
+	Name       string    `gorm:"column:name;type:varchar(255);not null;uniqueIndex:idx_files_owner_name"`
+	WithHeader bool      `gorm:"column:with_header;type:boolean;default:false;"`
+	Delimiter  string    `gorm:"column:delimiter;default:',';"`
+	Host       string    `gorm:"column:host;type:varchar(128);not null;uniqueIndex:idx_files_owner_name"`
+	Username   string    `gorm:"column:username;type:varchar(128);not null;uniqueIndex:idx_files_owner_name"`
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;autoCreateTime"`
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;autoUpdateTime"`
+}
+
+// FileUpload is an unfinished chunked upload, the received bytes are kept in a part file of the upload dir
+type FileUpload struct {
+	ID         int       `gorm:"column:id;primaryKey;autoIncrement"`
+	BID        string    `gorm:"column:b_id;not null;type:char(32);uniqueIndex;comment:upload id"`
+	Name       string    `gorm:"column:name;type:varchar(255);not null"`
+	Size       int64     `gorm:"column:size;not null"`
 	WithHeader bool      `gorm:"column:with_header;type:boolean;default:false;"`
 	Delimiter  string    `gorm:"column:delimiter;default:',';"`
 	Host       string    `gorm:"column:host;type:varchar(128);not null"`
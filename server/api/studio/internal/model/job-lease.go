@@ -0,0 +1,19 @@
+package db
+
+import (
+	"time"
+)
+
+// JobLease records which studio instance currently owns a background job,
+// so that several instances sharing one database never run the same job twice.
+type JobLease struct {
+	ID            int       `gorm:"column:id;primaryKey;autoIncrement"`
+	Kind          string    `gorm:"column:kind;type:varchar(32);not null;uniqueIndex:idx_job_lease"`
+	JobID         string    `gorm:"column:job_id;type:varchar(64);not null;uniqueIndex:idx_job_lease"`
+	Owner         string    `gorm:"column:owner;type:varchar(128);not null;index"`
+	ExpireTime    time.Time `gorm:"column:expire_time;type:datetime;index"`
+	StopRequested bool      `gorm:"column:stop_requested;not null;default:false"`
+
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;autoCreateTime"`
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;autoUpdateTime"`
+}
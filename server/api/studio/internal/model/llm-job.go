@@ -8,16 +8,21 @@ import (
 )
 
 type LLMJob struct {
-	ID         int            `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserName   string         `json:"user_name" gorm:"index:"`
-	Host       string         `json:"host" gorm:"index"`
-	JobID      string         `json:"job_id" gorm:"index:,unique"`
-	Space      string         `json:"space"`
-	File       string         `json:"file"`
-	JobType    string         `json:"job_type"`
-	Status     base.LLMStatus `json:"status"`
-	UserPrompt string         `json:"user_prompt"`
-	Process    datatypes.JSON `json:"process"`
-	CreateTime time.Time      `json:"create_time" gorm:"column:create_time;type:datetime;autoCreateTime"`
-	UpdateTime time.Time      `json:"update_ime" gorm:"column:update_time;type:datetime;autoUpdateTime"`
+	ID              int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserName        string         `json:"user_name" gorm:"index:"`
+	Host            string         `json:"host" gorm:"index"`
+	JobID           string         `json:"job_id" gorm:"index:,unique"`
+	Space           string         `json:"space"`
+	File            string         `json:"file"`
+	JobType         string         `json:"job_type"`
+	Status          base.LLMStatus `json:"status"`
+	UserPrompt      string         `json:"user_prompt"`
+	Aliases         string         `json:"aliases"`
+	ResolveEntities bool           `json:"resolve_entities"`
+	ChunkStrategy   string         `json:"chunk_strategy"`
+	ChunkSize       int            `json:"chunk_size"`
+	ChunkOverlap    int            `json:"chunk_overlap"`
+	Process         datatypes.JSON `json:"process"`
+	CreateTime      time.Time      `json:"create_time" gorm:"column:create_time;type:datetime;autoCreateTime"`
+	UpdateTime      time.Time      `json:"update_ime" gorm:"column:update_time;type:datetime;autoUpdateTime"`
 }
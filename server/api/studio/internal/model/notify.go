@@ -0,0 +1,43 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NotifyChannel is a webhook or an smtp mailbox notified when a job of the user completes
+type NotifyChannel struct {
+	ID     int    `gorm:"column:id;primaryKey;autoIncrement"`
+	BID    string `gorm:"column:b_id;not null;type:char(32);uniqueIndex;comment:channel id"`
+	Name   string `gorm:"column:name;type:varchar(128);not null"`
+	Type   string `gorm:"column:type;type:varchar(32);not null"`
+	Config string `gorm:"column:config;type:text;not null"`
+	Secret string `gorm:"column:secret;type:varchar(256);not null;comment:encrypted webhook signing key or smtp password"`
+	// a default channel is notified for all the jobs of the user, the others only for the tasks choosing them
+	IsDefault  bool      `gorm:"column:is_default;not null;default:false"`
+	Host       string    `gorm:"column:host;type:varchar(128);not null"`
+	Username   string    `gorm:"column:username;type:varchar(128);not null"`
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;autoCreateTime"`
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;autoUpdateTime"`
+
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index;type:datetime"`
+}
+
+// NotifyDelivery logs the delivery of a notification to a channel
+type NotifyDelivery struct {
+	ID        int    `gorm:"column:id;primaryKey;autoIncrement"`
+	ChannelID string `gorm:"column:channel_id;type:char(32);not null;index"`
+	Host      string `gorm:"column:host;type:varchar(128);not null"`
+	Username  string `gorm:"column:username;type:varchar(128);not null"`
+	JobKind   string `gorm:"column:job_kind;type:varchar(32);not null"`
+	JobID     string `gorm:"column:job_id;type:varchar(64);not null;index"`
+	Event     string `gorm:"column:event;type:varchar(32);not null"`
+	// pending, success or failed
+	Status     string    `gorm:"column:status;type:varchar(32);not null"`
+	Attempts   int       `gorm:"column:attempts;not null;default:0"`
+	LastError  string    `gorm:"column:last_error;type:text"`
+	Payload    string    `gorm:"column:payload;type:text"`
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;autoCreateTime"`
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;autoUpdateTime"`
+}
@@ -31,6 +31,15 @@ type TaskInfo struct {
 	TaskMessage   string `gorm:"column:task_message;"`
 	Stats         Stats  `gorm:"embedded"`
 	RawConfig     string `gorm:"column:raw_config;type:mediumtext;"`
+	Checkpoint    string `gorm:"column:checkpoint;type:text;comment:committed offset of each source"`
+	// queue settings, the tasks with higher priority start first
+	Priority int        `gorm:"column:priority;not null;default:0;"`
+	StartAt  *time.Time `gorm:"column:start_at;type:datetime;"`
+	Cron     string     `gorm:"column:cron;type:varchar(255);"`
+	// the task whose failed records are retried by this task
+	ParentID string `gorm:"column:parent_id;type:char(32);index;"`
+	// the comma separated notify channels chosen for the task, besides the default channels of the user
+	NotifyChannels string `gorm:"column:notify_channels;type:varchar(512);"`
 
 	CreateTime time.Time `gorm:"column:create_time;type:datetime;autoCreateTime"`
 	UpdateTime time.Time `gorm:"column:update_time;type:datetime;autoUpdateTime"`
@@ -42,6 +51,10 @@ type TaskEffect struct {
 	BID    string `gorm:"column:task_id;not null;type:char(32);uniqueIndex;comment:task id"`
 	Log    string `gorm:"column:log;type:mediumtext;comment:partial task log"`
 	Config string `gorm:"column:config;type:mediumtext;comment:task config.yaml"`
+	// the config with credentials, used to resume the task after a restart
+	RuntimeConfig string `gorm:"column:runtime_config;type:mediumtext;comment:encrypted runtime config"`
+	// the sources read by studio through the datasource stores when each run starts
+	StoreSources string `gorm:"column:store_sources;type:text;comment:sources read by studio"`
 
 	CreateTime time.Time `gorm:"column:create_time;type:datetime;autoCreateTime"`
 }
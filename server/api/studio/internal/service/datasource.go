@@ -8,6 +8,7 @@ import (
 	"strconv"
 
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service/importer"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
@@ -26,6 +27,7 @@ type (
 		BatchRemove(request types.DatasourceBatchRemoveRequest) error
 		ListContents(request types.DatasourceListContentsRequest) (*types.DatasourceListContentsData, error)
 		PreviewFile(request types.DatasourcePreviewFileRequest) (*types.DatasourcePreviewFileData, error)
+		PreviewQuery(request types.DatasourcePreviewQueryRequest) (*types.DatasourcePreviewQueryData, error)
 	}
 
 	datasourceService struct {
@@ -36,8 +38,10 @@ type (
 	}
 )
 
-// TODO: make it configurable
-const cipher = "6b6579736f6d6574616c6b6579736f6d"
+const cipher = utils.CipherKey
+
+// the max rows returned by the preview of a query
+const previewQueryMaxRows = 1000
 
 func NewDatasourceService(ctx context.Context, svcCtx *svc.ServiceContext) DatasourceService {
 	return &datasourceService{
@@ -57,9 +61,22 @@ func (d *datasourceService) Add(request types.DatasourceAddRequest) (*types.Data
 		cfg = request.S3Config
 	case "sftp":
 		cfg = request.SFTPConfig
+	case "ftp":
+		cfg = request.FTPConfig
+	case "hdfs":
+		cfg = request.HDFSConfig
+	case "azure":
+		cfg = request.AzureConfig
+	case "gcs":
+		cfg = request.GCSConfig
+	case "sql":
+		cfg = request.SQLConfig
 	default:
 		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, nil, "Invalid datasource type")
 	}
+	if err := d.checkSqlite(cfg); err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
 	cfgStr, crypto, err := validate(typ, platform, cfg)
 	if err != nil {
 		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err)
@@ -97,18 +114,116 @@ func (d *datasourceService) Update(request types.DatasourceUpdateRequest) error
 		}
 	case "sftp":
 		sftpCfg := request.SFTPConfig
+		secret := filestore.ParseSftpSecret(dbs.Secret)
 		if sftpCfg.Password == "" {
-			sftpCfg.Password = dbs.Secret
+			sftpCfg.Password = secret.Password
+		}
+		if sftpCfg.PrivateKey == "" {
+			sftpCfg.PrivateKey = secret.PrivateKey
+			if sftpCfg.Passphrase == "" {
+				sftpCfg.Passphrase = secret.Passphrase
+			}
+		}
+		// keep verifying the same server with the saved host key
+		var savedCfg types.DatasourceSFTPConfig
+		if sftpCfg.HostKey == "" && sftpCfg.KnownHosts == "" && json.Unmarshal([]byte(dbs.Config), &savedCfg) == nil &&
+			savedCfg.Host == sftpCfg.Host && savedCfg.Port == sftpCfg.Port {
+			sftpCfg.HostKey = savedCfg.HostKey
+			sftpCfg.KnownHosts = savedCfg.KnownHosts
 		}
 		cfg = &types.DatasourceSFTPConfig{
-			Host:     sftpCfg.Host,
-			Port:     sftpCfg.Port,
-			Username: sftpCfg.Username,
-			Password: sftpCfg.Password,
+			Host:       sftpCfg.Host,
+			Port:       sftpCfg.Port,
+			Username:   sftpCfg.Username,
+			Password:   sftpCfg.Password,
+			PrivateKey: sftpCfg.PrivateKey,
+			Passphrase: sftpCfg.Passphrase,
+			HostKey:    sftpCfg.HostKey,
+			KnownHosts: sftpCfg.KnownHosts,
+		}
+	case "ftp":
+		ftpCfg := request.FTPConfig
+		if ftpCfg == nil {
+			return ecode.WithErrorMessage(ecode.ErrBadRequest, errors.New("ftpConfig is required"))
+		}
+		if ftpCfg.Password == "" {
+			ftpCfg.Password = dbs.Secret
+		}
+		cfg = &types.DatasourceFTPConfig{
+			Host:               ftpCfg.Host,
+			Port:               ftpCfg.Port,
+			Username:           ftpCfg.Username,
+			Password:           ftpCfg.Password,
+			TLS:                ftpCfg.TLS,
+			InsecureSkipVerify: ftpCfg.InsecureSkipVerify,
+		}
+	case "hdfs":
+		hdfsCfg := request.HDFSConfig
+		if hdfsCfg == nil {
+			return ecode.WithErrorMessage(ecode.ErrBadRequest, errors.New("hdfsConfig is required"))
+		}
+		if hdfsCfg.Password == "" {
+			hdfsCfg.Password = dbs.Secret
+		}
+		cfg = &types.DatasourceHDFSConfig{
+			Endpoint: hdfsCfg.Endpoint,
+			Address:  hdfsCfg.Address,
+			User:     hdfsCfg.User,
+			Auth:     hdfsCfg.Auth,
+			Realm:    hdfsCfg.Realm,
+			KDC:      hdfsCfg.KDC,
+			SPN:      hdfsCfg.SPN,
+			Password: hdfsCfg.Password,
+		}
+	case "azure":
+		azureCfg := request.AzureConfig
+		if azureCfg == nil {
+			return ecode.WithErrorMessage(ecode.ErrBadRequest, errors.New("azureConfig is required"))
+		}
+		if azureCfg.AccountKey == "" {
+			azureCfg.AccountKey = dbs.Secret
+		}
+		cfg = &types.DatasourceAzureConfig{
+			Endpoint:    azureCfg.Endpoint,
+			AccountName: azureCfg.AccountName,
+			Container:   azureCfg.Container,
+			AccountKey:  azureCfg.AccountKey,
+		}
+	case "gcs":
+		gcsCfg := request.GCSConfig
+		if gcsCfg == nil {
+			return ecode.WithErrorMessage(ecode.ErrBadRequest, errors.New("gcsConfig is required"))
+		}
+		if gcsCfg.Credentials == "" {
+			gcsCfg.Credentials = dbs.Secret
+		}
+		cfg = &types.DatasourceGCSConfig{
+			Endpoint:    gcsCfg.Endpoint,
+			Bucket:      gcsCfg.Bucket,
+			Credentials: gcsCfg.Credentials,
+		}
+	case "sql":
+		sqlCfg := request.SQLConfig
+		if sqlCfg == nil {
+			return ecode.WithErrorMessage(ecode.ErrBadRequest, errors.New("sqlConfig is required"))
+		}
+		// keep the saved password unless the dsn has a new one
+		if sqlCfg.Password == "" {
+			if _, password, _ := filestore.SplitSqlPassword(sqlCfg.Driver, sqlCfg.DSN); password == "" {
+				sqlCfg.Password = dbs.Secret
+			}
+		}
+		cfg = &types.DatasourceSQLConfig{
+			Driver:   sqlCfg.Driver,
+			DSN:      sqlCfg.DSN,
+			Password: sqlCfg.Password,
 		}
 	default:
 		return ecode.WithErrorMessage(ecode.ErrBadRequest, nil, "Invalid datasource type")
 	}
+	if err := d.checkSqlite(cfg); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
 	cfgStr, crypto, err := validate(typ, platform, cfg)
 	if err != nil {
 		return ecode.WithErrorMessage(ecode.ErrBadRequest, err)
@@ -155,6 +270,36 @@ func (d *datasourceService) List(request types.DatasourceListRequest) (*types.Da
 			if err := json.Unmarshal([]byte(jsonConfig), &config.SFTPConfig); err != nil {
 				return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "parse json failed")
 			}
+		case "ftp":
+			config.FTPConfig = &types.DatasourceFTPConfig{}
+			jsonConfig := item.Config
+			if err := json.Unmarshal([]byte(jsonConfig), &config.FTPConfig); err != nil {
+				return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "parse json failed")
+			}
+		case "hdfs":
+			config.HDFSConfig = &types.DatasourceHDFSConfig{}
+			jsonConfig := item.Config
+			if err := json.Unmarshal([]byte(jsonConfig), &config.HDFSConfig); err != nil {
+				return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "parse json failed")
+			}
+		case "azure":
+			config.AzureConfig = &types.DatasourceAzureConfig{}
+			jsonConfig := item.Config
+			if err := json.Unmarshal([]byte(jsonConfig), &config.AzureConfig); err != nil {
+				return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "parse json failed")
+			}
+		case "gcs":
+			config.GCSConfig = &types.DatasourceGCSConfig{}
+			jsonConfig := item.Config
+			if err := json.Unmarshal([]byte(jsonConfig), &config.GCSConfig); err != nil {
+				return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "parse json failed")
+			}
+		case "sql":
+			config.SQLConfig = &types.DatasourceSQLConfig{}
+			jsonConfig := item.Config
+			if err := json.Unmarshal([]byte(jsonConfig), &config.SQLConfig); err != nil {
+				return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "parse json failed")
+			}
 		}
 		items = append(items, config)
 	}
@@ -253,6 +398,34 @@ func (d *datasourceService) PreviewFile(request types.DatasourcePreviewFileReque
 	}, nil
 }
 
+func (d *datasourceService) PreviewQuery(request types.DatasourcePreviewQueryRequest) (*types.DatasourcePreviewQueryData, error) {
+	dbs, err := d.findOne(request.DatasourceID)
+	if err != nil {
+		return nil, err
+	}
+	if dbs.Type != "sql" {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, errors.New("only the sql datasource runs a query"))
+	}
+	store, err := d.getFileStore(dbs)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	limit := request.Limit
+	if limit <= 0 || limit > previewQueryMaxRows {
+		limit = previewQueryMaxRows
+	}
+	columns, rows, err := store.(*filestore.SqlStore).Query(request.Query, limit)
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err, "query failed")
+	}
+
+	return &types.DatasourcePreviewQueryData{
+		Columns: columns,
+		Rows:    rows,
+	}, nil
+}
+
 func (d *datasourceService) findOne(datasourceId string) (*db.Datasource, error) {
 	var dbs db.Datasource
 	result := db.CtxDB.Model(&db.Datasource{}).Where("b_id = ?", datasourceId).
@@ -317,15 +490,38 @@ func (d *datasourceService) getFileStore(dbs *db.Datasource) (filestore.FileStor
 	if err := json.Unmarshal([]byte(dbs.Config), &config); err != nil {
 		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "parse the datasource config error")
 	}
+	if dbs.Type == "sql" {
+		var c filestore.SqlConfig
+		if err := json.Unmarshal([]byte(dbs.Config), &c); err != nil {
+			return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "parse the datasource config error")
+		}
+		if err := importer.CheckSqlite(c.Driver, c.DSN, dbs.Host, dbs.Username); err != nil {
+			return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+		}
+	}
 	store, err := filestore.NewFileStore(dbs.Type, dbs.Config, dbs.Secret, dbs.Platform)
 	if err != nil {
 		d.Logger.Errorf("create the file store error")
 		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "create the file store error")
 	}
+	if err := importer.PinHostKey(dbs, store); err != nil {
+		store.Close()
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "pin the host key error")
+	}
 
 	return store, nil
 }
 
+// checkSqlite rejects the sqlite database files out of the sqlite dir and the uploaded files of the user
+func (d *datasourceService) checkSqlite(cfg interface{}) error {
+	sqlCfg, ok := cfg.(*types.DatasourceSQLConfig)
+	if !ok || sqlCfg == nil {
+		return nil
+	}
+	user := d.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	return importer.CheckSqlite(sqlCfg.Driver, sqlCfg.DSN, user.Address+":"+strconv.Itoa(user.Port), user.Username)
+}
+
 func formatDatasourceConfig(config interface{}, password string) (string, string, error) {
 	cfgStr, err := json.Marshal(config)
 	if err != nil {
@@ -360,7 +556,90 @@ func validate(typ string, platform string, config interface{}) (string, string,
 		return cfgStr, crypto, err
 	case "sftp":
 		cfg := config.(*types.DatasourceSFTPConfig)
-		err := validateSftp(cfg)
+		if cfg == nil {
+			return "", "", errors.New("sftpConfig is required")
+		}
+		hostKey, err := validateSftp(cfg)
+		if err != nil {
+			return "", "", err
+		}
+		// trust the host key on the first use
+		cfg.HostKey = hostKey
+		secret := &filestore.SftpSecret{
+			Password:   cfg.Password,
+			PrivateKey: cfg.PrivateKey,
+			Passphrase: cfg.Passphrase,
+		}
+		cfg.Password, cfg.PrivateKey, cfg.Passphrase = "", "", ""
+		cfgStr, crypto, err := formatDatasourceConfig(config, secret.String())
+		return cfgStr, crypto, err
+	case "ftp":
+		cfg := config.(*types.DatasourceFTPConfig)
+		if cfg == nil {
+			return "", "", errors.New("ftpConfig is required")
+		}
+		err := validateFtp(cfg)
+		if err != nil {
+			return "", "", err
+		}
+		secret := cfg.Password
+		cfg.Password = ""
+		cfgStr, crypto, err := formatDatasourceConfig(config, secret)
+		return cfgStr, crypto, err
+	case "hdfs":
+		cfg := config.(*types.DatasourceHDFSConfig)
+		if cfg == nil {
+			return "", "", errors.New("hdfsConfig is required")
+		}
+		err := validateHdfs(cfg)
+		if err != nil {
+			return "", "", err
+		}
+		secret := cfg.Password
+		cfg.Password = ""
+		cfgStr, crypto, err := formatDatasourceConfig(config, secret)
+		return cfgStr, crypto, err
+	case "azure":
+		cfg := config.(*types.DatasourceAzureConfig)
+		if cfg == nil {
+			return "", "", errors.New("azureConfig is required")
+		}
+		err := validateAzure(cfg)
+		if err != nil {
+			return "", "", err
+		}
+		secret := cfg.AccountKey
+		cfg.AccountKey = ""
+		cfgStr, crypto, err := formatDatasourceConfig(config, secret)
+		return cfgStr, crypto, err
+	case "gcs":
+		cfg := config.(*types.DatasourceGCSConfig)
+		if cfg == nil {
+			return "", "", errors.New("gcsConfig is required")
+		}
+		err := validateGcs(cfg)
+		if err != nil {
+			return "", "", err
+		}
+		secret := cfg.Credentials
+		cfg.Credentials = ""
+		cfgStr, crypto, err := formatDatasourceConfig(config, secret)
+		return cfgStr, crypto, err
+	case "sql":
+		cfg := config.(*types.DatasourceSQLConfig)
+		if cfg == nil {
+			return "", "", errors.New("sqlConfig is required")
+		}
+		// the password is stored encrypted apart from the dsn
+		dsn, password, err := filestore.SplitSqlPassword(cfg.Driver, cfg.DSN)
+		if err != nil {
+			return "", "", err
+		}
+		cfg.DSN = dsn
+		if cfg.Password == "" {
+			cfg.Password = password
+		}
+		err = validateSql(cfg)
 		if err != nil {
 			return "", "", err
 		}
@@ -373,15 +652,66 @@ func validate(typ string, platform string, config interface{}) (string, string,
 	}
 }
 
-func validateSftp(cfg *types.DatasourceSFTPConfig) error {
-	store, err := filestore.NewSftpStore(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+// validateSftp returns the fingerprint of the host key
+func validateSftp(cfg *types.DatasourceSFTPConfig) (string, error) {
+	store, err := filestore.NewSftpStoreWithOptions(&filestore.SftpOptions{
+		Host:       cfg.Host,
+		Port:       cfg.Port,
+		Username:   cfg.Username,
+		Password:   cfg.Password,
+		PrivateKey: cfg.PrivateKey,
+		Passphrase: cfg.Passphrase,
+		HostKey:    cfg.HostKey,
+		KnownHosts: cfg.KnownHosts,
+	})
+	if err != nil {
+		return "", fmt.Errorf("connect the sftp client error: %s", err)
+	}
+	store.Close()
+	return store.HostKey, nil
+}
+
+func validateFtp(cfg *types.DatasourceFTPConfig) error {
+	store, err := filestore.NewFtpStore(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.TLS, cfg.InsecureSkipVerify)
 	if err != nil {
-		return fmt.Errorf("connect the sftp client error: %s", err)
+		return fmt.Errorf("connect the ftp client error: %s", err)
 	}
-	store.SftpClient.Close()
+	store.Close()
 	return nil
 }
 
+func validateHdfs(cfg *types.DatasourceHDFSConfig) error {
+	_, err := filestore.NewHdfsStore(cfg.Endpoint, cfg.User, cfg.Password, cfg.Auth, cfg.Realm, cfg.KDC, cfg.SPN)
+	if err != nil {
+		return fmt.Errorf("connect the webhdfs error: %s", err)
+	}
+	return nil
+}
+
+func validateAzure(cfg *types.DatasourceAzureConfig) error {
+	_, err := filestore.NewAzureStore(cfg.Endpoint, cfg.AccountName, cfg.AccountKey, cfg.Container)
+	if err != nil {
+		return fmt.Errorf("connect the azure blob storage error: %s", err)
+	}
+	return nil
+}
+
+func validateGcs(cfg *types.DatasourceGCSConfig) error {
+	_, err := filestore.NewGcsStore(cfg.Endpoint, cfg.Bucket, cfg.Credentials)
+	if err != nil {
+		return fmt.Errorf("connect the google cloud storage error: %s", err)
+	}
+	return nil
+}
+
+func validateSql(cfg *types.DatasourceSQLConfig) error {
+	store, err := filestore.NewSqlStore(cfg.Driver, cfg.DSN, cfg.Password)
+	if err != nil {
+		return fmt.Errorf("connect the database error: %s", err)
+	}
+	return store.Close()
+}
+
 func validateS3(platform string, cfg *types.DatasourceS3Config) error {
 	_, err := filestore.NewS3Store(platform, cfg.Endpoint, cfg.Region, cfg.Bucket, cfg.AccessKeyID, cfg.AccessSecret)
 	if err != nil {
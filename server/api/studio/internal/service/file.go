@@ -3,27 +3,35 @@ package service
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"io/fs"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
 
 	"github.com/axgle/mahonia"
 	"github.com/saintfish/chardet"
 	"github.com/vesoft-inc/go-pkg/middleware"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service/storage"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/filestore"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/lease"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
 	"github.com/zeromicro/go-zero/core/logx"
 	"go.uber.org/zap"
@@ -32,11 +40,16 @@ import (
 
 const (
 	defaultMulipartMemory = 500 << 20 // 500 MB
+	// the lines of a file sampled for the preview
+	fileSampleLines = 5
 )
 
 var (
 	errNoCharset             = errors.New("this charset can not be changed")
 	_            FileService = (*fileService)(nil)
+
+	// the members of the uploaded archives, keyed by the name, the size and the modification time of the archive
+	archiveMembersCache sync.Map
 )
 
 type fileConfig struct {
@@ -51,6 +64,13 @@ type (
 		FileDestroy(request types.FileDestroyRequest) error
 		FilesIndex() (*types.FilesIndexData, error)
 		FileConfigUpdate(request types.FileConfigUpdateRequest) error
+		FileUploadInit(request types.FileUploadInitRequest) (*types.FileUploadData, error)
+		GetFileUpload(request types.FileUploadRequest) (*types.FileUploadData, error)
+		FileUploadChunk(request types.FileUploadChunkRequest) (*types.FileUploadData, error)
+		FileUploadComplete(request types.FileUploadCompleteRequest) error
+		FileUploadAbort(request types.FileUploadRequest) error
+		FileFolderCreate(request types.FileFolderCreateRequest) error
+		FileMove(request types.FileMoveRequest) error
 	}
 
 	fileService struct {
@@ -70,30 +90,62 @@ func NewFileService(ctx context.Context, svcCtx *svc.ServiceContext) FileService
 	}
 }
 
+// namespace returns the dir of the files uploaded by the user, the nebula host and the user
+func (f *fileService) namespace() (string, string, *auth.AuthData) {
+	user := f.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	host := user.Address + ":" + strconv.Itoa(user.Port)
+	return utils.UserUploadDir(f.svcCtx.Config.File.UploadDir, host, user.Username), host, user
+}
+
+// resolveFilePath resolves a path in the namespace of the user into the cleaned path and the file path
+func resolveFilePath(dir, p string) (string, string, error) {
+	name, err := utils.CleanUploadPath(p)
+	if err != nil {
+		return "", "", ecode.WithErrorMessage(ecode.ErrInvalidParameter, err, "invalid path %s", p)
+	}
+	return name, filepath.Join(dir, filepath.FromSlash(name)), nil
+}
+
+// ownedFiles finds the file configs of the user at the path, and in the folder or the archive at the path
+func ownedFiles(host, username, name string) ([]db.File, error) {
+	var files []db.File
+	if err := db.CtxDB.Where("host = ? AND username = ?", host, username).Find(&files).Error; err != nil {
+		return nil, err
+	}
+	owned := files[:0]
+	for _, file := range files {
+		if file.Name == name || strings.HasPrefix(file.Name, name+"/") {
+			owned = append(owned, file)
+		}
+	}
+	return owned, nil
+}
+
 func (f *fileService) FileDestroy(request types.FileDestroyRequest) error {
-	dir := f.svcCtx.Config.File.UploadDir
-	for _, name := range request.Names {
-		target := filepath.Join(dir, name)
+	dir, host, user := f.namespace()
+	for _, p := range request.Names {
+		name, target, err := resolveFilePath(dir, p)
+		if err != nil {
+			return err
+		}
 		if _, err := os.Stat(target); err != nil {
 			logx.Infof("del file error %v", err)
 			return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
 		}
-		//	if target is directory, it is not empty
-		if err := os.Remove(target); err != nil {
+		// a folder is removed with its files
+		if err := os.RemoveAll(target); err != nil {
 			logx.Infof("del file error %v", err)
 			return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
 		}
 		// delete db record
-		var file db.File
-		result := db.CtxDB.Where("name = ?", name).First(&file)
-		if result.Error == gorm.ErrRecordNotFound {
-			continue
-		} else if result.Error != nil {
-			return f.gormErrorWrapper(result.Error)
+		files, err := ownedFiles(host, user.Username, name)
+		if err != nil {
+			return f.gormErrorWrapper(err)
 		}
-		result = db.CtxDB.Delete(&file)
-		if result.Error != nil {
-			return f.gormErrorWrapper(result.Error)
+		for i := range files {
+			if result := db.CtxDB.Delete(&files[i]); result.Error != nil {
+				return f.gormErrorWrapper(result.Error)
+			}
 		}
 	}
 	return nil
@@ -101,68 +153,156 @@ func (f *fileService) FileDestroy(request types.FileDestroyRequest) error {
 
 func (f *fileService) FilesIndex() (data *types.FilesIndexData, err error) {
 	data = &types.FilesIndexData{
-		List: []types.FileStat{},
+		List:    []types.FileStat{},
+		Folders: []string{},
 	}
-	dir := f.svcCtx.Config.File.UploadDir
-	filesInfo, err := ioutil.ReadDir(dir)
-	if err != nil {
+	dir, host, user := f.namespace()
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
 		logx.Infof("open files error %v", err)
 		return nil, err
 	}
-
-	for _, fileInfo := range filesInfo {
-		if fileInfo.IsDir() {
-			continue
+	store := filestore.WithArchives(filestore.NewLocalStore(dir))
+	err = filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if entry.IsDir() {
+			data.Folders = append(data.Folders, name)
+			return nil
+		}
+		fileInfo, err := entry.Info()
+		if err != nil || !fileInfo.Mode().IsRegular() {
+			return nil
 		}
 		var fileConfig db.File
-		result := db.CtxDB.Where("name = ?", fileInfo.Name()).First(&fileConfig)
+		result := db.CtxDB.Where("name = ? AND host = ? AND username = ?", name, host, user.Username).First(&fileConfig)
 		if result.Error != nil {
 			logx.Errorf("get file config record in db error %v", result.Error)
 			fileConfig.Delimiter = ","
 			fileConfig.WithHeader = false
 		}
-		path := filepath.Join(dir, fileInfo.Name())
-		file, err := os.Open(path)
-		if err != nil {
-			logx.Infof("open files error %v", err)
-			continue
-		}
-		reader := bufio.NewReader(file)
+		_, archive := filestore.Compression(name)
 		sample := ""
-		for count := 0; count < 5; count++ {
-			line, _, err := reader.ReadLine()
+		if archive == "" {
+			// the compressed files are sampled decompressed
+			file, err := store.(filestore.Opener).Open(name)
 			if err != nil {
-				break
+				logx.Infof("open files error %v", err)
+				return nil
 			}
-			sample += string(line) + "\r\n"
+			reader := bufio.NewReader(file)
+			for count := 0; count < fileSampleLines; count++ {
+				line, _, err := reader.ReadLine()
+				if err != nil {
+					break
+				}
+				sample += string(line) + "\r\n"
+			}
+			file.Close()
 		}
-		file.Close()
 		data.List = append(data.List, types.FileStat{
 			Sample:     sample,
-			Name:       fileInfo.Name(),
+			Name:       name,
 			Size:       fileInfo.Size(),
 			WithHeader: fileConfig.WithHeader,
 			Delimiter:  fileConfig.Delimiter,
 		})
+		if archive != "" {
+			data.List = append(data.List, archiveMemberStats(store, p, name, fileInfo, host, user.Username)...)
+		}
+		return nil
+	})
+	if err != nil {
+		logx.Infof("open files error %v", err)
+		return nil, err
 	}
 	return data, nil
 }
 
+// archiveMemberStats lists the csv files in an uploaded archive as the files named after the archive and their paths
+func archiveMemberStats(store filestore.FileStore, fullPath, name string, info fs.FileInfo, host, username string) []types.FileStat {
+	key := fmt.Sprintf("%s|%d|%d", fullPath, info.Size(), info.ModTime().UnixNano())
+	if stats, ok := archiveMembersCache.Load(key); ok {
+		return withFileConfigs(stats.([]types.FileStat), host, username)
+	}
+	members, err := filestore.ArchiveMembers(store, name, fileSampleLines)
+	if err != nil {
+		logx.Infof("list the archive %s error %v", name, err)
+		return nil
+	}
+	stats := make([]types.FileStat, 0, len(members))
+	for _, m := range members {
+		stats = append(stats, types.FileStat{
+			Sample:  strings.Join(m.Sample, "\r\n") + "\r\n",
+			Name:    name + "/" + m.Name,
+			Size:    m.Size,
+			Archive: name,
+		})
+	}
+	// the stale entries of the replaced archive are dropped
+	archiveMembersCache.Range(func(k, _ any) bool {
+		if strings.HasPrefix(k.(string), fullPath+"|") {
+			archiveMembersCache.Delete(k)
+		}
+		return true
+	})
+	archiveMembersCache.Store(key, stats)
+	return withFileConfigs(stats, host, username)
+}
+
+// withFileConfigs fills the header and the delimiter saved for each file, the stats are copied
+func withFileConfigs(stats []types.FileStat, host, username string) []types.FileStat {
+	result := make([]types.FileStat, 0, len(stats))
+	for _, stat := range stats {
+		var fileConfig db.File
+		if err := db.CtxDB.Where("name = ? AND host = ? AND username = ?", stat.Name, host, username).First(&fileConfig).Error; err != nil {
+			fileConfig.Delimiter = ","
+		}
+		stat.WithHeader, stat.Delimiter = fileConfig.WithHeader, fileConfig.Delimiter
+		result = append(result, stat)
+	}
+	return result
+}
+
 func (f *fileService) FileConfigUpdate(request types.FileConfigUpdateRequest) error {
+	dir, host, user := f.namespace()
+	name, target, err := resolveFilePath(dir, request.Name)
+	if err != nil {
+		return err
+	}
+	// the config of an archive member is saved with the path of the member
+	if archive, _, ok := filestore.SplitArchivePath(name); ok {
+		target = filepath.Join(dir, filepath.FromSlash(archive))
+	}
+	if _, err := os.Stat(target); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrNotFound, err, "file %s not found", name)
+	}
 	File := &db.File{}
-	result := db.CtxDB.Where("name = ?", request.Name).First(File)
+	result := db.CtxDB.Where("name = ? AND host = ? AND username = ?", name, host, user.Username).First(File)
 	if result.Error == gorm.ErrRecordNotFound {
 		// in case user upload file through ftp, without init file record in db
-		auth := f.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
-		host := auth.Address + ":" + strconv.Itoa(auth.Port)
 		id := f.svcCtx.IDGenerator.Generate()
 		File = &db.File{
 			BID:        id,
-			Name:       request.Name,
+			Name:       name,
 			WithHeader: request.WithHeader,
 			Delimiter:  request.Delimiter,
 			Host:       host,
-			Username:   auth.Username,
+			Username:   user.Username,
 		}
 		createResult := db.CtxDB.Create(File)
 		if createResult.Error != nil {
@@ -180,10 +320,66 @@ func (f *fileService) FileConfigUpdate(request types.FileConfigUpdateRequest) er
 	return nil
 }
 
+// FileFolderCreate creates a folder in the namespace of the user
+func (f *fileService) FileFolderCreate(request types.FileFolderCreateRequest) error {
+	dir, _, _ := f.namespace()
+	_, target, err := resolveFilePath(dir, request.Path)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(target); err == nil && !info.IsDir() {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("%s is a file", request.Path), "a file named %s exists", request.Path)
+	}
+	if err := os.MkdirAll(target, os.ModePerm); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "create the folder failed")
+	}
+	return nil
+}
+
+// FileMove renames or moves a file or a folder in the namespace of the user with the configs of its files
+func (f *fileService) FileMove(request types.FileMoveRequest) error {
+	dir, host, user := f.namespace()
+	from, source, err := resolveFilePath(dir, request.From)
+	if err != nil {
+		return err
+	}
+	to, target, err := resolveFilePath(dir, request.To)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	if strings.HasPrefix(to, from+"/") {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("move %s into itself", from), "can not move %s into itself", from)
+	}
+	if _, err := os.Stat(source); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrNotFound, err, "file %s not found", from)
+	}
+	if _, err := os.Stat(target); err == nil {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("%s exists", to), "%s already exists", to)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "move failed")
+	}
+	if err := os.Rename(source, target); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "move failed")
+	}
+	files, err := ownedFiles(host, user.Username, from)
+	if err != nil {
+		return f.gormErrorWrapper(err)
+	}
+	for _, file := range files {
+		name := to + strings.TrimPrefix(file.Name, from)
+		if result := db.CtxDB.Model(&db.File{}).Where("id = ?", file.ID).Update("name", name); result.Error != nil {
+			return f.gormErrorWrapper(result.Error)
+		}
+	}
+	return nil
+}
+
 func (f *fileService) FileUpload() error {
-	dir := f.svcCtx.Config.File.UploadDir
-	auth := f.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
-	host := auth.Address + ":" + strconv.Itoa(auth.Port)
+	dir, host, auth := f.namespace()
 	_, err := os.Stat(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -197,6 +393,9 @@ func (f *fileService) FileUpload() error {
 	if !ok {
 		return ecode.WithErrorMessage(ecode.ErrInternalServer, fmt.Errorf("unset KeepRequest"), "upload failed")
 	}
+	if err := storage.CheckQuota(host, auth.Username, httpReq.ContentLength); err != nil {
+		return err
+	}
 
 	files, _, err := f.UploadFormFiles(httpReq, dir, auth, host)
 	if err != nil {
@@ -205,9 +404,8 @@ func (f *fileService) FileUpload() error {
 	}
 	for _, file := range files {
 		// 检查文件后缀
-		ext := strings.ToLower(filepath.Ext(file.Filename))
-		if ext != ".txt" && ext != ".csv" {
-			return ecode.WithErrorMessage(ecode.ErrInvalidParameter, fmt.Errorf("unsupported file type: %s", ext), "Only .txt and .csv files are supported")
+		if err := checkFileType(file.Filename); err != nil {
+			return err
 		}
 		if file.Size == 0 || file.Header.Get("Content-Type") != "text/csv" {
 			continue
@@ -250,21 +448,31 @@ func (f *fileService) UploadFormFiles(r *http.Request, destDirectory string, aut
 				configMap[cfg.Name] = cfg
 			}
 		}
+		// the files are uploaded into a folder of the namespace optionally
+		folder := r.FormValue("dir")
 		if fhs := r.MultipartForm.File; fhs != nil {
 			for _, files := range fhs {
 				for _, file := range files {
+					name, err := utils.CleanUploadPath(folder + "/" + file.Filename)
+					if err != nil {
+						return nil, 0, err
+					}
 					// save file config in db
 					if _, ok := configMap[file.Filename]; ok {
 						_cfg := configMap[file.Filename]
+						_cfg.Name = name
 						err := f.SaveFileConfig(auth, host, _cfg)
 						if err != nil {
 							return nil, 0, err
 						}
 					}
-					file.Filename = strings.ReplaceAll(file.Filename, "../", "")
-					file.Filename = strings.ReplaceAll(file.Filename, "..\\", "")
+					file.Filename = name
+					dest := filepath.Join(destDirectory, filepath.FromSlash(name))
+					if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+						return nil, 0, err
+					}
 
-					n0, err0 := SaveFormFile(file, filepath.Join(destDirectory, file.Filename))
+					n0, err0 := SaveFormFile(file, dest)
 					if err0 != nil {
 						return nil, 0, err0
 					}
@@ -281,7 +489,7 @@ func (f *fileService) UploadFormFiles(r *http.Request, destDirectory string, aut
 
 func (f *fileService) SaveFileConfig(auth *auth.AuthData, host string, config fileConfig) error {
 	File := &db.File{}
-	result := db.CtxDB.Where("name = ?", config.Name).First(File)
+	result := db.CtxDB.Where("name = ? AND host = ? AND username = ?", config.Name, host, auth.Username).First(File)
 	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
 		return ecode.WithErrorMessage(ecode.ErrInternalDatabase, result.Error)
 	}
@@ -299,7 +507,7 @@ func (f *fileService) SaveFileConfig(auth *auth.AuthData, host string, config fi
 			return ecode.WithErrorMessage(ecode.ErrInternalDatabase, result.Error)
 		}
 	} else {
-		result = db.CtxDB.Model(&db.File{}).Where("name = ?", config.Name).Updates(map[string]interface{}{"with_header": config.WithHeader, "delimiter": config.Delimiter, "host": host})
+		result = db.CtxDB.Model(File).Updates(map[string]interface{}{"with_header": config.WithHeader, "delimiter": config.Delimiter})
 		if result.Error != nil {
 			return ecode.WithErrorMessage(ecode.ErrInternalDatabase, result.Error)
 		}
@@ -324,14 +532,31 @@ func SaveFormFile(fh *multipart.FileHeader, dest string) (int64, error) {
 	return io.Copy(out, src)
 }
 
+// checkFileType checks the file is a txt or a csv file, a compressed one, or an archive
+func checkFileType(name string) error {
+	if _, archive := filestore.Compression(name); archive != "" {
+		return nil
+	}
+	ext := strings.ToLower(filepath.Ext(filestore.DecompressedName(name)))
+	if ext != ".txt" && ext != ".csv" {
+		return ecode.WithErrorMessage(ecode.ErrInvalidParameter, fmt.Errorf("unsupported file type: %s", ext), "Only .txt and .csv files, their .gz and .bz2 files and the .zip, .tar, .tar.gz and .tar.bz2 archives are supported")
+	}
+	return nil
+}
+
 func checkCharset(file *multipart.FileHeader) (string, error) {
 	f, err := file.Open()
 	if err != nil {
 		return "", err
 	}
 	defer f.Close()
+	return detectCharset(f)
+}
+
+// detectCharset detects the charset by the first 1024 bytes
+func detectCharset(r io.Reader) (string, error) {
 	bytes := make([]byte, 1024)
-	if _, err = f.Read(bytes); err != nil {
+	if _, err := r.Read(bytes); err != nil {
 		return "", err
 	}
 	detector := chardet.NewTextDetector()
@@ -387,3 +612,365 @@ func changeFileCharset2UTF8(filePath string, charSet string) error {
 	}
 	return nil
 }
+
+/*
+A multi-GB file is uploaded in chunks which survive the network failures and the restarts of the server:
+the upload is started by FileUploadInit, the chunks are appended in order by FileUploadChunk to a part file
+in the upload dir, and the received offset, which is the size of the part file, tells where to resume.
+FileUploadComplete verifies the sha256 checksum of the whole file and moves it into the namespace of the user.
+*/
+const (
+	// the suggested size of a chunk
+	uploadChunkSize = 8 << 20 // 8 MB
+	// the unfinished uploads untouched for longer are removed
+	uploadExpiration = 7 * 24 * time.Hour
+	// the directory of the part files in the upload dir
+	uploadPartDir = ".uploads"
+)
+
+// the locks of the unfinished uploads in this instance
+var uploadLocks sync.Map
+
+/*
+lockUpload makes a part file written by one request at a time, it locks the upload in this instance
+and leases it from the other instances sharing the upload dir. It fails while another instance writes the upload.
+*/
+func lockUpload(id string) (func(), error) {
+	v, _ := uploadLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	ok, err := lease.Claim(lease.KindUpload, id)
+	if err != nil {
+		mu.Unlock()
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+	}
+	if !ok {
+		mu.Unlock()
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("upload %s is locked", id), "the upload is being written by another request, retry later")
+	}
+	return func() {
+		if err := lease.Release(lease.KindUpload, id); err != nil {
+			logx.Errorf("release the upload %s error: %s", id, err)
+		}
+		mu.Unlock()
+	}, nil
+}
+
+func (f *fileService) uploadPartPath(id string) string {
+	return filepath.Join(f.svcCtx.Config.File.UploadDir, uploadPartDir, id+".part")
+}
+
+// uploadOffset returns the bytes received by an upload
+func (f *fileService) uploadOffset(id string) (int64, error) {
+	info, err := os.Stat(f.uploadPartPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (f *fileService) uploadData(upload *db.FileUpload) (*types.FileUploadData, error) {
+	offset, err := f.uploadOffset(upload.BID)
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+	}
+	return &types.FileUploadData{
+		Id:        upload.BID,
+		Name:      upload.Name,
+		Size:      upload.Size,
+		Offset:    offset,
+		ChunkSize: uploadChunkSize,
+	}, nil
+}
+
+// getUpload gets an unfinished upload of the user
+func (f *fileService) getUpload(id string) (*db.FileUpload, error) {
+	auth := f.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	host := auth.Address + ":" + strconv.Itoa(auth.Port)
+	upload := &db.FileUpload{}
+	result := db.CtxDB.Where("b_id = ? AND host = ? AND username = ?", id, host, auth.Username).Limit(1).Find(upload)
+	if result.Error != nil {
+		return nil, f.gormErrorWrapper(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ecode.WithErrorMessage(ecode.ErrNotFound, fmt.Errorf("upload %s not found", id), "the upload is not found or has been finished")
+	}
+	return upload, nil
+}
+
+func (f *fileService) removeUpload(id string) error {
+	if err := os.Remove(f.uploadPartPath(id)); err != nil && !os.IsNotExist(err) {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "remove the upload failed")
+	}
+	if result := db.CtxDB.Where("b_id = ?", id).Delete(&db.FileUpload{}); result.Error != nil {
+		return f.gormErrorWrapper(result.Error)
+	}
+	uploadLocks.Delete(id)
+	return nil
+}
+
+// removeExpiredUploads removes the part files of the unfinished uploads of all the users untouched for long
+func (f *fileService) removeExpiredUploads() {
+	var uploads []db.FileUpload
+	if err := db.CtxDB.Where("update_time < ?", time.Now().Add(-uploadExpiration)).Find(&uploads).Error; err != nil {
+		f.Errorf("find the expired uploads error: %s", err)
+		return
+	}
+	for _, upload := range uploads {
+		if err := f.removeUpload(upload.BID); err != nil {
+			f.Errorf("remove the expired upload %s error: %s", upload.BID, err)
+		}
+	}
+}
+
+// FileUploadInit starts an upload, or resumes the unfinished upload of the same file by the user
+func (f *fileService) FileUploadInit(request types.FileUploadInitRequest) (*types.FileUploadData, error) {
+	dir, host, auth := f.namespace()
+	// the name is the path of the file in the namespace, which can be in a folder
+	name, _, err := resolveFilePath(dir, request.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFileType(name); err != nil {
+		return nil, err
+	}
+	delimiter := request.Delimiter
+	if delimiter == "" {
+		delimiter = ","
+	}
+	f.removeExpiredUploads()
+	if err := os.MkdirAll(filepath.Join(f.svcCtx.Config.File.UploadDir, uploadPartDir), os.ModePerm); err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+	}
+
+	upload := &db.FileUpload{}
+	result := db.CtxDB.Where("name = ? AND size = ? AND host = ? AND username = ?", name, request.Size, host, auth.Username).
+		Order("update_time desc").Limit(1).Find(upload)
+	if result.Error != nil {
+		return nil, f.gormErrorWrapper(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// the bytes of a resumed upload are checked when it starts
+		if err := storage.CheckQuota(host, auth.Username, request.Size); err != nil {
+			return nil, err
+		}
+		upload = &db.FileUpload{
+			BID:        f.svcCtx.IDGenerator.Generate(),
+			Name:       name,
+			Size:       request.Size,
+			WithHeader: request.WithHeader,
+			Delimiter:  delimiter,
+			Host:       host,
+			Username:   auth.Username,
+		}
+		result = db.CtxDB.Create(upload)
+	} else {
+		upload.WithHeader, upload.Delimiter = request.WithHeader, delimiter
+		result = db.CtxDB.Model(upload).Updates(map[string]interface{}{"with_header": upload.WithHeader, "delimiter": upload.Delimiter})
+	}
+	if result.Error != nil {
+		return nil, f.gormErrorWrapper(result.Error)
+	}
+	return f.uploadData(upload)
+}
+
+func (f *fileService) GetFileUpload(request types.FileUploadRequest) (*types.FileUploadData, error) {
+	upload, err := f.getUpload(request.Id)
+	if err != nil {
+		return nil, err
+	}
+	return f.uploadData(upload)
+}
+
+// FileUploadChunk appends the request body to the part file, the chunk must start at the received offset
+func (f *fileService) FileUploadChunk(request types.FileUploadChunkRequest) (*types.FileUploadData, error) {
+	// an upload is locked before found, the requests waiting for a finished upload find nothing
+	unlock, err := lockUpload(request.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	upload, err := f.getUpload(request.Id)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, ok := middleware.GetRequest(f.ctx)
+	if !ok {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, fmt.Errorf("unset KeepRequest"), "upload failed")
+	}
+
+	file, err := os.OpenFile(f.uploadPartPath(upload.BID), os.O_WRONLY|os.O_CREATE, 0o666)
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+	}
+	offset := info.Size()
+	if request.Offset != offset {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("unexpected offset %d", request.Offset), "the chunk starts at %d but %d bytes are received, resume from %d", request.Offset, offset, offset)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+	}
+	// the bytes written before a broken connection are kept to resume from
+	n, err := io.Copy(file, io.LimitReader(httpReq.Body, upload.Size-offset))
+	db.CtxDB.Model(&db.FileUpload{}).Where("b_id = ?", upload.BID).Update("update_time", time.Now())
+	if err != nil {
+		logx.Infof("upload chunk of %s error: %v", upload.Name, err)
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed, %d bytes are received", offset+n)
+	}
+	if extra, _ := httpReq.Body.Read(make([]byte, 1)); extra > 0 {
+		// the chunk is dropped
+		file.Truncate(offset)
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("chunk beyond the size"), "the chunk exceeds the size %d of the file", upload.Size)
+	}
+	data, err := f.uploadData(upload)
+	if err != nil {
+		return nil, err
+	}
+	data.Offset = offset + n
+	return data, nil
+}
+
+// FileUploadComplete verifies the checksum of the received file, moves it into the upload dir and saves its config
+func (f *fileService) FileUploadComplete(request types.FileUploadCompleteRequest) error {
+	unlock, err := lockUpload(request.Id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	upload, err := f.getUpload(request.Id)
+	if err != nil {
+		return err
+	}
+
+	offset, err := f.uploadOffset(upload.BID)
+	if err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+	}
+	if offset != upload.Size {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("incomplete upload"), "%d of %d bytes are received", offset, upload.Size)
+	}
+	part := f.uploadPartPath(upload.BID)
+	if offset == 0 {
+		if err := os.WriteFile(part, nil, 0o666); err != nil {
+			return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+		}
+	}
+	checksum, err := fileChecksum(part)
+	if err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+	}
+	if !strings.EqualFold(checksum, strings.TrimPrefix(strings.TrimSpace(request.Checksum), "sha256:")) {
+		// the received bytes are corrupted, the file is uploaded again from the beginning
+		os.Remove(part)
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("checksum mismatch"), "the checksum of the received file is %s, upload the file again", checksum)
+	}
+
+	dir, host, auth := f.namespace()
+	_, dest, err := resolveFilePath(dir, upload.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+	}
+	if err := os.Rename(part, dest); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+	}
+	if compression, archive := filestore.Compression(upload.Name); compression == "" && archive == "" && upload.Size > 0 {
+		file, err := os.Open(dest)
+		if err != nil {
+			return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+		}
+		charSet, err := detectCharset(file)
+		file.Close()
+		if err != nil {
+			logx.Infof("upload file error, check charset fail:%v", err)
+			return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "upload failed")
+		}
+		if charSet != "UTF-8" {
+			if err = changeFileCharset2UTF8(dest, charSet); err != nil {
+				logx.Infof("upload file error:%v", err)
+				return err
+			}
+		}
+	}
+
+	if err := f.SaveFileConfig(auth, host, fileConfig{Name: upload.Name, WithHeader: upload.WithHeader, Delimiter: upload.Delimiter}); err != nil {
+		return err
+	}
+	logx.Infof("upload file %s of %d bytes in chunks", upload.Name, upload.Size)
+	return f.removeUpload(upload.BID)
+}
+
+func (f *fileService) FileUploadAbort(request types.FileUploadRequest) error {
+	unlock, err := lockUpload(request.Id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	upload, err := f.getUpload(request.Id)
+	if err != nil {
+		return err
+	}
+	return f.removeUpload(upload.BID)
+}
+
+// fileChecksum returns the hex sha256 checksum of a file
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+/*
+MigrateUploadNamespaces moves the files uploaded before the namespaces, which are in the upload dir directly,
+into the namespaces of their owners recorded with their configs. The files of no owner are left where they are.
+*/
+func MigrateUploadNamespaces(uploadDir string) {
+	entries, err := os.ReadDir(uploadDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		name := entry.Name()
+		var file db.File
+		// the file itself, or a member of the archive
+		result := db.CtxDB.Where("name = ? OR SUBSTR(name, 1, ?) = ?", name, utf8.RuneCountInString(name)+1, name+"/").Limit(1).Find(&file)
+		if result.Error != nil || result.RowsAffected == 0 {
+			logx.Infof("the uploaded file %s has no owner, leave it in the upload dir", name)
+			continue
+		}
+		dir := utils.UserUploadDir(uploadDir, file.Host, file.Username)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			logx.Errorf("move the uploaded file %s error: %s", name, err)
+			continue
+		}
+		dest := filepath.Join(dir, name)
+		if _, err := os.Stat(dest); err == nil {
+			continue
+		}
+		if err := os.Rename(filepath.Join(uploadDir, name), dest); err != nil {
+			logx.Errorf("move the uploaded file %s error: %s", name, err)
+			continue
+		}
+		logx.Infof("move the uploaded file %s into the namespace of %s of %s", name, file.Username, file.Host)
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vesoft-inc/go-pkg/middleware"
+	studioConfig "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/idx"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/lease"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testUser = &auth.AuthData{Address: "127.0.0.1", Port: 9669, Username: "root"}
+
+func setupUploadService(t *testing.T) *svc.ServiceContext {
+	c := &studioConfig.Config{}
+	c.File.UploadDir = filepath.Join(t.TempDir(), "upload")
+	c.File.TasksDir = filepath.Join(t.TempDir(), "tasks")
+	if err := c.InitConfig(); err != nil {
+		t.Fatal(err)
+	}
+	d, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection opens a new memory database
+	sqlDB, err := d.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := d.AutoMigrate(&db.File{}, &db.FileUpload{}, &db.JobLease{}); err != nil {
+		t.Fatal(err)
+	}
+	db.CtxDB = d
+	return &svc.ServiceContext{Config: *c, IDGenerator: idx.New()}
+}
+
+// uploadChunk sends the chunk as the body of a request, the way the handler keeps it for the service
+func uploadChunk(svcCtx *svc.ServiceContext, id string, offset int64, chunk string) (data *types.FileUploadData, err error) {
+	handler := middleware.ReserveRequest(middleware.ReserveRequestConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), auth.CtxKeyUserInfo{}, testUser)
+		data, err = NewFileService(ctx, svcCtx).FileUploadChunk(types.FileUploadChunkRequest{Id: id, Offset: offset})
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/files/upload/"+id, bytes.NewBufferString(chunk)))
+	return data, err
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFileUploadChunks(t *testing.T) {
+	svcCtx := setupUploadService(t)
+	f := NewFileService(context.WithValue(context.Background(), auth.CtxKeyUserInfo{}, testUser), svcCtx)
+	content := "id,name\np1,Tom\np2,Jerry\n"
+	upload, err := f.FileUploadInit(types.FileUploadInitRequest{Name: "data/player.csv", Size: int64(len(content)), WithHeader: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upload.Offset != 0 || upload.Size != int64(len(content)) {
+		t.Fatalf("unexpected upload: %+v", upload)
+	}
+	part := filepath.Join(svcCtx.Config.File.UploadDir, uploadPartDir, upload.Id+".part")
+	offset := func() int64 {
+		data, err := f.GetFileUpload(types.FileUploadRequest{Id: upload.Id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data.Offset
+	}
+
+	data, err := uploadChunk(svcCtx, upload.Id, 0, content[:8])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Offset != 8 {
+		t.Errorf("unexpected offset after the first chunk: %d", data.Offset)
+	}
+
+	// the chunk is sent again after a lost response
+	if _, err := uploadChunk(svcCtx, upload.Id, 0, content[:8]); err == nil {
+		t.Error("expect an error of the offset mismatch")
+	}
+	if got := offset(); got != 8 {
+		t.Errorf("unexpected offset after the offset mismatch: %d", got)
+	}
+
+	// the chunk beyond the size is dropped
+	if _, err := uploadChunk(svcCtx, upload.Id, 8, content[8:]+"p3,Spike\n"); err == nil {
+		t.Error("expect an error of the chunk beyond the size")
+	}
+	if got := offset(); got != 8 {
+		t.Errorf("unexpected offset after the chunk beyond the size: %d", got)
+	}
+
+	// another instance is writing the upload
+	other := &db.JobLease{Kind: lease.KindUpload, JobID: upload.Id, Owner: "other", ExpireTime: time.Now().Add(time.Minute)}
+	if err := db.CtxDB.Create(other).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uploadChunk(svcCtx, upload.Id, 8, content[8:]); err == nil {
+		t.Error("expect an error of the upload leased by another instance")
+	}
+	if err := db.CtxDB.Delete(other).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := uploadChunk(svcCtx, upload.Id, 8, content[8:]); err != nil {
+		t.Fatal(err)
+	}
+	if l, err := lease.Get(lease.KindUpload, upload.Id); err != nil || l != nil {
+		t.Errorf("expect the upload released, got %v: %v", l, err)
+	}
+
+	// the corrupted file is uploaded again from the beginning
+	if err := f.FileUploadComplete(types.FileUploadCompleteRequest{Id: upload.Id, Checksum: checksum("corrupted")}); err == nil {
+		t.Error("expect an error of the checksum mismatch")
+	}
+	if _, err := os.Stat(part); !os.IsNotExist(err) {
+		t.Errorf("expect the part file removed: %v", err)
+	}
+	if got := offset(); got != 0 {
+		t.Errorf("unexpected offset after the checksum mismatch: %d", got)
+	}
+
+	if _, err := uploadChunk(svcCtx, upload.Id, 0, content); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.FileUploadComplete(types.FileUploadCompleteRequest{Id: upload.Id, Checksum: "sha256:" + checksum(content)}); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(utils.UserUploadDir(svcCtx.Config.File.UploadDir, "127.0.0.1:9669", "root"), "data", "player.csv")
+	saved, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != content {
+		t.Errorf("unexpected uploaded file: %q", saved)
+	}
+	var file db.File
+	if err := db.CtxDB.Where("name = ? AND host = ? AND username = ?", "data/player.csv", "127.0.0.1:9669", "root").First(&file).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !file.WithHeader || file.Delimiter != "," {
+		t.Errorf("unexpected file config: %+v", file)
+	}
+	if _, err := f.GetFileUpload(types.FileUploadRequest{Id: upload.Id}); err == nil {
+		t.Error("expect the upload finished")
+	}
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
@@ -18,12 +21,15 @@ import (
 	"github.com/vesoft-inc/go-pkg/middleware"
 	"github.com/vesoft-inc/nebula-importer/v4/pkg/config"
 	configv3 "github.com/vesoft-inc/nebula-importer/v4/pkg/config/v3"
+	importerSource "github.com/vesoft-inc/nebula-importer/v4/pkg/source"
 	studioConfig "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service/importer"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service/storage"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/filestore"
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
@@ -34,15 +40,19 @@ var (
 
 const (
 	importLogName = "import.log"
-	errContentDir = "err"
+	// the rows sampled from each source to validate a task
+	validateSampleRows = 100
 )
 
 type (
 	ImportService interface {
 		CreateImportTask(*types.CreateImportTaskRequest) (*types.CreateImportTaskData, error)
+		ValidateImportTask(*types.CreateImportTaskRequest) (*types.ValidateImportTaskData, error)
+		SuggestImportMapping(*types.SuggestImportMappingRequest) (*types.SuggestImportMappingData, error)
 		CreateTaskDraft(*types.CreateTaskDraftRequest) error
 		UpdateTaskDraft(*types.UpdateTaskDraftRequest) error
 		StopImportTask(request *types.StopImportTaskRequest) error
+		RetryImportTask(request *types.RetryImportTaskRequest) (*types.CreateImportTaskData, error)
 		DownloadConfig(*types.DownloadConfigsRequest) error
 		DownloadLogs(request *types.DownloadLogsRequest) error
 		DeleteImportTask(*types.DeleteImportTaskRequest) error
@@ -128,26 +138,145 @@ func (i *importService) updateDatasourceConfig(conf *types.CreateImportTaskReque
 						source.S3.Region = "us-east-1"
 					}
 				}
-			case "sftp":
-				sftpConfig := &types.DatasourceSFTPConfig{}
+			case "ftp":
+				ftpConfig := &types.DatasourceFTPConfig{}
 				jsonConfig := dbs.Config
-				if err := json.Unmarshal([]byte(jsonConfig), sftpConfig); err != nil {
+				if err := json.Unmarshal([]byte(jsonConfig), ftpConfig); err != nil {
 					return nil, ecode.WithInternalServer(err, "get datasource config failed")
 				}
-				source.SFTP = &types.SFTPConfig{
-					Host:     sftpConfig.Host,
-					Port:     sftpConfig.Port,
-					User:     sftpConfig.Username,
+				if ftpConfig.Username == "" {
+					ftpConfig.Username = "anonymous"
+				}
+				source.FTP = &types.FTPConfig{
+					Host:     ftpConfig.Host,
+					Port:     ftpConfig.Port,
+					User:     ftpConfig.Username,
 					Password: string(secret),
 					Path:     *source.DatasourceFilePath,
 				}
+			case "hdfs":
+				hdfsConfig := &types.DatasourceHDFSConfig{}
+				jsonConfig := dbs.Config
+				if err := json.Unmarshal([]byte(jsonConfig), hdfsConfig); err != nil {
+					return nil, ecode.WithInternalServer(err, "get datasource config failed")
+				}
+				source.HDFS = &types.HDFSConfig{
+					Address: hdfsConfig.Address,
+					User:    hdfsConfig.User,
+					Path:    *source.DatasourceFilePath,
+				}
+			case "sql":
+				if source.DatasourceQuery == nil {
+					return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, errors.New("datasourceQuery is required"))
+				}
+				// the csv of the query result starts with the column names
+				withHeader := true
+				source.CSV.WithHeader = &withHeader
 			}
 		}
 	}
 	return &config, nil
 }
 
-func updateConfig(conf config.Configurator, taskDir, uploadDir string) {
+/*
+studioSources takes the sources read by studio out of the config, studio streams them through the stores
+when each run of the task starts, so every run of a schedule reads the files and runs the query again.
+They are the datasource files the importer can not read by itself, such as sftp, which the importer reads ignoring
+the host key, ftp over TLS, hdfs without a namenode rpc address or with kerberos, azure blob storage, gcs
+and the result of a sql query, and the archive members, uploaded or in a datasource.
+The importer reads the other compressed files decompressed as streams.
+*/
+func studioSources(taskConfig *types.ImportTaskConfig, conf config.Configurator, uploadDir string) ([]*importer.StoreSource, error) {
+	confv3 := conf.(*configv3.Config)
+	var sources []*importer.StoreSource
+	for idx, source := range taskConfig.Sources {
+		ref := &importer.StoreSource{Index: idx}
+		path, ok := datasourcePath(source)
+		switch {
+		case ok:
+			var dbs db.Datasource
+			if err := db.CtxDB.Where("b_id = ?", *source.DatasourceId).First(&dbs).Error; err != nil {
+				return nil, err
+			}
+			if !readByStudio(&dbs) && !isArchiveMember(path) {
+				continue
+			}
+			ref.DatasourceID, ref.Path = dbs.BID, path
+		case source.Path != "" && isArchiveMember(source.Path):
+			ref.UploadDir, ref.Path = uploadDir, source.Path
+		default:
+			continue
+		}
+		sources = append(sources, ref)
+		confv3.Sources[idx].SourceConfig = importerSource.Config{CSV: confv3.Sources[idx].SourceConfig.CSV}
+	}
+	return sources, nil
+}
+
+func isArchiveMember(p string) bool {
+	_, _, ok := filestore.SplitArchivePath(p)
+	return ok
+}
+
+func readByStudio(dbs *db.Datasource) bool {
+	switch dbs.Type {
+	case "ftp":
+		var c filestore.FtpConfig
+		return json.Unmarshal([]byte(dbs.Config), &c) == nil && c.TLS != ""
+	case "hdfs":
+		var c filestore.HdfsConfig
+		return json.Unmarshal([]byte(dbs.Config), &c) == nil && (c.Address == "" || c.Auth == filestore.HdfsAuthKerberos)
+	case "sftp", "azure", "gcs", "sql":
+		return true
+	}
+	return false
+}
+
+// zipBytes returns the size of the zip archives in the datasources, which are downloaded into the task dir to read
+func zipBytes(sources []*importer.StoreSource) (int64, error) {
+	var total int64
+	for _, ref := range sources {
+		archive, _, ok := filestore.SplitArchivePath(ref.Path)
+		if ref.DatasourceID == "" || !ok {
+			continue
+		}
+		if _, format := filestore.Compression(archive); format != filestore.ArchiveZip {
+			continue
+		}
+		store, dbs, err := importer.OpenDatasource(ref.DatasourceID)
+		if err != nil {
+			return 0, err
+		}
+		if dbs.Type == "sql" {
+			store.Close()
+			continue
+		}
+		f, err := filestore.Stat(store, archive)
+		store.Close()
+		if err != nil {
+			return 0, err
+		}
+		total += f.Size
+	}
+	return total, nil
+}
+
+// datasourcePath returns the file path of a datasource source, or the query of a sql datasource source
+func datasourcePath(source *types.Source) (string, bool) {
+	if source.DatasourceId == nil {
+		return "", false
+	}
+	if source.DatasourceQuery != nil {
+		return *source.DatasourceQuery, true
+	}
+	if source.DatasourceFilePath != nil {
+		return *source.DatasourceFilePath, true
+	}
+	return "", false
+}
+
+// updateConfig resolves the local files in the namespace of the task owner, uploadDir is the dir of the namespace
+func updateConfig(conf config.Configurator, taskDir, uploadDir string) error {
 	confv3 := conf.(*configv3.Config)
 	if confv3.Log == nil {
 		confv3.Log = &config.Log{}
@@ -157,12 +286,55 @@ func updateConfig(conf config.Configurator, taskDir, uploadDir string) {
 	confv3.Log.Files = append(confv3.Log.Files, filepath.Join(taskDir, importLogName))
 	for _, source := range confv3.Sources {
 		if source.SourceConfig.Local != nil {
-			source.SourceConfig.Local.Path = filepath.Join(uploadDir, source.SourceConfig.Local.Path)
+			path, err := utils.ResolveUploadPath(uploadDir, source.SourceConfig.Local.Path)
+			if err != nil {
+				return err
+			}
+			source.SourceConfig.Local.Path = path
 		}
 	}
+	return nil
+}
+
+// uploadDir returns the dir of the files uploaded by the user
+func (i *importService) uploadDir() string {
+	authData := i.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	return utils.UserUploadDir(i.svcCtx.Config.File.UploadDir, authData.Address+":"+strconv.Itoa(authData.Port), authData.Username)
+}
+
+func parseQueueOptions(req *types.CreateImportTaskRequest) (importer.QueueOptions, error) {
+	opts := importer.QueueOptions{
+		Priority: req.Priority,
+		Cron:     strings.TrimSpace(req.Cron),
+	}
+	if req.StartAt > 0 {
+		startAt := time.UnixMilli(req.StartAt)
+		opts.StartAt = &startAt
+	}
+	if opts.Cron != "" {
+		schedule, err := importer.ParseCron(opts.Cron)
+		if err != nil {
+			return opts, ecode.WithErrorMessage(ecode.ErrBadRequest, err, "invalid cron expression")
+		}
+		// the first run of a schedule waits for its first time
+		if opts.StartAt == nil {
+			next := schedule.Next(time.Now())
+			opts.StartAt = &next
+		}
+	}
+	return opts, nil
 }
 
 func (i *importService) CreateImportTask(req *types.CreateImportTaskRequest) (*types.CreateImportTaskData, error) {
+	opts, err := parseQueueOptions(req)
+	if err != nil {
+		return nil, err
+	}
+	authData := i.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	host := authData.Address + ":" + strconv.Itoa(authData.Port)
+	if err := checkNotifyChannels(host, authData.Username, req.NotifyChannelIds); err != nil {
+		return nil, err
+	}
 	_config, err := i.updateDatasourceConfig(req)
 
 	if err != nil {
@@ -176,6 +348,19 @@ func (i *importService) CreateImportTask(req *types.CreateImportTaskRequest) (*t
 	if err != nil {
 		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err)
 	}
+	uploadDir := i.uploadDir()
+	storeSources, err := studioSources(_config, conf, uploadDir)
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
+	// the zip archives, the logs and the failed records of the task are written to the storage
+	zipSize, err := zipBytes(storeSources)
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
+	if err := storage.CheckQuota(host, authData.Username, zipSize); err != nil {
+		return nil, err
+	}
 	// create task dir
 	id := req.Id
 	if id == nil {
@@ -192,39 +377,242 @@ func (i *importService) CreateImportTask(req *types.CreateImportTaskRequest) (*t
 		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err)
 	}
 	// modify source file path & add log config
-	updateConfig(conf, taskDir, i.svcCtx.Config.File.UploadDir)
+	if err := updateConfig(conf, taskDir, uploadDir); err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
+
+	// the task effect keeps config.yaml and the runtime config to resume the task
+	runtimeConfig, err := importer.EncryptRuntimeConfig(conf)
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	encodedSources, err := importer.EncodeStoreSources(storeSources)
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	taskEffect := &db.TaskEffect{BID: *id, Config: configFile, RuntimeConfig: runtimeConfig, StoreSources: encodedSources}
+	opts.NotifyChannels = req.NotifyChannelIds
 
 	// init task in db
-	auth := i.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
-	host := auth.Address + ":" + strconv.Itoa(auth.Port)
 	taskMgr := importer.GetTaskMgr()
-	var task *importer.Task
 	if req.Id != nil {
-		task, err = taskMgr.TurnDraftToTask(*req.Id, req.Name, req.RawConfig, conf)
+		_, err = taskMgr.TurnDraftToTask(*req.Id, req.Name, req.RawConfig, conf, opts, taskEffect)
 	} else {
-		task, err = taskMgr.NewTask(*id, host, auth.Username, req.Name, req.RawConfig, conf)
+		_, err = taskMgr.NewTask(*id, host, authData.Username, req.Name, req.RawConfig, conf, opts, taskEffect)
 	}
 	if err != nil {
 		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err)
 	}
 
-	// init task effect in db, store config.yaml
-	err = taskMgr.NewTaskEffect(&db.TaskEffect{BID: *id, Config: configFile})
+	// the queue starts the task once the concurrency limits allow
+	importer.Schedule()
+
+	return &types.CreateImportTaskData{
+		Id: *id,
+	}, nil
+}
+
+/*
+ValidateImportTask checks the task config against the schema of the space and the rows sampled from each source,
+it reports the errors and warnings of each source without writing anything into the space.
+*/
+func (i *importService) ValidateImportTask(req *types.CreateImportTaskRequest) (*types.ValidateImportTaskData, error) {
+	var taskConfig types.ImportTaskConfig
+	if err := json.Unmarshal([]byte(req.Config), &taskConfig); err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
+	conf, err := config.FromBytes([]byte(req.Config))
 	if err != nil {
-		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
+	confv3 := conf.(*configv3.Config)
+	if len(confv3.Sources) != len(taskConfig.Sources) {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("parse sources failed"))
+	}
+	authData := i.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	schema, err := importer.DescribeSpace(authData.NSID, confv3)
+	if err != nil {
+		return nil, err
 	}
 
-	// start import
-	if err = importer.StartImport(*id); err != nil {
-		task.TaskInfo.TaskStatus = importer.Aborted.String()
-		task.TaskInfo.TaskMessage = err.Error()
-		importer.GetTaskMgr().AbortTask(*id)
-		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	result := &types.ValidateImportTaskData{
+		Valid:   true,
+		Sources: make([]types.ValidateImportTaskSource, 0, len(taskConfig.Sources)),
+	}
+	for idx, source := range taskConfig.Sources {
+		report := types.ValidateImportTaskSource{
+			Index:    idx,
+			Errors:   []*types.ValidateImportTaskIssue{},
+			Warnings: []*types.ValidateImportTaskIssue{},
+		}
+		path, rows, err := i.sampleSource(source)
+		report.Path = path
+		if err != nil {
+			report.Errors = append(report.Errors, &types.ValidateImportTaskIssue{Target: "source", Message: err.Error()})
+		} else {
+			report.SampledRows = len(rows)
+			report.Errors, report.Warnings = importer.ValidateSource(&confv3.Sources[idx], schema, rows)
+		}
+		if len(report.Errors) > 0 {
+			result.Valid = false
+		}
+		result.Sources = append(result.Sources, report)
 	}
+	return result, nil
+}
 
-	return &types.CreateImportTaskData{
-		Id: *id,
-	}, nil
+// sampleSource reads the first rows of an uploaded file or a datasource file
+func (i *importService) sampleSource(source *types.Source) (string, []importer.SampleRow, error) {
+	store, path, withHeader, err := i.openSource(source)
+	if err != nil {
+		return path, nil, err
+	}
+	defer store.Close()
+
+	skip := 0
+	if withHeader {
+		skip = 1
+	}
+	rows, err := readSampleRecords(store, path, source.CSV, skip, validateSampleRows)
+	if err != nil {
+		return path, nil, err
+	}
+	return path, rows, nil
+}
+
+// openSource returns the store and the path of an uploaded file or a datasource file, and whether the file has the header
+func (i *importService) openSource(source *types.Source) (filestore.FileStore, string, bool, error) {
+	withHeader := source.CSV.WithHeader != nil && *source.CSV.WithHeader
+	datasourcePath, isDatasource := datasourcePath(source)
+	switch {
+	case isDatasource:
+		store, dbs, err := importer.OpenDatasource(*source.DatasourceId)
+		if err != nil {
+			return nil, datasourcePath, false, err
+		}
+		// the result of a query starts with the column names
+		withHeader = withHeader || dbs.Type == "sql"
+		return store, datasourcePath, withHeader, nil
+	case source.Path != "":
+		return filestore.WithArchives(filestore.NewLocalStore(i.uploadDir())), source.Path, withHeader, nil
+	}
+	return nil, "", false, fmt.Errorf("only the uploaded files and the datasource files can be sampled")
+}
+
+/*
+readSampleRecords parses the first n csv records of a file after the skipped records, as the importer reads them,
+so a quoted field may take several lines. The line of a record is the line it starts at.
+*/
+func readSampleRecords(store filestore.FileStore, path string, csvConfig types.ImportTaskCSV, skip, n int) ([]importer.SampleRow, error) {
+	opener, ok := store.(filestore.Opener)
+	if !ok {
+		return nil, fmt.Errorf("the store can not read %s as a stream", path)
+	}
+	f, err := opener.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	if csvConfig.Delimiter != nil && *csvConfig.Delimiter != "" {
+		r.Comma = []rune(*csvConfig.Delimiter)[0]
+	}
+	r.LazyQuotes = csvConfig.LazyQuotes != nil && *csvConfig.LazyQuotes
+	r.FieldsPerRecord = -1
+	rows := make([]importer.SampleRow, 0, n)
+	for idx := 0; idx < skip+n; idx++ {
+		values, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("parse line %d failed: %s", parseErr.StartLine, parseErr.Err)
+			}
+			return nil, err
+		}
+		if idx < skip {
+			continue
+		}
+		line, _ := r.FieldPos(0)
+		rows = append(rows, importer.SampleRow{Line: line, Values: values})
+	}
+	return rows, nil
+}
+
+/*
+SuggestImportMapping samples a file and drafts a task config mapping its columns onto the tags and edges of the space.
+The header is detected from the sampled rows unless csv.withHeader is given.
+*/
+func (i *importService) SuggestImportMapping(req *types.SuggestImportMappingRequest) (*types.SuggestImportMappingData, error) {
+	authData := i.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	schema, err := importer.DescribeWholeSpace(authData.NSID, req.Space)
+	if err != nil {
+		return nil, err
+	}
+	source := &types.Source{
+		CSV:                req.CSV,
+		Path:               req.Path,
+		DatasourceId:       req.DatasourceId,
+		DatasourceFilePath: req.DatasourceFilePath,
+		DatasourceQuery:    req.DatasourceQuery,
+		Tags:               []types.Tag{},
+		Edges:              []types.Edge{},
+	}
+	store, path, withHeader, err := i.openSource(source)
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
+	defer store.Close()
+	records, err := readSampleRecords(store, path, req.CSV, 0, validateSampleRows+1)
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, record.Values)
+	}
+	if req.CSV.WithHeader == nil && !withHeader {
+		withHeader = importer.DetectHeader(rows)
+	}
+	var header []string
+	if withHeader && len(rows) > 0 {
+		header, rows = rows[0], rows[1:]
+	}
+	source.CSV.WithHeader = &withHeader
+
+	columns := importer.ProfileColumns(header, rows)
+	result := &types.SuggestImportMappingData{
+		WithHeader: withHeader,
+		Columns:    make([]types.SuggestImportColumn, 0, len(columns)),
+		Targets:    []types.SuggestImportTarget{},
+		Config: types.ImportTaskConfig{
+			Client: types.Client{
+				Version: "v3",
+				Address: authData.Address + ":" + strconv.Itoa(authData.Port),
+				User:    authData.Username,
+			},
+			Manager: types.Manager{SpaceName: importer.EscapeName(req.Space)},
+			Sources: []*types.Source{source},
+		},
+	}
+	for _, c := range columns {
+		samples := c.Values
+		if len(samples) > 5 {
+			samples = samples[:5]
+		}
+		result.Columns = append(result.Columns, types.SuggestImportColumn{Index: c.Index, Name: c.Name, Type: c.Type, Samples: samples})
+	}
+	for _, s := range importer.SuggestMapping(schema, columns, path) {
+		result.Targets = append(result.Targets, types.SuggestImportTarget{Kind: s.Kind, Name: s.Name, Score: s.Score})
+		if s.Tag != nil {
+			source.Tags = append(source.Tags, *s.Tag)
+		} else {
+			source.Edges = append(source.Edges, *s.Edge)
+		}
+	}
+	return result, nil
 }
 
 func (i *importService) CreateTaskDraft(req *types.CreateTaskDraftRequest) error {
@@ -254,6 +642,18 @@ func (i *importService) StopImportTask(req *types.StopImportTaskRequest) error {
 	return importer.StopImportTask(req.Id, host, auth.Username)
 }
 
+func (i *importService) RetryImportTask(req *types.RetryImportTaskRequest) (*types.CreateImportTaskData, error) {
+	auth := i.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	host := fmt.Sprintf("%s:%d", auth.Address, auth.Port)
+	id := i.svcCtx.IDGenerator.Generate()
+	if err := importer.RetryImportTask(req.Id, id, host, auth.Username); err != nil {
+		return nil, err
+	}
+	return &types.CreateImportTaskData{
+		Id: id,
+	}, nil
+}
+
 func (i *importService) DownloadConfig(req *types.DownloadConfigsRequest) error {
 	httpResp, ok := middleware.GetResponseWriter(i.ctx)
 	if !ok {
@@ -401,6 +801,6 @@ func (i *importService) GetAIImportLog(task *db.TaskInfo) (*types.GetManyImportT
 func (i *importService) GetWorkingDir() (*types.GetWorkingDirResult, error) {
 	return &types.GetWorkingDirResult{
 		TaskDir:   i.svcCtx.Config.File.TasksDir,
-		UploadDir: i.svcCtx.Config.File.UploadDir,
+		UploadDir: i.uploadDir(),
 	}, nil
 }
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/filestore"
+)
+
+func TestReadSampleRecords(t *testing.T) {
+	dir := t.TempDir()
+	content := "id|bio\np1|\"likes\nbasketball\"\np2|\"plays \"\"guard\"\"\"\np3|\"a|b\"\np4|end\n"
+	if err := os.WriteFile(filepath.Join(dir, "player.csv"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	delimiter := "|"
+	csvConfig := types.ImportTaskCSV{Delimiter: &delimiter}
+	store := filestore.WithArchives(filestore.NewLocalStore(dir))
+
+	rows, err := readSampleRecords(store, "player.csv", csvConfig, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		line   int
+		values []string
+	}{
+		{2, []string{"p1", "likes\nbasketball"}},
+		{4, []string{"p2", "plays \"guard\""}},
+		{5, []string{"p3", "a|b"}},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("unexpected rows: %v", rows)
+	}
+	for idx, w := range want {
+		row := rows[idx]
+		if row.Line != w.line || len(row.Values) != len(w.values) || row.Values[0] != w.values[0] || row.Values[1] != w.values[1] {
+			t.Errorf("unexpected row %d: %v, want %v", idx, row, w)
+		}
+	}
+
+	// a bare quote is an error unless the quotes are lazy
+	if err := os.WriteFile(filepath.Join(dir, "lazy.csv"), []byte("p1|say \"hi\np2|ok\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSampleRecords(store, "lazy.csv", csvConfig, 0, 10); err == nil || err.Error() != `parse line 1 failed: bare " in non-quoted-field` {
+		t.Errorf("unexpected error: %v", err)
+	}
+	lazyQuotes := true
+	csvConfig.LazyQuotes = &lazyQuotes
+	rows, err = readSampleRecords(store, "lazy.csv", csvConfig, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Values[1] != "say \"hi" || rows[1].Line != 2 {
+		t.Errorf("unexpected rows of lazy quotes: %v", rows)
+	}
+}
@@ -0,0 +1,366 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/vesoft-inc/nebula-importer/v4/pkg/client"
+	configv3 "github.com/vesoft-inc/nebula-importer/v4/pkg/config/v3"
+	importerpkg "github.com/vesoft-inc/nebula-importer/v4/pkg/importer"
+	"github.com/vesoft-inc/nebula-importer/v4/pkg/manager"
+	"github.com/vesoft-inc/nebula-importer/v4/pkg/reader"
+	"github.com/vesoft-inc/nebula-importer/v4/pkg/source"
+	"github.com/vesoft-inc/nebula-importer/v4/pkg/spec"
+	importerUtils "github.com/vesoft-inc/nebula-importer/v4/pkg/utils"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/filestore"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	errContentDir  = "err"
+	tempContentDir = "temp"
+)
+
+func errFileName(sourceIndex int) string {
+	return fmt.Sprintf("source-%d.csv", sourceIndex)
+}
+
+/*
+build works like configv3.Config.Build, besides every source is tracked,
+so that the committed offset of each source can be saved as checkpoint
+and a source can be resumed from the offset given in `c.Resume`.
+*/
+func (c *Client) build() (err error) {
+	cfg := c.Cfg.(*configv3.Config)
+	l, err := cfg.BuildLogger()
+	if err != nil {
+		return err
+	}
+	pool, err := cfg.BuildClientPool(
+		client.WithLogger(l),
+		client.WithClientInitFunc(useSpaceFunc(cfg.Manager.GraphName)),
+	)
+	if err != nil {
+		_ = l.Close()
+		return err
+	}
+	// the manager closes the opened sources only after it starts
+	var opened []source.Source
+	defer func() {
+		if err != nil {
+			for _, src := range opened {
+				_ = src.Close()
+			}
+			_ = pool.Close()
+			_ = l.Close()
+		}
+	}()
+
+	m := cfg.Manager
+	mgr := manager.NewWithOpts(
+		manager.WithClientPool(pool),
+		manager.WithBatch(m.Batch),
+		manager.WithReaderConcurrency(m.ReaderConcurrency),
+		manager.WithImporterConcurrency(m.ImporterConcurrency),
+		manager.WithStatsInterval(m.StatsInterval),
+		manager.WithBeforeHooks(m.Hooks.Before...),
+		manager.WithAfterHooks(m.Hooks.After...),
+		manager.WithLogger(l),
+		manager.WithGetClientOptions(client.WithClientInitFunc(nil)), // clean the USE SPACE in 3.x
+	)
+
+	trackers := make([]*sourceTracker, 0, len(cfg.Sources))
+	for idx := range cfg.Sources {
+		s := cfg.Sources[idx]
+		var offset int64
+		if idx < len(c.Resume) {
+			offset = c.Resume[idx]
+		}
+		var src source.Source
+		if ref := c.storeSource(idx); ref != nil {
+			src = newStoreSource(ref, &s.SourceConfig, c.TempDir)
+		} else {
+			if src, err = source.New(&s.SourceConfig); err != nil {
+				return err
+			}
+			if compression := sourceCompression(&s.SourceConfig); compression != "" {
+				src = &decompressSource{Source: src, compression: compression}
+			}
+		}
+		tracker := &sourceTracker{base: offset, pending: make(map[*spec.Record]*pendingBatch)}
+		if c.ErrDir != "" {
+			tracker.errFile = filepath.Join(c.ErrDir, errFileName(idx))
+			if csvConfig := s.SourceConfig.CSV; csvConfig != nil && csvConfig.Delimiter != "" {
+				tracker.delimiter = []rune(csvConfig.Delimiter)[0]
+			}
+		}
+		if offset > 0 {
+			src = &offsetSource{Source: src, offset: offset}
+		}
+		batch := m.Batch
+		if s.Batch > 0 {
+			batch = s.Batch
+		}
+		brr := &trackedBatchReader{
+			BatchRecordReader: reader.NewBatchRecordReader(reader.NewRecordReader(src), reader.WithBatch(batch), reader.WithLogger(l)),
+			tracker:           tracker,
+		}
+		importers, err := s.BuildImporters(m.GraphName, pool)
+		if err != nil {
+			return err
+		}
+		tracker.importers = len(importers)
+		for i := range importers {
+			importers[i] = &trackedImporter{Importer: importers[i], tracker: tracker}
+		}
+		if err = mgr.Import(src, brr, importers...); err != nil {
+			return err
+		}
+		opened = append(opened, src)
+		trackers = append(trackers, tracker)
+	}
+
+	c.Logger = l
+	c.Manager = mgr
+	c.trackers = trackers
+	return nil
+}
+
+func (c *Client) storeSource(idx int) *StoreSource {
+	for _, ref := range c.StoreSources {
+		if ref.Index == idx {
+			return ref
+		}
+	}
+	return nil
+}
+
+func useSpaceFunc(space string) func(client.Client) error {
+	return func(cli client.Client) error {
+		resp, err := cli.Execute(fmt.Sprintf("USE %s", importerUtils.ConvertIdentifier(space)))
+		if err != nil {
+			return err
+		}
+		if !resp.IsSucceed() {
+			return resp.GetError()
+		}
+		return nil
+	}
+}
+
+/*
+Checkpoint returns the committed offset of each source,
+all the records before the offset have been sent to the graph.
+*/
+func (c *Client) Checkpoint() []int64 {
+	if len(c.trackers) == 0 {
+		return nil
+	}
+	offsets := make([]int64, 0, len(c.trackers))
+	for _, t := range c.trackers {
+		offsets = append(offsets, t.committed())
+	}
+	return offsets
+}
+
+type (
+	pendingBatch struct {
+		start     int64
+		remaining int
+		failed    bool
+	}
+
+	// sourceTracker follows the batches of one source, which are imported concurrently
+	sourceTracker struct {
+		mu        sync.Mutex
+		base      int64
+		cursor    int64
+		importers int
+		pending   map[*spec.Record]*pendingBatch
+		// the failed records are written into errFile as csv
+		errFile   string
+		delimiter rune
+	}
+
+	trackedBatchReader struct {
+		reader.BatchRecordReader
+		tracker *sourceTracker
+	}
+
+	trackedImporter struct {
+		importerpkg.Importer
+		tracker *sourceTracker
+	}
+
+	// offsetSource skips the first bytes of a source which have been imported before
+	offsetSource struct {
+		source.Source
+		offset int64
+	}
+
+	// decompressSource reads a gzip or a bzip2 source decompressed as a stream
+	decompressSource struct {
+		source.Source
+		compression string
+		r           io.Reader
+	}
+)
+
+func (t *sourceTracker) add(nBytes int, records spec.Records) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	start := t.cursor
+	t.cursor += int64(nBytes)
+	// the manager never imports empty batches
+	if len(records) == 0 || t.importers == 0 {
+		return
+	}
+	t.pending[&records[0]] = &pendingBatch{start: start, remaining: t.importers}
+}
+
+func (t *sourceTracker) done(records []spec.Record, failed bool) {
+	if len(records) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := &records[0]
+	b, ok := t.pending[key]
+	if !ok {
+		return
+	}
+	b.failed = b.failed || failed
+	b.remaining--
+	if b.remaining > 0 {
+		return
+	}
+	delete(t.pending, key)
+	// write the batch once, even if it failed in several importers
+	if b.failed && t.errFile != "" {
+		if err := t.writeFailed(records); err != nil {
+			logx.Errorf("write failed records into %s failed: %s", t.errFile, err)
+		}
+	}
+}
+
+func (t *sourceTracker) writeFailed(records []spec.Record) error {
+	if err := os.MkdirAll(filepath.Dir(t.errFile), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(t.errFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if t.delimiter != 0 {
+		w.Comma = t.delimiter
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (t *sourceTracker) committed() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	offset := t.cursor
+	for _, b := range t.pending {
+		if b.start < offset {
+			offset = b.start
+		}
+	}
+	return t.base + offset
+}
+
+func (r *trackedBatchReader) ReadBatch() (int, spec.Records, error) {
+	n, records, err := r.BatchRecordReader.ReadBatch()
+	if err == nil {
+		r.tracker.add(n, records)
+	}
+	return n, records, err
+}
+
+func (i *trackedImporter) Import(records ...spec.Record) (*importerpkg.ImportResp, error) {
+	resp, err := i.Importer.Import(records...)
+	i.tracker.done(records, err != nil)
+	return resp, err
+}
+
+func (s *offsetSource) Open() error {
+	if err := s.Source.Open(); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(io.Discard, s.Source, s.offset); err != nil {
+		_ = s.Source.Close()
+		return fmt.Errorf("skip %d bytes of %s failed: %w", s.offset, s.Source.Name(), err)
+	}
+	return nil
+}
+
+func (s *offsetSource) Config() *source.Config {
+	c := s.Source.Config()
+	if c == nil || c.CSV == nil || !c.CSV.WithHeader {
+		return c
+	}
+	// the header has been read before the offset
+	cpy := *c
+	csvConfig := *c.CSV
+	csvConfig.WithHeader = false
+	cpy.CSV = &csvConfig
+	return &cpy
+}
+
+// sourceCompression returns the compression of a file read by the importer, the archives are read by studio
+func sourceCompression(c *source.Config) string {
+	var p string
+	switch {
+	case c.Local != nil:
+		p = c.Local.Path
+	case c.S3 != nil:
+		p = c.S3.Key
+	case c.OSS != nil:
+		p = c.OSS.Key
+	case c.FTP != nil:
+		p = c.FTP.Path
+	case c.SFTP != nil:
+		p = c.SFTP.Path
+	case c.HDFS != nil:
+		p = c.HDFS.Path
+	}
+	compression, archive := filestore.Compression(p)
+	if archive != "" {
+		return ""
+	}
+	return compression
+}
+
+func (s *decompressSource) Open() error {
+	if err := s.Source.Open(); err != nil {
+		return err
+	}
+	r, err := filestore.Decompress(s.compression, s.Source)
+	if err != nil {
+		_ = s.Source.Close()
+		return fmt.Errorf("decompress %s failed: %w", s.Source.Name(), err)
+	}
+	s.r = r
+	return nil
+}
+
+func (s *decompressSource) Read(p []byte) (int, error) {
+	return s.r.Read(p)
+}
+
+// Size is 0 because the size is unknown before the source is read to the end
+func (s *decompressSource) Size() (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vesoft-inc/nebula-importer/v4/pkg/source"
+)
+
+func TestDecompressSource(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte("id,name\n1,Tom\n2,Jerry\n"))
+	w.Close()
+	path := filepath.Join(t.TempDir(), "person.csv.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := &source.Config{Local: &source.LocalConfig{Path: path}}
+	if compression := sourceCompression(c); compression != "gzip" {
+		t.Fatalf("unexpected compression: %s", compression)
+	}
+	if compression := sourceCompression(&source.Config{Local: &source.LocalConfig{Path: "export.tar.gz"}}); compression != "" {
+		t.Errorf("expect the archive read by studio, got the compression %s", compression)
+	}
+
+	for offset, expected := range map[int64]string{0: "id,name\n1,Tom\n2,Jerry\n", 14: "2,Jerry\n"} {
+		src, err := source.New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src = &decompressSource{Source: src, compression: "gzip"}
+		if offset > 0 {
+			// the offset is in the decompressed bytes
+			src = &offsetSource{Source: src, offset: offset}
+		}
+		if err := src.Open(); err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(src)
+		src.Close()
+		if err != nil || string(content) != expected {
+			t.Errorf("unexpected content from offset %d: %q %v", offset, content, err)
+		}
+	}
+}
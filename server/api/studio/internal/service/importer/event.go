@@ -0,0 +1,55 @@
+package importer
+
+import (
+	"path/filepath"
+
+	studioConfig "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
+	wsUtils "github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ws/utils"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// the max bytes of the log read for an event
+const eventLogLimit = 64 * 1024
+
+// taskEvents publishes the status, stats and new logs of a task to the websocket subscribers
+type taskEvents struct {
+	tail *utils.FileTail
+}
+
+func newTaskEvents(taskID string) *taskEvents {
+	logFile := filepath.Join(studioConfig.GetConfig().File.TasksDir, taskID, taskLogName)
+	return &taskEvents{tail: utils.NewFileTail(logFile)}
+}
+
+func (e *taskEvents) publish(taskInfo *db.TaskInfo) {
+	logs, err := e.tail.Next(eventLogLimit)
+	if err != nil {
+		logx.Errorf("read log of task %s failed: %s", taskInfo.BID, err)
+	}
+	wsUtils.Publish(&wsUtils.Event{
+		Topic:   wsUtils.Topic(wsUtils.TopicImportTask, taskInfo.BID),
+		Owner:   wsUtils.Owner(taskInfo.Address, taskInfo.User),
+		Status:  taskInfo.TaskStatus,
+		Message: taskInfo.TaskMessage,
+		Stats:   toImportTaskStats(taskInfo.Stats),
+		Logs:    logs,
+	})
+}
+
+func toImportTaskStats(stats db.Stats) types.ImportTaskStats {
+	return types.ImportTaskStats{
+		TotalBytes:      stats.TotalBytes,
+		ProcessedBytes:  stats.ProcessedBytes,
+		FailedRecords:   stats.FailedRecords,
+		TotalRecords:    stats.TotalRecords,
+		TotalRequest:    stats.TotalRequest,
+		FailedRequest:   stats.FailedRequest,
+		TotalLatency:    int64(stats.TotalLatency),
+		TotalRespTime:   int64(stats.TotalRespTime),
+		FailedProcessed: stats.FailedProcessed,
+		TotalProcessed:  stats.TotalProcessed,
+	}
+}
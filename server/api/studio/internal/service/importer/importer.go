@@ -3,13 +3,16 @@ package importer
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"time"
 
+	studioConfig "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/notify"
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
@@ -37,11 +40,14 @@ func StartImport(taskID string) (err error) {
 	go func() {
 		ticker := time.NewTicker(2 * time.Second)
 		defer ticker.Stop()
+		events := newTaskEvents(taskID)
 		for {
 			select {
 			case <-ticker.C:
 				GetTaskMgr().UpdateTaskInfo(taskID)
+				events.publish(task.TaskInfo)
 			case <-signal:
+				events.publish(task.TaskInfo)
 				return
 			}
 		}
@@ -53,17 +59,28 @@ func StartImport(taskID string) (err error) {
 				task.TaskInfo.TaskStatus = Aborted.String()
 				task.TaskInfo.TaskMessage = fmt.Sprintf("%s", err)
 				GetTaskMgr().AbortTask(taskID)
+				select {
+				case signal <- struct{}{}:
+				default:
+				}
 			}
 		}()
-		cfg := task.Client.Cfg
-		if err = cfg.Build(); err != nil {
+		task.Client.ErrDir = filepath.Join(studioConfig.GetConfig().File.TasksDir, taskID, errContentDir)
+		task.Client.TempDir = filepath.Join(studioConfig.GetConfig().File.TasksDir, taskID, tempContentDir)
+		if err = task.Client.build(); err != nil {
 			abort()
 			return
 		}
-		mgr := cfg.GetManager()
-		logger := cfg.GetLogger()
-		task.Client.Manager = mgr
-		task.Client.Logger = logger
+		mgr := task.Client.Manager
+		if _, ok := GetTaskMgr().getTaskFromMap(taskID); !ok {
+			// dropped after its lease was lost
+			_ = mgr.Stop()
+			signal <- struct{}{}
+			return
+		}
+		if task.Client.LogNote != "" {
+			task.Client.Logger.Info(task.Client.LogNote)
+		}
 		if err = mgr.Start(); err != nil {
 			abort()
 			return
@@ -74,6 +91,7 @@ func StartImport(taskID string) (err error) {
 			task.TaskInfo.TaskStatus = Aborted.String()
 			task.TaskInfo.TaskMessage = err.Error()
 			GetTaskMgr().AbortTask(taskID)
+			signal <- struct{}{}
 			return
 		}
 		if task.TaskInfo.TaskStatus == Processing.String() {
@@ -98,47 +116,21 @@ func DeleteImportTask(tasksDir, taskID, address, username string) error {
 }
 
 func GetImportTask(taskID, address, username string) (*types.GetImportTaskData, error) {
-	task := Task{}
-	result := &types.GetImportTaskData{}
-
-	_, err := taskmgr.db.FindTaskInfoByIdAndAddresssAndUser(taskID, address, username)
+	taskInfo, err := taskmgr.db.FindTaskInfoByIdAndAddresssAndUser(taskID, address, username)
 	if err != nil {
 		return nil, errors.New("task not existed")
 	}
-
-	if t, ok := GetTaskMgr().GetTask(taskID); ok {
-		task = *t
-		importAddress, err := parseImportAddress(task.TaskInfo.ImportAddress)
-		if err != nil {
-			return nil, err
-		}
-		stats := task.TaskInfo.Stats
-		result.Id = t.TaskInfo.BID
-		result.Status = task.TaskInfo.TaskStatus
-		result.Message = task.TaskInfo.TaskMessage
-		result.CreateTime = task.TaskInfo.CreateTime.UnixMilli()
-		result.UpdateTime = task.TaskInfo.UpdateTime.UnixMilli()
-		result.Address = task.TaskInfo.Address
-		result.ImportAddress = importAddress
-		result.User = task.TaskInfo.User
-		result.Name = task.TaskInfo.Name
-		result.Space = task.TaskInfo.Space
-		result.RawConfig = task.TaskInfo.RawConfig
-		result.Stats = types.ImportTaskStats{
-			TotalBytes:      stats.TotalBytes,
-			ProcessedBytes:  stats.ProcessedBytes,
-			FailedRecords:   stats.FailedRecords,
-			TotalRecords:    stats.TotalRecords,
-			TotalRequest:    stats.TotalRequest,
-			FailedRequest:   stats.FailedRequest,
-			TotalLatency:    int64(stats.TotalLatency),
-			TotalRespTime:   int64(stats.TotalRespTime),
-			FailedProcessed: stats.FailedProcessed,
-			TotalProcessed:  stats.TotalProcessed,
-		}
+	// the task running on this instance has the live stats
+	if task, ok := GetTaskMgr().getTaskFromMap(taskID); ok {
+		taskInfo.TaskStatus = task.TaskInfo.TaskStatus
+		taskInfo.TaskMessage = task.TaskInfo.TaskMessage
+		taskInfo.Stats = task.TaskInfo.Stats
 	}
-
-	return result, nil
+	positions, err := QueuePositions()
+	if err != nil {
+		return nil, err
+	}
+	return toImportTaskData(taskInfo, positions)
 }
 
 func GetManyImportTask(address, username, space string, pageIndex, pageSize int) (*types.GetManyImportTaskData, error) {
@@ -152,12 +144,11 @@ func GetManyImportTask(address, username, space string, pageIndex, pageSize int)
 		return nil, err
 	}
 
+	positions, err := QueuePositions()
+	if err != nil {
+		return nil, err
+	}
 	for _, t := range tasks {
-		importAddress, err := parseImportAddress(t.ImportAddress)
-		if err != nil {
-			return nil, err
-		}
-		stats := t.Stats
 		var llmJob interface{}
 		if t.LLMJobID != 0 {
 			err = db.CtxDB.First(&t.LLMJob, t.LLMJobID).Error
@@ -169,33 +160,12 @@ func GetManyImportTask(address, username, space string, pageIndex, pageSize int)
 			}
 			t.TaskStatus = string(t.LLMJob.Status)
 		}
-		data := types.GetImportTaskData{
-			Id:            t.BID,
-			Status:        t.TaskStatus,
-			Message:       t.TaskMessage,
-			CreateTime:    t.CreateTime.UnixMilli(),
-			UpdateTime:    t.UpdateTime.UnixMilli(),
-			Address:       t.Address,
-			ImportAddress: importAddress,
-			User:          t.User,
-			Name:          t.Name,
-			Space:         t.Space,
-			RawConfig:     t.RawConfig,
-			Stats: types.ImportTaskStats{
-				TotalBytes:      stats.TotalBytes,
-				ProcessedBytes:  stats.ProcessedBytes,
-				FailedRecords:   stats.FailedRecords,
-				TotalRecords:    stats.TotalRecords,
-				TotalRequest:    stats.TotalRequest,
-				FailedRequest:   stats.FailedRequest,
-				TotalLatency:    int64(stats.TotalLatency),
-				TotalRespTime:   int64(stats.TotalRespTime),
-				FailedProcessed: stats.FailedProcessed,
-				TotalProcessed:  stats.TotalProcessed,
-			},
-			LLMJob: llmJob,
+		data, err := toImportTaskData(t, positions)
+		if err != nil {
+			return nil, err
 		}
-		result.List = append(result.List, data)
+		data.LLMJob = llmJob
+		result.List = append(result.List, *data)
 	}
 	result.Total = count
 
@@ -216,6 +186,36 @@ func StopImportTask(taskID, address, username string) error {
 	}
 }
 
+func toImportTaskData(t *db.TaskInfo, positions map[string]int) (*types.GetImportTaskData, error) {
+	importAddress, err := parseImportAddress(t.ImportAddress)
+	if err != nil {
+		return nil, err
+	}
+	data := &types.GetImportTaskData{
+		Id:               t.BID,
+		Status:           t.TaskStatus,
+		Message:          t.TaskMessage,
+		CreateTime:       t.CreateTime.UnixMilli(),
+		UpdateTime:       t.UpdateTime.UnixMilli(),
+		Address:          t.Address,
+		ImportAddress:    importAddress,
+		User:             t.User,
+		Name:             t.Name,
+		Space:            t.Space,
+		RawConfig:        t.RawConfig,
+		ParentId:         t.ParentID,
+		NotifyChannelIds: notify.SplitChannels(t.NotifyChannels),
+		Stats:            toImportTaskStats(t.Stats),
+		Priority:         t.Priority,
+		Cron:             t.Cron,
+		QueuePosition:    positions[t.BID],
+	}
+	if t.StartAt != nil {
+		data.StartAt = t.StartAt.UnixMilli()
+	}
+	return data, nil
+}
+
 func parseImportAddress(address string) ([]string, error) {
 	re := regexp.MustCompile(`,\s*`)
 	split := re.Split(address, -1)
@@ -0,0 +1,31 @@
+package importer
+
+import (
+	"fmt"
+	"net/url"
+
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/notify"
+)
+
+// notifyTask sends the final status of a task to the notify channels of its owner
+func notifyTask(taskInfo *db.TaskInfo) {
+	event := notify.EventFinished
+	switch taskInfo.TaskStatus {
+	case Stoped.String():
+		event = notify.EventStopped
+	case Aborted.String():
+		event = notify.EventFailed
+	}
+	notify.Notify(taskInfo.Address, taskInfo.User, notify.SplitChannels(taskInfo.NotifyChannels), &notify.Payload{
+		Event:   event,
+		Kind:    notify.KindImportTask,
+		ID:      taskInfo.BID,
+		Name:    taskInfo.Name,
+		Space:   taskInfo.Space,
+		Status:  taskInfo.TaskStatus,
+		Message: taskInfo.TaskMessage,
+		Stats:   toImportTaskStats(taskInfo.Stats),
+		LogsURL: notify.LogsURL(fmt.Sprintf("/api/import-tasks/%s/download-logs?name=%s", taskInfo.BID, url.QueryEscape(taskLogName))),
+	})
+}
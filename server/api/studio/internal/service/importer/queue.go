@@ -0,0 +1,245 @@
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	configv3 "github.com/vesoft-inc/nebula-importer/v4/pkg/config/v3"
+	studioConfig "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/idx"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/lease"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/notify"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	queueLeaderID = "import_queue"
+	queueInterval = 5 * time.Second
+)
+
+var queueSignal = make(chan struct{}, 1)
+
+// ParseCron parses a standard cron expression, such as `0 2 * * *`
+func ParseCron(expr string) (cron.Schedule, error) {
+	return cron.ParseStandard(expr)
+}
+
+// Schedule wakes up the queue to start the tasks which are ready
+func Schedule() {
+	select {
+	case queueSignal <- struct{}{}:
+	default:
+	}
+}
+
+/*
+runQueue starts the queued tasks while the concurrency limits allow.
+Only the instance holding the queue lease schedules, so the limits hold across instances.
+*/
+func runQueue() {
+	ticker := time.NewTicker(queueInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-queueSignal:
+		}
+		GetTaskMgr().startQueuedTasks()
+	}
+}
+
+func isReady(taskInfo *db.TaskInfo, now time.Time) bool {
+	return taskInfo.StartAt == nil || !taskInfo.StartAt.After(now)
+}
+
+// queueSlots counts the running tasks against the global and the per space concurrency limits, 0 means no limit
+type queueSlots struct {
+	limit      int64
+	spaceLimit int64
+	running    int64
+	bySpace    map[string]int64
+}
+
+func (s *queueSlots) full() bool {
+	return s.limit > 0 && s.running >= s.limit
+}
+
+func (s *queueSlots) spaceFull(space string) bool {
+	return s.spaceLimit > 0 && s.bySpace[space] >= s.spaceLimit
+}
+
+func (s *queueSlots) take(space string) {
+	s.running++
+	s.bySpace[space]++
+}
+
+func (mgr *TaskMgr) startQueuedTasks() {
+	defer func() {
+		if err := recover(); err != nil {
+			logx.Errorf("[import queue] panic: %v", err)
+		}
+	}()
+	ok, err := lease.Claim(lease.KindLeader, queueLeaderID)
+	if err != nil || !ok {
+		return
+	}
+	running, runningBySpace, err := mgr.db.CountProcessingTasks()
+	if err != nil {
+		logx.Errorf("[import queue] count running tasks failed: %s", err)
+		return
+	}
+	tasks, err := mgr.db.FindQueuedTaskInfos()
+	if err != nil {
+		logx.Errorf("[import queue] find queued tasks failed: %s", err)
+		return
+	}
+	limits := studioConfig.GetConfig().Import
+	slots := &queueSlots{
+		limit:      int64(limits.Concurrency),
+		spaceLimit: int64(limits.SpaceConcurrency),
+		running:    running,
+		bySpace:    runningBySpace,
+	}
+	now := time.Now()
+	for _, taskInfo := range tasks {
+		if slots.full() {
+			return
+		}
+		if !isReady(taskInfo, now) || slots.spaceFull(taskInfo.Space) {
+			continue
+		}
+		if !mgr.startQueuedTask(taskInfo) {
+			continue
+		}
+		slots.take(taskInfo.Space)
+	}
+}
+
+func (mgr *TaskMgr) startQueuedTask(taskInfo *db.TaskInfo) bool {
+	taskID := taskInfo.BID
+	ok, err := mgr.db.UpdateQueuedTask2Processing(taskID)
+	if err != nil || !ok {
+		return false
+	}
+	if err := claimTask(taskID); err != nil {
+		mgr.db.UpdateProcessingTask2Aborted(taskID, err.Error())
+		return false
+	}
+	if taskInfo.Cron != "" {
+		if err := mgr.scheduleNextRun(taskInfo); err != nil {
+			logx.Errorf("[import queue] schedule the next run of task %s failed: %s", taskID, err)
+		}
+	}
+	cfg, storeSources, err := mgr.loadRuntimeConfig(taskID)
+	if err != nil {
+		mgr.db.UpdateProcessingTask2Aborted(taskID, fmt.Sprintf("load task config failed: %s", err))
+		releaseTask(taskID)
+		return false
+	}
+	client := &Client{
+		Cfg:          cfg,
+		HasStarted:   false,
+		StoreSources: storeSources,
+	}
+	if taskInfo.ParentID != "" {
+		client.LogNote = fmt.Sprintf("studio: retry the failed records of task %s", taskInfo.ParentID)
+	}
+	taskInfo.TaskStatus = Processing.String()
+	mgr.PutTask(taskID, &Task{
+		Client:   client,
+		TaskInfo: taskInfo,
+	})
+	if err := StartImport(taskID); err != nil {
+		taskInfo.TaskStatus = Aborted.String()
+		taskInfo.TaskMessage = err.Error()
+		mgr.AbortTask(taskID)
+		return false
+	}
+	return true
+}
+
+/*
+scheduleNextRun queues a copy of the cron task for the next time of its schedule,
+so every run of the schedule has its own stats and logs.
+*/
+func (mgr *TaskMgr) scheduleNextRun(taskInfo *db.TaskInfo) error {
+	schedule, err := ParseCron(taskInfo.Cron)
+	if err != nil {
+		return err
+	}
+	cfg, _, err := mgr.loadRuntimeConfig(taskInfo.BID)
+	if err != nil {
+		return err
+	}
+	taskEffect, err := mgr.db.FindTaskEffect(taskInfo.BID)
+	if err != nil {
+		return err
+	}
+
+	id := idx.Generate()
+	taskDir, err := CreateNewTaskDir(studioConfig.GetConfig().File.TasksDir, id)
+	if err != nil {
+		return err
+	}
+	// write the log of the next run into its own task dir
+	confv3 := cfg.(*configv3.Config)
+	if confv3.Log != nil {
+		for i, file := range confv3.Log.Files {
+			if filepath.Base(file) == taskLogName {
+				confv3.Log.Files[i] = filepath.Join(taskDir, taskLogName)
+			}
+		}
+	}
+	cfgBytes, err := yaml.Marshal(confv3)
+	if err != nil {
+		return err
+	}
+	configFile, err := CreateConfigFile(taskDir, cfgBytes)
+	if err != nil {
+		return err
+	}
+	runtimeConfig, err := EncryptRuntimeConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	next := schedule.Next(time.Now())
+	_, err = mgr.NewTask(id, taskInfo.Address, taskInfo.User, taskInfo.Name, taskInfo.RawConfig, cfg, QueueOptions{
+		Priority:       taskInfo.Priority,
+		StartAt:        &next,
+		Cron:           taskInfo.Cron,
+		NotifyChannels: notify.SplitChannels(taskInfo.NotifyChannels),
+	}, &db.TaskEffect{BID: id, Config: configFile, RuntimeConfig: runtimeConfig, StoreSources: taskEffect.StoreSources})
+	if err != nil {
+		return err
+	}
+	// the schedule belongs to the next run from now on
+	return mgr.db.ClearTaskCron(taskInfo.BID)
+}
+
+/*
+QueuePositions returns the position of each ready task in the queue, starting from 1.
+The tasks waiting for their start time are not in line yet.
+*/
+func QueuePositions() (map[string]int, error) {
+	tasks, err := GetTaskMgr().db.FindQueuedTaskInfos()
+	if err != nil {
+		return nil, err
+	}
+	return queuePositions(tasks, time.Now()), nil
+}
+
+// queuePositions numbers the ready tasks in the order of the queue
+func queuePositions(tasks []*db.TaskInfo, now time.Time) map[string]int {
+	positions := make(map[string]int, len(tasks))
+	for _, taskInfo := range tasks {
+		if isReady(taskInfo, now) {
+			positions[taskInfo.BID] = len(positions) + 1
+		}
+	}
+	return positions
+}
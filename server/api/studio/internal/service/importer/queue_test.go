@@ -0,0 +1,235 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	configv3 "github.com/vesoft-inc/nebula-importer/v4/pkg/config/v3"
+	studioConfig "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+)
+
+func setupTasksDir(t *testing.T) string {
+	c := &studioConfig.Config{}
+	c.File.UploadDir = filepath.Join(t.TempDir(), "upload")
+	c.File.TasksDir = filepath.Join(t.TempDir(), "tasks")
+	if err := c.InitConfig(); err != nil {
+		t.Fatal(err)
+	}
+	return c.File.TasksDir
+}
+
+func TestIsReady(t *testing.T) {
+	now := time.Date(2023, 1, 1, 2, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Second), now.Add(time.Second)
+	tests := []struct {
+		name    string
+		startAt *time.Time
+		want    bool
+	}{
+		{"no start time", nil, true},
+		{"started", &before, true},
+		{"start now", &now, true},
+		{"not started", &after, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isReady(&db.TaskInfo{StartAt: tt.startAt}, now); got != tt.want {
+				t.Errorf("isReady() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	now := time.Date(2023, 1, 1, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		// the zero time means an invalid expression
+		want time.Time
+	}{
+		{"0 2 * * *", time.Date(2023, 1, 2, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, 1, 1, 3, 15, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2023, 1, 1, 4, 0, 0, 0, time.UTC)},
+		{"0 2 * *", time.Time{}},
+		{"61 * * * *", time.Time{}},
+		{"every day", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if tt.want.IsZero() {
+				if err == nil {
+					t.Errorf("expect an error of %q", tt.expr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next := schedule.Next(now); !next.Equal(tt.want) {
+				t.Errorf("unexpected next run %s, want %s", next, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueSlots(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int64
+		spaceLimit int64
+		running    int64
+		bySpace    map[string]int64
+		full       bool
+		spaceFull  bool
+	}{
+		{"no limit", 0, 0, 100, map[string]int64{"nba": 100}, false, false},
+		{"below the limits", 5, 2, 4, map[string]int64{"nba": 1}, false, false},
+		{"global limit", 5, 2, 5, map[string]int64{"nba": 1}, true, false},
+		{"space limit", 5, 2, 3, map[string]int64{"nba": 2}, false, true},
+		{"space limit without global limit", 0, 2, 3, map[string]int64{"nba": 3}, false, true},
+		{"other spaces", 5, 2, 2, map[string]int64{"other": 2}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &queueSlots{limit: tt.limit, spaceLimit: tt.spaceLimit, running: tt.running, bySpace: tt.bySpace}
+			if got := s.full(); got != tt.full {
+				t.Errorf("full() = %v, want %v", got, tt.full)
+			}
+			if got := s.spaceFull("nba"); got != tt.spaceFull {
+				t.Errorf("spaceFull() = %v, want %v", got, tt.spaceFull)
+			}
+		})
+	}
+
+	s := &queueSlots{limit: 2, spaceLimit: 1, bySpace: map[string]int64{}}
+	s.take("nba")
+	if s.full() || !s.spaceFull("nba") || s.spaceFull("other") {
+		t.Errorf("unexpected slots after a task of nba: %+v", s)
+	}
+	s.take("other")
+	if !s.full() {
+		t.Errorf("unexpected slots after two tasks: %+v", s)
+	}
+}
+
+func TestQueuePositions(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	tasks := []*db.TaskInfo{
+		{BID: "task1"},
+		{BID: "task2", StartAt: &later},
+		{BID: "task3", StartAt: &now},
+		{BID: "task4"},
+	}
+	positions := queuePositions(tasks, now)
+	want := map[string]int{"task1": 1, "task3": 2, "task4": 3}
+	if len(positions) != len(want) {
+		t.Fatalf("unexpected positions: %v", positions)
+	}
+	for id, position := range want {
+		if positions[id] != position {
+			t.Errorf("unexpected position of %s: %d, want %d", id, positions[id], position)
+		}
+	}
+}
+
+func TestFindQueuedTaskInfos(t *testing.T) {
+	setupTaskDB(t)
+	mgr := GetTaskMgr()
+	now := time.Now()
+	for _, taskInfo := range []*db.TaskInfo{
+		{BID: "low", Priority: 0, CreateTime: now.Add(-time.Hour)},
+		{BID: "high-new", Priority: 1, CreateTime: now},
+		{BID: "high-old", Priority: 1, CreateTime: now.Add(-time.Minute)},
+		{BID: "running", Priority: 2, CreateTime: now, TaskStatus: Processing.String()},
+		{BID: "low-new", Priority: 0, CreateTime: now},
+	} {
+		if taskInfo.TaskStatus == "" {
+			taskInfo.TaskStatus = Queued.String()
+		}
+		if err := mgr.db.InsertTaskInfo(taskInfo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tasks, err := mgr.db.FindQueuedTaskInfos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, taskInfo := range tasks {
+		ids = append(ids, taskInfo.BID)
+	}
+	if got := strings.Join(ids, ","); got != "high-old,high-new,low,low-new" {
+		t.Errorf("unexpected queue order: %s", got)
+	}
+}
+
+func TestScheduleNextRun(t *testing.T) {
+	setupTaskDB(t)
+	tasksDir := setupTasksDir(t)
+	mgr := GetTaskMgr()
+	runtimeConfig, err := EncryptRuntimeConfig(testImportConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskInfo := &db.TaskInfo{BID: "task1", Name: "daily", TaskStatus: Processing.String(), Priority: 1, Cron: "0 2 * * *"}
+	if err := mgr.db.InsertQueuedTask(taskInfo, &db.TaskEffect{BID: "task1", Config: testConfig, RuntimeConfig: runtimeConfig}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mgr.scheduleNextRun(taskInfo); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := mgr.db.FindQueuedTaskInfos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("unexpected queued tasks: %v", tasks)
+	}
+	next := tasks[0]
+	if next.Cron != "0 2 * * *" || next.Priority != 1 || next.Name != "daily" {
+		t.Errorf("unexpected next run: %+v", next)
+	}
+	if next.StartAt == nil || next.StartAt.Hour() != 2 || next.StartAt.Minute() != 0 || !next.StartAt.After(time.Now()) {
+		t.Errorf("unexpected start time of the next run: %v", next.StartAt)
+	}
+	parent, err := mgr.db.FindProcessingTaskInfo("task1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.Cron != "" {
+		t.Errorf("expect the schedule moved to the next run, got %q", parent.Cron)
+	}
+
+	// the next run writes its log into its own dir
+	logFile := filepath.Join(tasksDir, next.BID, taskLogName)
+	taskEffect, err := mgr.db.FindTaskEffect(next.BID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configFile, err := os.ReadFile(filepath.Join(tasksDir, next.BID, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, config := range map[string]string{"config.yaml": string(configFile), "task effect": taskEffect.Config} {
+		if !strings.Contains(config, logFile) || strings.Contains(config, "/tasks/task1/") {
+			t.Errorf("unexpected log of the next run in the %s: %s", name, config)
+		}
+		if strings.Contains(config, "secret") {
+			t.Errorf("the password is kept in the %s", name)
+		}
+	}
+	cfg, _, err := mgr.loadRuntimeConfig(next.BID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := cfg.(*configv3.Config).Log.Files; len(files) != 1 || files[0] != logFile {
+		t.Errorf("unexpected log of the next run in the runtime config: %v", files)
+	}
+}
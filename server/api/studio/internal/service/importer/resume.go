@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	importconfig "github.com/vesoft-inc/nebula-importer/v4/pkg/config"
+	configv3 "github.com/vesoft-inc/nebula-importer/v4/pkg/config/v3"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gopkg.in/yaml.v3"
+)
+
+// EncryptRuntimeConfig encrypts the config the task runs with, which keeps the credentials
+func EncryptRuntimeConfig(cfg importconfig.Configurator) (string, error) {
+	out, err := yaml.Marshal(cfg.(*configv3.Config))
+	if err != nil {
+		return "", ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	encrypted, err := utils.Encrypt(out, []byte(utils.CipherKey))
+	if err != nil {
+		return "", ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	return encrypted, nil
+}
+
+func decryptRuntimeConfig(encrypted string) (importconfig.Configurator, error) {
+	out, err := utils.Decrypt(encrypted, []byte(utils.CipherKey))
+	if err != nil {
+		return nil, err
+	}
+	return importconfig.FromBytes(out)
+}
+
+// created by an older version, which did not keep the runtime config
+var errNoRuntimeConfig = errors.New("runtime config not found")
+
+// loadRuntimeConfig returns the config the task runs with and the sources read by studio
+func (mgr *TaskMgr) loadRuntimeConfig(taskID string) (importconfig.Configurator, []*StoreSource, error) {
+	taskEffect, err := mgr.db.FindTaskEffect(taskID)
+	if err != nil || taskEffect.RuntimeConfig == "" {
+		return nil, nil, errNoRuntimeConfig
+	}
+	cfg, err := decryptRuntimeConfig(taskEffect.RuntimeConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	storeSources, err := decodeStoreSources(taskEffect.StoreSources)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, storeSources, nil
+}
+
+/*
+ResumeTask rebuilds a task left processing by a stopped instance and starts it again.
+The local sources and the sources read by studio continue from the checkpoint of the task,
+the other sources can not seek and are imported again from the beginning.
+*/
+func (mgr *TaskMgr) ResumeTask(taskInfo *db.TaskInfo, reason string) {
+	taskID := taskInfo.BID
+	if err := claimTask(taskID); err != nil {
+		// resumed by another instance
+		return
+	}
+	abort := func(message string) {
+		if err := mgr.db.UpdateProcessingTask2Aborted(taskID, message); err != nil {
+			logx.Errorf("abort task %s failed: %s", taskID, err)
+		}
+		releaseTask(taskID)
+	}
+
+	cfg, storeSources, err := mgr.loadRuntimeConfig(taskID)
+	if err == errNoRuntimeConfig {
+		abort("Service execption")
+		return
+	}
+	if err != nil {
+		abort(fmt.Sprintf("Service execption: resume task failed: %s", err))
+		return
+	}
+
+	var checkpoint []int64
+	if taskInfo.Checkpoint != "" {
+		if err := json.Unmarshal([]byte(taskInfo.Checkpoint), &checkpoint); err != nil {
+			logx.Errorf("parse checkpoint of task %s failed: %s", taskID, err)
+			checkpoint = nil
+		}
+	}
+	sources := cfg.(*configv3.Config).Sources
+	resume := make([]int64, len(sources))
+	var skipped int64
+	notes := make([]string, 0, len(sources))
+	client := &Client{
+		Cfg:          cfg,
+		Resume:       resume,
+		HasStarted:   false,
+		StoreSources: storeSources,
+	}
+	for i, s := range sources {
+		if i >= len(checkpoint) || checkpoint[i] <= 0 {
+			continue
+		}
+		// the streamed sources skip the imported bytes
+		var name string
+		if ref := client.storeSource(i); ref != nil {
+			name = newStoreSource(ref, &s.SourceConfig, "").Name()
+		} else if s.SourceConfig.Local != nil {
+			name = s.SourceConfig.Local.String()
+		} else {
+			continue
+		}
+		resume[i] = checkpoint[i]
+		skipped += checkpoint[i]
+		notes = append(notes, fmt.Sprintf("%s from offset %d", name, checkpoint[i]))
+	}
+	if skipped > 0 {
+		client.BaseStats = taskInfo.Stats
+		client.BaseStats.ProcessedBytes = skipped
+		client.BaseStats.TotalBytes = 0
+		client.LogNote = fmt.Sprintf("studio: task resumed after %s, continue %s, the other sources restart from the beginning",
+			reason, strings.Join(notes, ", "))
+	} else {
+		taskInfo.Stats = db.Stats{}
+		client.LogNote = fmt.Sprintf("studio: task restarted from the beginning after %s", reason)
+	}
+
+	taskInfo.TaskStatus = Processing.String()
+	taskInfo.TaskMessage = ""
+	if err := mgr.db.UpdateTaskStatus(taskID, taskInfo.TaskStatus, taskInfo.TaskMessage); err != nil {
+		abort(fmt.Sprintf("Service execption: resume task failed: %s", err))
+		return
+	}
+	mgr.PutTask(taskID, &Task{Client: client, TaskInfo: taskInfo})
+	logx.Infof("resume import task %s: %s", taskID, client.LogNote)
+	if err := StartImport(taskID); err != nil {
+		taskInfo.TaskStatus = Aborted.String()
+		taskInfo.TaskMessage = err.Error()
+		mgr.AbortTask(taskID)
+	}
+}
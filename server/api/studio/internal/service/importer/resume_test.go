@@ -0,0 +1,192 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	importconfig "github.com/vesoft-inc/nebula-importer/v4/pkg/config"
+	configv3 "github.com/vesoft-inc/nebula-importer/v4/pkg/config/v3"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/lease"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testConfig = `
+client:
+  version: v3
+  address: "127.0.0.1:9669"
+  user: root
+  password: secret
+manager:
+  spaceName: basketball
+log:
+  files:
+    - /tasks/task1/import.log
+sources:
+  - path: /upload/player.csv
+    csv:
+      delimiter: ","
+      withHeader: true
+    tags:
+      - name: player
+        id:
+          type: "STRING"
+          index: 0
+  - path: /upload/team.csv
+    tags:
+      - name: team
+        id:
+          type: "STRING"
+          index: 0
+`
+
+func setupTaskDB(t *testing.T) {
+	d, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection opens a new memory database
+	sqlDB, err := d.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := d.AutoMigrate(&db.LLMJob{}, &db.TaskInfo{}, &db.TaskEffect{}, &db.JobLease{}); err != nil {
+		t.Fatal(err)
+	}
+	db.CtxDB = d
+	GetTaskMgr().db = &TaskDb{DB: d}
+}
+
+func testImportConfig(t *testing.T) importconfig.Configurator {
+	cfg, err := importconfig.FromBytes([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestRuntimeConfig(t *testing.T) {
+	encrypted, err := EncryptRuntimeConfig(testImportConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(encrypted, "secret") || strings.Contains(encrypted, "player.csv") {
+		t.Errorf("the runtime config is kept in plain text: %s", encrypted)
+	}
+	cfg, err := decryptRuntimeConfig(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confv3 := cfg.(*configv3.Config)
+	if confv3.Client.Password != "secret" {
+		t.Errorf("unexpected password: %s", confv3.Client.Password)
+	}
+	if len(confv3.Sources) != 2 || confv3.Sources[0].SourceConfig.Local.Path != "/upload/player.csv" {
+		t.Errorf("unexpected sources: %v", confv3.Sources)
+	}
+	if _, err := decryptRuntimeConfig("not encrypted"); err == nil {
+		t.Error("expect an error of the config not encrypted")
+	}
+}
+
+func TestLoadRuntimeConfig(t *testing.T) {
+	setupTaskDB(t)
+	mgr := GetTaskMgr()
+
+	// no task effect
+	if _, _, err := mgr.loadRuntimeConfig("task1"); err != errNoRuntimeConfig {
+		t.Errorf("unexpected error of a task without effect: %v", err)
+	}
+
+	// created by an older version
+	if err := mgr.db.InsertTaskEffect(&db.TaskEffect{BID: "task1", Config: "config.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mgr.loadRuntimeConfig("task1"); err != errNoRuntimeConfig {
+		t.Errorf("unexpected error of a task without runtime config: %v", err)
+	}
+
+	runtimeConfig, err := EncryptRuntimeConfig(testImportConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeSources, err := EncodeStoreSources([]*StoreSource{{Index: 1, DatasourceID: "ds1", Path: "/team.csv"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskEffect := &db.TaskEffect{BID: "task2", Config: "config.yaml", RuntimeConfig: runtimeConfig, StoreSources: storeSources}
+	if err := mgr.db.InsertTaskEffect(taskEffect); err != nil {
+		t.Fatal(err)
+	}
+	cfg, sources, err := mgr.loadRuntimeConfig("task2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.(*configv3.Config).Manager.GraphName != "basketball" {
+		t.Errorf("unexpected space: %s", cfg.(*configv3.Config).Manager.GraphName)
+	}
+	if len(sources) != 1 || sources[0].Index != 1 || sources[0].DatasourceID != "ds1" || sources[0].Path != "/team.csv" {
+		t.Errorf("unexpected store sources: %v", sources)
+	}
+
+	// the key has changed
+	if err := mgr.db.Model(&db.TaskEffect{}).Where("task_id = ?", "task2").Update("runtime_config", "broken").Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mgr.loadRuntimeConfig("task2"); err == nil || err == errNoRuntimeConfig {
+		t.Errorf("unexpected error of a broken runtime config: %v", err)
+	}
+}
+
+func TestResumeTaskWithoutRuntimeConfig(t *testing.T) {
+	setupTaskDB(t)
+	mgr := GetTaskMgr()
+	taskInfo := &db.TaskInfo{BID: "task1", TaskStatus: Processing.String()}
+	if err := mgr.db.InsertTaskInfo(taskInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr.ResumeTask(taskInfo, "studio restart")
+	stored, err := mgr.db.FindTaskInfoByIdAndAddresssAndUser("task1", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.TaskStatus != Aborted.String() || stored.TaskMessage != "Service execption" {
+		t.Errorf("unexpected task status %s: %s", stored.TaskStatus, stored.TaskMessage)
+	}
+	if l, err := lease.Get(lease.KindImportTask, "task1"); err != nil || l != nil {
+		t.Errorf("expect the lease released, got %v: %v", l, err)
+	}
+	if _, ok := mgr.getTaskFromMap("task1"); ok {
+		t.Error("unexpected task in memory")
+	}
+}
+
+func TestResumeTaskLeasedByOther(t *testing.T) {
+	setupTaskDB(t)
+	mgr := GetTaskMgr()
+	taskInfo := &db.TaskInfo{BID: "task1", TaskStatus: Processing.String()}
+	if err := mgr.db.InsertTaskInfo(taskInfo); err != nil {
+		t.Fatal(err)
+	}
+	other := &db.JobLease{Kind: lease.KindImportTask, JobID: "task1", Owner: "other", ExpireTime: time.Now().Add(time.Minute)}
+	if err := db.CtxDB.Create(other).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// the task is resumed by the other instance
+	mgr.ResumeTask(taskInfo, "studio restart")
+	stored, err := mgr.db.FindTaskInfoByIdAndAddresssAndUser("task1", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.TaskStatus != Processing.String() {
+		t.Errorf("unexpected task status %s", stored.TaskStatus)
+	}
+	if l, err := lease.Get(lease.KindImportTask, "task1"); err != nil || l == nil || l.Owner != "other" {
+		t.Errorf("expect the lease kept by the other instance, got %v: %v", l, err)
+	}
+}
@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	configv3 "github.com/vesoft-inc/nebula-importer/v4/pkg/config/v3"
+	"github.com/vesoft-inc/nebula-importer/v4/pkg/logger"
+	"github.com/vesoft-inc/nebula-importer/v4/pkg/source"
+	studioConfig "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/notify"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+RetryImportTask queues a child task which imports the failed records of the task again.
+Every source of the child reads the error file of the parent source,
+the sources without failed records are left out.
+*/
+func RetryImportTask(taskID, childID, address, username string) error {
+	mgr := GetTaskMgr()
+	parent, err := mgr.db.FindTaskInfoByIdAndAddresssAndUser(taskID, address, username)
+	if err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	switch parent.TaskStatus {
+	case Processing.String(), Queued.String(), Draft.String():
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("task is %s", parent.TaskStatus), "only finished tasks can be retried")
+	}
+	// the failed records are local files, none of them is read by studio
+	cfg, _, err := mgr.loadRuntimeConfig(taskID)
+	if err == errNoRuntimeConfig {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, err, "the task is created by an older version, please create it again")
+	}
+	if err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+
+	tasksDir := studioConfig.GetConfig().File.TasksDir
+	errDir := filepath.Join(tasksDir, taskID, errContentDir)
+	confv3 := cfg.(*configv3.Config)
+	sources := make(configv3.Sources, 0, len(confv3.Sources))
+	for idx, s := range confv3.Sources {
+		errFile := filepath.Join(errDir, errFileName(idx))
+		if fi, err := os.Stat(errFile); err != nil || fi.Size() == 0 {
+			continue
+		}
+		csvConfig := &source.CSVConfig{}
+		if s.SourceConfig.CSV != nil {
+			*csvConfig = *s.SourceConfig.CSV
+		}
+		// the failed records are written without header
+		csvConfig.WithHeader = false
+		s.SourceConfig = source.Config{
+			Local: &source.LocalConfig{Path: errFile},
+			CSV:   csvConfig,
+		}
+		sources = append(sources, s)
+	}
+	if len(sources) == 0 {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, errors.New("no failed records"), "the task has no failed records to retry")
+	}
+	confv3.Sources = sources
+
+	childDir, err := CreateNewTaskDir(tasksDir, childID)
+	if err != nil {
+		return err
+	}
+	if confv3.Log != nil {
+		for i, file := range confv3.Log.Files {
+			if filepath.Base(file) == taskLogName {
+				confv3.Log.Files[i] = filepath.Join(childDir, taskLogName)
+			}
+		}
+	}
+	cfgBytes, err := yaml.Marshal(confv3)
+	if err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	configFile, err := CreateConfigFile(childDir, cfgBytes)
+	if err != nil {
+		return err
+	}
+	runtimeConfig, err := EncryptRuntimeConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s (retry)", parent.Name)
+	taskEffect := &db.TaskEffect{BID: childID, Config: configFile, RuntimeConfig: runtimeConfig}
+	opts := QueueOptions{
+		Priority:       parent.Priority,
+		NotifyChannels: notify.SplitChannels(parent.NotifyChannels),
+		ParentID:       taskID,
+	}
+	// the child is queued with its config and lineage at once, then the queue is woken up
+	if _, err = mgr.NewTask(childID, parent.Address, parent.User, name, parent.RawConfig, cfg, opts, taskEffect); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	appendTaskLog(taskID, fmt.Sprintf("studio: the failed records are retried by task %s", childID))
+	Schedule()
+	return nil
+}
+
+// appendTaskLog writes a line into the log of a task which has stopped
+func appendTaskLog(taskID, msg string) {
+	logFile := filepath.Join(studioConfig.GetConfig().File.TasksDir, taskID, taskLogName)
+	l, err := logger.New(logger.WithConsole(false), logger.WithFiles(logFile))
+	if err != nil {
+		logx.Errorf("open log of task %s failed: %s", taskID, err)
+		return
+	}
+	l.Info(msg)
+	l.Close()
+	if err := GetTaskMgr().StorePartTaskLog(taskID); err != nil {
+		logx.Errorf("store log of task %s failed: %s", taskID, err)
+	}
+}
@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	configv3 "github.com/vesoft-inc/nebula-importer/v4/pkg/config/v3"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+)
+
+// setupRetryParent stores a finished task of the test config, with the failed records of the given sources
+func setupRetryParent(t *testing.T, status string, failed map[int]string) string {
+	setupTaskDB(t)
+	tasksDir := setupTasksDir(t)
+	mgr := GetTaskMgr()
+	runtimeConfig, err := EncryptRuntimeConfig(testImportConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskInfo := &db.TaskInfo{
+		BID:            "task1",
+		Name:           "players",
+		Address:        "127.0.0.1:9669",
+		User:           "root",
+		TaskStatus:     status,
+		Priority:       3,
+		NotifyChannels: "channel1",
+	}
+	if err := mgr.db.InsertQueuedTask(taskInfo, &db.TaskEffect{BID: "task1", Config: testConfig, RuntimeConfig: runtimeConfig}); err != nil {
+		t.Fatal(err)
+	}
+	errDir := filepath.Join(tasksDir, "task1", errContentDir)
+	if err := os.MkdirAll(errDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for idx, records := range failed {
+		if err := os.WriteFile(filepath.Join(errDir, errFileName(idx)), []byte(records), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tasksDir
+}
+
+func TestRetryImportTask(t *testing.T) {
+	// the second source has an empty error file
+	tasksDir := setupRetryParent(t, Finished.String(), map[int]string{0: "p1,Tom\n", 1: ""})
+	mgr := GetTaskMgr()
+
+	if err := RetryImportTask("task1", "child1", "127.0.0.1:9669", "root"); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := mgr.db.FindQueuedTaskInfos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].BID != "child1" {
+		t.Fatalf("unexpected queued tasks: %v", tasks)
+	}
+	child := tasks[0]
+	if child.ParentID != "task1" || child.Name != "players (retry)" || child.Priority != 3 || child.NotifyChannels != "channel1" {
+		t.Errorf("unexpected child: %+v", child)
+	}
+	if child.Address != "127.0.0.1:9669" || child.User != "root" || child.Space != "basketball" {
+		t.Errorf("unexpected owner of the child: %+v", child)
+	}
+
+	cfg, storeSources, err := mgr.loadRuntimeConfig("child1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(storeSources) != 0 {
+		t.Errorf("unexpected store sources: %v", storeSources)
+	}
+	confv3 := cfg.(*configv3.Config)
+	if len(confv3.Sources) != 1 {
+		t.Fatalf("unexpected sources: %v", confv3.Sources)
+	}
+	s := confv3.Sources[0]
+	errFile := filepath.Join(tasksDir, "task1", errContentDir, errFileName(0))
+	if s.SourceConfig.Local == nil || s.SourceConfig.Local.Path != errFile {
+		t.Errorf("unexpected source of the child: %v", s.SourceConfig)
+	}
+	// the failed records are written without header, in the format of the source
+	if s.SourceConfig.CSV == nil || s.SourceConfig.CSV.WithHeader || s.SourceConfig.CSV.Delimiter != "," {
+		t.Errorf("unexpected csv of the child: %v", s.SourceConfig.CSV)
+	}
+	if len(s.Nodes) != 1 || s.Nodes[0].Name != "player" {
+		t.Errorf("unexpected tags of the child: %v", s.Nodes)
+	}
+	if files := confv3.Log.Files; len(files) != 1 || files[0] != filepath.Join(tasksDir, "child1", taskLogName) {
+		t.Errorf("unexpected log of the child: %v", files)
+	}
+	configFile, err := os.ReadFile(filepath.Join(tasksDir, "child1", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(configFile), errFile) || strings.Contains(string(configFile), "secret") {
+		t.Errorf("unexpected config.yaml of the child: %s", configFile)
+	}
+
+	// the parent log tells the retry
+	parentLog, err := os.ReadFile(filepath.Join(tasksDir, "task1", taskLogName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(parentLog), "retried by task child1") {
+		t.Errorf("unexpected log of the parent: %s", parentLog)
+	}
+}
+
+func TestRetryImportTaskRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		failed   map[int]string
+		username string
+	}{
+		{"running", Processing.String(), map[int]string{0: "p1,Tom\n"}, "root"},
+		{"queued", Queued.String(), map[int]string{0: "p1,Tom\n"}, "root"},
+		{"no failed records", Finished.String(), map[int]string{0: ""}, "root"},
+		{"no error files", Finished.String(), nil, "root"},
+		{"other user", Finished.String(), map[int]string{0: "p1,Tom\n"}, "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRetryParent(t, tt.status, tt.failed)
+			if err := RetryImportTask("task1", "child1", "127.0.0.1:9669", tt.username); err == nil {
+				t.Error("expect an error")
+			}
+			tasks, err := GetTaskMgr().db.FindQueuedTaskInfos()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, taskInfo := range tasks {
+				if taskInfo.BID == "child1" {
+					t.Errorf("unexpected child queued")
+				}
+			}
+		})
+	}
+}
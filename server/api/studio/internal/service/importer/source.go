@@ -0,0 +1,224 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/vesoft-inc/nebula-importer/v4/pkg/source"
+	studioConfig "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/filestore"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
+)
+
+/*
+StoreSource is a source read by studio through the store of its datasource, for the datasources
+the importer can not read by itself, such as sftp with the verified host key, ftp over TLS, hdfs without a namenode rpc address or with kerberos,
+azure blob storage, gcs and the result of a sql query, the path of which is the query,
+and for the archive members, uploaded or in a datasource.
+The file is streamed when each run of the task starts, nothing is downloaded before
+but a zip archive which is not a local file.
+*/
+type StoreSource struct {
+	// the index of the source in the config
+	Index        int    `json:"index"`
+	DatasourceID string `json:"datasourceId,omitempty"`
+	// the dir of the uploaded files, for an uploaded archive member
+	UploadDir string `json:"uploadDir,omitempty"`
+	Path      string `json:"path"`
+}
+
+// EncodeStoreSources returns the store sources kept by the task effect
+func EncodeStoreSources(sources []*StoreSource) (string, error) {
+	if len(sources) == 0 {
+		return "", nil
+	}
+	out, err := json.Marshal(sources)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func decodeStoreSources(s string) ([]*StoreSource, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var sources []*StoreSource
+	if err := json.Unmarshal([]byte(s), &sources); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// OpenDatasource connects the store of a datasource with its decrypted secret
+func OpenDatasource(id string) (filestore.FileStore, *db.Datasource, error) {
+	var dbs db.Datasource
+	if err := db.CtxDB.Where("b_id = ?", id).First(&dbs).Error; err != nil {
+		return nil, nil, fmt.Errorf("datasource don't exist")
+	}
+	secret, err := utils.Decrypt(dbs.Secret, []byte(utils.CipherKey))
+	if err != nil {
+		return nil, nil, err
+	}
+	if dbs.Type == "sql" {
+		var c filestore.SqlConfig
+		if err := json.Unmarshal([]byte(dbs.Config), &c); err != nil {
+			return nil, &dbs, err
+		}
+		if err := CheckSqlite(c.Driver, c.DSN, dbs.Host, dbs.Username); err != nil {
+			return nil, &dbs, err
+		}
+	}
+	store, err := filestore.NewFileStore(dbs.Type, dbs.Config, string(secret), dbs.Platform)
+	if err != nil {
+		return nil, &dbs, err
+	}
+	if err := PinHostKey(&dbs, store); err != nil {
+		store.Close()
+		return nil, &dbs, err
+	}
+	return store, &dbs, nil
+}
+
+/*
+PinHostKey saves the host key trusted on the first connection of a sftp datasource saved without any,
+such as by an older version, so that the later connections refuse another server.
+*/
+func PinHostKey(dbs *db.Datasource, store filestore.FileStore) error {
+	hostKey := filestore.SftpHostKey(store)
+	if dbs.Type != "sftp" || hostKey == "" {
+		return nil
+	}
+	var c filestore.SftpConfig
+	if err := json.Unmarshal([]byte(dbs.Config), &c); err != nil {
+		return err
+	}
+	if c.HostKey != "" || c.KnownHosts != "" {
+		return nil
+	}
+	var cfg map[string]interface{}
+	if err := json.Unmarshal([]byte(dbs.Config), &cfg); err != nil {
+		return err
+	}
+	cfg["hostKey"] = hostKey
+	pinned, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	result := db.CtxDB.Model(&db.Datasource{}).Where("b_id = ? AND config = ?", dbs.BID, dbs.Config).Update("config", string(pinned))
+	if result.Error != nil {
+		return fmt.Errorf("pin the host key of the sftp server error: %s", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// pinned by another connection at the same time
+		var saved db.Datasource
+		if err := db.CtxDB.Where("b_id = ?", dbs.BID).First(&saved).Error; err != nil {
+			return fmt.Errorf("datasource don't exist")
+		}
+		c = filestore.SftpConfig{}
+		if err := json.Unmarshal([]byte(saved.Config), &c); err != nil {
+			return err
+		}
+		if c.HostKey != hostKey {
+			return fmt.Errorf("the host key %s of %s does not match the pinned %s", hostKey, c.Host, c.HostKey)
+		}
+		dbs.Config = saved.Config
+		return nil
+	}
+	dbs.Config = string(pinned)
+	return nil
+}
+
+// CheckSqlite accepts a sqlite database in the sqlite dir or in the uploaded files of the user, never the database of studio
+func CheckSqlite(driver, dsn, host, username string) error {
+	if driver != filestore.SqlDriverSQLite {
+		return nil
+	}
+	c := studioConfig.GetConfig()
+	dirs := []string{c.File.SqliteDir, utils.UserUploadDir(c.File.UploadDir, host, username)}
+	return filestore.CheckSqliteFile(dsn, dirs, []string{c.DB.SqliteDbFilePath})
+}
+
+// storeSource reads a StoreSource as an importer source
+type storeSource struct {
+	ref *StoreSource
+	c   *source.Config
+	// the dir to download a zip archive into
+	tempDir string
+	store   filestore.FileStore
+	r       io.ReadCloser
+	size    int64
+}
+
+func newStoreSource(ref *StoreSource, c *source.Config, tempDir string) *storeSource {
+	return &storeSource{ref: ref, c: c, tempDir: tempDir}
+}
+
+func (s *storeSource) Config() *source.Config {
+	return s.c
+}
+
+func (s *storeSource) Name() string {
+	if s.ref.DatasourceID == "" {
+		return "uploaded file " + s.ref.Path
+	}
+	return fmt.Sprintf("datasource %s %s", s.ref.DatasourceID, s.ref.Path)
+}
+
+func (s *storeSource) Open() error {
+	var (
+		store filestore.FileStore
+		typ   = "local"
+	)
+	if s.ref.DatasourceID == "" {
+		store = filestore.WithArchives(filestore.NewLocalStore(s.ref.UploadDir))
+	} else {
+		var (
+			dbs *db.Datasource
+			err error
+		)
+		if store, dbs, err = OpenDatasource(s.ref.DatasourceID); err != nil {
+			return err
+		}
+		typ = dbs.Type
+	}
+	store = filestore.WithTempDir(store, s.tempDir)
+	opener, ok := store.(filestore.Opener)
+	if !ok {
+		store.Close()
+		return fmt.Errorf("the datasource can not read %s as a stream", s.ref.Path)
+	}
+	// list the size before the transfer, which holds the connection of some stores such as ftp,
+	// the size of a query result is unknown
+	if typ != "sql" && !filestore.IsCompressed(s.ref.Path) {
+		if f, err := filestore.Stat(store, s.ref.Path); err == nil {
+			s.size = f.Size
+		}
+	}
+	r, err := opener.Open(s.ref.Path)
+	if err != nil {
+		store.Close()
+		return err
+	}
+	s.store, s.r = store, r
+	return nil
+}
+
+// Size is 0 if the size is unknown before the file is read to the end
+func (s *storeSource) Size() (int64, error) {
+	return s.size, nil
+}
+
+func (s *storeSource) Read(p []byte) (int, error) {
+	return s.r.Read(p)
+}
+
+func (s *storeSource) Close() error {
+	err := s.r.Close()
+	if closeErr := s.store.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"encoding/json"
+	"testing"
+
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/filestore"
+)
+
+func TestPinHostKey(t *testing.T) {
+	setupTaskDB(t)
+	if err := db.CtxDB.AutoMigrate(&db.Datasource{}); err != nil {
+		t.Fatal(err)
+	}
+	// saved by an older version without the host key
+	dbs := &db.Datasource{BID: "ds1", Type: "sftp", Config: `{"host":"127.0.0.1","port":22,"username":"root"}`}
+	if err := db.CtxDB.Create(dbs).Error; err != nil {
+		t.Fatal(err)
+	}
+	stale := *dbs
+	store := filestore.WithArchives(&filestore.SftpStore{HostKey: "SHA256:first"})
+
+	if err := PinHostKey(dbs, store); err != nil {
+		t.Fatal(err)
+	}
+	var saved db.Datasource
+	if err := db.CtxDB.Where("b_id = ?", "ds1").First(&saved).Error; err != nil {
+		t.Fatal(err)
+	}
+	var c filestore.SftpConfig
+	if err := json.Unmarshal([]byte(saved.Config), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.HostKey != "SHA256:first" || c.Host != "127.0.0.1" || c.Port != 22 || c.Username != "root" {
+		t.Errorf("unexpected pinned config: %s", saved.Config)
+	}
+	if dbs.Config != saved.Config {
+		t.Errorf("unexpected config of the datasource: %s", dbs.Config)
+	}
+
+	// the same server is accepted by a connection started before the pin
+	if err := PinHostKey(&stale, store); err != nil {
+		t.Errorf("unexpected error of the same host key: %s", err)
+	}
+	// another server is refused
+	stale.Config = `{"host":"127.0.0.1","port":22,"username":"root"}`
+	if err := PinHostKey(&stale, filestore.WithArchives(&filestore.SftpStore{HostKey: "SHA256:second"})); err == nil {
+		t.Error("expect an error of another host key")
+	}
+	if err := db.CtxDB.Where("b_id = ?", "ds1").First(&saved).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.Config != dbs.Config {
+		t.Errorf("unexpected config after another host key: %s", saved.Config)
+	}
+
+	// the other stores are not pinned
+	other := &db.Datasource{BID: "ds2", Type: "ftp", Config: `{"host":"127.0.0.1"}`}
+	if err := PinHostKey(other, filestore.WithArchives(&filestore.FtpStore{})); err != nil {
+		t.Fatal(err)
+	}
+}
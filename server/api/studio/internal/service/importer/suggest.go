@@ -0,0 +1,529 @@
+package importer
+
+import (
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+)
+
+// the lowest score of a name or a mapping to be suggested
+const suggestMinScore = 0.6
+
+type (
+	// ColumnProfile describes a column of the sampled rows
+	ColumnProfile struct {
+		Index int
+		// Name is the header of the column, it is empty without the header
+		Name string
+		// Type is inferred from the values, one of int, double, bool, date, datetime and string
+		Type string
+		// Values are the non-empty sampled values
+		Values []string
+		Empty  int
+		Unique bool
+		MaxLen int
+	}
+
+	// Suggestion is a tag or an edge the columns are mapped onto
+	Suggestion struct {
+		Kind  string
+		Name  string
+		Score float64
+		Tag   *types.Tag
+		Edge  *types.Edge
+	}
+
+	propMatch struct {
+		prop   *SchemaProp
+		column *ColumnProfile
+		score  float64
+	}
+)
+
+var (
+	headerCellRe = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_ .:()-]*$`)
+
+	srcWords = map[string]bool{"src": true, "source": true, "from": true}
+	dstWords = map[string]bool{"dst": true, "dest": true, "destination": true, "target": true, "to": true}
+)
+
+// EscapeName escapes the name of a space, tag, edge or property in the task config
+func EscapeName(name string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(name)
+}
+
+// inferType returns the narrowest type all the values can be converted to
+func inferType(values []string) string {
+	if len(values) == 0 {
+		return "string"
+	}
+	matchAll := func(check func(string) bool) bool {
+		for _, v := range values {
+			if !check(v) {
+				return false
+			}
+		}
+		return true
+	}
+	switch {
+	case matchAll(func(v string) bool { _, err := strconv.ParseInt(v, 10, 64); return err == nil }):
+		return "int"
+	case matchAll(func(v string) bool { _, err := strconv.ParseFloat(v, 64); return err == nil }):
+		return "double"
+	case matchAll(func(v string) bool { _, err := strconv.ParseBool(v); return err == nil }):
+		return "bool"
+	case matchAll(func(v string) bool { _, err := time.Parse("2006-01-02", v); return err == nil }):
+		return "date"
+	case matchAll(func(v string) bool {
+		for _, layout := range timestampLayouts {
+			if _, err := time.Parse(layout, v); err == nil {
+				return true
+			}
+		}
+		return false
+	}):
+		return "datetime"
+	}
+	return "string"
+}
+
+/*
+DetectHeader guesses whether the first row is the header.
+It is when the cells of the first row look like names, and either a column of the other rows has a type
+the cell does not match, or none of the cells appears in the other rows of its column.
+*/
+func DetectHeader(rows [][]string) bool {
+	if len(rows) < 2 || len(rows[0]) == 0 {
+		return false
+	}
+	typed, repeated := false, false
+	for idx, cell := range rows[0] {
+		cell = strings.TrimSpace(cell)
+		if !headerCellRe.MatchString(cell) || inferType([]string{cell}) != "string" {
+			return false
+		}
+		var values []string
+		for _, row := range rows[1:] {
+			if idx < len(row) && row[idx] != "" {
+				values = append(values, row[idx])
+				repeated = repeated || row[idx] == rows[0][idx]
+			}
+		}
+		typed = typed || inferType(values) != "string"
+	}
+	return typed || !repeated
+}
+
+// ProfileColumns infers the type of each column of the rows, the header is nil without the header
+func ProfileColumns(header []string, rows [][]string) []*ColumnProfile {
+	width := len(header)
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	columns := make([]*ColumnProfile, 0, width)
+	for idx := 0; idx < width; idx++ {
+		c := &ColumnProfile{Index: idx, Unique: true}
+		if idx < len(header) {
+			c.Name = strings.TrimSpace(header[idx])
+		}
+		seen := make(map[string]bool, len(rows))
+		for _, row := range rows {
+			if idx >= len(row) || row[idx] == "" {
+				c.Empty++
+				continue
+			}
+			v := row[idx]
+			c.Unique = c.Unique && !seen[v]
+			seen[v] = true
+			c.Values = append(c.Values, v)
+			if len(v) > c.MaxLen {
+				c.MaxLen = len(v)
+			}
+		}
+		c.Type = inferType(c.Values)
+		columns = append(columns, c)
+	}
+	return columns
+}
+
+// nameTokens splits a name into the lower case words of the snake case, kebab case and camel case
+func nameTokens(name string) []string {
+	var (
+		tokens []string
+		word   []rune
+	)
+	runes := []rune(name)
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			flush()
+		}
+		word = append(word, r)
+	}
+	flush()
+	return tokens
+}
+
+func normalizeName(name string) string {
+	return strings.Join(nameTokens(name), "")
+}
+
+// levenshtein returns the edit distance of the runes of the two strings
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+/*
+nameSimilarity scores the similarity of two names in [0, 1].
+The names equal after the normalization score 1, a name made of the words of the other and one more word scores 0.8,
+the others score by the edit distance of the normalized names.
+*/
+func nameSimilarity(a, b string) float64 {
+	na, nb := normalizeName(a), normalizeName(b)
+	if na == "" || nb == "" {
+		return 0
+	}
+	if na == nb {
+		return 1
+	}
+	ta, tb := nameTokens(a), nameTokens(b)
+	if len(ta) > len(tb) {
+		ta, tb = tb, ta
+	}
+	if len(tb) == len(ta)+1 && (strings.Join(tb[1:], "") == strings.Join(ta, "") || strings.Join(tb[:len(ta)], "") == strings.Join(ta, "")) {
+		return 0.8
+	}
+	ra, rb := []rune(na), []rune(nb)
+	return 1 - float64(levenshtein(ra, rb))/math.Max(float64(len(ra)), float64(len(rb)))
+}
+
+// typeScore scores how well the column fits the schema type, 0 means some value can not be converted
+func typeScore(c *ColumnProfile, sp *SchemaProp) float64 {
+	if c.Empty > 0 && !sp.Nullable {
+		return 0
+	}
+	for _, v := range c.Values {
+		if !isValidValue(sp.Type, v) {
+			return 0
+		}
+	}
+	if m := fixedStringRe.FindStringSubmatch(sp.Type); m != nil {
+		if length, _ := strconv.Atoi(m[1]); c.MaxLen > length {
+			return 0
+		}
+	}
+	switch typ := importType(sp.Type); {
+	case typ == c.Type, typ == "float" && c.Type == "double", typ == "timestamp" && (c.Type == "int" || c.Type == "datetime"):
+		return 1
+	case typ == "double" || typ == "float":
+		// the integers fit the floating types, but the integer types fit better
+		return 0.9
+	case typ == "string" && c.Type != "string":
+		return 0.8
+	}
+	return 0.9
+}
+
+// vidCompatible checks whether all the values of the column can be the vid of the space
+func vidCompatible(c *ColumnProfile, vidType string) bool {
+	if c.Empty > 0 || len(c.Values) == 0 {
+		return false
+	}
+	if strings.EqualFold(vidType, "INT64") {
+		return c.Type == "int"
+	}
+	if m := fixedStringRe.FindStringSubmatch(vidType); m != nil {
+		length, _ := strconv.Atoi(m[1])
+		return c.MaxLen <= length
+	}
+	return true
+}
+
+// vidScore scores a column as the vid of the tag by its name
+func vidScore(c *ColumnProfile, tag string) float64 {
+	best := 0.0
+	for _, name := range []string{tag + "_id", tag + "_vid", tag + "_key"} {
+		best = math.Max(best, nameSimilarity(c.Name, name))
+	}
+	for _, name := range []string{"vid", "id"} {
+		best = math.Max(best, 0.9*nameSimilarity(c.Name, name))
+	}
+	return best
+}
+
+// endpointScore scores a column as the src or the dst of an edge by its words, such as src, src_id and from_player_id
+func (s *SpaceSchema) endpointScore(c *ColumnProfile, words map[string]bool) float64 {
+	tokens := nameTokens(c.Name)
+	if len(tokens) == 0 || !words[tokens[0]] {
+		return 0
+	}
+	rest := tokens[1:]
+	if len(rest) > 0 && (rest[len(rest)-1] == "id" || rest[len(rest)-1] == "vid") {
+		rest = rest[:len(rest)-1]
+	}
+	if len(rest) == 0 {
+		return 1
+	}
+	for tag := range s.Tags {
+		if normalizeName(tag) == strings.Join(rest, "") {
+			return 0.9
+		}
+	}
+	return 0
+}
+
+// matchProps maps each prop onto the column of the highest score, a column is mapped once at most
+func matchProps(target string, props []SchemaProp, columns []*ColumnProfile) []propMatch {
+	var candidates []propMatch
+	for i := range props {
+		sp := &props[i]
+		for _, c := range columns {
+			if c.Name == "" {
+				continue
+			}
+			nameScore := math.Max(nameSimilarity(c.Name, sp.Name), nameSimilarity(c.Name, target+"_"+sp.Name))
+			if nameScore < suggestMinScore {
+				continue
+			}
+			if score := nameScore * typeScore(c, sp); score >= suggestMinScore {
+				candidates = append(candidates, propMatch{prop: sp, column: c, score: score})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	var (
+		matches       []propMatch
+		mappedProps   = make(map[string]bool)
+		mappedColumns = make(map[int]bool)
+	)
+	for _, m := range candidates {
+		if mappedProps[m.prop.Name] || mappedColumns[m.column.Index] {
+			continue
+		}
+		mappedProps[m.prop.Name], mappedColumns[m.column.Index] = true, true
+		matches = append(matches, m)
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].column.Index < matches[j].column.Index })
+	return matches
+}
+
+/*
+scoreProps sums the scores of the mapped props, an unmapped prop which is not nullable and has no default value
+costs a point because the import fails without it.
+*/
+func scoreProps(props []SchemaProp, matches []propMatch) (float64, []types.Prop) {
+	score := 0.0
+	mapped := make(map[string]bool, len(matches))
+	configProps := make([]types.Prop, 0, len(matches))
+	for _, m := range matches {
+		score += m.score
+		mapped[m.prop.Name] = true
+		configProps = append(configProps, types.Prop{
+			Name:     EscapeName(m.prop.Name),
+			Type:     importType(m.prop.Type),
+			Index:    int64(m.column.Index),
+			Nullable: m.column.Empty > 0 && m.prop.Nullable,
+		})
+	}
+	for _, sp := range props {
+		if !mapped[sp.Name] && !sp.Nullable && !sp.HasDefault {
+			score--
+		}
+	}
+	return score, configProps
+}
+
+func vidNodeId(c *ColumnProfile, vidType string) types.NodeId {
+	typ := "string"
+	if strings.EqualFold(vidType, "INT64") {
+		typ = "int"
+	}
+	return types.NodeId{Type: typ, Index: int64(c.Index)}
+}
+
+func (s *SpaceSchema) suggestTag(name string, columns []*ColumnProfile, fileScore float64) *Suggestion {
+	var (
+		vid      *ColumnProfile
+		vidMatch float64
+	)
+	for _, c := range columns {
+		if !vidCompatible(c, s.VidType) || !c.Unique {
+			continue
+		}
+		if score := vidScore(c, name); score > vidMatch {
+			vid, vidMatch = c, score
+		}
+	}
+	if vidMatch < suggestMinScore {
+		vid, vidMatch = nil, 0
+		// the first column of unique values is the most likely vid of a vertex file
+		if len(columns) > 0 && vidCompatible(columns[0], s.VidType) && columns[0].Unique {
+			vid = columns[0]
+		}
+	}
+	if vid == nil {
+		return nil
+	}
+	props := s.Tags[name]
+	matches := matchProps(name, props, columns)
+	// a tag without props is suggested by the name of the file or the vid column only
+	if len(matches) == 0 && fileScore == 0 && (vidMatch < 1 || len(props) > 0) {
+		return nil
+	}
+	score, configProps := scoreProps(props, matches)
+	return &Suggestion{
+		Kind:  "tag",
+		Name:  name,
+		Score: score + vidMatch + 2*fileScore,
+		Tag:   &types.Tag{Name: EscapeName(name), ID: vidNodeId(vid, s.VidType), Props: configProps},
+	}
+}
+
+func (s *SpaceSchema) suggestEdge(name string, columns []*ColumnProfile, fileScore float64) *Suggestion {
+	var (
+		src, dst           *ColumnProfile
+		srcMatch, dstMatch float64
+		rank               *ColumnProfile
+	)
+	for _, c := range columns {
+		if c.Type == "int" && c.Empty == 0 && normalizeName(c.Name) == "rank" {
+			rank = c
+		}
+		if !vidCompatible(c, s.VidType) {
+			continue
+		}
+		if score := s.endpointScore(c, srcWords); score > srcMatch {
+			src, srcMatch = c, score
+		}
+		if score := s.endpointScore(c, dstWords); score > dstMatch {
+			dst, dstMatch = c, score
+		}
+	}
+	if src == nil || dst == nil {
+		// the columns of the vids of two tags, such as player_id and team_id, are the src and the dst in turn
+		var ids []*ColumnProfile
+		for _, c := range columns {
+			if !vidCompatible(c, s.VidType) {
+				continue
+			}
+			for tag := range s.Tags {
+				if score := vidScore(c, tag); score >= 0.8 && normalizeName(c.Name) != "id" && normalizeName(c.Name) != "vid" {
+					ids = append(ids, c)
+					break
+				}
+			}
+		}
+		if len(ids) != 2 {
+			return nil
+		}
+		src, dst, srcMatch, dstMatch = ids[0], ids[1], suggestMinScore, suggestMinScore
+	}
+	if src == dst {
+		return nil
+	}
+	props := s.Edges[name]
+	matches := matchProps(name, props, columns)
+	if len(matches) == 0 && fileScore == 0 {
+		return nil
+	}
+	score, configProps := scoreProps(props, matches)
+	edge := &types.Edge{
+		Name:  EscapeName(name),
+		Src:   types.EdgeNodeRef{ID: vidNodeId(src, s.VidType)},
+		Dst:   types.EdgeNodeRef{ID: vidNodeId(dst, s.VidType)},
+		Props: configProps,
+	}
+	if rank != nil && rank != src && rank != dst {
+		index := int64(rank.Index)
+		edge.Rank = &types.EdgeRank{Index: &index}
+	}
+	return &Suggestion{
+		Kind:  "edge",
+		Name:  name,
+		Score: score + (srcMatch+dstMatch)/2 + 2*fileScore,
+		Edge:  edge,
+	}
+}
+
+/*
+SuggestMapping proposes the tags and edges the columns of a file are mapped onto by the name similarity
+of the columns and the props, the type compatibility of the sampled values, and the name of the file.
+The suggestions scoring less than half of the best are left out, the rest are sorted by the score.
+*/
+func SuggestMapping(schema *SpaceSchema, columns []*ColumnProfile, fileName string) []*Suggestion {
+	base := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+	fileScore := func(name string) float64 {
+		if score := nameSimilarity(base, name); score >= suggestMinScore {
+			return score
+		}
+		return 0
+	}
+	sortedNames := func(m map[string][]SchemaProp) []string {
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	var suggestions []*Suggestion
+	for _, name := range sortedNames(schema.Tags) {
+		if s := schema.suggestTag(name, columns, fileScore(name)); s != nil {
+			suggestions = append(suggestions, s)
+		}
+	}
+	for _, name := range sortedNames(schema.Edges) {
+		if s := schema.suggestEdge(name, columns, fileScore(name)); s != nil {
+			suggestions = append(suggestions, s)
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Score > suggestions[j].Score })
+	result := suggestions[:0]
+	for _, s := range suggestions {
+		if s.Score > 0 && s.Score >= suggestions[0].Score/2 {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"testing"
+)
+
+func TestDetectHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]string
+		want bool
+	}{
+		{"typed column", [][]string{{"id", "name"}, {"1", "Tom"}, {"2", "Jerry"}}, true},
+		{"date column", [][]string{{"day", "name"}, {"2023-01-01", "Tom"}}, true},
+		{"string columns without repeat", [][]string{{"name", "city"}, {"Tom", "Paris"}, {"Jerry", "Rome"}}, true},
+		{"string columns with repeat", [][]string{{"Tom", "Paris"}, {"Jerry", "Paris"}}, false},
+		{"number in the first row", [][]string{{"1", "Tom"}, {"2", "Jerry"}}, false},
+		{"not a name", [][]string{{"a@b", "name"}, {"1", "Tom"}}, false},
+		{"single row", [][]string{{"id", "name"}}, false},
+		{"empty row", [][]string{{}, {"1"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectHeader(tt.rows); got != tt.want {
+				t.Errorf("DetectHeader(%v) = %v, want %v", tt.rows, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestTagVid(t *testing.T) {
+	props := []SchemaProp{{Name: "name", Type: "string"}, {Name: "age", Type: "int64"}}
+	tests := []struct {
+		name    string
+		vidType string
+		header  []string
+		rows    [][]string
+		// the index of the vid column, -1 means no suggestion
+		want int64
+	}{
+		{
+			name:    "tag id",
+			vidType: "FIXED_STRING(32)",
+			header:  []string{"name", "player_id", "age"},
+			rows:    [][]string{{"Tom", "p1", "20"}, {"Jerry", "p2", "21"}},
+			want:    1,
+		},
+		{
+			name:    "id",
+			vidType: "FIXED_STRING(32)",
+			header:  []string{"name", "id", "age"},
+			rows:    [][]string{{"Tom", "p1", "20"}, {"Jerry", "p2", "21"}},
+			want:    1,
+		},
+		{
+			name:    "first unique column",
+			vidType: "FIXED_STRING(32)",
+			header:  []string{"code", "name", "age"},
+			rows:    [][]string{{"p1", "Tom", "20"}, {"p2", "Jerry", "21"}},
+			want:    0,
+		},
+		{
+			name:    "repeated id",
+			vidType: "FIXED_STRING(32)",
+			header:  []string{"player_id", "name", "age"},
+			rows:    [][]string{{"p1", "Tom", "20"}, {"p1", "Jerry", "21"}},
+			want:    -1,
+		},
+		{
+			name:    "too long for the vid",
+			vidType: "FIXED_STRING(2)",
+			header:  []string{"player_id", "name", "age"},
+			rows:    [][]string{{"p100", "Tom", "20"}, {"p200", "Jerry", "21"}},
+			want:    -1,
+		},
+		{
+			name:    "int vid",
+			vidType: "INT64",
+			header:  []string{"name", "player_id", "age"},
+			rows:    [][]string{{"Tom", "100", "20"}, {"Jerry", "200", "21"}},
+			want:    1,
+		},
+		{
+			name:    "string id of int vid",
+			vidType: "INT64",
+			header:  []string{"player_id", "name", "age"},
+			rows:    [][]string{{"p1", "Tom", "20"}, {"p2", "Jerry", "21"}},
+			want:    -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := &SpaceSchema{VidType: tt.vidType, Tags: map[string][]SchemaProp{"player": props}}
+			s := schema.suggestTag("player", ProfileColumns(tt.header, tt.rows), 0)
+			if tt.want < 0 {
+				if s != nil {
+					t.Errorf("unexpected suggestion of the vid column %d", s.Tag.ID.Index)
+				}
+				return
+			}
+			if s == nil {
+				t.Fatal("expect a suggestion")
+			}
+			if s.Tag.ID.Index != tt.want {
+				t.Errorf("unexpected vid column %d, want %d", s.Tag.ID.Index, tt.want)
+			}
+			if len(s.Tag.Props) != 2 {
+				t.Errorf("unexpected props: %v", s.Tag.Props)
+			}
+		})
+	}
+}
+
+func TestSuggestEdgeEndpoints(t *testing.T) {
+	schema := &SpaceSchema{
+		VidType: "FIXED_STRING(32)",
+		Tags:    map[string][]SchemaProp{"player": nil, "team": nil, "coach": nil},
+		Edges:   map[string][]SchemaProp{"serve": {{Name: "start_year", Type: "int64"}}},
+	}
+	rows := [][]string{{"p1", "t1", "c1", "2001"}, {"p2", "t2", "c2", "2002"}}
+	tests := []struct {
+		name   string
+		header []string
+		// the indexes of the src and the dst columns, nil means no suggestion
+		want []int64
+	}{
+		{"src and dst", []string{"dst", "src", "name", "start_year"}, []int64{1, 0}},
+		{"from and to", []string{"from_player_id", "to_team_id", "name", "start_year"}, []int64{0, 1}},
+		{"endpoint of unknown tag", []string{"from_user_id", "to_team_id", "name", "start_year"}, nil},
+		{"fallback to the tag ids", []string{"player_id", "team_id", "name", "start_year"}, []int64{0, 1}},
+		{"fallback with three tag ids", []string{"player_id", "team_id", "coach_id", "start_year"}, nil},
+		{"fallback with one tag id", []string{"player_id", "name", "code", "start_year"}, nil},
+		{"fallback ignores id", []string{"id", "team_id", "name", "start_year"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := schema.suggestEdge("serve", ProfileColumns(tt.header, rows), 0)
+			if tt.want == nil {
+				if s != nil {
+					t.Errorf("unexpected suggestion of the src %d and the dst %d", s.Edge.Src.ID.Index, s.Edge.Dst.ID.Index)
+				}
+				return
+			}
+			if s == nil {
+				t.Fatal("expect a suggestion")
+			}
+			if s.Edge.Src.ID.Index != tt.want[0] || s.Edge.Dst.ID.Index != tt.want[1] {
+				t.Errorf("unexpected src %d and dst %d, want %v", s.Edge.Src.ID.Index, s.Edge.Dst.ID.Index, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestEdgeRank(t *testing.T) {
+	schema := &SpaceSchema{
+		VidType: "INT64",
+		Tags:    map[string][]SchemaProp{"player": nil},
+		Edges:   map[string][]SchemaProp{"follow": {{Name: "degree", Type: "int64"}}},
+	}
+	columns := ProfileColumns([]string{"src", "dst", "rank", "degree"}, [][]string{{"1", "2", "0", "90"}, {"2", "3", "1", "80"}})
+	s := schema.suggestEdge("follow", columns, 0)
+	if s == nil {
+		t.Fatal("expect a suggestion")
+	}
+	if s.Edge.Rank == nil || *s.Edge.Rank.Index != 2 {
+		t.Errorf("unexpected rank: %v", s.Edge.Rank)
+	}
+	if s.Edge.Src.ID.Type != "int" || s.Edge.Dst.ID.Type != "int" {
+		t.Errorf("unexpected vid types of the int64 vid: %s %s", s.Edge.Src.ID.Type, s.Edge.Dst.ID.Type)
+	}
+}
@@ -1,6 +1,8 @@
 package importer
 
 import (
+	"encoding/json"
+
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
 
 	"github.com/vesoft-inc/nebula-importer/v4/pkg/config"
@@ -13,6 +15,19 @@ type Client struct {
 	Logger     logger.Logger       `json:"logger,omitempty"`
 	Manager    manager.Manager     `json:"manager,omitempty"`
 	HasStarted bool                `json:"has_started,omitempty"`
+	// offsets of the sources to resume from, and the stats before the resume
+	Resume    []int64  `json:"resume,omitempty"`
+	BaseStats db.Stats `json:"base_stats,omitempty"`
+	// written into the task log when the task starts
+	LogNote string `json:"log_note,omitempty"`
+	// the dir to keep the records failed to import
+	ErrDir string `json:"err_dir,omitempty"`
+	// the sources read by studio through the datasource stores
+	StoreSources []*StoreSource `json:"store_sources,omitempty"`
+	// the dir of the temp files of the task, such as a zip archive downloaded to read its member
+	TempDir string `json:"temp_dir,omitempty"`
+
+	trackers []*sourceTracker
 }
 type Task struct {
 	Client   *Client      `json:"client,omitempty"`
@@ -24,17 +39,25 @@ func (t *Task) UpdateQueryStats() error {
 		return nil
 	}
 	stats := t.Client.Manager.Stats()
+	base := t.Client.BaseStats
 	t.TaskInfo.Stats = db.Stats{
-		ProcessedBytes:  stats.ProcessedBytes,
+		ProcessedBytes:  base.ProcessedBytes + stats.ProcessedBytes,
 		TotalBytes:      stats.TotalBytes,
-		FailedRecords:   stats.FailedRecords,
-		TotalRecords:    stats.TotalRecords,
-		FailedRequest:   stats.FailedRequest,
-		TotalRequest:    stats.TotalRequest,
-		TotalLatency:    stats.TotalLatency,
-		TotalRespTime:   stats.TotalRespTime,
-		FailedProcessed: stats.FailedProcessed,
-		TotalProcessed:  stats.TotalProcessed,
+		FailedRecords:   base.FailedRecords + stats.FailedRecords,
+		TotalRecords:    base.TotalRecords + stats.TotalRecords,
+		FailedRequest:   base.FailedRequest + stats.FailedRequest,
+		TotalRequest:    base.TotalRequest + stats.TotalRequest,
+		TotalLatency:    base.TotalLatency + stats.TotalLatency,
+		TotalRespTime:   base.TotalRespTime + stats.TotalRespTime,
+		FailedProcessed: base.FailedProcessed + stats.FailedProcessed,
+		TotalProcessed:  base.TotalProcessed + stats.TotalProcessed,
+	}
+	if offsets := t.Client.Checkpoint(); offsets != nil {
+		checkpoint, err := json.Marshal(offsets)
+		if err != nil {
+			return err
+		}
+		t.TaskInfo.Checkpoint = string(checkpoint)
 	}
 	return nil
 }
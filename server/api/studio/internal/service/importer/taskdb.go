@@ -1,8 +1,12 @@
 package importer
 
 import (
+	"fmt"
+
+	studioConfig "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/lease"
 
 	"github.com/zeromicro/go-zero/core/logx"
 	"gorm.io/gorm"
@@ -12,14 +16,46 @@ type TaskDb struct {
 	*gorm.DB
 }
 
+/*
+InitTaskStatus resumes the tasks left processing by the last run.
+With multiple instances, only the tasks without any lease are resumed at once,
+the tasks of a dead instance are resumed once their lease expires.
+*/
 func InitTaskStatus() {
 	GetTaskMgr().db = &TaskDb{
 		DB: db.CtxDB,
 	}
-	if err := GetTaskMgr().db.UpdateProcessingTasks2Aborted(); err != nil {
-		logx.Errorf("update processing tasks to aborted failed: %s", err)
+	lease.Register(lease.KindImportTask, lease.Handler{
+		OnStop: GetTaskMgr().StopTask,
+		OnTakeover: func(taskID, prevOwner string) {
+			taskInfo, err := GetTaskMgr().db.FindProcessingTaskInfo(taskID)
+			if err != nil {
+				return
+			}
+			GetTaskMgr().ResumeTask(taskInfo, fmt.Sprintf("instance %s is gone", prevOwner))
+		},
+		Running: GetTaskMgr().runningTasks,
+		OnLost: func(taskID, newOwner string) {
+			GetTaskMgr().DropTask(taskID)
+		},
+	})
+	multi := studioConfig.GetConfig().AppInstance == "multi"
+	if !multi {
+		if err := lease.ExpireAll(); err != nil {
+			logx.Errorf("expire leases failed: %s", err)
+			panic(err)
+		}
+	}
+	tasks, err := GetTaskMgr().db.FindProcessingTaskInfos(multi)
+	if err != nil {
+		logx.Errorf("find processing tasks failed: %s", err)
 		panic(err)
 	}
+	for _, taskInfo := range tasks {
+		GetTaskMgr().ResumeTask(taskInfo, "studio restart")
+	}
+	lease.Start()
+	go runQueue()
 }
 
 // FindTaskInfoByIdAndAddresssAndUser used to check whether the task belongs to the user
@@ -53,6 +89,36 @@ func (t *TaskDb) InsertTaskInfo(info *db.TaskInfo) error {
 	return t.Create(info).Error
 }
 
+/*
+InsertQueuedTask inserts a queued task with its effect in a transaction,
+so the queue never starts a task without the runtime config and the notify channels.
+*/
+func (t *TaskDb) InsertQueuedTask(info *db.TaskInfo, taskEffect *db.TaskEffect) error {
+	return t.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(taskEffect).Error; err != nil {
+			return err
+		}
+		return tx.Create(info).Error
+	})
+}
+
+// QueueDraftTask turns a draft into a queued task with its effect in a transaction
+func (t *TaskDb) QueueDraftTask(info *db.TaskInfo, taskEffect *db.TaskEffect) error {
+	return t.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(taskEffect).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&db.TaskInfo{}).Where("b_id = ? AND task_status = ?", info.BID, Draft.String()).Updates(info)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("draft %s not found", info.BID)
+		}
+		return nil
+	})
+}
+
 func (t *TaskDb) UpdateTaskInfo(info *db.TaskInfo) error {
 	return t.Model(&db.TaskInfo{}).Where("b_id = ?", info.BID).Updates(info).Error
 }
@@ -61,13 +127,93 @@ func (t *TaskDb) DelTaskInfo(ID string) error {
 	return t.Delete(&db.TaskInfo{}, "b_id = ?", ID).Error
 }
 
-func (t *TaskDb) UpdateProcessingTasks2Aborted() error {
-	if err := t.Model(&db.TaskInfo{}).Where("task_status = ?", Processing.String()).Updates(&db.TaskInfo{TaskStatus: Aborted.String(), TaskMessage: "Service execption"}).Error; err != nil {
+// FindProcessingTaskInfos finds the processing tasks, or only the ones without any lease when `unleased` is set
+func (t *TaskDb) FindProcessingTaskInfos(unleased bool) ([]*db.TaskInfo, error) {
+	tasks := make([]*db.TaskInfo, 0)
+	tx := t.Model(&db.TaskInfo{}).Where("task_status = ?", Processing.String())
+	if unleased {
+		tx = tx.Where("b_id NOT IN (?)", t.Model(&db.JobLease{}).Select("job_id").Where("kind = ?", lease.KindImportTask))
+	}
+	if err := tx.Find(&tasks).Error; err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	return tasks, nil
+}
+
+func (t *TaskDb) FindProcessingTaskInfo(ID string) (*db.TaskInfo, error) {
+	taskInfo := new(db.TaskInfo)
+	if err := t.Model(&db.TaskInfo{}).Where("b_id = ? AND task_status = ?", ID, Processing.String()).First(taskInfo).Error; err != nil {
+		return nil, err
+	}
+	return taskInfo, nil
+}
+
+func (t *TaskDb) FindTaskEffect(ID string) (*db.TaskEffect, error) {
+	taskEffect := new(db.TaskEffect)
+	if err := t.Model(&db.TaskEffect{}).Where("task_id = ?", ID).First(taskEffect).Error; err != nil {
+		return nil, err
+	}
+	return taskEffect, nil
+}
+
+func (t *TaskDb) UpdateProcessingTask2Aborted(ID, message string) error {
+	if err := t.Model(&db.TaskInfo{}).Where("b_id = ? AND task_status = ?", ID, Processing.String()).Updates(&db.TaskInfo{TaskStatus: Aborted.String(), TaskMessage: message}).Error; err != nil {
 		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
 	}
 	return nil
 }
 
+func (t *TaskDb) UpdateQueuedTask2Stopped(ID string) (bool, error) {
+	result := t.Model(&db.TaskInfo{}).Where("b_id = ? AND task_status = ?", ID, Queued.String()).Update("task_status", Stoped.String())
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateQueuedTask2Processing takes the task out of the queue, it returns false when the task has left the queue
+func (t *TaskDb) UpdateQueuedTask2Processing(ID string) (bool, error) {
+	result := t.Model(&db.TaskInfo{}).Where("b_id = ? AND task_status = ?", ID, Queued.String()).Update("task_status", Processing.String())
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindQueuedTaskInfos finds the queued tasks in the order they leave the queue
+func (t *TaskDb) FindQueuedTaskInfos() ([]*db.TaskInfo, error) {
+	tasks := make([]*db.TaskInfo, 0)
+	if err := t.Model(&db.TaskInfo{}).Where("task_status = ?", Queued.String()).
+		Order("priority desc").Order("create_time asc").Order("id asc").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// CountProcessingTasks counts the running tasks in total and by space
+func (t *TaskDb) CountProcessingTasks() (int64, map[string]int64, error) {
+	var rows []struct {
+		Space string
+		Count int64
+	}
+	if err := t.Model(&db.TaskInfo{}).Select("space, count(*) as count").
+		Where("task_status = ?", Processing.String()).Group("space").Scan(&rows).Error; err != nil {
+		return 0, nil, err
+	}
+	var total int64
+	bySpace := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		total += row.Count
+		bySpace[row.Space] = row.Count
+	}
+	return total, bySpace, nil
+}
+
+func (t *TaskDb) ClearTaskCron(ID string) error {
+	return t.Model(&db.TaskInfo{}).Where("b_id = ?", ID).Update("cron", "").Error
+}
+
+func (t *TaskDb) UpdateTaskStatus(ID, status, message string) error {
+	return t.Model(&db.TaskInfo{}).Where("b_id = ?", ID).Updates(map[string]interface{}{
+		"task_status":  status,
+		"task_message": message,
+	}).Error
+}
+
 func (t *TaskDb) InsertTaskEffect(taskEffect *db.TaskEffect) error {
 	return t.Create(taskEffect).Error
 }
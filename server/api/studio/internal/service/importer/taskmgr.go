@@ -14,12 +14,16 @@ import (
 	studioConfig "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/lease"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
+	"github.com/zeromicro/go-zero/core/logx"
 	"gopkg.in/yaml.v3"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+const taskLogName = "import.log"
+
 var (
 	taskmgr *TaskMgr = &TaskMgr{
 		tasks: sync.Map{},
@@ -57,6 +61,7 @@ func CreateConfigFile(taskdir string, cfgBytes []byte) (string, error) {
 		S3Config := source.SourceConfig.S3
 		SFTPConfig := source.SourceConfig.SFTP
 		OSSConfig := source.SourceConfig.OSS
+		FTPConfig := source.SourceConfig.FTP
 		if S3Config != nil {
 			S3Config.AccessKeyID = "${YOUR_S3_ACCESS_KEY}"
 			S3Config.AccessKeySecret = "${YOUR_S3_SECRET_KEY}"
@@ -64,11 +69,21 @@ func CreateConfigFile(taskdir string, cfgBytes []byte) (string, error) {
 		if SFTPConfig != nil {
 			SFTPConfig.User = "${YOUR_SFTP_USER}"
 			SFTPConfig.Password = "${YOUR_SFTP_PASSWORD}"
+			if SFTPConfig.KeyData != "" {
+				SFTPConfig.KeyData = "${YOUR_SFTP_KEY_DATA}"
+			}
+			if SFTPConfig.Passphrase != "" {
+				SFTPConfig.Passphrase = "${YOUR_SFTP_PASSPHRASE}"
+			}
 		}
 		if OSSConfig != nil {
 			OSSConfig.AccessKeyID = "${YOUR_OSS_ACCESS_KEY}"
 			OSSConfig.AccessKeySecret = "${YOUR_OSS_SECRET_KEY}"
 		}
+		if FTPConfig != nil {
+			FTPConfig.User = "${YOUR_FTP_USER}"
+			FTPConfig.Password = "${YOUR_FTP_PASSWORD}"
+		}
 	}
 	outYaml, err := yaml.Marshal(confv3)
 	if err != nil {
@@ -80,24 +95,44 @@ func CreateConfigFile(taskdir string, cfgBytes []byte) (string, error) {
 	return string(outYaml), nil
 }
 
-func (mgr *TaskMgr) NewTask(id, host, user, taskName, rawCfg string, cfg importconfig.Configurator) (*Task, error) {
+// QueueOptions are the options a task is queued with, they are written with the task so the queue sees them at once
+type QueueOptions struct {
+	Priority int
+	StartAt  *time.Time
+	Cron     string
+	// the channels notified besides the default channels of the owner
+	NotifyChannels []string
+	// the task whose failed records are retried by the task
+	ParentID string
+}
+
+/*
+NewTask puts a new task into the queue with its effect,
+the task is started by the queue once the concurrency limits allow.
+*/
+func (mgr *TaskMgr) NewTask(id, host, user, taskName, rawCfg string, cfg importconfig.Configurator, opts QueueOptions, taskEffect *db.TaskEffect) (*Task, error) {
 	mux.Lock()
 	defer mux.Unlock()
 	confv3 := cfg.(*configv3.Config)
 
 	// init task db
 	taskInfo := &db.TaskInfo{
-		BID:           id,
-		Name:          taskName,
-		Address:       host,
-		Space:         confv3.Manager.GraphName,
-		TaskStatus:    Processing.String(),
-		ImportAddress: confv3.Client.Address,
-		User:          user,
-		RawConfig:     rawCfg,
-	}
-
-	if err := mgr.db.InsertTaskInfo(taskInfo); err != nil {
+		BID:            id,
+		Name:           taskName,
+		Address:        host,
+		Space:          confv3.Manager.GraphName,
+		TaskStatus:     Queued.String(),
+		ImportAddress:  confv3.Client.Address,
+		User:           user,
+		RawConfig:      rawCfg,
+		Priority:       opts.Priority,
+		StartAt:        opts.StartAt,
+		Cron:           opts.Cron,
+		NotifyChannels: strings.Join(opts.NotifyChannels, ","),
+		ParentID:       opts.ParentID,
+	}
+
+	if err := mgr.db.InsertQueuedTask(taskInfo, taskEffect); err != nil {
 		return nil, err
 	}
 
@@ -110,37 +145,31 @@ func (mgr *TaskMgr) NewTask(id, host, user, taskName, rawCfg string, cfg importc
 		},
 		TaskInfo: taskInfo,
 	}
-
-	mgr.PutTask(id, task)
 	return task, nil
 }
 
-func (mgr *TaskMgr) NewTaskEffect(taskEffect *db.TaskEffect) error {
-	mux.Lock()
-	defer mux.Unlock()
-	if err := mgr.db.InsertTaskEffect(taskEffect); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (mgr *TaskMgr) TurnDraftToTask(id, taskName, rawCfg string, cfg importconfig.Configurator) (*Task, error) {
+// TurnDraftToTask puts the draft into the queue with its effect
+func (mgr *TaskMgr) TurnDraftToTask(id, taskName, rawCfg string, cfg importconfig.Configurator, opts QueueOptions, taskEffect *db.TaskEffect) (*Task, error) {
 	mux.Lock()
 	defer mux.Unlock()
 	confv3 := cfg.(*configv3.Config)
 
 	// init task db
 	taskInfo := &db.TaskInfo{
-		BID:           id,
-		Name:          taskName,
-		Space:         confv3.Manager.GraphName,
-		TaskStatus:    Processing.String(),
-		ImportAddress: confv3.Client.Address,
-		RawConfig:     rawCfg,
-		CreateTime:    time.Now(),
-	}
-
-	if err := mgr.db.UpdateTaskInfo(taskInfo); err != nil {
+		BID:            id,
+		Name:           taskName,
+		Space:          confv3.Manager.GraphName,
+		TaskStatus:     Queued.String(),
+		ImportAddress:  confv3.Client.Address,
+		RawConfig:      rawCfg,
+		Priority:       opts.Priority,
+		StartAt:        opts.StartAt,
+		Cron:           opts.Cron,
+		NotifyChannels: strings.Join(opts.NotifyChannels, ","),
+		CreateTime:     time.Now(),
+	}
+
+	if err := mgr.db.QueueDraftTask(taskInfo, taskEffect); err != nil {
 		return nil, err
 	}
 
@@ -153,8 +182,6 @@ func (mgr *TaskMgr) TurnDraftToTask(id, taskName, rawCfg string, cfg importconfi
 		},
 		TaskInfo: taskInfo,
 	}
-
-	mgr.PutTask(id, task)
 	return task, nil
 }
 
@@ -225,6 +252,7 @@ func (mgr *TaskMgr) PutTask(taskID string, task *Task) {
 FinishTask will query task stats
   - delete task in the map
   - update taskInfo in db
+  - notify the channels of the task
   - update taskEffect in db
 */
 func (mgr *TaskMgr) FinishTask(taskID string) (err error) {
@@ -240,6 +268,8 @@ func (mgr *TaskMgr) FinishTask(taskID string) (err error) {
 		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
 	}
 	mgr.tasks.Delete(taskID)
+	releaseTask(taskID)
+	notifyTask(task.TaskInfo)
 
 	return mgr.StorePartTaskLog(taskID)
 }
@@ -254,6 +284,8 @@ func (mgr *TaskMgr) AbortTask(taskID string) (err error) {
 		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
 	}
 	mgr.tasks.Delete(taskID)
+	releaseTask(taskID)
+	notifyTask(task.TaskInfo)
 	return mgr.StorePartTaskLog(taskID)
 }
 
@@ -268,12 +300,15 @@ func (mgr *TaskMgr) DelTask(tasksDir, taskID string) error {
 	if err := mgr.db.DelTaskEffect(taskID); err != nil {
 		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
 	}
+	if err := lease.Remove(lease.KindImportTask, taskID); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
 	taskDir := filepath.Join(tasksDir, taskID)
 	return os.RemoveAll(taskDir)
 }
 
 func (mgr *TaskMgr) StorePartTaskLog(taskID string) error {
-	filePath := filepath.Join(studioConfig.GetConfig().File.TasksDir, taskID, "import.log")
+	filePath := filepath.Join(studioConfig.GetConfig().File.TasksDir, taskID, taskLogName)
 	content, err := utils.ReadPartFile(filePath)
 	if err != nil {
 		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
@@ -306,7 +341,8 @@ func (mgr *TaskMgr) UpdateTaskInfo(taskID string) error {
 
 /*
 StopTask will change the task status to `Stoped`,
-and then call FinishTask
+and then call FinishTask.
+A task running on another instance is stopped by its owner on the next lease heartbeat.
 */
 func (mgr *TaskMgr) StopTask(taskID string) error {
 	if task, ok := mgr.getTaskFromMap(taskID); ok {
@@ -332,9 +368,51 @@ func (mgr *TaskMgr) StopTask(taskID string) error {
 		}
 		return nil
 	}
+	dequeued, err := mgr.db.UpdateQueuedTask2Stopped(taskID)
+	if err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	if dequeued {
+		notifyTask(mgr.getTaskFromSQL(taskID).TaskInfo)
+		return nil
+	}
+	requested, err := lease.RequestStop(lease.KindImportTask, taskID)
+	if err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	if requested {
+		return nil
+	}
 	return errors.New("task is finished or not exist")
 }
 
+/*
+DropTask stops a task whose lease has been taken over by another instance,
+neither its status nor its log is written since the task belongs to the new owner.
+*/
+func (mgr *TaskMgr) DropTask(taskID string) {
+	task, ok := mgr.getTaskFromMap(taskID)
+	if !ok {
+		return
+	}
+	mgr.tasks.Delete(taskID)
+	// the task not started yet is dropped before it starts
+	if manager := task.Client.Manager; manager != nil && task.Client.HasStarted {
+		if err := manager.Stop(); err != nil {
+			logx.Errorf("stop the lost task %s failed: %s", taskID, err)
+		}
+	}
+}
+
+func (mgr *TaskMgr) runningTasks() []string {
+	ids := make([]string, 0)
+	mgr.tasks.Range(func(key, _ any) bool {
+		ids = append(ids, key.(string))
+		return true
+	})
+	return ids
+}
+
 func (mgr *TaskMgr) getTaskFromMap(taskID string) (*Task, bool) {
 	if task, ok := mgr.tasks.Load(taskID); ok {
 		return task.(*Task), true
@@ -344,17 +422,35 @@ func (mgr *TaskMgr) getTaskFromMap(taskID string) (*Task, bool) {
 
 func (mgr *TaskMgr) getTaskFromSQL(taskID string) *Task {
 	taskInfo := new(db.TaskInfo)
-	mgr.db.First(taskInfo, taskID)
+	mgr.db.Where("b_id = ?", taskID).First(taskInfo)
 	task := new(Task)
 	task.TaskInfo = taskInfo
 	return task
 }
 
+func claimTask(taskID string) error {
+	ok, err := lease.Claim(lease.KindImportTask, taskID)
+	if err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	if !ok {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, fmt.Errorf("task %s is running on another instance", taskID))
+	}
+	return nil
+}
+
+func releaseTask(taskID string) {
+	if err := lease.Release(lease.KindImportTask, taskID); err != nil {
+		logx.Errorf("release lease of task %s failed: %s", taskID, err)
+	}
+}
+
 type TaskStatus int
 
 /*
 the task in memory (map) has 2 status: processing, aborted;
 and the task in local sql has 2 status: finished, stoped;
+the task waiting in the queue is only in local sql: queued;
 */
 const (
 	StatusUnknown TaskStatus = iota
@@ -364,6 +460,7 @@ const (
 	NotExisted
 	Aborted
 	Draft
+	Queued
 )
 
 var taskStatusMap = map[TaskStatus]string{
@@ -373,6 +470,7 @@ var taskStatusMap = map[TaskStatus]string{
 	NotExisted: "NotExisted",
 	Aborted:    "Failed",
 	Draft:      "Draft",
+	Queued:     "Queued",
 }
 
 var taskStatusRevMap = map[string]TaskStatus{
@@ -382,6 +480,7 @@ var taskStatusRevMap = map[string]TaskStatus{
 	"notExisted": NotExisted,
 	"aborted":    Aborted,
 	"draft":      Draft,
+	"queued":     Queued,
 }
 
 func NewTaskStatus(status string) TaskStatus {
@@ -0,0 +1,415 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	configv3 "github.com/vesoft-inc/nebula-importer/v4/pkg/config/v3"
+	specv3 "github.com/vesoft-inc/nebula-importer/v4/pkg/spec/v3"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/client"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
+)
+
+type (
+	SchemaProp struct {
+		Name       string
+		Type       string
+		Nullable   bool
+		HasDefault bool
+	}
+
+	// SpaceSchema is the part of the space schema an import task refers to
+	SpaceSchema struct {
+		Space   string
+		VidType string
+		Tags    map[string][]SchemaProp
+		Edges   map[string][]SchemaProp
+	}
+
+	// SampleRow is a row sampled from a source, Line is its line number in the source file
+	SampleRow struct {
+		Line   int
+		Values []string
+	}
+
+	// sourceReport collects the issues of a source, the same issue of many rows is reported once
+	sourceReport struct {
+		errors   []*types.ValidateImportTaskIssue
+		warnings []*types.ValidateImportTaskIssue
+		index    map[string]*types.ValidateImportTaskIssue
+	}
+)
+
+var fixedStringRe = regexp.MustCompile(`(?i)^fixed_string\((\d+)\)$`)
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(name) + "`"
+}
+
+// unescapeName reverts the escaping of the names in the task config
+func unescapeName(name string) string {
+	return strings.NewReplacer("\\`", "`", "\\\\", "\\").Replace(name)
+}
+
+/*
+DescribeSpace reads the vid type of the space and the props of the tags and edges the task config refers to.
+The tags and edges which do not exist in the space are left out of the schema.
+*/
+func DescribeSpace(nsid string, conf *configv3.Config) (*SpaceSchema, error) {
+	tags, edges := schemaNames(conf.Sources)
+	return describeSpace(nsid, unescapeName(conf.Manager.GraphName), tags, edges, false)
+}
+
+// DescribeWholeSpace reads the vid type of the space and the props of all its tags and edges
+func DescribeWholeSpace(nsid, space string) (*SpaceSchema, error) {
+	return describeSpace(nsid, space, nil, nil, true)
+}
+
+func describeSpace(nsid, space string, tags, edges []string, all bool) (*SpaceSchema, error) {
+	gqls := []string{fmt.Sprintf("DESCRIBE SPACE %s", quoteIdentifier(space)), "SHOW TAGS", "SHOW EDGES"}
+	res, err := client.Execute(nsid, space, gqls)
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	for _, r := range res {
+		if r.Error != nil {
+			return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, r.Error, "describe space %s failed", space)
+		}
+	}
+	if len(res) < 3 || len(res[0].Result.Tables) == 0 {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("space %s not found", space))
+	}
+	schema := &SpaceSchema{
+		Space:   space,
+		VidType: fmt.Sprint(res[0].Result.Tables[0]["Vid Type"]),
+		Tags:    make(map[string][]SchemaProp),
+		Edges:   make(map[string][]SchemaProp),
+	}
+	existed := func(tables []map[string]client.Any) (map[string]bool, []string) {
+		names := make(map[string]bool, len(tables))
+		list := make([]string, 0, len(tables))
+		for _, row := range tables {
+			name := fmt.Sprint(row["Name"])
+			names[name] = true
+			list = append(list, name)
+		}
+		return names, list
+	}
+	existedTags, allTags := existed(res[1].Result.Tables)
+	existedEdges, allEdges := existed(res[2].Result.Tables)
+	if all {
+		tags, edges = allTags, allEdges
+	}
+
+	gqls = gqls[:0]
+	describes := make([]map[string][]SchemaProp, 0)
+	names := make([]string, 0)
+	for _, name := range tags {
+		if existedTags[name] {
+			gqls = append(gqls, fmt.Sprintf("DESCRIBE TAG %s", quoteIdentifier(name)))
+			describes, names = append(describes, schema.Tags), append(names, name)
+		}
+	}
+	for _, name := range edges {
+		if existedEdges[name] {
+			gqls = append(gqls, fmt.Sprintf("DESCRIBE EDGE %s", quoteIdentifier(name)))
+			describes, names = append(describes, schema.Edges), append(names, name)
+		}
+	}
+	if len(gqls) == 0 {
+		return schema, nil
+	}
+	res, err = client.Execute(nsid, space, gqls)
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	for i, r := range res {
+		if r.Error != nil {
+			return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, r.Error, "%s failed", r.Gql)
+		}
+		props := make([]SchemaProp, 0, len(r.Result.Tables))
+		for _, row := range r.Result.Tables {
+			props = append(props, SchemaProp{
+				Name:       fmt.Sprint(row["Field"]),
+				Type:       strings.ToLower(fmt.Sprint(row["Type"])),
+				Nullable:   fmt.Sprint(row["Null"]) == "YES",
+				HasDefault: row["Default"] != nil && fmt.Sprint(row["Default"]) != "",
+			})
+		}
+		describes[i][names[i]] = props
+	}
+	return schema, nil
+}
+
+// schemaNames returns the unescaped names of the tags and edges in the sources
+func schemaNames(sources configv3.Sources) (tags, edges []string) {
+	seen := make(map[string]bool)
+	for _, s := range sources {
+		for _, n := range s.Nodes {
+			if name := unescapeName(n.Name); !seen["tag:"+name] {
+				seen["tag:"+name] = true
+				tags = append(tags, name)
+			}
+		}
+		for _, e := range s.Edges {
+			if name := unescapeName(e.Name); !seen["edge:"+name] {
+				seen["edge:"+name] = true
+				edges = append(edges, name)
+			}
+		}
+	}
+	return tags, edges
+}
+
+/*
+ValidateSource checks the mapping of a source against the space schema and the sampled rows,
+nothing is written into the space.
+*/
+func ValidateSource(s *configv3.Source, schema *SpaceSchema, rows []SampleRow) (errs, warnings []*types.ValidateImportTaskIssue) {
+	r := &sourceReport{index: make(map[string]*types.ValidateImportTaskIssue)}
+	space := schema.Space
+	if _, err := s.BuildGraph(space); err != nil {
+		r.add(true, "config", err.Error(), nil, "")
+		return r.errors, r.warnings
+	}
+	if len(rows) == 0 {
+		r.add(false, "source", "no rows are sampled from the source", nil, "")
+	}
+	for _, n := range s.Nodes {
+		name := unescapeName(n.Name)
+		target := "tag " + name
+		props, ok := schema.Tags[name]
+		if !ok {
+			r.add(true, target, fmt.Sprintf("tag %s does not exist in space %s", name, space), nil, "")
+			continue
+		}
+		r.checkVidConfig(target+" vid", n.ID, schema.VidType)
+		r.checkProps(target, n.Props, props)
+		for i := range rows {
+			r.checkVid(target+" vid", n.ID, schema.VidType, &rows[i])
+			r.checkPropValues(target, n.Props, props, &rows[i])
+		}
+	}
+	for _, e := range s.Edges {
+		name := unescapeName(e.Name)
+		target := "edge " + name
+		props, ok := schema.Edges[name]
+		if !ok {
+			r.add(true, target, fmt.Sprintf("edge %s does not exist in space %s", name, space), nil, "")
+			continue
+		}
+		r.checkVidConfig(target+" src", e.Src.ID, schema.VidType)
+		r.checkVidConfig(target+" dst", e.Dst.ID, schema.VidType)
+		r.checkProps(target, e.Props, props)
+		for i := range rows {
+			row := &rows[i]
+			r.checkVid(target+" src", e.Src.ID, schema.VidType, row)
+			r.checkVid(target+" dst", e.Dst.ID, schema.VidType, row)
+			if e.Rank != nil {
+				if v, ok := r.pick(target+" rank", e.Rank.Index, row); ok {
+					if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+						r.add(true, target+" rank", "rank is not an integer", row, v)
+					}
+				}
+			}
+			r.checkPropValues(target, e.Props, props, row)
+		}
+	}
+	return r.errors, r.warnings
+}
+
+/*
+add reports an issue, row is nil for the issues of the config.
+The first row and value are kept as an example of the issue.
+*/
+func (r *sourceReport) add(isError bool, target, message string, row *SampleRow, value string) {
+	key := fmt.Sprintf("%t|%s|%s", isError, target, message)
+	if issue, ok := r.index[key]; ok {
+		if row != nil {
+			issue.Rows++
+		}
+		return
+	}
+	issue := &types.ValidateImportTaskIssue{Target: target, Message: message, Value: value}
+	if row != nil {
+		issue.Rows = 1
+		issue.Line = row.Line
+	}
+	r.index[key] = issue
+	if isError {
+		r.errors = append(r.errors, issue)
+	} else {
+		r.warnings = append(r.warnings, issue)
+	}
+}
+
+func (r *sourceReport) pick(target string, index int, row *SampleRow) (string, bool) {
+	if index < 0 || index >= len(row.Values) {
+		r.add(true, target, fmt.Sprintf("column index %d is out of range", index), row, strconv.Itoa(len(row.Values)))
+		return "", false
+	}
+	return row.Values[index], true
+}
+
+func (r *sourceReport) checkVidConfig(target string, id *specv3.NodeID, vidType string) {
+	isInt := strings.EqualFold(vidType, "INT64")
+	if id.Function != nil && *id.Function != "" {
+		if !isInt {
+			r.add(true, target, fmt.Sprintf("function %s only works with the INT64 vid", *id.Function), nil, "")
+		}
+		return
+	}
+	if isInt != strings.EqualFold(string(id.Type), string(specv3.ValueTypeInt)) {
+		r.add(true, target, fmt.Sprintf("vid type %s does not match the vid type %s of the space", id.Type, vidType), nil, "")
+	}
+}
+
+func (r *sourceReport) checkVid(target string, id *specv3.NodeID, vidType string, row *SampleRow) {
+	var value string
+	if len(id.ConcatItems) > 0 {
+		for _, item := range id.ConcatItems {
+			index := -1
+			switch v := item.(type) {
+			case string:
+				value += v
+			case int:
+				index = v
+			case float64:
+				index = int(v)
+			}
+			if index < 0 {
+				continue
+			}
+			s, ok := r.pick(target, index, row)
+			if !ok {
+				return
+			}
+			value += s
+		}
+	} else {
+		v, ok := r.pick(target, id.Index, row)
+		if !ok {
+			return
+		}
+		value = v
+	}
+	if value == "" {
+		r.add(true, target, "vid is empty", row, value)
+		return
+	}
+	if id.Function != nil && *id.Function != "" {
+		return
+	}
+	if strings.EqualFold(vidType, "INT64") {
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			r.add(true, target, "vid is not an INT64", row, value)
+		}
+		return
+	}
+	if m := fixedStringRe.FindStringSubmatch(vidType); m != nil {
+		if length, _ := strconv.Atoi(m[1]); len(value) > length {
+			r.add(true, target, fmt.Sprintf("vid is longer than %d bytes", length), row, value)
+		}
+	}
+}
+
+// importType maps a schema type to the prop type of the task config
+func importType(schemaType string) string {
+	switch {
+	case strings.HasPrefix(schemaType, "fixed_string"):
+		return "string"
+	case strings.HasPrefix(schemaType, "int"):
+		return "int"
+	}
+	return schemaType
+}
+
+func findSchemaProp(schemaProps []SchemaProp, name string) *SchemaProp {
+	for i := range schemaProps {
+		if schemaProps[i].Name == name {
+			return &schemaProps[i]
+		}
+	}
+	return nil
+}
+
+func (r *sourceReport) checkProps(target string, props specv3.Props, schemaProps []SchemaProp) {
+	mapped := make(map[string]bool, len(props))
+	for _, p := range props {
+		name := unescapeName(p.Name)
+		mapped[name] = true
+		sp := findSchemaProp(schemaProps, name)
+		if sp == nil {
+			r.add(true, target+"."+name, fmt.Sprintf("property %s does not exist", name), nil, "")
+			continue
+		}
+		if !strings.EqualFold(string(p.Type), importType(sp.Type)) {
+			r.add(true, target+"."+name, fmt.Sprintf("type %s does not match the type %s in the schema", p.Type, sp.Type), nil, "")
+		}
+	}
+	for _, sp := range schemaProps {
+		if mapped[sp.Name] {
+			continue
+		}
+		if !sp.Nullable && !sp.HasDefault {
+			r.add(true, target+"."+sp.Name, "property is not nullable and has no default value, but it is not mapped", nil, "")
+		} else {
+			r.add(false, target+"."+sp.Name, "property is not mapped, the null or default value is used", nil, "")
+		}
+	}
+}
+
+func (r *sourceReport) checkPropValues(target string, props specv3.Props, schemaProps []SchemaProp, row *SampleRow) {
+	for _, p := range props {
+		name := unescapeName(p.Name)
+		sp := findSchemaProp(schemaProps, name)
+		if sp == nil {
+			continue
+		}
+		propTarget := target + "." + name
+		value, ok := r.pick(propTarget, p.Index, row)
+		if !ok || (p.Nullable && value == p.NullValue) {
+			continue
+		}
+		if !isValidValue(sp.Type, value) {
+			r.add(true, propTarget, fmt.Sprintf("value is not a valid %s", sp.Type), row, value)
+			continue
+		}
+		if m := fixedStringRe.FindStringSubmatch(sp.Type); m != nil {
+			if length, _ := strconv.Atoi(m[1]); len(value) > length {
+				r.add(false, propTarget, fmt.Sprintf("value is longer than %d bytes and will be truncated", length), row, value)
+			}
+		}
+	}
+}
+
+var timestampLayouts = []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", time.RFC3339}
+
+// isValidValue checks whether the value can be converted to the schema type, the other types are checked by the graph
+func isValidValue(schemaType, value string) bool {
+	var err error
+	switch importType(schemaType) {
+	case "int":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "float", "double":
+		_, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	case "date":
+		_, err = time.Parse("2006-01-02", value)
+	case "timestamp":
+		if _, err = strconv.ParseInt(value, 10, 64); err == nil {
+			return true
+		}
+		for _, layout := range timestampLayouts {
+			if _, err = time.Parse(layout, value); err == nil {
+				return true
+			}
+		}
+	}
+	return err == nil
+}
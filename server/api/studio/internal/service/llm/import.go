@@ -11,10 +11,15 @@ import (
 	"github.com/vesoft-inc/go-pkg/response"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service/storage"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/base"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/checkpoint"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/chunk"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/resolve"
 	"gorm.io/datatypes"
 )
 
@@ -33,6 +38,17 @@ func (g *llmService) AddImportJob(req *types.LLMImportRequest) (resp *types.LLMR
 	if err != nil {
 		return nil, err
 	}
+	if err := storage.CheckQuota(config.Host, config.UserName, 0); err != nil {
+		return nil, err
+	}
+	if _, err := resolve.ParseAliases(req.Aliases); err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err, "invalid aliases")
+	}
+	switch req.ChunkStrategy {
+	case "", chunk.StrategyLines, chunk.StrategyTokens:
+	default:
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, fmt.Errorf("unknown chunk strategy %q", req.ChunkStrategy), "invalid chunk strategy")
+	}
 	space := req.Space
 	runes := []rune(space)
 	if len(runes) > 14 {
@@ -40,14 +56,19 @@ func (g *llmService) AddImportJob(req *types.LLMImportRequest) (resp *types.LLMR
 	}
 	space = string(runes)
 	job := db.LLMJob{
-		Space:      req.Space,
-		File:       req.File,
-		JobType:    req.Type,
-		Status:     base.LLMStatusPending,
-		Host:       config.Host,
-		UserName:   config.UserName,
-		UserPrompt: req.UserPrompt,
-		JobID:      time.Now().Format("20060102150405000") + "_" + hashString(space),
+		Space:           req.Space,
+		File:            req.File,
+		JobType:         req.Type,
+		Status:          base.LLMStatusPending,
+		Host:            config.Host,
+		UserName:        config.UserName,
+		UserPrompt:      req.UserPrompt,
+		Aliases:         req.Aliases,
+		ResolveEntities: req.ResolveEntities,
+		ChunkStrategy:   req.ChunkStrategy,
+		ChunkSize:       req.ChunkSize,
+		ChunkOverlap:    req.ChunkOverlap,
+		JobID:           time.Now().Format("20060102150405000") + "_" + hashString(space),
 	}
 	task := &db.TaskInfo{
 		BID:     job.JobID,
@@ -103,18 +124,35 @@ func (g *llmService) HandleLLMImportJob(req *types.HandleLLMImportRequest) (resp
 
 	if req.Action == "cancel" {
 		job.Status = base.LLMStatusCancel
-		llm.ChangeRunningJobStatus(job.JobID, base.LLMStatusCancel)
+		if err = llm.CancelJob(job.JobID); err != nil {
+			return nil, fmt.Errorf("cancel job error: %v", err)
+		}
 	}
 	if req.Action == "rerun" {
 		job.Status = base.LLMStatusPending
 		// datatypes.JSON
 		job.Process = datatypes.JSON("{}")
-		//delete log & ngql
+		//delete log & ngql, the checkpoints of the blocks are kept so the unchanged blocks are not queried again
 		jobPath := filepath.Join(config.GetConfig().LLM.GQLPath, job.JobID)
-		err = os.RemoveAll(jobPath)
-		if err != nil {
-			return nil, fmt.Errorf("remove job path error: %v", err)
+		entries, err := os.ReadDir(jobPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read job path error: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.Name() == checkpoint.Dir {
+				continue
+			}
+			if err = os.RemoveAll(filepath.Join(jobPath, entry.Name())); err != nil {
+				return nil, fmt.Errorf("remove job path error: %v", err)
+			}
+		}
+	}
+	if req.Action == "resume" {
+		// resume runs the job again with the log and the checkpoints, only the blocks not finished are queried
+		if job.Status != base.LLMStatusFailed && job.Status != base.LLMStatusCancel {
+			return nil, fmt.Errorf("only the failed or canceled job can be resumed, the job is %s", job.Status)
 		}
+		job.Status = base.LLMStatusPending
 	}
 
 	err = db.CtxDB.Save(&job).Error
@@ -134,21 +172,9 @@ func (g *llmService) DeleteLLMImportJob(req *types.DeleteLLMImportRequest) (resp
 		return nil, fmt.Errorf("get job error: %v", err)
 	}
 
-	err = db.CtxDB.Delete(&job).Error
-	if err != nil {
-		return nil, fmt.Errorf("delete job error: %v", err)
-	}
-	//delete task info
-	err = db.CtxDB.Where("llm_job_id = ?", job.ID).Delete(&db.TaskInfo{}).Error
-	if err != nil {
+	if err = llm.DeleteJob(&job); err != nil {
 		return nil, err
 	}
-	//delete log & ngql
-	jobPath := filepath.Join(config.GetConfig().LLM.GQLPath, job.JobID)
-	err = os.RemoveAll(jobPath)
-	if err != nil {
-		return nil, fmt.Errorf("remove job path error: %v", err)
-	}
 
 	return &types.LLMResponse{
 		Data: response.StandardHandlerDataFieldAny(job),
@@ -10,7 +10,9 @@ import (
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/transformer"
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
@@ -22,6 +24,7 @@ type LLMService interface {
 	GetLLMImportJobs(req *types.LLMImportJobsRequest) (resp *types.LLMResponse, err error)
 	HandleLLMImportJob(req *types.HandleLLMImportRequest) (resp *types.LLMResponse, err error)
 	DeleteLLMImportJob(req *types.DeleteLLMImportRequest) (resp *types.LLMResponse, err error)
+	Text2NGQL(req *types.LLMText2NGQLRequest) (resp *types.LLMResponse, err error)
 }
 
 type llmService struct {
@@ -72,6 +75,18 @@ func (g *llmService) GetLLMConfig() (resp *types.LLMResponse, err error) {
 
 func (g *llmService) LLMConfig(req *types.LLMConfigRequest) (err error) {
 	auth := g.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	llmConfig := db.LLMConfig{
+		URL:                req.URL,
+		Key:                req.Key,
+		APIType:            db.APIType(req.APIType),
+		Config:             req.Config,
+		Host:               fmt.Sprintf("%s:%d", auth.Address, auth.Port),
+		UserName:           auth.Username,
+		ContextLengthLimit: req.MaxContextLength,
+	}
+	if err := transformer.Validate(&llmConfig); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
 	oldConfig := db.LLMConfig{
 		Host:     auth.Address,
 		UserName: auth.Username,
@@ -82,15 +97,6 @@ func (g *llmService) LLMConfig(req *types.LLMConfigRequest) (err error) {
 			return res.Error
 		}
 	}
-	llmConfig := db.LLMConfig{
-		URL:                req.URL,
-		Key:                req.Key,
-		APIType:            db.APIType(req.APIType),
-		Config:             req.Config,
-		Host:               fmt.Sprintf("%s:%d", auth.Address, auth.Port),
-		UserName:           auth.Username,
-		ContextLengthLimit: req.MaxContextLength,
-	}
 	res := db.CtxDB.Create(&llmConfig)
 	if res.Error != nil {
 		return res.Error
@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"fmt"
+
+	"github.com/vesoft-inc/go-pkg/response"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/client"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/text2ngql"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/transformer"
+)
+
+func executeError(err error) error {
+	if auth.IsSessionError(err) {
+		return ecode.WithSessionMessage(err)
+	}
+	return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "execute failed")
+}
+
+/*
+Text2NGQL translates the question into a NGQL query of the space by the configured model.
+The prompt is grounded by the live schema of the space, and the answer is validated by EXPLAIN,
+the errors are fed back to the model to repair the query in the bounded rounds.
+The valid query is executed for the result if required and it only reads the graph.
+*/
+func (g *llmService) Text2NGQL(req *types.LLMText2NGQLRequest) (resp *types.LLMResponse, err error) {
+	authData := g.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	config := db.LLMConfig{
+		Host:     fmt.Sprintf("%s:%d", authData.Address, authData.Port),
+		UserName: authData.Username,
+	}
+	if err := db.CtxDB.Where(config).First(&config).Error; err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err, "the llm is not configured")
+	}
+
+	job := llm.ImportJob{
+		NSID:   authData.NSID,
+		LLMJob: &db.LLMJob{Space: req.Space},
+	}
+	if err := job.MakeSchema(); err != nil {
+		return nil, executeError(err)
+	}
+	if err := job.GetSchemaMap(); err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+
+	result, err := text2ngql.Generate(&text2ngql.Options{
+		Question:   req.Question,
+		Space:      req.Space,
+		VidType:    job.Schema.VidType,
+		Schema:     job.SpaceSchemaString,
+		MaxRepairs: req.MaxRepairs,
+		Ask: func(messages []map[string]any) (string, int, int, error) {
+			res, err := llm.FetchWithLLMConfig(&config, map[string]any{
+				"stream":   false,
+				"messages": messages,
+			}, func(str string) {})
+			if err != nil {
+				return "", 0, 0, err
+			}
+			choices, _ := res["choices"].([]any)
+			if len(choices) == 0 {
+				return "", 0, 0, fmt.Errorf("no answer in the response: %v", res)
+			}
+			choice, _ := choices[0].(map[string]any)
+			message, _ := choice["message"].(map[string]any)
+			content, _ := message["content"].(string)
+			usage := transformer.UsageOf(res)
+			return content, usage.PromptTokens, usage.CompletionTokens, nil
+		},
+		Validate: func(query string) (error, error) {
+			res, err := client.Execute(authData.NSID, req.Space, []string{text2ngql.Explain(query)})
+			if err != nil {
+				return nil, err
+			}
+			if res[0].Error != nil && auth.IsSessionError(res[0].Error) {
+				return nil, res[0].Error
+			}
+			return res[0].Error, nil
+		},
+	})
+	if err != nil {
+		if auth.IsSessionError(err) {
+			return nil, executeError(err)
+		}
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "generate the query failed")
+	}
+
+	if req.Execute && result.Valid {
+		if !text2ngql.IsReadOnly(result.Query) {
+			result.ExecuteError = "the query is not executed, only the query reading the graph can be executed"
+		} else {
+			res, err := client.Execute(authData.NSID, req.Space, []string{result.Query})
+			if err != nil {
+				return nil, executeError(err)
+			}
+			if res[0].Error != nil {
+				if auth.IsSessionError(res[0].Error) {
+					return nil, executeError(res[0].Error)
+				}
+				result.ExecuteError = res[0].Error.Error()
+			} else {
+				result.Data = res[0].Result
+			}
+		}
+	}
+	return &types.LLMResponse{
+		Data: response.StandardHandlerDataFieldAny(result),
+	}, nil
+}
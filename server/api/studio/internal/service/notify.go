@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/notify"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type (
+	NotifyService interface {
+		AddChannel(request types.NotifyChannelAddRequest) (*types.NotifyChannelAddData, error)
+		UpdateChannel(request types.NotifyChannelUpdateRequest) error
+		RemoveChannel(request types.NotifyChannelRemoveRequest) error
+		ListChannels() (*types.NotifyChannelListData, error)
+		TestChannel(request types.NotifyChannelTestRequest) error
+		ListDeliveries(request types.NotifyDeliveryListRequest) (*types.NotifyDeliveryListData, error)
+	}
+
+	notifyService struct {
+		logx.Logger
+		ctx              context.Context
+		svcCtx           *svc.ServiceContext
+		gormErrorWrapper utils.GormErrorWrapper
+	}
+)
+
+func NewNotifyService(ctx context.Context, svcCtx *svc.ServiceContext) NotifyService {
+	return &notifyService{
+		Logger:           logx.WithContext(ctx),
+		ctx:              ctx,
+		svcCtx:           svcCtx,
+		gormErrorWrapper: utils.GormErrorWithLogger(ctx),
+	}
+}
+
+func (n *notifyService) owner() (string, string) {
+	user := n.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	return user.Address + ":" + strconv.Itoa(user.Port), user.Username
+}
+
+func (n *notifyService) AddChannel(request types.NotifyChannelAddRequest) (*types.NotifyChannelAddData, error) {
+	cfg, secret, err := formatNotifyChannelConfig(request.Type, request.WebhookConfig, request.SMTPConfig, "")
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
+	host, username := n.owner()
+	channel := &db.NotifyChannel{
+		BID:       n.svcCtx.IDGenerator.Generate(),
+		Name:      request.Name,
+		Type:      request.Type,
+		Config:    cfg,
+		Secret:    secret,
+		IsDefault: request.IsDefault,
+		Host:      host,
+		Username:  username,
+	}
+	if err := db.CtxDB.Create(channel).Error; err != nil {
+		return nil, n.gormErrorWrapper(err)
+	}
+	return &types.NotifyChannelAddData{
+		ID: channel.BID,
+	}, nil
+}
+
+func (n *notifyService) UpdateChannel(request types.NotifyChannelUpdateRequest) error {
+	channel, err := n.findChannel(request.ID)
+	if err != nil {
+		return err
+	}
+	// the saved secret is kept when the request leaves it empty
+	var savedSecret string
+	if channel.Secret != "" {
+		secret, err := utils.Decrypt(channel.Secret, []byte(cipher))
+		if err != nil {
+			return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+		}
+		savedSecret = string(secret)
+	}
+	cfg, secret, err := formatNotifyChannelConfig(request.Type, request.WebhookConfig, request.SMTPConfig, savedSecret)
+	if err != nil {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, err)
+	}
+	result := db.CtxDB.Model(&db.NotifyChannel{}).Where("b_id = ?", channel.BID).Updates(map[string]interface{}{
+		"name":       request.Name,
+		"type":       request.Type,
+		"config":     cfg,
+		"secret":     secret,
+		"is_default": request.IsDefault,
+	})
+	if result.Error != nil {
+		return n.gormErrorWrapper(result.Error)
+	}
+	return nil
+}
+
+func (n *notifyService) RemoveChannel(request types.NotifyChannelRemoveRequest) error {
+	host, username := n.owner()
+	result := db.CtxDB.Delete(&db.NotifyChannel{}, "b_id = ? AND host = ? AND username = ?", request.ID, host, username)
+	if result.Error != nil {
+		return n.gormErrorWrapper(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, errors.New("notify channel doesn't exist"))
+	}
+	return nil
+}
+
+func (n *notifyService) ListChannels() (*types.NotifyChannelListData, error) {
+	host, username := n.owner()
+	var channels []db.NotifyChannel
+	if err := db.CtxDB.Where("host = ? AND username = ?", host, username).
+		Order("create_time desc").Find(&channels).Error; err != nil {
+		return nil, n.gormErrorWrapper(err)
+	}
+	items := make([]types.NotifyChannel, 0, len(channels))
+	for _, channel := range channels {
+		item := types.NotifyChannel{
+			ID:         channel.BID,
+			Type:       channel.Type,
+			Name:       channel.Name,
+			IsDefault:  channel.IsDefault,
+			CreateTime: channel.CreateTime.UnixMilli(),
+		}
+		var err error
+		switch channel.Type {
+		case notify.TypeWebhook:
+			item.WebhookConfig = &types.NotifyWebhookConfig{}
+			err = json.Unmarshal([]byte(channel.Config), item.WebhookConfig)
+		case notify.TypeSMTP:
+			item.SMTPConfig = &types.NotifySMTPConfig{}
+			err = json.Unmarshal([]byte(channel.Config), item.SMTPConfig)
+		}
+		if err != nil {
+			return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "parse json failed")
+		}
+		items = append(items, item)
+	}
+	return &types.NotifyChannelListData{
+		List: items,
+	}, nil
+}
+
+func (n *notifyService) TestChannel(request types.NotifyChannelTestRequest) error {
+	channel, err := n.findChannel(request.ID)
+	if err != nil {
+		return err
+	}
+	if err := notify.Test(channel); err != nil {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, err, "send the test notification failed")
+	}
+	return nil
+}
+
+func (n *notifyService) ListDeliveries(request types.NotifyDeliveryListRequest) (*types.NotifyDeliveryListData, error) {
+	host, username := n.owner()
+	query := db.CtxDB.Model(&db.NotifyDelivery{}).Where("host = ? AND username = ?", host, username)
+	if request.ChannelID != "" {
+		query = query.Where("channel_id = ?", request.ChannelID)
+	}
+	if request.JobID != "" {
+		query = query.Where("job_id = ?", request.JobID)
+	}
+	if request.Status != "" {
+		query = query.Where("status = ?", request.Status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, n.gormErrorWrapper(err)
+	}
+	var deliveries []db.NotifyDelivery
+	if err := query.Order("id desc").Offset((request.Page - 1) * request.PageSize).Limit(request.PageSize).
+		Find(&deliveries).Error; err != nil {
+		return nil, n.gormErrorWrapper(err)
+	}
+	items := make([]types.NotifyDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		items = append(items, types.NotifyDelivery{
+			ID:         delivery.ID,
+			ChannelID:  delivery.ChannelID,
+			JobKind:    delivery.JobKind,
+			JobID:      delivery.JobID,
+			Event:      delivery.Event,
+			Status:     delivery.Status,
+			Attempts:   delivery.Attempts,
+			LastError:  delivery.LastError,
+			Payload:    delivery.Payload,
+			CreateTime: delivery.CreateTime.UnixMilli(),
+			UpdateTime: delivery.UpdateTime.UnixMilli(),
+		})
+	}
+	return &types.NotifyDeliveryListData{
+		Total: total,
+		List:  items,
+	}, nil
+}
+
+func (n *notifyService) findChannel(id string) (*db.NotifyChannel, error) {
+	host, username := n.owner()
+	var channel db.NotifyChannel
+	result := db.CtxDB.Where("b_id = ? AND host = ? AND username = ?", id, host, username).Limit(1).Find(&channel)
+	if result.Error != nil {
+		return nil, n.gormErrorWrapper(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ecode.WithErrorMessage(ecode.ErrBadRequest, errors.New("notify channel doesn't exist"))
+	}
+	return &channel, nil
+}
+
+// formatNotifyChannelConfig validates the channel config, it returns the json config without the secret and the encrypted secret
+func formatNotifyChannelConfig(typ string, webhookConfig *types.NotifyWebhookConfig, smtpConfig *types.NotifySMTPConfig, savedSecret string) (string, string, error) {
+	var cfg interface{}
+	secret := savedSecret
+	switch typ {
+	case notify.TypeWebhook:
+		if webhookConfig == nil {
+			return "", "", errors.New("webhookConfig is required")
+		}
+		c := &notify.WebhookConfig{URL: webhookConfig.URL, Headers: webhookConfig.Headers}
+		if err := c.Validate(); err != nil {
+			return "", "", err
+		}
+		if webhookConfig.Secret != "" {
+			secret = webhookConfig.Secret
+		}
+		cfg = c
+	case notify.TypeSMTP:
+		if smtpConfig == nil {
+			return "", "", errors.New("smtpConfig is required")
+		}
+		c := &notify.SMTPConfig{
+			Host:     smtpConfig.Host,
+			Port:     smtpConfig.Port,
+			Username: smtpConfig.Username,
+			From:     smtpConfig.From,
+			To:       smtpConfig.To,
+		}
+		if err := c.Validate(); err != nil {
+			return "", "", err
+		}
+		if smtpConfig.Password != "" {
+			secret = smtpConfig.Password
+		}
+		cfg = c
+	default:
+		return "", "", fmt.Errorf("unsupported notify channel type: %s", typ)
+	}
+	cfgStr, err := json.Marshal(cfg)
+	if err != nil {
+		return "", "", fmt.Errorf("json stringify config error: %v", err)
+	}
+	if secret == "" {
+		return string(cfgStr), "", nil
+	}
+	crypto, err := utils.Encrypt([]byte(secret), []byte(cipher))
+	if err != nil {
+		return "", "", fmt.Errorf("encrypt secret error: %v", err)
+	}
+	return string(cfgStr), crypto, nil
+}
+
+// checkNotifyChannels makes sure the channels chosen for a job belong to the user
+func checkNotifyChannels(host, username string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var count int64
+	if err := db.CtxDB.Model(&db.NotifyChannel{}).Where("b_id IN (?) AND host = ? AND username = ?", ids, host, username).
+		Count(&count).Error; err != nil {
+		return ecode.WithErrorMessage(ecode.ErrInternalServer, err)
+	}
+	if count != int64(len(ids)) {
+		return ecode.WithErrorMessage(ecode.ErrBadRequest, errors.New("some notify channels don't exist"))
+	}
+	return nil
+}
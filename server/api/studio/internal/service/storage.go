@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service/storage"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/types"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type (
+	StorageService interface {
+		Usage() (*types.StorageUsageData, error)
+		Purge() (*types.StoragePurgeData, error)
+	}
+
+	storageService struct {
+		logx.Logger
+		ctx    context.Context
+		svcCtx *svc.ServiceContext
+	}
+)
+
+func NewStorageService(ctx context.Context, svcCtx *svc.ServiceContext) StorageService {
+	return &storageService{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// isAdmin checks the user against the admins, each one is host:port/user of the graph signed in, such as 127.0.0.1:9669/root
+func (s *storageService) isAdmin(user *auth.AuthData) bool {
+	name := user.Address + ":" + strconv.Itoa(user.Port) + "/" + user.Username
+	for _, admin := range s.svcCtx.Config.Storage.Admins {
+		if admin == name {
+			return true
+		}
+	}
+	return false
+}
+
+func toStorageUsage(usage *storage.Usage) types.StorageUsage {
+	return types.StorageUsage{
+		Host:        usage.Host,
+		Username:    usage.Username,
+		UploadBytes: usage.UploadBytes,
+		TaskBytes:   usage.TaskBytes,
+		LLMBytes:    usage.LLMBytes,
+		TotalBytes:  usage.Total(),
+	}
+}
+
+// Usage reports the usage of all the users to the admins, and the usage of the current user to the others
+func (s *storageService) Usage() (*types.StorageUsageData, error) {
+	user := s.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	data := &types.StorageUsageData{
+		Users:         []types.StorageUsage{},
+		UserQuota:     s.svcCtx.Config.Storage.UserQuota,
+		InstanceQuota: s.svcCtx.Config.Storage.InstanceQuota,
+	}
+	if !s.isAdmin(user) {
+		usage, err := storage.UserUsage(user.Address+":"+strconv.Itoa(user.Port), user.Username)
+		if err != nil {
+			return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "get the storage usage failed")
+		}
+		data.Users = append(data.Users, toStorageUsage(usage))
+		return data, nil
+	}
+	usages, total, err := storage.Report()
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "get the storage usage failed")
+	}
+	for _, usage := range usages {
+		data.Users = append(data.Users, toStorageUsage(usage))
+	}
+	data.TotalBytes = total
+	return data, nil
+}
+
+// Purge purges the expired files at once, which the janitor does periodically
+func (s *storageService) Purge() (*types.StoragePurgeData, error) {
+	user := s.ctx.Value(auth.CtxKeyUserInfo{}).(*auth.AuthData)
+	if !s.isAdmin(user) {
+		return nil, ecode.WithErrorMessage(ecode.ErrForbidden, fmt.Errorf("%s is not a storage admin", user.Username), "only the admins can purge the files")
+	}
+	result, err := storage.Purge(time.Now())
+	if err != nil {
+		return nil, ecode.WithErrorMessage(ecode.ErrInternalServer, err, "purge failed")
+	}
+	return &types.StoragePurgeData{
+		Uploads: result.Uploads,
+		Tasks:   result.Tasks,
+		LLMJobs: result.LLMJobs,
+		Orphans: result.Orphans,
+		Bytes:   result.Bytes,
+	}, nil
+}
@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service/importer"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/base"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/lease"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	janitorLeaderID = "storage_janitor"
+	// the dirs without records are kept for a while, which are being created or deleted
+	orphanGrace = time.Hour
+	// the dir of the part files of the chunked uploads in the upload dir
+	uploadPartDir = ".uploads"
+)
+
+// PurgeResult counts the artifacts purged by the janitor and the bytes freed
+type PurgeResult struct {
+	Uploads int
+	Tasks   int
+	LLMJobs int
+	Orphans int
+	Bytes   int64
+}
+
+// artifact is a finished import task or llm job with the bytes of its files
+type artifact struct {
+	id       string
+	finished time.Time
+	size     int64
+}
+
+/*
+expired selects the artifacts to purge from the finished ones ordered from the oldest:
+the ones finished longer than maxAge ago, and then the oldest ones while all the files take more than maxBytes.
+*/
+func expired(artifacts []artifact, now time.Time, maxAge time.Duration, total, maxBytes int64) []artifact {
+	var selected []artifact
+	for _, a := range artifacts {
+		if (maxAge > 0 && now.Sub(a.finished) > maxAge) || (maxBytes > 0 && total > maxBytes) {
+			selected = append(selected, a)
+			total -= a.size
+		}
+	}
+	return selected
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// StartJanitor purges the expired files periodically on the instance holding the janitor lease
+func StartJanitor() {
+	interval := time.Duration(config.GetConfig().Storage.JanitorInterval) * time.Second
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			runJanitor()
+		}
+	}()
+}
+
+func runJanitor() {
+	defer func() {
+		if err := recover(); err != nil {
+			logx.Errorf("[storage janitor] panic: %v", err)
+		}
+	}()
+	ok, err := lease.Claim(lease.KindLeader, janitorLeaderID)
+	if err != nil || !ok {
+		return
+	}
+	result, err := Purge(time.Now())
+	if err != nil {
+		logx.Errorf("[storage janitor] purge failed: %s", err)
+	}
+	if result.Uploads+result.Tasks+result.LLMJobs+result.Orphans > 0 {
+		logx.Infof("[storage janitor] purged %d uploaded files, %d import tasks, %d llm jobs and %d orphan dirs, %d bytes freed",
+			result.Uploads, result.Tasks, result.LLMJobs, result.Orphans, result.Bytes)
+	}
+}
+
+// Purge purges the files expired by the retention policies at now with their records
+func Purge(now time.Time) (*PurgeResult, error) {
+	result := &PurgeResult{}
+	for _, purge := range []func(time.Time, *PurgeResult) error{purgeTasks, purgeLLMJobs, purgeUploads, purgeOrphans} {
+		if err := purge(now, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func purgeTasks(now time.Time, result *PurgeResult) error {
+	c := config.GetConfig()
+	policy := c.Storage
+	if policy.TaskRetentionDays <= 0 && policy.TaskRetentionBytes <= 0 {
+		return nil
+	}
+	// the failed records of a task are read by its retry until the retry completes
+	var retried []string
+	if err := db.CtxDB.Model(&db.TaskInfo{}).Where("parent_id <> '' AND task_status IN ?", []string{importer.Processing.String(), importer.Queued.String()}).
+		Pluck("parent_id", &retried).Error; err != nil {
+		return err
+	}
+	var tasks []db.TaskInfo
+	tx := db.CtxDB.Select("b_id", "update_time").
+		Where("task_status IN ?", []string{importer.Finished.String(), importer.Stoped.String(), importer.Aborted.String()}).
+		Where("llm_job_id = 0 OR llm_job_id IS NULL")
+	if len(retried) > 0 {
+		tx = tx.Where("b_id NOT IN ?", retried)
+	}
+	if err := tx.Order("update_time asc").Find(&tasks).Error; err != nil {
+		return err
+	}
+	total, err := DirSize(c.File.TasksDir)
+	if err != nil {
+		return err
+	}
+	artifacts := make([]artifact, 0, len(tasks))
+	for _, task := range tasks {
+		size, err := DirSize(filepath.Join(c.File.TasksDir, task.BID))
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, artifact{id: task.BID, finished: task.UpdateTime, size: size})
+	}
+	for _, a := range expired(artifacts, now, days(policy.TaskRetentionDays), total, policy.TaskRetentionBytes) {
+		if err := importer.GetTaskMgr().DelTask(c.File.TasksDir, a.id); err != nil {
+			return err
+		}
+		result.Tasks++
+		result.Bytes += a.size
+	}
+	return nil
+}
+
+func purgeLLMJobs(now time.Time, result *PurgeResult) error {
+	c := config.GetConfig()
+	policy := c.Storage
+	if policy.LLMRetentionDays <= 0 && policy.LLMRetentionBytes <= 0 {
+		return nil
+	}
+	var jobs []*db.LLMJob
+	if err := db.CtxDB.Where("status IN ?", []base.LLMStatus{base.LLMStatusSuccess, base.LLMStatusFailed, base.LLMStatusCancel}).
+		Order("update_time asc").Find(&jobs).Error; err != nil {
+		return err
+	}
+	total, err := DirSize(c.LLM.GQLPath)
+	if err != nil {
+		return err
+	}
+	artifacts := make([]artifact, 0, len(jobs))
+	byID := make(map[string]*db.LLMJob, len(jobs))
+	for _, job := range jobs {
+		size, err := DirSize(filepath.Join(c.LLM.GQLPath, job.JobID))
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, artifact{id: job.JobID, finished: job.UpdateTime, size: size})
+		byID[job.JobID] = job
+	}
+	for _, a := range expired(artifacts, now, days(policy.LLMRetentionDays), total, policy.LLMRetentionBytes) {
+		if err := llm.DeleteJob(byID[a.id]); err != nil {
+			return err
+		}
+		result.LLMJobs++
+		result.Bytes += a.size
+	}
+	return nil
+}
+
+/*
+purgeUploads removes the uploaded files unmodified longer than the retention with their configs.
+The files of a user are kept while the import tasks or the llm jobs of the user are waiting or running, which may read them.
+*/
+func purgeUploads(now time.Time, result *PurgeResult) error {
+	c := config.GetConfig()
+	if c.Storage.UploadRetentionDays <= 0 {
+		return nil
+	}
+	namespaces, err := utils.UploadNamespaces(c.File.UploadDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	deadline := now.Add(-days(c.Storage.UploadRetentionDays))
+	for _, ns := range namespaces {
+		var active int64
+		if err := db.CtxDB.Model(&db.TaskInfo{}).Where("address = ? AND user = ? AND task_status IN ?", ns.Host, ns.Username,
+			[]string{importer.Processing.String(), importer.Queued.String()}).Count(&active).Error; err != nil {
+			return err
+		}
+		if active == 0 {
+			if err := db.CtxDB.Model(&db.LLMJob{}).Where("host = ? AND user_name = ? AND status IN ?", ns.Host, ns.Username,
+				[]base.LLMStatus{base.LLMStatusPending, base.LLMStatusRunning}).Count(&active).Error; err != nil {
+				return err
+			}
+		}
+		if active > 0 {
+			continue
+		}
+		var names []string
+		err := filepath.WalkDir(ns.Dir, func(p string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(entry.Name(), ".") && p != ns.Dir {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() || info.ModTime().After(deadline) {
+				return nil
+			}
+			if err := os.Remove(p); err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(ns.Dir, p)
+			names = append(names, filepath.ToSlash(rel))
+			result.Uploads++
+			result.Bytes += info.Size()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			// the configs of the file, and of the members of the archive
+			if err := db.CtxDB.Where("host = ? AND username = ? AND (name = ? OR SUBSTR(name, 1, ?) = ?)", ns.Host, ns.Username,
+				name, len([]rune(name))+1, name+"/").Delete(&db.File{}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// purgeOrphans removes the task dirs, the llm job dirs and the upload part files left without their records
+func purgeOrphans(now time.Time, result *PurgeResult) error {
+	c := config.GetConfig()
+	for _, orphans := range []struct {
+		root  string
+		model interface{}
+		where string
+		trim  string
+	}{
+		{c.File.TasksDir, &db.TaskInfo{}, "b_id = ?", ""},
+		{c.LLM.GQLPath, &db.LLMJob{}, "job_id = ?", ""},
+		{filepath.Join(c.File.UploadDir, uploadPartDir), &db.FileUpload{}, "b_id = ?", ".part"},
+	} {
+		entries, err := os.ReadDir(orphans.root)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || now.Sub(info.ModTime()) < orphanGrace {
+				continue
+			}
+			var count int64
+			id := strings.TrimSuffix(entry.Name(), orphans.trim)
+			if err := db.CtxDB.Model(orphans.model).Where(orphans.where, id).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			p := filepath.Join(orphans.root, entry.Name())
+			size, _ := DirSize(p)
+			if err := os.RemoveAll(p); err != nil {
+				return err
+			}
+			result.Orphans++
+			result.Bytes += size
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ecode"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
+)
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// Usage is the bytes of the files kept for a user, the files of no owner are counted for the empty user
+type Usage struct {
+	Host        string
+	Username    string
+	UploadBytes int64
+	TaskBytes   int64
+	LLMBytes    int64
+}
+
+func (u *Usage) Total() int64 {
+	return u.UploadBytes + u.TaskBytes + u.LLMBytes
+}
+
+// DirSize returns the total size of the regular files in a dir, a missing dir is empty
+func DirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size, err
+}
+
+// UserUsage computes the bytes of the files kept for a user of a nebula host
+func UserUsage(host, username string) (*Usage, error) {
+	c := config.GetConfig()
+	usage := &Usage{Host: host, Username: username}
+	var err error
+	if usage.UploadBytes, err = DirSize(utils.UserUploadDir(c.File.UploadDir, host, username)); err != nil {
+		return nil, err
+	}
+	var taskIDs, jobIDs []string
+	if err := db.CtxDB.Model(&db.TaskInfo{}).Where("address = ? AND user = ?", host, username).Pluck("b_id", &taskIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := db.CtxDB.Model(&db.LLMJob{}).Where("host = ? AND user_name = ?", host, username).Pluck("job_id", &jobIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range taskIDs {
+		size, err := DirSize(filepath.Join(c.File.TasksDir, id))
+		if err != nil {
+			return nil, err
+		}
+		usage.TaskBytes += size
+	}
+	for _, id := range jobIDs {
+		size, err := DirSize(filepath.Join(c.LLM.GQLPath, id))
+		if err != nil {
+			return nil, err
+		}
+		usage.LLMBytes += size
+	}
+	return usage, nil
+}
+
+// InstanceUsage computes the bytes of all the files of the instance
+func InstanceUsage() (int64, error) {
+	c := config.GetConfig()
+	var total int64
+	for _, dir := range []string{c.File.UploadDir, c.File.TasksDir, c.LLM.GQLPath} {
+		size, err := DirSize(dir)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// Report computes the usage of each user from the most consuming, and the bytes of all the files of the instance
+func Report() ([]*Usage, int64, error) {
+	c := config.GetConfig()
+	usages := make(map[[2]string]*Usage)
+	usageOf := func(host, username string) *Usage {
+		key := [2]string{host, username}
+		if usages[key] == nil {
+			usages[key] = &Usage{Host: host, Username: username}
+		}
+		return usages[key]
+	}
+
+	namespaces, err := utils.UploadNamespaces(c.File.UploadDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, 0, err
+	}
+	for _, ns := range namespaces {
+		size, err := DirSize(ns.Dir)
+		if err != nil {
+			return nil, 0, err
+		}
+		usageOf(ns.Host, ns.Username).UploadBytes += size
+	}
+
+	var tasks []db.TaskInfo
+	if err := db.CtxDB.Select("b_id", "address", "user").Find(&tasks).Error; err != nil {
+		return nil, 0, err
+	}
+	owners := make(map[string][2]string, len(tasks))
+	for _, task := range tasks {
+		owners[task.BID] = [2]string{task.Address, task.User}
+	}
+	if err := eachDir(c.File.TasksDir, func(id, dir string) error {
+		size, err := DirSize(dir)
+		owner := owners[id]
+		usageOf(owner[0], owner[1]).TaskBytes += size
+		return err
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []db.LLMJob
+	if err := db.CtxDB.Select("job_id", "host", "user_name").Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	owners = make(map[string][2]string, len(jobs))
+	for _, job := range jobs {
+		owners[job.JobID] = [2]string{job.Host, job.UserName}
+	}
+	if err := eachDir(c.LLM.GQLPath, func(id, dir string) error {
+		size, err := DirSize(dir)
+		owner := owners[id]
+		usageOf(owner[0], owner[1]).LLMBytes += size
+		return err
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	list := make([]*Usage, 0, len(usages))
+	for _, usage := range usages {
+		list = append(list, usage)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Total() != list[j].Total() {
+			return list[i].Total() > list[j].Total()
+		}
+		return list[i].Host+"/"+list[i].Username < list[j].Host+"/"+list[j].Username
+	})
+	total, err := InstanceUsage()
+	return list, total, err
+}
+
+// eachDir calls fn with the name and the path of each sub dir of root
+func eachDir(root string, fn func(name, dir string) error) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if err := fn(entry.Name(), filepath.Join(root, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+CheckQuota checks the files of the user and the instance do not exceed their quotas with more bytes written,
+bytes is 0 for the writes of unknown size, which are rejected once a quota is exceeded.
+*/
+func CheckQuota(host, username string, bytes int64) error {
+	c := config.GetConfig().Storage
+	if c.UserQuota > 0 {
+		usage, err := UserUsage(host, username)
+		if err != nil {
+			return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "check the storage quota failed")
+		}
+		if usage.Total()+bytes > c.UserQuota {
+			return ecode.WithErrorMessage(ecode.ErrForbidden, ErrQuotaExceeded, "your files take %d bytes, %d bytes more exceed your quota of %d bytes", usage.Total(), bytes, c.UserQuota)
+		}
+	}
+	if c.InstanceQuota > 0 {
+		total, err := InstanceUsage()
+		if err != nil {
+			return ecode.WithErrorMessage(ecode.ErrInternalServer, err, "check the storage quota failed")
+		}
+		if total+bytes > c.InstanceQuota {
+			return ecode.WithErrorMessage(ecode.ErrForbidden, ErrQuotaExceeded, "the files of studio take %d bytes, %d bytes more exceed the quota of %d bytes", total, bytes, c.InstanceQuota)
+		}
+	}
+	return nil
+}
@@ -35,10 +35,23 @@ type FileStat struct {
 	Delimiter  string `json:"delimiter"`
 	Name       string `json:"name"`
 	Size       int64  `json:"size"`
+	// the archive of a member, the name of a member is the name of the archive joined with its path in the archive
+	Archive string `json:"archive,omitempty"`
 }
 
 type FilesIndexData struct {
 	List []FileStat `json:"list"`
+	// the folders in the namespace of the user, the names of the files are their paths in it
+	Folders []string `json:"folders"`
+}
+
+type FileFolderCreateRequest struct {
+	Path string `json:"path" validate:"required"`
+}
+
+type FileMoveRequest struct {
+	From string `json:"from" validate:"required"`
+	To   string `json:"to" validate:"required"`
 }
 
 type FileConfigUpdateRequest struct {
@@ -47,6 +60,39 @@ type FileConfigUpdateRequest struct {
 	Name       string `json:"name" validate:"required"`
 }
 
+type FileUploadInitRequest struct {
+	Name       string `json:"name" validate:"required"`
+	Size       int64  `json:"size" validate:"gte=0"`
+	WithHeader bool   `json:"withHeader,optional"`
+	Delimiter  string `json:"delimiter,optional"`
+}
+
+type FileUploadRequest struct {
+	Id string `path:"id" validate:"required"`
+}
+
+type FileUploadChunkRequest struct {
+	Id string `path:"id" validate:"required"`
+	// the offset of the chunk in the file, which must be the offset received so far
+	Offset int64 `form:"offset"`
+}
+
+type FileUploadCompleteRequest struct {
+	Id string `path:"id" validate:"required"`
+	// the hex sha256 checksum of the whole file
+	Checksum string `json:"checksum" validate:"required"`
+}
+
+type FileUploadData struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// the bytes received so far, the next chunk starts at it
+	Offset int64 `json:"offset"`
+	// the suggested size of a chunk
+	ChunkSize int64 `json:"chunkSize"`
+}
+
 type ImportTaskCSV struct {
 	WithHeader *bool   `json:"withHeader,optional"`
 	LazyQuotes *bool   `json:"lazyQuotes,optional"`
@@ -116,6 +162,20 @@ type SFTPConfig struct {
 	Path       string `json:"path,omitempty"`
 }
 
+type FTPConfig struct {
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+type HDFSConfig struct {
+	Address string `json:"address,omitempty"`
+	User    string `json:"user,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
 type OSSConfig struct {
 	Endpoint        string `json:"endpoint,omitempty"`
 	AccessKeyID     string `json:"accessKeyID,omitempty"`
@@ -159,9 +219,12 @@ type Source struct {
 	Path               string        `json:"path,optional,omitempty"`
 	S3                 *S3Config     `json:"s3,optional,omitempty"`
 	SFTP               *SFTPConfig   `json:"sftp,optional,omitempty"`
+	FTP                *FTPConfig    `json:"ftp,optional,omitempty"`
+	HDFS               *HDFSConfig   `json:"hdfs,optional,omitempty"`
 	OSS                *OSSConfig    `json:"oss,optional,omitempty"`
 	DatasourceId       *string       `json:"datasourceId,optional,omitempty"`
 	DatasourceFilePath *string       `json:"datasourceFilePath,optional,omitempty"`
+	DatasourceQuery    *string       `json:"datasourceQuery,optional,omitempty"`
 	Tags               []Tag         `json:"tags,optional"`
 	Edges              []Edge        `json:"edges,optional"`
 }
@@ -183,6 +246,11 @@ type CreateImportTaskRequest struct {
 	Name      string  `json:"name" validate:"required"`
 	Config    string  `json:"config" validate:"required"`
 	RawConfig string  `json:"rawConfig" validate:"required"`
+	Priority  int     `json:"priority,optional"`
+	StartAt   int64   `json:"startAt,optional"`
+	Cron      string  `json:"cron,optional"`
+	// the notify channels of the task besides the default channels
+	NotifyChannelIds []string `json:"notifyChannelIds,optional"`
 }
 
 type CreateTaskDraftRequest struct {
@@ -202,24 +270,80 @@ type CreateImportTaskData struct {
 	Id string `json:"id"`
 }
 
+type ValidateImportTaskIssue struct {
+	Target  string `json:"target"`
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+	Rows    int    `json:"rows"`
+	Value   string `json:"value"`
+}
+
+type ValidateImportTaskSource struct {
+	Index       int                        `json:"index"`
+	Path        string                     `json:"path"`
+	SampledRows int                        `json:"sampledRows"`
+	Errors      []*ValidateImportTaskIssue `json:"errors"`
+	Warnings    []*ValidateImportTaskIssue `json:"warnings"`
+}
+
+type SuggestImportMappingRequest struct {
+	Space              string        `json:"space" validate:"required"`
+	Path               string        `json:"path,optional"`
+	DatasourceId       *string       `json:"datasourceId,optional"`
+	DatasourceFilePath *string       `json:"datasourceFilePath,optional"`
+	DatasourceQuery    *string       `json:"datasourceQuery,optional"`
+	CSV                ImportTaskCSV `json:"csv,optional"`
+}
+
+type SuggestImportColumn struct {
+	Index   int      `json:"index"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Samples []string `json:"samples"`
+}
+
+type SuggestImportTarget struct {
+	Kind  string  `json:"kind"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+type SuggestImportMappingData struct {
+	WithHeader bool                  `json:"withHeader"`
+	Columns    []SuggestImportColumn `json:"columns"`
+	Targets    []SuggestImportTarget `json:"targets"`
+	Config     ImportTaskConfig      `json:"config"`
+}
+
+type ValidateImportTaskData struct {
+	Valid   bool                       `json:"valid"`
+	Sources []ValidateImportTaskSource `json:"sources"`
+}
+
 type GetImportTaskRequest struct {
 	Id string `path:"id" validate:"required"`
 }
 
 type GetImportTaskData struct {
-	Id            string          `json:"id"`
-	Name          string          `json:"name"`
-	User          string          `json:"user"`
-	Address       string          `json:"address"`
-	ImportAddress []string        `json:"importAddress"`
-	Space         string          `json:"space"`
-	Status        string          `json:"status"`
-	Message       string          `json:"message"`
-	CreateTime    int64           `json:"createTime"`
-	UpdateTime    int64           `json:"updateTime"`
-	Stats         ImportTaskStats `json:"stats"`
-	RawConfig     string          `json:"rawConfig"`
-	LLMJob        interface{}     `json:"llmJob"`
+	Id               string          `json:"id"`
+	Name             string          `json:"name"`
+	User             string          `json:"user"`
+	Address          string          `json:"address"`
+	ImportAddress    []string        `json:"importAddress"`
+	Space            string          `json:"space"`
+	Status           string          `json:"status"`
+	Message          string          `json:"message"`
+	CreateTime       int64           `json:"createTime"`
+	UpdateTime       int64           `json:"updateTime"`
+	Stats            ImportTaskStats `json:"stats"`
+	RawConfig        string          `json:"rawConfig"`
+	LLMJob           interface{}     `json:"llmJob"`
+	Priority         int             `json:"priority"`
+	StartAt          int64           `json:"startAt"`
+	Cron             string          `json:"cron"`
+	QueuePosition    int             `json:"queuePosition"`
+	ParentId         string          `json:"parentId"`
+	NotifyChannelIds []string        `json:"notifyChannelIds"`
 }
 
 type ImportTaskStats struct {
@@ -270,6 +394,10 @@ type StopImportTaskRequest struct {
 	Id string `path:"id"`
 }
 
+type RetryImportTaskRequest struct {
+	Id string `path:"id" validate:"required"`
+}
+
 type DownloadLogsRequest struct {
 	Id   string `path:"id" validate:"required"`
 	Name string `form:"name" validate:"required"`
@@ -378,7 +506,14 @@ type DatasourceSFTPConfig struct {
 	Host     string `json:"host"`
 	Port     int    `json:"port"`
 	Username string `json:"username"`
-	Password string `json:"password"`
+	Password string `json:"password,optional"`
+	// the private key in the PEM or OpenSSH format
+	PrivateKey string `json:"privateKey,optional"`
+	Passphrase string `json:"passphrase,optional"`
+	// the SHA256 fingerprint of the host key, it is trusted and recorded on the first use without the known hosts
+	HostKey string `json:"hostKey,optional,omitempty"`
+	// the lines of a known_hosts file
+	KnownHosts string `json:"knownHosts,optional,omitempty"`
 }
 
 type DatasourceS3UpdateConfig struct {
@@ -390,27 +525,130 @@ type DatasourceS3UpdateConfig struct {
 }
 
 type DatasourceSFTPUpdateConfig struct {
-	Host     string `json:"host,optional,omitempty"`
-	Port     int    `json:"port,optional,omitempty"`
-	Username string `json:"username,optional,omitempty"`
+	Host       string `json:"host,optional,omitempty"`
+	Port       int    `json:"port,optional,omitempty"`
+	Username   string `json:"username,optional,omitempty"`
+	Password   string `json:"password,optional,omitempty"`
+	PrivateKey string `json:"privateKey,optional,omitempty"`
+	Passphrase string `json:"passphrase,optional,omitempty"`
+	HostKey    string `json:"hostKey,optional,omitempty"`
+	KnownHosts string `json:"knownHosts,optional,omitempty"`
+}
+
+type DatasourceFTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username,optional"`
+	Password string `json:"password,optional"`
+	// explicit or implicit, empty for plain ftp
+	TLS                string `json:"tls,optional,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,optional,omitempty"`
+}
+
+type DatasourceFTPUpdateConfig struct {
+	Host               string `json:"host,optional,omitempty"`
+	Port               int    `json:"port,optional,omitempty"`
+	Username           string `json:"username,optional,omitempty"`
+	Password           string `json:"password,optional,omitempty"`
+	TLS                string `json:"tls,optional,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,optional,omitempty"`
+}
+
+type DatasourceHDFSConfig struct {
+	// the webhdfs url of the namenode, such as http://namenode:9870
+	Endpoint string `json:"endpoint"`
+	// the rpc address of the namenode the importer reads from, such as namenode:8020
+	Address string `json:"address,optional,omitempty"`
+	User    string `json:"user"`
+	// simple or kerberos
+	Auth     string `json:"auth,optional,omitempty"`
+	Realm    string `json:"realm,optional,omitempty"`
+	KDC      string `json:"kdc,optional,omitempty"`
+	SPN      string `json:"spn,optional,omitempty"`
+	Password string `json:"password,optional"`
+}
+
+type DatasourceHDFSUpdateConfig struct {
+	Endpoint string `json:"endpoint,optional,omitempty"`
+	Address  string `json:"address,optional,omitempty"`
+	User     string `json:"user,optional,omitempty"`
+	Auth     string `json:"auth,optional,omitempty"`
+	Realm    string `json:"realm,optional,omitempty"`
+	KDC      string `json:"kdc,optional,omitempty"`
+	SPN      string `json:"spn,optional,omitempty"`
+	Password string `json:"password,optional,omitempty"`
+}
+
+type DatasourceAzureConfig struct {
+	// the blob service url, https://<account>.blob.core.windows.net by default, such as http://127.0.0.1:10000/devstoreaccount1 of Azurite
+	Endpoint    string `json:"endpoint,optional,omitempty"`
+	AccountName string `json:"accountName"`
+	Container   string `json:"container"`
+	// empty to read a public container anonymously
+	AccountKey string `json:"accountKey,optional"`
+}
+
+type DatasourceAzureUpdateConfig struct {
+	Endpoint    string `json:"endpoint,optional,omitempty"`
+	AccountName string `json:"accountName,optional,omitempty"`
+	Container   string `json:"container,optional,omitempty"`
+	AccountKey  string `json:"accountKey,optional,omitempty"`
+}
+
+type DatasourceGCSConfig struct {
+	// the json api url, https://storage.googleapis.com by default, such as http://localhost:4443 of fake-gcs-server
+	Endpoint string `json:"endpoint,optional,omitempty"`
+	Bucket   string `json:"bucket"`
+	// the json key of a service account, empty to read a public bucket anonymously
+	Credentials string `json:"credentials,optional"`
+}
+
+type DatasourceGCSUpdateConfig struct {
+	Endpoint    string `json:"endpoint,optional,omitempty"`
+	Bucket      string `json:"bucket,optional,omitempty"`
+	Credentials string `json:"credentials,optional,omitempty"`
+}
+
+type DatasourceSQLConfig struct {
+	// mysql, postgres or sqlite
+	Driver string `json:"driver"`
+	// such as user@tcp(host:3306)/db, postgres://user@host:5432/db or the file path of sqlite,
+	// which is in the sqlite dir of studio or in the uploaded files of the user
+	DSN      string `json:"dsn"`
+	Password string `json:"password,optional"`
+}
+
+type DatasourceSQLUpdateConfig struct {
+	Driver   string `json:"driver,optional,omitempty"`
+	DSN      string `json:"dsn,optional,omitempty"`
 	Password string `json:"password,optional,omitempty"`
 }
 
 type DatasourceAddRequest struct {
-	Type       string                `json:"type"`
-	Platform   string                `json:"platform,optional,omitempty"`
-	Name       string                `json:"name"`
-	S3Config   *DatasourceS3Config   `json:"s3Config,optional"`
-	SFTPConfig *DatasourceSFTPConfig `json:"sftpConfig,optional"`
+	Type        string                 `json:"type"`
+	Platform    string                 `json:"platform,optional,omitempty"`
+	Name        string                 `json:"name"`
+	S3Config    *DatasourceS3Config    `json:"s3Config,optional"`
+	SFTPConfig  *DatasourceSFTPConfig  `json:"sftpConfig,optional"`
+	FTPConfig   *DatasourceFTPConfig   `json:"ftpConfig,optional"`
+	HDFSConfig  *DatasourceHDFSConfig  `json:"hdfsConfig,optional"`
+	AzureConfig *DatasourceAzureConfig `json:"azureConfig,optional"`
+	GCSConfig   *DatasourceGCSConfig   `json:"gcsConfig,optional"`
+	SQLConfig   *DatasourceSQLConfig   `json:"sqlConfig,optional"`
 }
 
 type DatasourceUpdateRequest struct {
-	ID         string                      `path:"id"`
-	Platform   string                      `json:"platform,optional,omitempty"`
-	Type       string                      `json:"type"`
-	Name       string                      `json:"name"`
-	S3Config   *DatasourceS3UpdateConfig   `json:"s3Config,optional"`
-	SFTPConfig *DatasourceSFTPUpdateConfig `json:"sftpConfig,optional"`
+	ID          string                       `path:"id"`
+	Platform    string                       `json:"platform,optional,omitempty"`
+	Type        string                       `json:"type"`
+	Name        string                       `json:"name"`
+	S3Config    *DatasourceS3UpdateConfig    `json:"s3Config,optional"`
+	SFTPConfig  *DatasourceSFTPUpdateConfig  `json:"sftpConfig,optional"`
+	FTPConfig   *DatasourceFTPUpdateConfig   `json:"ftpConfig,optional"`
+	HDFSConfig  *DatasourceHDFSUpdateConfig  `json:"hdfsConfig,optional"`
+	AzureConfig *DatasourceAzureUpdateConfig `json:"azureConfig,optional"`
+	GCSConfig   *DatasourceGCSUpdateConfig   `json:"gcsConfig,optional"`
+	SQLConfig   *DatasourceSQLUpdateConfig   `json:"sqlConfig,optional"`
 }
 
 type DatasourceAddData struct {
@@ -430,13 +668,18 @@ type DatasourceBatchRemoveRequest struct {
 }
 
 type DatasourceConfig struct {
-	ID         string                `json:"id"`
-	Type       string                `json:"type"`
-	Name       string                `json:"name"`
-	Platform   string                `json:"platform"`
-	S3Config   *DatasourceS3Config   `json:"s3Config,optional"`
-	SFTPConfig *DatasourceSFTPConfig `json:"sftpConfig,optional"`
-	CreateTime int64                 `json:"createTime,optional"`
+	ID          string                 `json:"id"`
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name"`
+	Platform    string                 `json:"platform"`
+	S3Config    *DatasourceS3Config    `json:"s3Config,optional"`
+	SFTPConfig  *DatasourceSFTPConfig  `json:"sftpConfig,optional"`
+	FTPConfig   *DatasourceFTPConfig   `json:"ftpConfig,optional"`
+	HDFSConfig  *DatasourceHDFSConfig  `json:"hdfsConfig,optional"`
+	AzureConfig *DatasourceAzureConfig `json:"azureConfig,optional"`
+	GCSConfig   *DatasourceGCSConfig   `json:"gcsConfig,optional"`
+	SQLConfig   *DatasourceSQLConfig   `json:"sqlConfig,optional"`
+	CreateTime  int64                  `json:"createTime,optional"`
 }
 
 type DatasourceListContentsRequest struct {
@@ -467,6 +710,17 @@ type DatasourcePreviewFileData struct {
 	Contents []string `json:"contents"`
 }
 
+type DatasourcePreviewQueryRequest struct {
+	DatasourceID string `path:"id"`
+	Query        string `json:"query"`
+	Limit        int    `json:"limit,optional,default=10"`
+}
+
+type DatasourcePreviewQueryData struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
 type LLMRequest struct {
 	Data map[string]interface{} `json:"data"`
 }
@@ -484,11 +738,16 @@ type LLMConfigRequest struct {
 }
 
 type LLMImportRequest struct {
-	Space      string `json:"space"`
-	File       string `json:"file,optional"`
-	FilePath   string `json:"filePath,optional"`
-	Type       string `json:"type"`
-	UserPrompt string `json:"userPrompt"`
+	Space           string `json:"space"`
+	File            string `json:"file,optional"`
+	FilePath        string `json:"filePath,optional"`
+	Type            string `json:"type"`
+	UserPrompt      string `json:"userPrompt"`
+	Aliases         string `json:"aliases,optional"`
+	ResolveEntities bool   `json:"resolveEntities,optional"`
+	ChunkStrategy   string `json:"chunkStrategy,optional"`
+	ChunkSize       int    `json:"chunkSize,optional" validate:"gte=0"`
+	ChunkOverlap    int    `json:"chunkOverlap,optional" validate:"gte=0"`
 }
 
 type LLMImportJobsRequest struct {
@@ -513,3 +772,121 @@ type DeleteLLMImportRequest struct {
 type DownloadLLMImportNgqlRequest struct {
 	JobID string `json:"jobId"`
 }
+
+type LLMText2NGQLRequest struct {
+	Space      string `json:"space" validate:"required"`
+	Question   string `json:"question" validate:"required"`
+	MaxRepairs int    `json:"maxRepairs,optional" validate:"gte=0,lte=5"`
+	Execute    bool   `json:"execute,optional"`
+}
+
+type NotifyWebhookConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,optional,omitempty"`
+	// the key signing the payload, empty to keep the saved key on update
+	Secret string `json:"secret,optional,omitempty"`
+}
+
+type NotifySMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username,optional,omitempty"`
+	// empty to keep the saved password on update
+	Password string   `json:"password,optional,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+type NotifyChannelAddRequest struct {
+	Type          string               `json:"type" validate:"required"`
+	Name          string               `json:"name" validate:"required"`
+	IsDefault     bool                 `json:"isDefault,optional"`
+	WebhookConfig *NotifyWebhookConfig `json:"webhookConfig,optional"`
+	SMTPConfig    *NotifySMTPConfig    `json:"smtpConfig,optional"`
+}
+
+type NotifyChannelAddData struct {
+	ID string `json:"id"`
+}
+
+type NotifyChannelUpdateRequest struct {
+	ID            string               `path:"id"`
+	Type          string               `json:"type" validate:"required"`
+	Name          string               `json:"name" validate:"required"`
+	IsDefault     bool                 `json:"isDefault,optional"`
+	WebhookConfig *NotifyWebhookConfig `json:"webhookConfig,optional"`
+	SMTPConfig    *NotifySMTPConfig    `json:"smtpConfig,optional"`
+}
+
+type NotifyChannelRemoveRequest struct {
+	ID string `path:"id"`
+}
+
+type NotifyChannelTestRequest struct {
+	ID string `path:"id"`
+}
+
+type NotifyChannel struct {
+	ID            string               `json:"id"`
+	Type          string               `json:"type"`
+	Name          string               `json:"name"`
+	IsDefault     bool                 `json:"isDefault"`
+	WebhookConfig *NotifyWebhookConfig `json:"webhookConfig,optional"`
+	SMTPConfig    *NotifySMTPConfig    `json:"smtpConfig,optional"`
+	CreateTime    int64                `json:"createTime"`
+}
+
+type NotifyChannelListData struct {
+	List []NotifyChannel `json:"list"`
+}
+
+type NotifyDeliveryListRequest struct {
+	Page      int    `form:"page,default=1"`
+	PageSize  int    `form:"pageSize,default=20"`
+	ChannelID string `form:"channelId,optional"`
+	JobID     string `form:"jobId,optional"`
+	Status    string `form:"status,optional"`
+}
+
+type NotifyDelivery struct {
+	ID         int    `json:"id"`
+	ChannelID  string `json:"channelId"`
+	JobKind    string `json:"jobKind"`
+	JobID      string `json:"jobId"`
+	Event      string `json:"event"`
+	Status     string `json:"status"`
+	Attempts   int    `json:"attempts"`
+	LastError  string `json:"lastError"`
+	Payload    string `json:"payload"`
+	CreateTime int64  `json:"createTime"`
+	UpdateTime int64  `json:"updateTime"`
+}
+
+type NotifyDeliveryListData struct {
+	Total int64            `json:"total"`
+	List  []NotifyDelivery `json:"list"`
+}
+
+type StorageUsage struct {
+	Host        string `json:"host"`
+	Username    string `json:"username"`
+	UploadBytes int64  `json:"uploadBytes"`
+	TaskBytes   int64  `json:"taskBytes"`
+	LLMBytes    int64  `json:"llmBytes"`
+	TotalBytes  int64  `json:"totalBytes"`
+}
+
+type StorageUsageData struct {
+	Users         []StorageUsage `json:"users"`
+	TotalBytes    int64          `json:"totalBytes"`
+	UserQuota     int64          `json:"userQuota"`
+	InstanceQuota int64          `json:"instanceQuota"`
+}
+
+type StoragePurgeData struct {
+	Uploads int   `json:"uploads"`
+	Tasks   int   `json:"tasks"`
+	LLMJobs int   `json:"llmJobs"`
+	Orphans int   `json:"orphans"`
+	Bytes   int64 `json:"bytes"`
+}
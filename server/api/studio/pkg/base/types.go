@@ -16,6 +16,10 @@ type Process struct {
 	FailedReason     string  `json:"failed_reason"`
 	PromptTokens     int     `json:"prompt_tokens"`
 	CompletionTokens int     `json:"completion_tokens"`
+	// the answers failed to parse or to validate against the schema, and the blocks dropped after the repairs
+	ParseFailures      int `json:"parse_failures"`
+	ValidationFailures int `json:"validation_failures"`
+	FailedBlocks       int `json:"failed_blocks"`
 }
 
 type LLMStatus string
@@ -0,0 +1,415 @@
+package filestore
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	CompressionGzip  = "gzip"
+	CompressionBzip2 = "bzip2"
+
+	ArchiveZip = "zip"
+	ArchiveTar = "tar"
+)
+
+/*
+archiveStore reads the compressed files and the members of the archives of a store transparently.
+An archive is browsed like a directory, the path of a member is the path of the archive joined with the member name,
+such as exports/2023.tar.gz/person.csv. The gzip and the bzip2 files and the tar archives are decompressed as streams,
+a zip archive which is not a local file is downloaded into a temp file first to read its central directory.
+*/
+type archiveStore struct {
+	FileStore
+	// the dir of the temp files, the default temp dir if empty
+	tempDir string
+}
+
+// ArchiveMember is a csv file in an archive, Name is its path in the archive
+type ArchiveMember struct {
+	FileConfig
+	// Sample is the first lines of the member
+	Sample []string
+}
+
+// WithArchives wraps the store to read the compressed files and the archive members
+func WithArchives(store FileStore) FileStore {
+	return &archiveStore{FileStore: store}
+}
+
+// WithTempDir makes the store download the zip archives which are not local files into the dir
+func WithTempDir(store FileStore, dir string) FileStore {
+	if a, ok := store.(*archiveStore); ok {
+		return &archiveStore{FileStore: a.FileStore, tempDir: dir}
+	}
+	return store
+}
+
+// Compression returns the compression and the archive format of a file by its extensions
+func Compression(name string) (compression, archive string) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return CompressionGzip, ArchiveTar
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"):
+		return CompressionBzip2, ArchiveTar
+	case strings.HasSuffix(lower, ".gz"), strings.HasSuffix(lower, ".gzip"):
+		return CompressionGzip, ""
+	case strings.HasSuffix(lower, ".bz2"):
+		return CompressionBzip2, ""
+	case strings.HasSuffix(lower, ".tar"):
+		return "", ArchiveTar
+	case strings.HasSuffix(lower, ".zip"):
+		return "", ArchiveZip
+	}
+	return "", ""
+}
+
+// DecompressedName returns the name of a compressed file without the compression extension, such as a.csv of a.csv.gz
+func DecompressedName(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".gz", ".gzip", ".bz2"} {
+		if strings.HasSuffix(lower, ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// listedType returns the type of a file listed by the stores, the files of the other types are not listed
+func listedType(name string) string {
+	if _, archive := Compression(name); archive != "" {
+		return "archive"
+	}
+	if strings.HasSuffix(DecompressedName(name), ".csv") {
+		return "csv"
+	}
+	return ""
+}
+
+/*
+SplitArchivePath splits the path of an archive member into the path of the archive and the member name,
+the member name is empty for the path of an archive itself. ok is false if the path is not in an archive.
+*/
+func SplitArchivePath(p string) (archive, member string, ok bool) {
+	parts := strings.Split(strings.TrimSuffix(p, "/"), "/")
+	for i, part := range parts {
+		if _, a := Compression(part); a != "" {
+			return strings.Join(parts[:i+1], "/"), strings.Join(parts[i+1:], "/"), true
+		}
+	}
+	return "", "", false
+}
+
+// IsCompressed reports whether the file is compressed or is an archive member, which the importer can not read directly
+func IsCompressed(p string) bool {
+	if _, _, ok := SplitArchivePath(p); ok {
+		return true
+	}
+	compression, _ := Compression(p)
+	return compression != ""
+}
+
+// Decompress reads a gzip or a bzip2 stream decompressed
+func Decompress(compression string, r io.Reader) (io.Reader, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionBzip2:
+		return bzip2.NewReader(r), nil
+	}
+	return r, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r *readCloser) Close() error {
+	return r.close()
+}
+
+func (s *archiveStore) openRaw(p string) (io.ReadCloser, error) {
+	if local, ok := s.FileStore.(*LocalStore); ok {
+		return local.Open(p)
+	}
+	opener, ok := s.FileStore.(Opener)
+	if !ok {
+		return nil, fmt.Errorf("the store can not read %s as a stream", p)
+	}
+	return opener.Open(p)
+}
+
+// Open reads a file decompressed, or an archive member
+func (s *archiveStore) Open(p string) (io.ReadCloser, error) {
+	archive, member, ok := SplitArchivePath(p)
+	if ok {
+		if member == "" {
+			return nil, fmt.Errorf("%s is an archive, choose a file in it", p)
+		}
+		return s.openMember(archive, member)
+	}
+	compression, _ := Compression(p)
+	raw, err := s.openRaw(p)
+	if err != nil || compression == "" {
+		return raw, err
+	}
+	r, err := Decompress(compression, raw)
+	if err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("decompress %s error: %s", p, err)
+	}
+	return &readCloser{Reader: r, close: raw.Close}, nil
+}
+
+func (s *archiveStore) ReadFile(p string, startLine ...int) ([]string, error) {
+	if !IsCompressed(p) {
+		return s.FileStore.ReadFile(p, startLine...)
+	}
+	start, numLines := lineRange(startLine...)
+	r, err := s.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return scanLines(r, start, numLines)
+}
+
+// ListFiles lists the members of an archive like the files of a directory
+func (s *archiveStore) ListFiles(dir string) ([]FileConfig, error) {
+	archive, member, ok := SplitArchivePath(dir)
+	if !ok {
+		return s.FileStore.ListFiles(dir)
+	}
+	members, err := s.archiveMembers(archive, 0)
+	if err != nil {
+		return nil, err
+	}
+	prefix := ""
+	if member != "" {
+		prefix = member + "/"
+	}
+	var files []FileConfig
+	dirs := make(map[string]bool)
+	for _, m := range members {
+		if !strings.HasPrefix(m.Name, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(m.Name, prefix)
+		if sub, _, nested := strings.Cut(name, "/"); nested {
+			if !dirs[sub] {
+				dirs[sub] = true
+				files = append(files, FileConfig{Name: sub, Type: "directory"})
+			}
+			continue
+		}
+		files = append(files, FileConfig{Name: name, Size: m.Size, Type: m.Type})
+	}
+	return files, nil
+}
+
+// ArchiveMembers lists the csv files in an archive of the store with their first sampleLines lines in a single pass
+func ArchiveMembers(store FileStore, archive string, sampleLines int) ([]ArchiveMember, error) {
+	s, ok := store.(*archiveStore)
+	if !ok {
+		s = &archiveStore{FileStore: store}
+	}
+	return s.archiveMembers(archive, sampleLines)
+}
+
+func memberName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func (s *archiveStore) archiveMembers(archive string, sampleLines int) ([]ArchiveMember, error) {
+	var members []ArchiveMember
+	add := func(name string, size int64, open func() (io.ReadCloser, error)) error {
+		name = memberName(name)
+		if !strings.HasSuffix(name, ".csv") {
+			return nil
+		}
+		m := ArchiveMember{FileConfig: FileConfig{Name: name, Size: size, Type: "csv"}}
+		if sampleLines > 0 && size > 0 {
+			r, err := open()
+			if err != nil {
+				return err
+			}
+			m.Sample, err = scanLines(r, 0, sampleLines)
+			r.Close()
+			if err != nil {
+				return fmt.Errorf("read %s in the archive %s error: %s", name, archive, err)
+			}
+		}
+		members = append(members, m)
+		return nil
+	}
+	compression, format := Compression(archive)
+	switch format {
+	case ArchiveZip:
+		zr, err := s.openZip(archive)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			if err := add(f.Name, int64(f.UncompressedSize64), f.Open); err != nil {
+				return nil, err
+			}
+		}
+	case ArchiveTar:
+		raw, err := s.openRaw(archive)
+		if err != nil {
+			return nil, err
+		}
+		defer raw.Close()
+		r, err := Decompress(compression, raw)
+		if err != nil {
+			return nil, fmt.Errorf("decompress %s error: %s", archive, err)
+		}
+		tr := tar.NewReader(r)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("read the archive %s error: %s", archive, err)
+			}
+			if h.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := add(h.Name, h.Size, func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s is not an archive", archive)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+	return members, nil
+}
+
+// openMember streams a member of an archive
+func (s *archiveStore) openMember(archive, member string) (io.ReadCloser, error) {
+	compression, format := Compression(archive)
+	if format == ArchiveZip {
+		zr, err := s.openZip(archive)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if memberName(f.Name) != member || f.FileInfo().IsDir() {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				zr.Close()
+				return nil, err
+			}
+			return &readCloser{Reader: r, close: func() error {
+				r.Close()
+				return zr.Close()
+			}}, nil
+		}
+		zr.Close()
+		return nil, fmt.Errorf("%s is not found in the archive %s", member, archive)
+	}
+
+	raw, err := s.openRaw(archive)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Decompress(compression, raw)
+	if err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("decompress %s error: %s", archive, err)
+	}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err != nil {
+			raw.Close()
+			if err == io.EOF {
+				return nil, fmt.Errorf("%s is not found in the archive %s", member, archive)
+			}
+			return nil, fmt.Errorf("read the archive %s error: %s", archive, err)
+		}
+		if h.Typeflag == tar.TypeReg && memberName(h.Name) == member {
+			return &readCloser{Reader: tr, close: raw.Close}, nil
+		}
+	}
+}
+
+type zipArchive struct {
+	*zip.Reader
+	file    *os.File
+	tempDir string
+}
+
+func (z *zipArchive) Close() error {
+	var err error
+	if z.file != nil {
+		err = z.file.Close()
+	}
+	if z.tempDir != "" {
+		os.RemoveAll(z.tempDir)
+	}
+	return err
+}
+
+// openZip opens a local zip archive in place, or downloads a remote one into a temp file
+func (s *archiveStore) openZip(archive string) (*zipArchive, error) {
+	z := &zipArchive{}
+	if local, ok := s.FileStore.(*LocalStore); ok {
+		full, err := local.resolve(archive)
+		if err != nil {
+			return nil, err
+		}
+		if z.file, err = os.Open(full); err != nil {
+			return nil, err
+		}
+	} else {
+		raw, err := s.openRaw(archive)
+		if err != nil {
+			return nil, err
+		}
+		defer raw.Close()
+		if s.tempDir != "" {
+			if err := os.MkdirAll(s.tempDir, 0o755); err != nil {
+				return nil, err
+			}
+		}
+		if z.tempDir, err = os.MkdirTemp(s.tempDir, "studio-zip-"); err != nil {
+			return nil, err
+		}
+		if z.file, err = os.Create(filepath.Join(z.tempDir, "archive.zip")); err == nil {
+			_, err = io.Copy(z.file, raw)
+		}
+		if err != nil {
+			z.Close()
+			return nil, fmt.Errorf("download the archive %s error: %s", archive, err)
+		}
+	}
+	info, err := z.file.Stat()
+	if err == nil {
+		z.Reader, err = zip.NewReader(z.file, info.Size())
+	}
+	if err != nil {
+		z.Close()
+		return nil, fmt.Errorf("read the archive %s error: %s", archive, err)
+	}
+	return z, nil
+}
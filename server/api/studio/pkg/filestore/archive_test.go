@@ -0,0 +1,194 @@
+package filestore
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// memStore is a remote store of the files in memory, which is read as streams only
+type memStore struct {
+	files map[string][]byte
+}
+
+func (s *memStore) ReadFile(path string, startLine ...int) ([]string, error) {
+	start, numLines := lineRange(startLine...)
+	return scanLines(bytes.NewReader(s.files[path]), start, numLines)
+}
+
+func (s *memStore) ListFiles(dir string) ([]FileConfig, error) {
+	return nil, nil
+}
+
+func (s *memStore) Open(path string) (io.ReadCloser, error) {
+	content, ok := s.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *memStore) Close() error {
+	return nil
+}
+
+func gzipBytes(t *testing.T, content []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func testArchives(t *testing.T, members map[string]string) (zipData, tgzData []byte) {
+	var zipBuf, tarBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	tw := tar.NewWriter(&tarBuf)
+	for _, name := range []string{"person.csv", "readme.md", "dir/follow.csv"} {
+		content := members[name]
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+		tw.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		io.WriteString(tw, content)
+	}
+	zw.Close()
+	tw.Close()
+	return zipBuf.Bytes(), gzipBytes(t, tarBuf.Bytes())
+}
+
+func TestArchiveStore(t *testing.T) {
+	members := map[string]string{
+		"person.csv":     "id,name\n1,Tom\n2,Jerry\n",
+		"readme.md":      "# readme\n",
+		"dir/follow.csv": "1,2\n",
+	}
+	zipData, tgzData := testArchives(t, members)
+	files := map[string][]byte{
+		"a.csv.gz":       gzipBytes(t, []byte("line 1\nline 2\nline 3\n")),
+		"export.zip":     zipData,
+		"export.tar.gz":  tgzData,
+		"plain.csv":      []byte("plain\n"),
+		"broken.csv.gz":  []byte("not gzip"),
+		"dir/nested.tgz": tgzData,
+	}
+	root := t.TempDir()
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0o755)
+		if err := os.WriteFile(filepath.Join(root, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for storeName, store := range map[string]FileStore{
+		"local":  WithArchives(NewLocalStore(root)),
+		"remote": WithArchives(&memStore{files: files}),
+	} {
+		lines, err := store.ReadFile("a.csv.gz", 1, 1)
+		if err != nil || !reflect.DeepEqual(lines, []string{"line 2"}) {
+			t.Errorf("%s: unexpected lines of the gzip file: %v %v", storeName, lines, err)
+		}
+		if _, err := store.ReadFile("broken.csv.gz"); err == nil {
+			t.Errorf("%s: expect an error for the broken gzip file", storeName)
+		}
+		lines, err = store.ReadFile("plain.csv")
+		if err != nil || !reflect.DeepEqual(lines, []string{"plain"}) {
+			t.Errorf("%s: unexpected lines of the plain file: %v %v", storeName, lines, err)
+		}
+
+		for _, archive := range []string{"export.zip", "export.tar.gz", "dir/nested.tgz"} {
+			lines, err := store.ReadFile(archive+"/person.csv", 1)
+			if err != nil || !reflect.DeepEqual(lines, []string{"1,Tom", "2,Jerry"}) {
+				t.Errorf("%s: unexpected lines of the member of %s: %v %v", storeName, archive, lines, err)
+			}
+			lines, err = store.ReadFile(archive + "/dir/follow.csv")
+			if err != nil || !reflect.DeepEqual(lines, []string{"1,2"}) {
+				t.Errorf("%s: unexpected lines of the nested member of %s: %v %v", storeName, archive, lines, err)
+			}
+			if _, err := store.ReadFile(archive + "/missing.csv"); err == nil || !strings.Contains(err.Error(), "not found") {
+				t.Errorf("%s: expect the member not found in %s, got %v", storeName, archive, err)
+			}
+
+			list, err := store.ListFiles(archive + "/")
+			expected := []FileConfig{{Name: "dir", Type: "directory"}, {Name: "person.csv", Size: 22, Type: "csv"}}
+			if err != nil || !reflect.DeepEqual(list, expected) {
+				t.Errorf("%s: unexpected files of %s: %v %v", storeName, archive, list, err)
+			}
+			list, err = store.ListFiles(archive + "/dir")
+			if err != nil || !reflect.DeepEqual(list, []FileConfig{{Name: "follow.csv", Size: 4, Type: "csv"}}) {
+				t.Errorf("%s: unexpected files of %s/dir: %v %v", storeName, archive, list, err)
+			}
+
+			samples, err := ArchiveMembers(store, archive, 2)
+			if err != nil || len(samples) != 2 || !reflect.DeepEqual(samples[1].Sample, []string{"id,name", "1,Tom"}) {
+				t.Errorf("%s: unexpected samples of %s: %v %v", storeName, archive, samples, err)
+			}
+		}
+	}
+
+	list, err := WithArchives(NewLocalStore(root)).ListFiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]string)
+	for _, f := range list {
+		types[f.Name] = f.Type
+	}
+	expected := map[string]string{"a.csv.gz": "csv", "broken.csv.gz": "csv", "dir": "directory", "export.tar.gz": "archive", "export.zip": "archive", "plain.csv": "csv"}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("unexpected files: got %v, want %v", types, expected)
+	}
+}
+
+func TestArchiveStore_TempDir(t *testing.T) {
+	zipData, _ := testArchives(t, map[string]string{"person.csv": "id,name\n1,Tom\n"})
+	tempDir := filepath.Join(t.TempDir(), "staging")
+	store := WithTempDir(WithArchives(&memStore{files: map[string][]byte{"export.zip": zipData}}), tempDir)
+
+	r, err := store.(Opener).Open("export.zip/person.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 1 {
+		t.Errorf("expect the zip archive downloaded into the temp dir, got %v", entries)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil || string(content) != "id,name\n1,Tom\n" {
+		t.Errorf("unexpected content of the member: %q %v", content, err)
+	}
+	r.Close()
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("expect the temp files removed, got %v", entries)
+	}
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	for p, expected := range map[string][3]string{
+		"data/a.csv":             {"", "", "false"},
+		"data/a.csv.gz":          {"", "", "false"},
+		"data/export.zip":        {"data/export.zip", "", "true"},
+		"data/export.tar.gz/":    {"data/export.tar.gz", "", "true"},
+		"export.tgz/dir/a.csv":   {"export.tgz", "dir/a.csv", "true"},
+		"export.tar.bz2/b.csv":   {"export.tar.bz2", "b.csv", "true"},
+		"dir.d/export.TAR/c.csv": {"dir.d/export.TAR", "c.csv", "true"},
+	} {
+		archive, member, ok := SplitArchivePath(p)
+		if got := [3]string{archive, member, map[bool]string{true: "true", false: "false"}[ok]}; got != expected {
+			t.Errorf("unexpected split of %s: got %v, want %v", p, got, expected)
+		}
+	}
+	if name := DecompressedName("a.CSV.GZ"); name != "a.CSV" {
+		t.Errorf("unexpected decompressed name: %s", name)
+	}
+}
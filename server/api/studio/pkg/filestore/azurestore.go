@@ -0,0 +1,237 @@
+package filestore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const azureAPIVersion = "2020-04-08"
+
+/*
+AzureStore reads the blobs of an Azure Blob Storage container through the REST API, signed by the shared key of the account.
+The endpoint is https://<account>.blob.core.windows.net by default,
+the path style endpoint of the emulator works too, such as http://127.0.0.1:10000/devstoreaccount1 of Azurite.
+The container is read anonymously without an account key.
+*/
+type AzureStore struct {
+	Endpoint    string
+	AccountName string
+	Container   string
+	key         []byte
+	client      *http.Client
+}
+
+func NewAzureStore(endpoint, accountName, accountKey, container string) (*AzureStore, error) {
+	if accountName == "" || container == "" {
+		return nil, fmt.Errorf("the account name and the container are required")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", accountName)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid azure blob endpoint: %s", endpoint)
+	}
+	key, err := base64.StdEncoding.DecodeString(accountKey)
+	if err != nil {
+		return nil, fmt.Errorf("the account key is not base64 encoded")
+	}
+	s := &AzureStore{
+		Endpoint:    strings.TrimSuffix(endpoint, "/"),
+		AccountName: accountName,
+		Container:   container,
+		key:         key,
+		client:      newHTTPClient(),
+	}
+	// check the credential and the container
+	params := url.Values{}
+	params.Set("restype", "container")
+	resp, err := s.do(http.MethodGet, "", params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the container: %s", err)
+	}
+	resp.Body.Close()
+	return s, nil
+}
+
+func (s *AzureStore) url(blob string, params url.Values) string {
+	path := "/" + s.Container
+	if blob != "" {
+		path += "/" + strings.TrimPrefix(blob, "/")
+	}
+	u := s.Endpoint + (&url.URL{Path: path}).EscapedPath()
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	return u
+}
+
+func (s *AzureStore) do(method, blob string, params url.Values, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.url(blob, params), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	if len(s.key) > 0 {
+		req.Header.Set("Authorization", "SharedKey "+s.AccountName+":"+s.sign(req))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		defer resp.Body.Close()
+		var azureErr struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if xml.Unmarshal(body, &azureErr) == nil && azureErr.Code != "" {
+			return nil, fmt.Errorf("%s: %s", azureErr.Code, strings.SplitN(azureErr.Message, "\n", 2)[0])
+		}
+		if code := resp.Header.Get("x-ms-error-code"); code != "" {
+			return nil, fmt.Errorf("azure blob responds %s: %s", resp.Status, code)
+		}
+		return nil, fmt.Errorf("azure blob responds %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// sign computes the shared key signature of a request, which has no body
+func (s *AzureStore) sign(req *http.Request) string {
+	var msHeaders []string
+	for k := range req.Header {
+		if k := strings.ToLower(k); strings.HasPrefix(k, "x-ms-") {
+			msHeaders = append(msHeaders, k)
+		}
+	}
+	sort.Strings(msHeaders)
+	var b strings.Builder
+	// VERB, Content-Encoding, Content-Language, Content-Length, Content-MD5, Content-Type, Date,
+	// If-Modified-Since, If-Match, If-None-Match, If-Unmodified-Since and Range
+	b.WriteString(req.Method + "\n\n\n\n\n\n\n\n\n\n\n" + req.Header.Get("Range") + "\n")
+	for _, k := range msHeaders {
+		b.WriteString(k + ":" + strings.TrimSpace(req.Header.Get(k)) + "\n")
+	}
+	b.WriteString("/" + s.AccountName + req.URL.EscapedPath())
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		b.WriteString("\n" + strings.ToLower(k) + ":" + strings.Join(values, ","))
+	}
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(b.String()))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Open reads a whole blob as a stream
+func (s *AzureStore) Open(path string) (io.ReadCloser, error) {
+	return s.OpenRange(path, 0, -1)
+}
+
+// OpenRange reads length bytes from the offset of a blob, a negative length reads to the end
+func (s *AzureStore) OpenRange(path string, offset, length int64) (io.ReadCloser, error) {
+	header := http.Header{}
+	if length >= 0 {
+		header.Set("x-ms-range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		header.Set("x-ms-range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := s.do(http.MethodGet, path, nil, header)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *AzureStore) ReadFile(path string, startLine ...int) ([]string, error) {
+	return readLines(s, path, startLine...)
+}
+
+func (s *AzureStore) ListFiles(dir string) ([]FileConfig, error) {
+	prefix := strings.TrimPrefix(dir, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	var files []FileConfig
+	marker := ""
+	for {
+		params := url.Values{}
+		params.Set("restype", "container")
+		params.Set("comp", "list")
+		params.Set("delimiter", "/")
+		if prefix != "" {
+			params.Set("prefix", prefix)
+		}
+		if marker != "" {
+			params.Set("marker", marker)
+		}
+		resp, err := s.do(http.MethodGet, "", params, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Blobs struct {
+				Blob []struct {
+					Name       string `xml:"Name"`
+					Properties struct {
+						ContentLength int64 `xml:"Content-Length"`
+					} `xml:"Properties"`
+				} `xml:"Blob"`
+				BlobPrefix []struct {
+					Name string `xml:"Name"`
+				} `xml:"BlobPrefix"`
+			} `xml:"Blobs"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range result.Blobs.BlobPrefix {
+			files = append(files, FileConfig{
+				Name: strings.TrimSuffix(strings.TrimPrefix(p.Name, prefix), "/"),
+				Type: "directory",
+			})
+		}
+		for _, blob := range result.Blobs.Blob {
+			name := strings.TrimPrefix(blob.Name, prefix)
+			if typ := listedType(name); name != "" && typ != "" {
+				files = append(files, FileConfig{
+					Name: name,
+					Size: blob.Properties.ContentLength,
+					Type: typ,
+				})
+			}
+		}
+		if result.NextMarker == "" {
+			break
+		}
+		marker = result.NextMarker
+	}
+	return files, nil
+}
+
+func (s *AzureStore) Close() error {
+	return nil
+}
@@ -0,0 +1,210 @@
+package filestore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the well-known account of Azurite
+const (
+	azuriteAccount = "devstoreaccount1"
+	azuriteKey     = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+)
+
+// serveAzurite emulates the path style blob endpoint of Azurite, it checks the shared key of the GET requests
+func serveAzurite(t *testing.T, container string, blobs map[string]string) *httptest.Server {
+	key, _ := base64.StdEncoding.DecodeString(azuriteKey)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		resource := "/" + azuriteAccount + r.URL.EscapedPath()
+		for _, k := range []string{"comp", "delimiter", "marker", "prefix", "restype"} {
+			if v := query.Get(k); v != "" {
+				resource += "\n" + k + ":" + v
+			}
+		}
+		msHeaders := "x-ms-date:" + r.Header.Get("x-ms-date") + "\n"
+		if rng := r.Header.Get("x-ms-range"); rng != "" {
+			msHeaders += "x-ms-range:" + rng + "\n"
+		}
+		msHeaders += "x-ms-version:" + r.Header.Get("x-ms-version") + "\n"
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte("GET\n\n\n\n\n\n\n\n\n\n\n\n" + msHeaders + resource))
+		if r.Header.Get("Authorization") != "SharedKey "+azuriteAccount+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>AuthorizationFailure</Code><Message>Server failed to authenticate the request.</Message></Error>`)
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/"+azuriteAccount+"/")
+		name, blob, _ := strings.Cut(path, "/")
+		if name != container {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>ContainerNotFound</Code><Message>The specified container does not exist.</Message></Error>`)
+			return
+		}
+		switch {
+		case blob == "" && query.Get("comp") == "list":
+			prefix := query.Get("prefix")
+			// one blob or prefix a page to check the marker
+			var entries []string
+			dirs := make(map[string]bool)
+			names := make([]string, 0, len(blobs))
+			for name := range blobs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				content := blobs[name]
+				if !strings.HasPrefix(name, prefix) {
+					continue
+				}
+				if dir, _, ok := strings.Cut(strings.TrimPrefix(name, prefix), "/"); ok {
+					if !dirs[dir] {
+						dirs[dir] = true
+						entries = append(entries, fmt.Sprintf("<BlobPrefix><Name>%s%s/</Name></BlobPrefix>", prefix, dir))
+					}
+					continue
+				}
+				entries = append(entries, fmt.Sprintf("<Blob><Name>%s</Name><Properties><Content-Length>%d</Content-Length></Properties></Blob>", name, len(content)))
+			}
+			page, _ := strconv.Atoi(query.Get("marker"))
+			next := ""
+			if page+1 < len(entries) {
+				next = strconv.Itoa(page + 1)
+			}
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>%s</Blobs><NextMarker>%s</NextMarker></EnumerationResults>`, entries[page], next)
+		case blob == "":
+			w.WriteHeader(http.StatusOK)
+		default:
+			content, ok := blobs[blob]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>BlobNotFound</Code><Message>The specified blob does not exist.</Message></Error>`)
+				return
+			}
+			if rng := r.Header.Get("x-ms-range"); rng != "" {
+				var start, end int
+				fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+				if end >= len(content) {
+					end = len(content) - 1
+				}
+				w.WriteHeader(http.StatusPartialContent)
+				io.WriteString(w, content[start:end+1])
+				return
+			}
+			io.WriteString(w, content)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAzureStore(t *testing.T) {
+	srv := serveAzurite(t, "data", map[string]string{
+		"a.csv":         "line 1\nline 2\nline 3\n",
+		"b.txt":         "text",
+		"dir/c.csv":     "c",
+		"dir/sub/d.csv": "d",
+	})
+	endpoint := srv.URL + "/" + azuriteAccount
+	if _, err := NewAzureStore(endpoint, azuriteAccount, base64.StdEncoding.EncodeToString([]byte("wrong")), "data"); err == nil || !strings.Contains(err.Error(), "AuthorizationFailure") {
+		t.Errorf("expect the authorization failure, got %v", err)
+	}
+	if _, err := NewAzureStore(endpoint, azuriteAccount, azuriteKey, "missing"); err == nil || !strings.Contains(err.Error(), "ContainerNotFound") {
+		t.Errorf("expect the container not found, got %v", err)
+	}
+	s, err := NewAzureStore(endpoint, azuriteAccount, azuriteKey, "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := s.ReadFile("a.csv", 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLines := []string{"line 2", "line 3"}
+	if !reflect.DeepEqual(lines, expectedLines) {
+		t.Errorf("unexpected lines read from blob: got %v, want %v", lines, expectedLines)
+	}
+
+	r, err := s.OpenRange("a.csv", 7, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, _ := io.ReadAll(r)
+	r.Close()
+	if string(buf) != "line 2" {
+		t.Errorf("unexpected range read: %q", buf)
+	}
+
+	if _, err := s.ReadFile("missing.csv", 0, 1); err == nil || !strings.Contains(err.Error(), "BlobNotFound") {
+		t.Errorf("expect the blob not found, got %v", err)
+	}
+
+	for dir, expected := range map[string]map[string]string{
+		"":    {"a.csv": "csv", "dir": "directory"},
+		"dir": {"c.csv": "csv", "sub": "directory"},
+	} {
+		files, err := s.ListFiles(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		types := make(map[string]string)
+		for _, f := range files {
+			types[f.Name] = f.Type
+		}
+		if !reflect.DeepEqual(types, expected) {
+			t.Errorf("unexpected files of %q: got %v, want %v", dir, types, expected)
+		}
+	}
+}
+
+func TestAzureStoreSlowBody(t *testing.T) {
+	defer func(timeout time.Duration) { httpTimeout = timeout }(httpTimeout)
+	httpTimeout = 100 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path.Base(r.URL.Path) {
+		case "a.csv":
+			writeSlowly(w, "line 1\n", "line 2\n", "line 3\n")
+		case "hang.csv":
+			time.Sleep(3 * httpTimeout)
+		}
+	}))
+	defer srv.Close()
+	// read anonymously
+	s, err := NewAzureStore(srv.URL+"/"+azuriteAccount, azuriteAccount, "", "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the body is streamed past the timeout
+	r, err := s.Open("a.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "line 1\nline 2\nline 3\n" {
+		t.Errorf("unexpected content: %q", content)
+	}
+
+	// the response headers time out
+	if _, err := s.Open("hang.csv"); err == nil {
+		t.Error("expect an error of the response headers timeout")
+	}
+}
@@ -1,10 +1,24 @@
 package filestore
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
 )
 
+// the bytes requested from a remote store to read some lines of a file, such as a preview
+const linesRange = 16 << 20
+
+// the time to connect an http store and to wait for the headers of its responses
+var httpTimeout = 60 * time.Second
+
 type (
 	FileStore interface {
 		ReadFile(path string, startLine ...int) ([]string, error)
@@ -12,6 +26,11 @@ type (
 		Close() error
 	}
 
+	// Opener is a FileStore reading a whole file as a stream
+	Opener interface {
+		Open(path string) (io.ReadCloser, error)
+	}
+
 	FileConfig struct {
 		Type string
 		Name string
@@ -19,10 +38,47 @@ type (
 	}
 
 	SftpConfig struct {
-		Host     string
-		Port     int
-		Username string
-		Password string
+		Host       string
+		Port       int
+		Username   string
+		Password   string
+		HostKey    string
+		KnownHosts string
+	}
+
+	FtpConfig struct {
+		Host               string
+		Port               int
+		Username           string
+		Password           string
+		TLS                string
+		InsecureSkipVerify bool
+	}
+
+	HdfsConfig struct {
+		Endpoint string
+		Address  string
+		User     string
+		Auth     string
+		Realm    string
+		KDC      string
+		SPN      string
+	}
+
+	AzureConfig struct {
+		Endpoint    string
+		AccountName string
+		Container   string
+	}
+
+	GcsConfig struct {
+		Endpoint string
+		Bucket   string
+	}
+
+	SqlConfig struct {
+		Driver string
+		DSN    string
 	}
 
 	S3Config struct {
@@ -34,7 +90,16 @@ type (
 	}
 )
 
+// NewFileStore creates the store of a datasource, the stores of the files read the compressed files and the archives
 func NewFileStore(typ, config, secret, platform string) (FileStore, error) {
+	store, err := newStore(typ, config, secret, platform)
+	if err != nil || typ == "sql" {
+		return store, err
+	}
+	return WithArchives(store), nil
+}
+
+func newStore(typ, config, secret, platform string) (FileStore, error) {
 	switch typ {
 	case "s3":
 		var c S3Config
@@ -47,8 +112,169 @@ func NewFileStore(typ, config, secret, platform string) (FileStore, error) {
 		if err := json.Unmarshal([]byte(config), &c); err != nil {
 			return nil, errors.New("parse the s3 config error")
 		}
-		return NewSftpStore(c.Host, c.Port, c.Username, secret)
+		sftpSecret := ParseSftpSecret(secret)
+		return NewSftpStoreWithOptions(&SftpOptions{
+			Host:       c.Host,
+			Port:       c.Port,
+			Username:   c.Username,
+			Password:   sftpSecret.Password,
+			PrivateKey: sftpSecret.PrivateKey,
+			Passphrase: sftpSecret.Passphrase,
+			HostKey:    c.HostKey,
+			KnownHosts: c.KnownHosts,
+		})
+	case "ftp":
+		var c FtpConfig
+		if err := json.Unmarshal([]byte(config), &c); err != nil {
+			return nil, errors.New("parse the ftp config error")
+		}
+		return NewFtpStore(c.Host, c.Port, c.Username, secret, c.TLS, c.InsecureSkipVerify)
+	case "hdfs":
+		var c HdfsConfig
+		if err := json.Unmarshal([]byte(config), &c); err != nil {
+			return nil, errors.New("parse the hdfs config error")
+		}
+		return NewHdfsStore(c.Endpoint, c.User, secret, c.Auth, c.Realm, c.KDC, c.SPN)
+	case "azure":
+		var c AzureConfig
+		if err := json.Unmarshal([]byte(config), &c); err != nil {
+			return nil, errors.New("parse the azure config error")
+		}
+		return NewAzureStore(c.Endpoint, c.AccountName, secret, c.Container)
+	case "gcs":
+		var c GcsConfig
+		if err := json.Unmarshal([]byte(config), &c); err != nil {
+			return nil, errors.New("parse the gcs config error")
+		}
+		return NewGcsStore(c.Endpoint, c.Bucket, secret)
+	case "sql":
+		var c SqlConfig
+		if err := json.Unmarshal([]byte(config), &c); err != nil {
+			return nil, errors.New("parse the sql config error")
+		}
+		return NewSqlStore(c.Driver, c.DSN, secret)
 	}
 
 	return nil, errors.New("don't support this store type")
 }
+
+/*
+newHTTPClient returns the client of the http stores, it has no overall timeout,
+so that a large file is streamed as long as the import takes, only the connection and the response headers time out.
+*/
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   httpTimeout,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   httpTimeout,
+			ResponseHeaderTimeout: httpTimeout,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
+
+// lineRange parses the start line and the number of lines passed to ReadFile, a negative number reads to the end
+func lineRange(startLine ...int) (start, numLines int) {
+	switch len(startLine) {
+	case 0:
+		return 0, -1
+	case 1:
+		return startLine[0], -1
+	default:
+		return startLine[0], startLine[1]
+	}
+}
+
+// rangeOpener is a remote store reading a range of a file
+type rangeOpener interface {
+	Opener
+	OpenRange(path string, offset, length int64) (io.ReadCloser, error)
+}
+
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+/*
+readLines reads the lines of a remote file from its first linesRange bytes,
+the file is read as a stream when the lines go beyond the range.
+One more line is scanned in the range, so the last line returned is never cut by the range.
+*/
+func readLines(s rangeOpener, path string, startLine ...int) ([]string, error) {
+	start, numLines := lineRange(startLine...)
+	if numLines >= 0 {
+		r, err := s.OpenRange(path, 0, linesRange)
+		if err != nil {
+			return nil, err
+		}
+		counter := &countingReader{Reader: r}
+		lines, err := scanLines(counter, start, numLines+1)
+		r.Close()
+		if err == nil && len(lines) > numLines {
+			return lines[:numLines], nil
+		}
+		// the whole file is in the range
+		if counter.n < linesRange {
+			return lines, err
+		}
+	}
+	r, err := s.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return scanLines(r, start, numLines)
+}
+
+func scanLines(r io.Reader, start, numLines int) ([]string, error) {
+	fileScanner := bufio.NewScanner(r)
+
+	var lines []string
+	for i := 0; i < start; i++ {
+		if !fileScanner.Scan() {
+			return nil, errors.New("start line is beyond end of file")
+		}
+	}
+
+	for i := 0; numLines < 0 || i < numLines; i++ {
+		if !fileScanner.Scan() {
+			break
+		}
+		lines = append(lines, fileScanner.Text())
+	}
+
+	if err := fileScanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// Stat finds a file in the listing of its dir
+func Stat(store FileStore, p string) (*FileConfig, error) {
+	dir, name := path.Split(p)
+	if dir != "/" {
+		dir = strings.TrimSuffix(dir, "/")
+	}
+	files, err := store.ListFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		if files[i].Name == name && files[i].Type != "directory" {
+			return &files[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s does not exist", p)
+}
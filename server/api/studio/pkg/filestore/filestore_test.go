@@ -2,9 +2,22 @@ package filestore
 
 import (
 	"encoding/json"
+	"net/http"
 	"testing"
+	"time"
 )
 
+// writeSlowly streams the parts of a body with pauses longer than the http timeout
+func writeSlowly(w http.ResponseWriter, parts ...string) {
+	for i, part := range parts {
+		if i > 0 {
+			time.Sleep(2 * httpTimeout)
+		}
+		w.Write([]byte(part))
+		w.(http.Flusher).Flush()
+	}
+}
+
 func TestS3Store(t *testing.T) {
 	s3Config := map[string]string{
 		"endpoint":     "s3.us-east-1.amazonaws.com",
@@ -0,0 +1,120 @@
+package filestore
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+)
+
+const (
+	FtpTLSExplicit = "explicit"
+	FtpTLSImplicit = "implicit"
+)
+
+/*
+FtpStore reads the files of a ftp server, the data connections are always passive.
+TLS is either explicit (AUTH TLS on the plain port) or implicit (usually port 990).
+*/
+type FtpStore struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	TLS      string
+	FtpConn  *ftp.ServerConn
+}
+
+func NewFtpStore(host string, port int, username, password, tlsMode string, insecureSkipVerify bool) (*FtpStore, error) {
+	options := []ftp.DialOption{ftp.DialWithTimeout(10 * time.Second)}
+	tlsConfig := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+	switch tlsMode {
+	case "":
+	case FtpTLSExplicit:
+		options = append(options, ftp.DialWithExplicitTLS(tlsConfig))
+	case FtpTLSImplicit:
+		options = append(options, ftp.DialWithTLS(tlsConfig))
+	default:
+		return nil, fmt.Errorf("unknown ftp tls mode: %s", tlsMode)
+	}
+
+	conn, err := ftp.Dial(fmt.Sprintf("%s:%d", host, port), options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial FTP server: %s", err)
+	}
+	if username == "" {
+		username = "anonymous"
+	}
+	if err := conn.Login(username, password); err != nil {
+		conn.Quit()
+		return nil, fmt.Errorf("failed to login FTP server: %s", err)
+	}
+
+	return &FtpStore{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		TLS:      tlsMode,
+		FtpConn:  conn,
+	}, nil
+}
+
+// Open retrieves a file, the reader must be closed before the next command
+func (s *FtpStore) Open(path string) (io.ReadCloser, error) {
+	return s.FtpConn.Retr(path)
+}
+
+func (s *FtpStore) ReadFile(path string, startLine ...int) ([]string, error) {
+	start, numLines := lineRange(startLine...)
+	r, err := s.FtpConn.Retr(path)
+	if err != nil {
+		return nil, err
+	}
+	// the server aborts the transfer when the file is not read to the end, the error is expected
+	defer r.Close()
+	return scanLines(r, start, numLines)
+}
+
+func (s *FtpStore) ListFiles(dir string) ([]FileConfig, error) {
+	var files []FileConfig
+	var err error
+	if dir == "" {
+		dir, err = s.FtpConn.CurrentDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := s.FtpConn.List(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name
+		var fileType string
+		if entry.Type == ftp.EntryTypeFolder && !strings.HasPrefix(name, ".") {
+			fileType = "directory"
+		} else if entry.Type == ftp.EntryTypeFile {
+			fileType = listedType(name)
+		}
+		if fileType != "" {
+			files = append(files, FileConfig{
+				Name: name,
+				Size: int64(entry.Size),
+				Type: fileType,
+			})
+		}
+	}
+	return files, nil
+}
+
+func (s *FtpStore) Close() error {
+	return s.FtpConn.Quit()
+}
@@ -0,0 +1,145 @@
+package filestore
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// serveFtp answers the commands of one ftp client with the files in memory, the data connections are passive
+func serveFtp(t *testing.T, files map[string]string) (string, int) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go handleFtpConn(c, files)
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func handleFtpConn(c net.Conn, files map[string]string) {
+	defer c.Close()
+	conn := textproto.NewConn(c)
+	var dataLn net.Listener
+	transfer := func(content string) {
+		if dataLn == nil {
+			conn.PrintfLine("425 no data connection")
+			return
+		}
+		conn.PrintfLine("150 opening data connection")
+		if d, err := dataLn.Accept(); err == nil {
+			d.Write([]byte(content))
+			d.Close()
+		}
+		dataLn.Close()
+		dataLn = nil
+		conn.PrintfLine("226 transfer complete")
+	}
+	conn.PrintfLine("220 ready")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch cmd {
+		case "USER":
+			conn.PrintfLine("331 password required")
+		case "PASS":
+			conn.PrintfLine("230 logged in")
+		case "TYPE":
+			conn.PrintfLine("200 ok")
+		case "PWD":
+			conn.PrintfLine(`257 "/" is the current directory`)
+		case "EPSV":
+			dataLn, _ = net.Listen("tcp", "127.0.0.1:0")
+			conn.PrintfLine("229 Entering Extended Passive Mode (|||%d|)", dataLn.Addr().(*net.TCPAddr).Port)
+		case "RETR":
+			content, ok := files[arg]
+			if !ok {
+				conn.PrintfLine("550 file not found")
+				continue
+			}
+			transfer(content)
+		case "LIST":
+			var list strings.Builder
+			list.WriteString("drwxr-xr-x 1 ftp ftp 0 Jan 01 00:00 data\r\n")
+			list.WriteString("drwxr-xr-x 1 ftp ftp 0 Jan 01 00:00 .hidden\r\n")
+			for name, content := range files {
+				list.WriteString(fmt.Sprintf("-rw-r--r-- 1 ftp ftp %d Jan 01 00:00 %s\r\n", len(content), strings.TrimPrefix(name, "/")))
+			}
+			transfer(list.String())
+		case "QUIT":
+			conn.PrintfLine("221 bye")
+			return
+		default:
+			conn.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestFtpStore(t *testing.T) {
+	host, port := serveFtp(t, map[string]string{
+		"/a.csv":   "line 1\nline 2\nline 3\n",
+		"/b.txt":   "text",
+		"/big.csv": strings.Repeat("row\n", 100000),
+	})
+	s, err := NewFtpStore(host, port, "user", "password", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	lines, err := s.ReadFile("/a.csv", 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLines := []string{"line 2", "line 3"}
+	if !reflect.DeepEqual(lines, expectedLines) {
+		t.Errorf("unexpected lines read from file: got %v, want %v", lines, expectedLines)
+	}
+
+	// a partial read keeps the connection usable
+	lines, err = s.ReadFile("/big.csv", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, []string{"row", "row"}) {
+		t.Errorf("unexpected lines read from file: got %v", lines)
+	}
+
+	if _, err := s.ReadFile("/missing.csv", 0, 1); err == nil {
+		t.Error("expect an error for the missing file")
+	}
+
+	files, err := s.ListFiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]string)
+	for _, f := range files {
+		types[f.Name] = f.Type
+	}
+	expectedTypes := map[string]string{"data": "directory", "a.csv": "csv", "big.csv": "csv"}
+	if !reflect.DeepEqual(types, expectedTypes) {
+		t.Errorf("unexpected files: got %v, want %v", types, expectedTypes)
+	}
+}
+
+func TestFtpStore_UnknownTLS(t *testing.T) {
+	if _, err := NewFtpStore("127.0.0.1", 21, "", "", "ssl", false); err == nil {
+		t.Error("expect an error for the unknown tls mode")
+	}
+}
@@ -0,0 +1,257 @@
+package filestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	gcsDefaultEndpoint = "https://storage.googleapis.com"
+	gcsReadOnlyScope   = "https://www.googleapis.com/auth/devstorage.read_only"
+)
+
+type gcsCredentials struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+/*
+GcsStore reads the objects of a Google Cloud Storage bucket through the JSON API.
+The credentials are the json key of a service account, which is exchanged for an access token of the read only scope,
+the bucket is read anonymously without the credentials, such as a public bucket or the fake-gcs-server emulator.
+*/
+type GcsStore struct {
+	Endpoint string
+	Bucket   string
+	creds    *gcsCredentials
+	client   *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func NewGcsStore(endpoint, bucket, credentials string) (*GcsStore, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("the bucket is required")
+	}
+	if endpoint == "" {
+		endpoint = gcsDefaultEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid gcs endpoint: %s", endpoint)
+	}
+	s := &GcsStore{
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		Bucket:   bucket,
+		client:   newHTTPClient(),
+	}
+	if credentials != "" {
+		s.creds = &gcsCredentials{}
+		if err := json.Unmarshal([]byte(credentials), s.creds); err != nil {
+			return nil, fmt.Errorf("the credentials is not a service account json key")
+		}
+		if s.creds.ClientEmail == "" || s.creds.PrivateKey == "" {
+			return nil, fmt.Errorf("the client_email and private_key of the credentials are required")
+		}
+		if s.creds.TokenURI == "" {
+			s.creds.TokenURI = "https://oauth2.googleapis.com/token"
+		}
+	}
+	// check the credentials and the bucket
+	resp, err := s.get("/storage/v1/b/"+url.PathEscape(bucket), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the bucket: %s", err)
+	}
+	resp.Body.Close()
+	return s, nil
+}
+
+// accessToken returns the cached access token, or signs a jwt of the service account to exchange a new one
+func (s *GcsStore) accessToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.expiry) {
+		return s.token, nil
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(s.creds.PrivateKey))
+	if err != nil {
+		return "", fmt.Errorf("invalid private key: %s", err)
+	}
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.creds.ClientEmail,
+		"scope": gcsReadOnlyScope,
+		"aud":   s.creds.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(key)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.PostForm(s.creds.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to get the access token: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", fmt.Errorf("failed to get the access token: %s %s", token.Error, token.ErrorDescription)
+	}
+	s.token = token.AccessToken
+	// refresh the token a minute before it expires
+	s.expiry = now.Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
+
+func (s *GcsStore) get(path string, params url.Values, header http.Header) (*http.Response, error) {
+	u := s.Endpoint + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if s.creds != nil {
+		token, err := s.accessToken()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		defer resp.Body.Close()
+		var gcsErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(body, &gcsErr) == nil && gcsErr.Error.Message != "" {
+			return nil, fmt.Errorf("gcs responds %s: %s", resp.Status, gcsErr.Error.Message)
+		}
+		return nil, fmt.Errorf("gcs responds %s", resp.Status)
+	}
+	return resp, nil
+}
+
+func (s *GcsStore) objectPath(object string) string {
+	return "/storage/v1/b/" + url.PathEscape(s.Bucket) + "/o/" + url.PathEscape(strings.TrimPrefix(object, "/"))
+}
+
+// Open reads a whole object as a stream
+func (s *GcsStore) Open(path string) (io.ReadCloser, error) {
+	return s.OpenRange(path, 0, -1)
+}
+
+// OpenRange reads length bytes from the offset of an object, a negative length reads to the end
+func (s *GcsStore) OpenRange(path string, offset, length int64) (io.ReadCloser, error) {
+	header := http.Header{}
+	if length >= 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := s.get(s.objectPath(path), url.Values{"alt": {"media"}}, header)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *GcsStore) ReadFile(path string, startLine ...int) ([]string, error) {
+	return readLines(s, path, startLine...)
+}
+
+func (s *GcsStore) ListFiles(dir string) ([]FileConfig, error) {
+	prefix := strings.TrimPrefix(dir, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	var files []FileConfig
+	pageToken := ""
+	for {
+		params := url.Values{}
+		params.Set("delimiter", "/")
+		if prefix != "" {
+			params.Set("prefix", prefix)
+		}
+		if pageToken != "" {
+			params.Set("pageToken", pageToken)
+		}
+		resp, err := s.get("/storage/v1/b/"+url.PathEscape(s.Bucket)+"/o", params, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Items []struct {
+				Name string `json:"name"`
+				// the size is an uint64 formatted as a string
+				Size string `json:"size"`
+			} `json:"items"`
+			Prefixes      []string `json:"prefixes"`
+			NextPageToken string   `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range result.Prefixes {
+			files = append(files, FileConfig{
+				Name: strings.TrimSuffix(strings.TrimPrefix(p, prefix), "/"),
+				Type: "directory",
+			})
+		}
+		for _, item := range result.Items {
+			name := strings.TrimPrefix(item.Name, prefix)
+			if typ := listedType(name); name != "" && typ != "" {
+				size, _ := strconv.ParseInt(item.Size, 10, 64)
+				files = append(files, FileConfig{
+					Name: name,
+					Size: size,
+					Type: typ,
+				})
+			}
+		}
+		if result.NextPageToken == "" {
+			break
+		}
+		pageToken = result.NextPageToken
+	}
+	return files, nil
+}
+
+func (s *GcsStore) Close() error {
+	return nil
+}
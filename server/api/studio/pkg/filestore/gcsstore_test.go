@@ -0,0 +1,257 @@
+package filestore
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+/*
+serveFakeGcs emulates the json api of fake-gcs-server with a token endpoint,
+the requests are authorized by the token exchanged for a jwt signed by the key when the key is not nil.
+*/
+func serveFakeGcs(t *testing.T, key *rsa.PrivateKey, bucket string, objects map[string]string) *httptest.Server {
+	const token = "test-access-token"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assertion := r.PostFormValue("assertion")
+		_, err := jwt.Parse(assertion, func(*jwt.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		})
+		if err != nil || r.PostFormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "Invalid JWT Signature."})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"access_token": token, "expires_in": 3600})
+	})
+	mux.HandleFunc("/storage/v1/b/", func(w http.ResponseWriter, r *http.Request) {
+		if key != nil && r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/storage/v1/b/"), "/")
+		if b, _ := url.PathUnescape(path[0]); b != bucket {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 404, "message": "Not Found"}})
+			return
+		}
+		switch {
+		case len(path) == 1:
+			json.NewEncoder(w).Encode(map[string]string{"name": bucket})
+		case len(path) == 2:
+			prefix := r.URL.Query().Get("prefix")
+			type item struct {
+				Name string `json:"name"`
+				Size string `json:"size"`
+			}
+			// one object or prefix a page to check the page token
+			var pages []map[string]any
+			dirs := make(map[string]bool)
+			names := make([]string, 0, len(objects))
+			for name := range objects {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				content := objects[name]
+				if !strings.HasPrefix(name, prefix) {
+					continue
+				}
+				if dir, _, ok := strings.Cut(strings.TrimPrefix(name, prefix), "/"); ok {
+					if !dirs[dir] {
+						dirs[dir] = true
+						pages = append(pages, map[string]any{"prefixes": []string{prefix + dir + "/"}})
+					}
+					continue
+				}
+				pages = append(pages, map[string]any{"items": []item{{Name: name, Size: strconv.Itoa(len(content))}}})
+			}
+			page, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+			if page+1 < len(pages) {
+				pages[page]["nextPageToken"] = strconv.Itoa(page + 1)
+			}
+			json.NewEncoder(w).Encode(pages[page])
+		default:
+			name, _ := url.PathUnescape(path[2])
+			content, ok := objects[name]
+			if !ok || r.URL.Query().Get("alt") != "media" {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 404, "message": "No such object: " + bucket + "/" + name}})
+				return
+			}
+			if rng := r.Header.Get("Range"); rng != "" {
+				var start, end int
+				fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+				if end >= len(content) {
+					end = len(content) - 1
+				}
+				w.WriteHeader(http.StatusPartialContent)
+				io.WriteString(w, content[start:end+1])
+				return
+			}
+			io.WriteString(w, content)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func gcsTestCredentials(t *testing.T, key *rsa.PrivateKey, tokenURI string) string {
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	creds, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "studio@test.iam.gserviceaccount.com",
+		"private_key":  string(keyPem),
+		"token_uri":    tokenURI,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(creds)
+}
+
+func TestGcsStore(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := map[string]string{
+		"a.csv":         "line 1\nline 2\nline 3\n",
+		"b.txt":         "text",
+		"dir/c.csv":     "c",
+		"dir/sub/d.csv": "d",
+	}
+	srv := serveFakeGcs(t, key, "data", objects)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGcsStore(srv.URL, "data", gcsTestCredentials(t, otherKey, srv.URL+"/token")); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("expect the invalid grant, got %v", err)
+	}
+	if _, err := NewGcsStore(srv.URL, "data", ""); err == nil {
+		t.Error("expect an error for the anonymous access")
+	}
+	creds := gcsTestCredentials(t, key, srv.URL+"/token")
+	if _, err := NewGcsStore(srv.URL, "missing", creds); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expect the bucket not found, got %v", err)
+	}
+	s, err := NewGcsStore(srv.URL, "data", creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := s.ReadFile("a.csv", 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLines := []string{"line 2", "line 3"}
+	if !reflect.DeepEqual(lines, expectedLines) {
+		t.Errorf("unexpected lines read from object: got %v, want %v", lines, expectedLines)
+	}
+
+	r, err := s.OpenRange("a.csv", 7, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, _ := io.ReadAll(r)
+	r.Close()
+	if string(buf) != "line 2" {
+		t.Errorf("unexpected range read: %q", buf)
+	}
+
+	lines, err = s.ReadFile("dir/c.csv")
+	if err != nil || !reflect.DeepEqual(lines, []string{"c"}) {
+		t.Errorf("unexpected lines read from the nested object: %v %v", lines, err)
+	}
+
+	if _, err := s.ReadFile("missing.csv", 0, 1); err == nil || !strings.Contains(err.Error(), "No such object") {
+		t.Errorf("expect the object not found, got %v", err)
+	}
+
+	for dir, expected := range map[string]map[string]string{
+		"":    {"a.csv": "csv", "dir": "directory"},
+		"dir": {"c.csv": "csv", "sub": "directory"},
+	} {
+		files, err := s.ListFiles(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		types := make(map[string]string)
+		for _, f := range files {
+			types[f.Name] = f.Type
+		}
+		if !reflect.DeepEqual(types, expected) {
+			t.Errorf("unexpected files of %q: got %v, want %v", dir, types, expected)
+		}
+	}
+
+	// the emulator without the credentials
+	anonymous := serveFakeGcs(t, nil, "data", objects)
+	s, err = NewGcsStore(anonymous.URL, "data", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines, err := s.ReadFile("a.csv", 0, 1); err != nil || !reflect.DeepEqual(lines, []string{"line 1"}) {
+		t.Errorf("unexpected lines read anonymously: %v %v", lines, err)
+	}
+}
+
+func TestGcsStoreSlowBody(t *testing.T) {
+	defer func(timeout time.Duration) { httpTimeout = timeout }(httpTimeout)
+	httpTimeout = 100 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path.Base(r.URL.Path) {
+		case "a.csv":
+			writeSlowly(w, "line 1\n", "line 2\n", "line 3\n")
+		case "hang.csv":
+			time.Sleep(3 * httpTimeout)
+		default:
+			io.WriteString(w, `{"name":"data"}`)
+		}
+	}))
+	defer srv.Close()
+	// read anonymously
+	s, err := NewGcsStore(srv.URL, "data", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the body is streamed past the timeout
+	r, err := s.Open("a.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "line 1\nline 2\nline 3\n" {
+		t.Errorf("unexpected content: %q", content)
+	}
+
+	// the response headers time out
+	if _, err := s.Open("hang.csv"); err == nil {
+		t.Error("expect an error of the response headers timeout")
+	}
+}
@@ -0,0 +1,208 @@
+package filestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	krb5client "github.com/jcmturner/gokrb5/v8/client"
+	krb5config "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+)
+
+const (
+	HdfsAuthSimple   = "simple"
+	HdfsAuthKerberos = "kerberos"
+)
+
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+/*
+HdfsStore reads the files of HDFS through the WebHDFS REST API of the namenode, such as http://namenode:9870.
+The simple auth passes the user as `user.name`, the kerberos auth logs in the KDC with the password of the user
+and negotiates with SPNEGO.
+*/
+type HdfsStore struct {
+	Endpoint string
+	User     string
+	Auth     string
+	client   httpDoer
+}
+
+func NewHdfsStore(endpoint, user, password, auth, realm, kdc, spn string) (*HdfsStore, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhdfs endpoint: %s", endpoint)
+	}
+	s := &HdfsStore{
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		User:     user,
+		Auth:     auth,
+	}
+	httpClient := newHTTPClient()
+	switch auth {
+	case "", HdfsAuthSimple:
+		s.Auth = HdfsAuthSimple
+		s.client = httpClient
+	case HdfsAuthKerberos:
+		if user == "" || realm == "" || kdc == "" {
+			return nil, errors.New("the user, realm and kdc are required by kerberos")
+		}
+		conf, err := krb5config.NewFromString(fmt.Sprintf("[libdefaults]\n default_realm = %s\n dns_lookup_kdc = false\n dns_lookup_realm = false\n[realms]\n %s = {\n  kdc = %s\n }\n", realm, realm, kdc))
+		if err != nil {
+			return nil, fmt.Errorf("invalid kerberos config: %s", err)
+		}
+		cl := krb5client.NewWithPassword(user, realm, password, conf, krb5client.DisablePAFXFAST(true))
+		if err := cl.Login(); err != nil {
+			return nil, fmt.Errorf("failed to login kerberos: %s", err)
+		}
+		if spn == "" {
+			spn = "HTTP/" + u.Hostname()
+		}
+		s.client = spnego.NewClient(cl, httpClient, spn)
+	default:
+		return nil, fmt.Errorf("unknown hdfs auth: %s", auth)
+	}
+	// check the endpoint and the user
+	if _, err := s.homeDir(); err != nil {
+		return nil, fmt.Errorf("failed to connect webhdfs: %s", err)
+	}
+	return s, nil
+}
+
+func (s *HdfsStore) url(path, op string, params url.Values) string {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("op", op)
+	if s.Auth == HdfsAuthSimple && s.User != "" {
+		params.Set("user.name", s.User)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return s.Endpoint + "/webhdfs/v1" + (&url.URL{Path: path}).EscapedPath() + "?" + params.Encode()
+}
+
+func (s *HdfsStore) get(path, op string, params url.Values) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url(path, op, params), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var remote struct {
+			RemoteException struct {
+				Exception string `json:"exception"`
+				Message   string `json:"message"`
+			} `json:"RemoteException"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(body, &remote) == nil && remote.RemoteException.Message != "" {
+			return nil, fmt.Errorf("%s: %s", remote.RemoteException.Exception, remote.RemoteException.Message)
+		}
+		return nil, fmt.Errorf("webhdfs responds %s", resp.Status)
+	}
+	return resp, nil
+}
+
+func (s *HdfsStore) getJSON(path, op string, v any) error {
+	resp, err := s.get(path, op, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (s *HdfsStore) homeDir() (string, error) {
+	var home struct {
+		Path string `json:"Path"`
+	}
+	if err := s.getJSON("/", "GETHOMEDIRECTORY", &home); err != nil {
+		return "", err
+	}
+	return home.Path, nil
+}
+
+// Open reads a whole file as a stream
+func (s *HdfsStore) Open(path string) (io.ReadCloser, error) {
+	resp, err := s.get(path, "OPEN", nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// OpenRange reads length bytes from the offset of a file, a negative length reads to the end
+func (s *HdfsStore) OpenRange(path string, offset, length int64) (io.ReadCloser, error) {
+	params := url.Values{}
+	params.Set("offset", strconv.FormatInt(offset, 10))
+	if length >= 0 {
+		params.Set("length", strconv.FormatInt(length, 10))
+	}
+	resp, err := s.get(path, "OPEN", params)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *HdfsStore) ReadFile(path string, startLine ...int) ([]string, error) {
+	return readLines(s, path, startLine...)
+}
+
+func (s *HdfsStore) ListFiles(dir string) ([]FileConfig, error) {
+	var err error
+	if dir == "" {
+		dir, err = s.homeDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var list struct {
+		FileStatuses struct {
+			FileStatus []struct {
+				PathSuffix string `json:"pathSuffix"`
+				Type       string `json:"type"`
+				Length     int64  `json:"length"`
+			} `json:"FileStatus"`
+		} `json:"FileStatuses"`
+	}
+	if err := s.getJSON(dir, "LISTSTATUS", &list); err != nil {
+		return nil, err
+	}
+	var files []FileConfig
+	for _, status := range list.FileStatuses.FileStatus {
+		name := status.PathSuffix
+		var fileType string
+		if status.Type == "DIRECTORY" && !strings.HasPrefix(name, ".") {
+			fileType = "directory"
+		} else if status.Type == "FILE" {
+			fileType = listedType(name)
+		}
+		if fileType != "" {
+			files = append(files, FileConfig{
+				Name: name,
+				Size: status.Length,
+				Type: fileType,
+			})
+		}
+	}
+	return files, nil
+}
+
+func (s *HdfsStore) Close() error {
+	return nil
+}
@@ -0,0 +1,188 @@
+package filestore
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveWebHdfs emulates a namenode redirecting the reads to a datanode
+func serveWebHdfs(t *testing.T, files map[string]string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/datanode", func(w http.ResponseWriter, r *http.Request) {
+		content := files[r.URL.Query().Get("path")]
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		content = content[offset:]
+		if length := r.URL.Query().Get("length"); length != "" {
+			if l, _ := strconv.Atoi(length); l < len(content) {
+				content = content[:l]
+			}
+		}
+		w.Write([]byte(content))
+	})
+	mux.HandleFunc("/webhdfs/v1/", func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/webhdfs/v1")
+		query := r.URL.Query()
+		if query.Get("user.name") != "hadoop" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch query.Get("op") {
+		case "GETHOMEDIRECTORY":
+			json.NewEncoder(w).Encode(map[string]string{"Path": "/user/hadoop"})
+		case "LISTSTATUS":
+			type status struct {
+				PathSuffix string `json:"pathSuffix"`
+				Type       string `json:"type"`
+				Length     int    `json:"length"`
+			}
+			list := []status{{PathSuffix: "data", Type: "DIRECTORY"}, {PathSuffix: ".Trash", Type: "DIRECTORY"}}
+			for name, content := range files {
+				if strings.HasPrefix(name, path+"/") {
+					list = append(list, status{PathSuffix: strings.TrimPrefix(name, path+"/"), Type: "FILE", Length: len(content)})
+				}
+			}
+			resp := map[string]any{"FileStatuses": map[string]any{"FileStatus": list}}
+			json.NewEncoder(w).Encode(resp)
+		case "OPEN":
+			if _, ok := files[path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]any{"RemoteException": map[string]string{
+					"exception": "FileNotFoundException",
+					"message":   "File " + path + " not found.",
+				}})
+				return
+			}
+			query.Set("path", path)
+			http.Redirect(w, r, "/datanode?"+query.Encode(), http.StatusTemporaryRedirect)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHdfsStore(t *testing.T) {
+	srv := serveWebHdfs(t, map[string]string{
+		"/user/hadoop/a.csv": "line 1\nline 2\nline 3\n",
+		"/user/hadoop/b.txt": "text",
+	})
+	if _, err := NewHdfsStore(srv.URL, "nobody", "", HdfsAuthSimple, "", "", ""); err == nil {
+		t.Error("expect an error for the unauthorized user")
+	}
+	s, err := NewHdfsStore(srv.URL, "hadoop", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := s.ReadFile("/user/hadoop/a.csv", 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLines := []string{"line 2", "line 3"}
+	if !reflect.DeepEqual(lines, expectedLines) {
+		t.Errorf("unexpected lines read from file: got %v, want %v", lines, expectedLines)
+	}
+
+	// the lines beyond the range of a preview are read as a stream
+	var big strings.Builder
+	for i := 0; big.Len() <= linesRange; i++ {
+		big.WriteString(strings.Repeat("x", 100) + "," + strconv.Itoa(i) + "\n")
+	}
+	big.WriteString("last 1\nlast 2\n")
+	numLines := strings.Count(big.String(), "\n")
+	srv = serveWebHdfs(t, map[string]string{"/user/hadoop/big.csv": big.String()})
+	big.Reset()
+	s, err = NewHdfsStore(srv.URL, "hadoop", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err = s.ReadFile("/user/hadoop/big.csv", numLines-2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, []string{"last 1", "last 2"}) {
+		t.Errorf("unexpected lines beyond the range: %v", lines)
+	}
+	srv = serveWebHdfs(t, map[string]string{"/user/hadoop/a.csv": "line 1\nline 2\nline 3\n"})
+	if s, err = NewHdfsStore(srv.URL, "hadoop", "", "", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := s.OpenRange("/user/hadoop/a.csv", 7, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	n, _ := r.Read(buf)
+	r.Close()
+	if string(buf[:n]) != "line 2" {
+		t.Errorf("unexpected range read: %q", buf[:n])
+	}
+
+	if _, err := s.ReadFile("/user/hadoop/missing.csv", 0, 1); err == nil || !strings.Contains(err.Error(), "FileNotFoundException") {
+		t.Errorf("expect the remote exception, got %v", err)
+	}
+
+	files, err := s.ListFiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]string)
+	for _, f := range files {
+		types[f.Name] = f.Type
+	}
+	expectedTypes := map[string]string{"data": "directory", "a.csv": "csv"}
+	if !reflect.DeepEqual(types, expectedTypes) {
+		t.Errorf("unexpected files: got %v, want %v", types, expectedTypes)
+	}
+}
+
+func TestHdfsStoreSlowBody(t *testing.T) {
+	defer func(timeout time.Duration) { httpTimeout = timeout }(httpTimeout)
+	httpTimeout = 100 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("op") {
+		case "GETHOMEDIRECTORY":
+			json.NewEncoder(w).Encode(map[string]string{"Path": "/user/hadoop"})
+		case "OPEN":
+			if r.URL.Path == "/webhdfs/v1/user/hadoop/hang.csv" {
+				time.Sleep(3 * httpTimeout)
+				return
+			}
+			writeSlowly(w, "line 1\n", "line 2\n", "line 3\n")
+		}
+	}))
+	defer srv.Close()
+	s, err := NewHdfsStore(srv.URL, "hadoop", "", "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the body is streamed past the timeout
+	r, err := s.Open("/user/hadoop/a.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "line 1\nline 2\nline 3\n" {
+		t.Errorf("unexpected content: %q", content)
+	}
+
+	// the response headers time out
+	if _, err := s.Open("/user/hadoop/hang.csv"); err == nil {
+		t.Error("expect an error of the response headers timeout")
+	}
+}
@@ -0,0 +1,122 @@
+package filestore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore reads the files under a root dir, such as the upload dir
+type LocalStore struct {
+	Root string
+}
+
+func NewLocalStore(root string) *LocalStore {
+	return &LocalStore{Root: root}
+}
+
+func (s *LocalStore) resolve(path string) (string, error) {
+	root := filepath.Clean(s.Root)
+	full := filepath.Join(root, path)
+	if full != root && !strings.HasPrefix(full, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is out of the root dir", path)
+	}
+	return full, nil
+}
+
+func (s *LocalStore) ReadFile(path string, startLine ...int) ([]string, error) {
+	var numLines int
+	var start int
+	if len(startLine) == 0 {
+		start = 0
+		numLines = -1
+	} else if len(startLine) == 1 {
+		start = startLine[0]
+		numLines = -1
+	} else {
+		start = startLine[0]
+		numLines = startLine[1]
+	}
+
+	full, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fileScanner := bufio.NewScanner(f)
+
+	var lines []string
+	for i := 0; i < start; i++ {
+		if !fileScanner.Scan() {
+			return nil, errors.New("start line is beyond end of file")
+		}
+	}
+
+	for i := 0; numLines < 0 || i < numLines; i++ {
+		if !fileScanner.Scan() {
+			break
+		}
+		lines = append(lines, fileScanner.Text())
+	}
+
+	if err := fileScanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+func (s *LocalStore) ListFiles(dir string) ([]FileConfig, error) {
+	full, err := s.resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(full)
+	if err != nil {
+		return nil, err
+	}
+	var files []FileConfig
+	for _, entry := range entries {
+		name := entry.Name()
+		var fileType string
+		if entry.IsDir() && !strings.HasPrefix(name, ".") {
+			fileType = "directory"
+		} else if !entry.IsDir() {
+			fileType = listedType(name)
+		}
+		if fileType == "" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, FileConfig{
+			Name: name,
+			Size: info.Size(),
+			Type: fileType,
+		})
+	}
+	return files, nil
+}
+
+func (s *LocalStore) Open(path string) (io.ReadCloser, error) {
+	full, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(full)
+}
+
+func (s *LocalStore) Close() error {
+	return nil
+}
@@ -0,0 +1,45 @@
+package filestore
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLocalStore_ReadFile(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.csv"), []byte("line 1\nline 2\nline 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewLocalStore(root)
+
+	lines, err := s.ReadFile("a.csv", 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLines := []string{"line 2", "line 3"}
+	if !reflect.DeepEqual(lines, expectedLines) {
+		t.Errorf("unexpected lines read from file: got %v, want %v", lines, expectedLines)
+	}
+
+	if _, err := s.ReadFile("../a.csv", 0, 1); err == nil {
+		t.Error("expect an error for the path out of the root dir")
+	}
+
+	files, err := s.ListFiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "a.csv" || files[0].Type != "csv" {
+		t.Errorf("unexpected files: %v", files)
+	}
+
+	f, err := Stat(s, "a.csv")
+	if err != nil || f.Size != 21 {
+		t.Errorf("unexpected stat of the file: %v %v", f, err)
+	}
+	if _, err := Stat(s, "b.csv"); err == nil {
+		t.Error("expect an error for the file not found")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -105,6 +106,17 @@ func (s *S3Store) ReadFile(s3path string, startLine ...int) ([]string, error) {
 	return lines, nil
 }
 
+func (s *S3Store) Open(s3path string) (io.ReadCloser, error) {
+	resp, err := s.S3Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s3path),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
 func (s *S3Store) ListFiles(s3path string) ([]FileConfig, error) {
 	resp, err := s.S3Client.ListObjectsV2(&s3.ListObjectsV2Input{
 		Bucket:    aws.String(s.Bucket),
@@ -127,8 +139,8 @@ func (s *S3Store) ListFiles(s3path string) ([]FileConfig, error) {
 		key := *obj.Key
 		if key[len(*obj.Key)-1:] == "/" {
 			objType = "directory"
-		} else if strings.HasSuffix(key, ".csv") {
-			objType = "csv"
+		} else {
+			objType = listedType(key)
 		}
 		name := strings.TrimPrefix(key, s3path)
 		if objType != "" && name != "" {
@@ -2,32 +2,123 @@ package filestore
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 type SftpStore struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// the SHA256 fingerprint of the host key of the server
+	HostKey    string
+	SftpClient *sftp.Client
+	sshClient  *ssh.Client
+}
+
+/*
+SftpOptions are the credentials of a sftp server and how its host key is verified.
+The host key is checked against the known hosts, or else the pinned fingerprint,
+it is trusted on the first use without both, and the caller records the HostKey of the store to pin it.
+*/
+type SftpOptions struct {
 	Host       string
 	Port       int
 	Username   string
 	Password   string
-	SftpClient *sftp.Client
+	PrivateKey string
+	Passphrase string
+	// the fingerprint of the host key, such as SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s
+	HostKey string
+	// the lines of a known_hosts file
+	KnownHosts string
+}
+
+// SftpSecret is the secret of a sftp datasource, it is the password alone without a private key
+type SftpSecret struct {
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"privateKey,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+func (s *SftpSecret) String() string {
+	if s.PrivateKey == "" {
+		return s.Password
+	}
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// ParseSftpSecret parses the secret of a sftp datasource, the secret saved before the private key is supported is a password
+func ParseSftpSecret(secret string) *SftpSecret {
+	var s SftpSecret
+	d := json.NewDecoder(strings.NewReader(secret))
+	d.DisallowUnknownFields()
+	if strings.HasPrefix(secret, "{") && d.Decode(&s) == nil && s.PrivateKey != "" {
+		return &s
+	}
+	return &SftpSecret{Password: secret}
 }
 
 func NewSftpStore(host string, port int, username string, password string) (*SftpStore, error) {
+	return NewSftpStoreWithOptions(&SftpOptions{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+	})
+}
+
+func NewSftpStoreWithOptions(opts *SftpOptions) (*SftpStore, error) {
+	var auth []ssh.AuthMethod
+	if opts.PrivateKey != "" {
+		var signer ssh.Signer
+		var err error
+		if opts.Passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(opts.PrivateKey), []byte(opts.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(opts.PrivateKey))
+		}
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, errors.New("the private key is protected by a passphrase")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %s", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if opts.Password != "" || len(auth) == 0 {
+		auth = append(auth, ssh.Password(opts.Password))
+	}
+
+	addr := fmt.Sprintf("%s:%d", opts.Host, opts.Port)
+	var hostKey string
+	verify, err := sftpHostKeyCallback(opts)
+	if err != nil {
+		return nil, err
+	}
 	config := &ssh.ClientConfig{
-		User: username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(password),
+		User: opts.Username,
+		Auth: auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = ssh.FingerprintSHA256(key)
+			return verify(hostname, remote, key)
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout: 30 * time.Second,
 	}
 
-	addr := fmt.Sprintf("%s:%d", host, port)
 	conn, err := ssh.Dial("tcp", addr, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial SSH server: %s", err)
@@ -35,18 +126,80 @@ func NewSftpStore(host string, port int, username string, password string) (*Sft
 
 	client, err := sftp.NewClient(conn)
 	if err != nil {
+		conn.Close()
 		return nil, fmt.Errorf("failed to create SFTP client: %s", err)
 	}
 
 	return &SftpStore{
-		Host:       host,
-		Port:       port,
-		Username:   username,
-		Password:   password,
+		Host:       opts.Host,
+		Port:       opts.Port,
+		Username:   opts.Username,
+		Password:   opts.Password,
+		HostKey:    hostKey,
 		SftpClient: client,
+		sshClient:  conn,
 	}, nil
 }
 
+func sftpHostKeyCallback(opts *SftpOptions) (ssh.HostKeyCallback, error) {
+	switch {
+	case opts.KnownHosts != "":
+		// knownhosts only reads files, it keeps the parsed hosts after the file is removed
+		f, err := os.CreateTemp("", "known_hosts")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(opts.KnownHosts)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		callback, err := knownhosts.New(f.Name())
+		if err != nil {
+			return nil, fmt.Errorf("invalid known hosts: %s", err)
+		}
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			err := callback(hostname, remote, key)
+			var keyErr *knownhosts.KeyError
+			if errors.As(err, &keyErr) {
+				if len(keyErr.Want) == 0 {
+					return fmt.Errorf("the host key %s of %s is not in the known hosts", ssh.FingerprintSHA256(key), hostname)
+				}
+				return fmt.Errorf("the host key %s of %s does not match the known hosts", ssh.FingerprintSHA256(key), hostname)
+			}
+			return err
+		}, nil
+	case opts.HostKey != "":
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if fingerprint := ssh.FingerprintSHA256(key); fingerprint != opts.HostKey {
+				return fmt.Errorf("the host key %s of %s does not match the pinned %s", fingerprint, hostname, opts.HostKey)
+			}
+			return nil
+		}, nil
+	}
+	// trust on first use, the fingerprint is recorded by the caller
+	return func(string, net.Addr, ssh.PublicKey) error {
+		return nil
+	}, nil
+}
+
+// SftpHostKey returns the fingerprint of the host key of a sftp store, it is empty for the other stores
+func SftpHostKey(store FileStore) string {
+	if a, ok := store.(*archiveStore); ok {
+		store = a.FileStore
+	}
+	if s, ok := store.(*SftpStore); ok {
+		return s.HostKey
+	}
+	return ""
+}
+
+// Open reads a whole file as a stream
+func (s *SftpStore) Open(path string) (io.ReadCloser, error) {
+	return s.SftpClient.Open(path)
+}
+
 func (s *SftpStore) ReadFile(path string, startLine ...int) ([]string, error) {
 	var numLines int
 	var start int
@@ -110,8 +263,8 @@ func (s *SftpStore) ListFiles(dir string) ([]FileConfig, error) {
 		var fileType string
 		if isDir && !strings.HasPrefix(name, ".") {
 			fileType = "directory"
-		} else if strings.HasSuffix(name, ".csv") {
-			fileType = "csv"
+		} else if !isDir {
+			fileType = listedType(name)
 		}
 		if fileType != "" {
 			files = append(files, FileConfig{
@@ -126,5 +279,9 @@ func (s *SftpStore) ListFiles(dir string) ([]FileConfig, error) {
 }
 
 func (s *SftpStore) Close() error {
-	return s.SftpClient.Close()
+	err := s.SftpClient.Close()
+	if s.sshClient != nil {
+		s.sshClient.Close()
+	}
+	return err
 }
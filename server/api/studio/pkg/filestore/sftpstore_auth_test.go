@@ -0,0 +1,156 @@
+package filestore
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// serveSftp runs a read only sftp server accepting the public key of the authorized signer only
+func serveSftp(t *testing.T, authorized ssh.PublicKey) (ssh.PublicKey, int) {
+	_, hostPriv, _ := ed25519.GenerateKey(rand.Reader)
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, fmt.Errorf("password rejected")
+		},
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("public key rejected")
+		},
+	}
+	config.AddHostKey(hostSigner)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					channel, requests, err := newChannel.Accept()
+					if err != nil {
+						continue
+					}
+					go func() {
+						for req := range requests {
+							req.Reply(req.Type == "subsystem" && string(req.Payload[4:]) == "sftp", nil)
+							if req.Type == "subsystem" {
+								server, err := sftp.NewServer(channel, sftp.ReadOnly())
+								if err == nil {
+									server.Serve()
+								}
+								channel.Close()
+							}
+						}
+					}()
+				}
+			}()
+		}
+	}()
+	return hostSigner.PublicKey(), l.Addr().(*net.TCPAddr).Port
+}
+
+func TestSftpStore_Auth(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.csv"), []byte("line 1\nline 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := string(pem.EncodeToMemory(block))
+	clientPub, _ := ssh.NewPublicKey(pub)
+	hostKey, port := serveSftp(t, clientPub)
+	fingerprint := ssh.FingerprintSHA256(hostKey)
+
+	opts := &SftpOptions{Host: "127.0.0.1", Port: port, Username: "nebula", PrivateKey: privateKey}
+	if _, err := NewSftpStoreWithOptions(opts); err == nil || !strings.Contains(err.Error(), "passphrase") {
+		t.Errorf("expect the passphrase error, got %v", err)
+	}
+
+	// trust on first use
+	opts.Passphrase = "secret"
+	s, err := NewSftpStoreWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.HostKey != fingerprint {
+		t.Errorf("unexpected host key: got %s, want %s", s.HostKey, fingerprint)
+	}
+	lines, err := s.ReadFile(filepath.Join(dir, "a.csv"), 1)
+	if err != nil || !reflect.DeepEqual(lines, []string{"line 2"}) {
+		t.Errorf("unexpected lines: %v %v", lines, err)
+	}
+	s.Close()
+
+	// the pinned fingerprint
+	opts.HostKey = fingerprint
+	s, err = NewSftpStoreWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	opts.HostKey = "SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s"
+	if _, err := NewSftpStoreWithOptions(opts); err == nil || !strings.Contains(err.Error(), "does not match the pinned") {
+		t.Errorf("expect the host key mismatch, got %v", err)
+	}
+
+	// the known hosts take precedence over the pinned fingerprint
+	opts.KnownHosts = fmt.Sprintf("[127.0.0.1]:%d %s", port, ssh.MarshalAuthorizedKey(hostKey))
+	s, err = NewSftpStoreWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	opts.KnownHosts = fmt.Sprintf("[127.0.0.1]:%d %s", port+1, ssh.MarshalAuthorizedKey(hostKey))
+	if _, err := NewSftpStoreWithOptions(opts); err == nil || !strings.Contains(err.Error(), "not in the known hosts") {
+		t.Errorf("expect the unknown host, got %v", err)
+	}
+
+	// the password is rejected
+	if _, err := NewSftpStore("127.0.0.1", port, "nebula", "wrong"); err == nil {
+		t.Error("expect an error for the wrong password")
+	}
+}
+
+func TestParseSftpSecret(t *testing.T) {
+	secret := &SftpSecret{Password: "p", PrivateKey: "key", Passphrase: "pass"}
+	if parsed := ParseSftpSecret(secret.String()); !reflect.DeepEqual(parsed, secret) {
+		t.Errorf("unexpected secret: %v", parsed)
+	}
+	// the password saved alone
+	for _, password := range []string{"password", `{"password":"p"}`, ""} {
+		if parsed := ParseSftpSecret(password); parsed.Password != password || parsed.PrivateKey != "" {
+			t.Errorf("unexpected secret of %q: %v", password, parsed)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	SqlDriverMySQL    = "mysql"
+	SqlDriverPostgres = "postgres"
+	SqlDriverSQLite   = "sqlite"
+
+	sqlConnectTimeout = 10 * time.Second
+	sqlPreviewTimeout = 30 * time.Second
+)
+
+var sqlLeadingComments = regexp.MustCompile(`^(\s+|--[^\n]*(\n|$)|/\*(.|\n)*?\*/)*`)
+
+/*
+SqlStore reads the result of a query of a relational database as a csv file, the header is the column names.
+The path of the file is the query, which is a single select statement run in a read only transaction,
+the sqlite database is opened read only.
+*/
+type SqlStore struct {
+	Driver string
+	db     *sql.DB
+}
+
+func NewSqlStore(driver, dsn, password string) (*SqlStore, error) {
+	driverName, dsn, err := sqlDSN(driver, dsn, password)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(2)
+	ctx, cancel := context.WithTimeout(context.Background(), sqlConnectTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect the database: %s", err)
+	}
+	return &SqlStore{Driver: driver, db: db}, nil
+}
+
+// sqlDSN returns the registered driver name and the dsn with the password
+func sqlDSN(driver, dsn, password string) (string, string, error) {
+	switch driver {
+	case SqlDriverMySQL:
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid mysql dsn: %s", err)
+		}
+		if password != "" {
+			cfg.Passwd = password
+		}
+		// never read the files of the server by LOAD DATA LOCAL INFILE
+		cfg.AllowAllFiles = false
+		return "mysql", cfg.FormatDSN(), nil
+	case SqlDriverPostgres:
+		if password == "" {
+			return "postgres", dsn, nil
+		}
+		if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+			u, err := url.Parse(dsn)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid postgres dsn: %s", err)
+			}
+			u.User = url.UserPassword(u.User.Username(), password)
+			return "postgres", u.String(), nil
+		}
+		escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(password)
+		return "postgres", dsn + " password='" + escaped + "'", nil
+	case SqlDriverSQLite:
+		if dsn == "" {
+			return "", "", errors.New("the sqlite database file is required")
+		}
+		if !strings.HasPrefix(dsn, "file:") {
+			dsn = "file:" + dsn
+		}
+		if strings.Contains(dsn, "?") {
+			dsn += "&mode=ro"
+		} else {
+			dsn += "?mode=ro"
+		}
+		return "sqlite3", dsn, nil
+	}
+	return "", "", fmt.Errorf("unknown sql driver: %s", driver)
+}
+
+/*
+CheckSqliteFile accepts the database file of a sqlite dsn only in one of the dirs,
+the denied files are rejected even in the dirs. The symbolic links are resolved before the check.
+*/
+func CheckSqliteFile(dsn string, dirs, denied []string) error {
+	file, err := sqliteFile(dsn)
+	if err != nil {
+		return err
+	}
+	if file, err = realPath(file); err != nil {
+		return fmt.Errorf("invalid sqlite database file: %s", err)
+	}
+	for _, p := range denied {
+		if p, err := realPath(p); err == nil && p == file {
+			return errors.New("the sqlite database file is not allowed")
+		}
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		dir, err := realPath(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return errors.New("the sqlite database file must be in the sqlite dir or in your uploaded files")
+}
+
+// sqliteFile returns the file path of a sqlite dsn, which is a file path or a file uri
+func sqliteFile(dsn string) (string, error) {
+	if !strings.HasPrefix(dsn, "file:") {
+		return dsn, nil
+	}
+	p := strings.TrimPrefix(dsn, "file:")
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	if strings.HasPrefix(p, "//") {
+		authority, rest, _ := strings.Cut(p[2:], "/")
+		if authority != "" && authority != "localhost" {
+			return "", errors.New("the sqlite database file must be local")
+		}
+		p = "/" + rest
+	}
+	p, err := url.PathUnescape(p)
+	if err != nil {
+		return "", fmt.Errorf("invalid sqlite dsn: %s", err)
+	}
+	return p, nil
+}
+
+func realPath(p string) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+/*
+SplitSqlPassword takes the password out of a dsn, so that the password is stored encrypted apart from the dsn.
+A dsn without a password is returned as it is.
+*/
+func SplitSqlPassword(driver, dsn string) (string, string, error) {
+	switch driver {
+	case SqlDriverMySQL:
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid mysql dsn: %s", err)
+		}
+		if cfg.Passwd == "" {
+			return dsn, "", nil
+		}
+		password := cfg.Passwd
+		cfg.Passwd = ""
+		return cfg.FormatDSN(), password, nil
+	case SqlDriverPostgres:
+		if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+			u, err := url.Parse(dsn)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid postgres dsn: %s", err)
+			}
+			password, ok := u.User.Password()
+			if !ok {
+				return dsn, "", nil
+			}
+			u.User = url.User(u.User.Username())
+			return u.String(), password, nil
+		}
+	}
+	return dsn, "", nil
+}
+
+// checkQuery accepts a single select statement, the writes are rejected by the read only transaction anyway
+func checkQuery(query string) (string, error) {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	body := sqlLeadingComments.ReplaceAllString(query, "")
+	fields := strings.Fields(body)
+	if len(fields) == 0 {
+		return "", errors.New("the query is empty")
+	}
+	switch strings.ToLower(fields[0]) {
+	case "select", "with", "values", "table":
+	default:
+		return "", errors.New("only the select statement is allowed")
+	}
+	if strings.Contains(query, ";") {
+		return "", errors.New("only a single statement is allowed")
+	}
+	return query, nil
+}
+
+type sqlRows struct {
+	tx      *sql.Tx
+	rows    *sql.Rows
+	cancel  context.CancelFunc
+	columns []string
+	values  []any
+}
+
+func (s *SqlStore) query(ctx context.Context, cancel context.CancelFunc, query string) (*sqlRows, error) {
+	query, err := checkQuery(query)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		cancel()
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		tx.Rollback()
+		cancel()
+		return nil, err
+	}
+	r := &sqlRows{tx: tx, rows: rows, cancel: cancel, columns: columns, values: make([]any, len(columns))}
+	return r, nil
+}
+
+// next scans the next row as strings, the null is an empty string
+func (r *sqlRows) next() ([]string, error) {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	ptrs := make([]any, len(r.values))
+	for i := range r.values {
+		ptrs[i] = &r.values[i]
+	}
+	if err := r.rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	record := make([]string, len(r.values))
+	for i, v := range r.values {
+		record[i] = formatSqlValue(v)
+	}
+	return record, nil
+}
+
+func (r *sqlRows) close() {
+	r.rows.Close()
+	r.tx.Rollback()
+	r.cancel()
+}
+
+func formatSqlValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format("2006-01-02T15:04:05.999999")
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Query returns the column names and at most limit rows of the result
+func (s *SqlStore) Query(query string, limit int) ([]string, [][]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlPreviewTimeout)
+	r, err := s.query(ctx, cancel, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.close()
+	rows := make([][]string, 0, limit)
+	for len(rows) < limit {
+		record, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, record)
+	}
+	return r.columns, rows, nil
+}
+
+// Open streams the whole result as a csv file
+func (s *SqlStore) Open(query string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r, err := s.query(ctx, cancel, query)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		defer r.close()
+		w := csv.NewWriter(pw)
+		err := w.Write(r.columns)
+		for err == nil {
+			var record []string
+			if record, err = r.next(); err == nil {
+				err = w.Write(record)
+			}
+		}
+		if err == io.EOF {
+			w.Flush()
+			err = w.Error()
+		}
+		pw.CloseWithError(err)
+	}()
+	return &sqlReader{PipeReader: pr, cancel: cancel}, nil
+}
+
+type sqlReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+// Close stops the query if the result is not read to the end
+func (r *sqlReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
+
+// ReadFile reads the lines of the result as a csv file, the first line is the column names
+func (s *SqlStore) ReadFile(query string, startLine ...int) ([]string, error) {
+	start, numLines := lineRange(startLine...)
+	ctx, cancel := context.WithTimeout(context.Background(), sqlPreviewTimeout)
+	r, err := s.query(ctx, cancel, query)
+	if err != nil {
+		return nil, err
+	}
+	defer r.close()
+
+	var lines []string
+	record := r.columns
+	for i := 0; numLines < 0 || i < start+numLines; i++ {
+		if i > 0 {
+			record, err = r.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if i < start {
+			continue
+		}
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(record)
+		w.Flush()
+		lines = append(lines, strings.TrimSuffix(buf.String(), "\n"))
+	}
+	if len(lines) == 0 && start > 0 && numLines != 0 {
+		return nil, errors.New("start line is beyond end of file")
+	}
+	return lines, nil
+}
+
+// ListFiles lists the tables and views of the database, the dir is ignored
+func (s *SqlStore) ListFiles(dir string) ([]FileConfig, error) {
+	var query string
+	switch s.Driver {
+	case SqlDriverMySQL:
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() ORDER BY table_name"
+	case SqlDriverPostgres:
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() ORDER BY table_name"
+	case SqlDriverSQLite:
+		query = "SELECT name FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sqlPreviewTimeout)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var files []FileConfig
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		files = append(files, FileConfig{Name: name, Type: "table"})
+	}
+	return files, rows.Err()
+}
+
+func (s *SqlStore) Close() error {
+	return s.db.Close()
+}
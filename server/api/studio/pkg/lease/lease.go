@@ -0,0 +1,284 @@
+package lease
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/zeromicro/go-zero/core/logx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	KindImportTask = "import_task"
+	KindLLMJob     = "llm_job"
+	// leases of the notifications being delivered, the job id is the id of the delivery
+	KindNotifyDelivery = "notify_delivery"
+	// leases of the chunked uploads being written, so that a part file in the shared upload dir has one writer
+	KindUpload = "file_upload"
+	// leases of the loops which only run on one instance at a time
+	KindLeader = "leader"
+)
+
+// Handler reacts to lease events of one job kind on the instance owning the lease.
+type Handler struct {
+	// OnStop is called when another instance asked to stop a job owned by this instance.
+	// The stop request is kept until OnStop returns nil.
+	OnStop func(jobID string) error
+	// OnTakeover is called after this instance took over an expired lease left by prevOwner.
+	// The lease has already been released when OnTakeover is called.
+	OnTakeover func(jobID, prevOwner string)
+	// Running returns the jobs of the kind running on this instance.
+	Running func() []string
+	// OnLost is called when the lease of a running job is owned by newOwner, such as after a long pause of this instance.
+	// The job must stop without writing its state, which belongs to the new owner now.
+	OnLost func(jobID, newOwner string)
+}
+
+var (
+	instanceID string
+	handlers   = make(map[string]Handler)
+	mu         sync.RWMutex
+	startOnce  sync.Once
+)
+
+func init() {
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	instanceID = fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// InstanceID returns the identity this process uses as lease owner.
+func InstanceID() string {
+	return instanceID
+}
+
+func ttl() time.Duration {
+	if c := config.GetConfig(); c != nil && c.Lease.TTL > 0 {
+		return time.Duration(c.Lease.TTL) * time.Second
+	}
+	return 30 * time.Second
+}
+
+func heartbeatInterval() time.Duration {
+	if c := config.GetConfig(); c != nil && c.Lease.HeartbeatInterval > 0 {
+		return time.Duration(c.Lease.HeartbeatInterval) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// Register sets the handler for the leases of the given kind.
+func Register(kind string, h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[kind] = h
+}
+
+func getHandler(kind string) (Handler, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	h, ok := handlers[kind]
+	return h, ok
+}
+
+/*
+Claim tries to own the job, it succeeds when the job is not leased yet,
+when the lease is already owned by this instance or when the lease has expired.
+*/
+func Claim(kind, jobID string) (bool, error) {
+	now := time.Now()
+	lease := &db.JobLease{
+		Kind:       kind,
+		JobID:      jobID,
+		Owner:      instanceID,
+		ExpireTime: now.Add(ttl()),
+	}
+	result := db.CtxDB.Clauses(clause.OnConflict{DoNothing: true}).Create(lease)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+	result = db.CtxDB.Model(&db.JobLease{}).
+		Where("kind = ? AND job_id = ? AND (owner = ? OR expire_time < ?)", kind, jobID, instanceID, now).
+		Updates(map[string]interface{}{
+			"owner":          instanceID,
+			"expire_time":    now.Add(ttl()),
+			"stop_requested": false,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Release gives up the lease of the job if it is owned by this instance.
+func Release(kind, jobID string) error {
+	return db.CtxDB.Where("kind = ? AND job_id = ? AND owner = ?", kind, jobID, instanceID).Delete(&db.JobLease{}).Error
+}
+
+// Remove deletes the lease of the job whoever owns it.
+func Remove(kind, jobID string) error {
+	return db.CtxDB.Where("kind = ? AND job_id = ?", kind, jobID).Delete(&db.JobLease{}).Error
+}
+
+// Get returns the live lease of the job, or nil when the job is not leased or the lease has expired.
+func Get(kind, jobID string) (*db.JobLease, error) {
+	var lease db.JobLease
+	err := db.CtxDB.Where("kind = ? AND job_id = ? AND expire_time >= ?", kind, jobID, time.Now()).First(&lease).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// IsOwner reports whether this instance owns the live lease of the job.
+func IsOwner(l *db.JobLease) bool {
+	return l != nil && l.Owner == instanceID
+}
+
+/*
+RequestStop flags the job so that its owner stops it on the next heartbeat.
+It returns false when nobody holds a live lease of the job.
+*/
+func RequestStop(kind, jobID string) (bool, error) {
+	result := db.CtxDB.Model(&db.JobLease{}).
+		Where("kind = ? AND job_id = ? AND expire_time >= ?", kind, jobID, time.Now()).
+		Update("stop_requested", true)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ExpireAll marks every lease as expired, so that jobs left by a previous run are taken over at once.
+func ExpireAll() error {
+	return db.CtxDB.Model(&db.JobLease{}).Where("1 = 1").Update("expire_time", time.Unix(0, 0)).Error
+}
+
+// Start runs the heartbeat loop of this instance in background, it is safe to call it more than once.
+func Start() {
+	startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(heartbeatInterval())
+			defer ticker.Stop()
+			for range ticker.C {
+				heartbeat()
+			}
+		}()
+	})
+}
+
+func heartbeat() {
+	defer func() {
+		if err := recover(); err != nil {
+			logx.Errorf("[lease] heartbeat panic: %v", err)
+		}
+	}()
+	now := time.Now()
+	err := db.CtxDB.Model(&db.JobLease{}).Where("owner = ?", instanceID).Update("expire_time", now.Add(ttl())).Error
+	if err != nil {
+		logx.Errorf("[lease] renew leases failed: %v", err)
+		return
+	}
+	stopLost()
+	handleStopRequests()
+	takeoverExpired(now)
+}
+
+/*
+stopLost stops the running jobs whose leases are owned by other instances,
+the leases have expired and been taken over while this instance could not renew them.
+*/
+func stopLost() {
+	mu.RLock()
+	kinds := make(map[string]Handler, len(handlers))
+	for kind, h := range handlers {
+		kinds[kind] = h
+	}
+	mu.RUnlock()
+	for kind, h := range kinds {
+		if h.Running == nil || h.OnLost == nil {
+			continue
+		}
+		running := h.Running()
+		if len(running) == 0 {
+			continue
+		}
+		var leases []*db.JobLease
+		err := db.CtxDB.Where("kind = ? AND job_id IN ? AND owner <> ?", kind, running, instanceID).Find(&leases).Error
+		if err != nil {
+			logx.Errorf("[lease] find lost leases failed: %v", err)
+			continue
+		}
+		for _, l := range leases {
+			logx.Errorf("[lease] %s %s is taken over by %s, stop it", l.Kind, l.JobID, l.Owner)
+			h.OnLost(l.JobID, l.Owner)
+		}
+	}
+}
+
+func handleStopRequests() {
+	var leases []*db.JobLease
+	err := db.CtxDB.Where("owner = ? AND stop_requested = ?", instanceID, true).Find(&leases).Error
+	if err != nil {
+		logx.Errorf("[lease] find stop requests failed: %v", err)
+		return
+	}
+	for _, l := range leases {
+		h, ok := getHandler(l.Kind)
+		if !ok || h.OnStop == nil {
+			continue
+		}
+		if err := h.OnStop(l.JobID); err != nil {
+			logx.Errorf("[lease] stop %s %s failed: %v", l.Kind, l.JobID, err)
+			continue
+		}
+		db.CtxDB.Model(&db.JobLease{}).Where("id = ? AND owner = ?", l.ID, instanceID).Update("stop_requested", false)
+	}
+}
+
+func takeoverExpired(now time.Time) {
+	var leases []*db.JobLease
+	err := db.CtxDB.Where("expire_time < ?", now).Find(&leases).Error
+	if err != nil {
+		logx.Errorf("[lease] find expired leases failed: %v", err)
+		return
+	}
+	for _, l := range leases {
+		h, ok := getHandler(l.Kind)
+		if !ok {
+			continue
+		}
+		// only one instance wins the takeover of an expired lease
+		result := db.CtxDB.Model(&db.JobLease{}).
+			Where("id = ? AND owner = ? AND expire_time < ?", l.ID, l.Owner, now).
+			Updates(map[string]interface{}{
+				"owner":          instanceID,
+				"expire_time":    now.Add(ttl()),
+				"stop_requested": false,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+		logx.Infof("[lease] take over %s %s from %s", l.Kind, l.JobID, l.Owner)
+		// release before handling, so that the job can be claimed again once it is handled
+		if err := Release(l.Kind, l.JobID); err != nil {
+			logx.Errorf("[lease] release %s %s failed: %v", l.Kind, l.JobID, err)
+		}
+		if h.OnTakeover != nil {
+			h.OnTakeover(l.JobID, l.Owner)
+		}
+	}
+}
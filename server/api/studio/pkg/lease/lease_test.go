@@ -0,0 +1,122 @@
+package lease
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDB(t *testing.T) {
+	d, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AutoMigrate(&db.JobLease{}); err != nil {
+		t.Fatal(err)
+	}
+	db.CtxDB = d
+}
+
+func TestClaim(t *testing.T) {
+	ast := assert.New(t)
+	setupDB(t)
+
+	ok, err := Claim(KindImportTask, "task1")
+	ast.NoError(err)
+	ast.True(ok)
+
+	// claim again by the owner
+	ok, err = Claim(KindImportTask, "task1")
+	ast.NoError(err)
+	ast.True(ok)
+
+	// claim a lease held by another instance
+	ast.NoError(db.CtxDB.Model(&db.JobLease{}).Where("job_id = ?", "task1").Update("owner", "other").Error)
+	ok, err = Claim(KindImportTask, "task1")
+	ast.NoError(err)
+	ast.False(ok)
+
+	// claim an expired lease
+	ast.NoError(db.CtxDB.Model(&db.JobLease{}).Where("job_id = ?", "task1").Update("expire_time", time.Now().Add(-time.Minute)).Error)
+	ok, err = Claim(KindImportTask, "task1")
+	ast.NoError(err)
+	ast.True(ok)
+
+	ast.NoError(Release(KindImportTask, "task1"))
+	l, err := Get(KindImportTask, "task1")
+	ast.NoError(err)
+	ast.Nil(l)
+}
+
+func TestTakeover(t *testing.T) {
+	ast := assert.New(t)
+	setupDB(t)
+
+	var stopped, takenOver []string
+	Register(KindLLMJob, Handler{
+		OnStop: func(jobID string) error {
+			stopped = append(stopped, jobID)
+			return nil
+		},
+		OnTakeover: func(jobID, prevOwner string) {
+			ast.Equal("other", prevOwner)
+			takenOver = append(takenOver, jobID)
+		},
+	})
+
+	ast.NoError(db.CtxDB.Create(&db.JobLease{Kind: KindLLMJob, JobID: "job1", Owner: instanceID, ExpireTime: time.Now().Add(time.Minute)}).Error)
+	ast.NoError(db.CtxDB.Create(&db.JobLease{Kind: KindLLMJob, JobID: "job2", Owner: "other", ExpireTime: time.Now().Add(-time.Minute)}).Error)
+	ast.NoError(db.CtxDB.Create(&db.JobLease{Kind: KindLLMJob, JobID: "job3", Owner: "other", ExpireTime: time.Now().Add(time.Minute)}).Error)
+
+	requested, err := RequestStop(KindLLMJob, "job1")
+	ast.NoError(err)
+	ast.True(requested)
+
+	heartbeat()
+
+	ast.Equal([]string{"job1"}, stopped)
+	ast.Equal([]string{"job2"}, takenOver)
+
+	l, err := Get(KindLLMJob, "job1")
+	ast.NoError(err)
+	ast.True(IsOwner(l))
+	ast.False(l.StopRequested)
+
+	l, err = Get(KindLLMJob, "job2")
+	ast.NoError(err)
+	ast.Nil(l)
+
+	l, err = Get(KindLLMJob, "job3")
+	ast.NoError(err)
+	ast.False(IsOwner(l))
+}
+
+func TestLost(t *testing.T) {
+	ast := assert.New(t)
+	setupDB(t)
+
+	var lost []string
+	Register(KindImportTask, Handler{
+		Running: func() []string {
+			return []string{"task1", "task2", "task3"}
+		},
+		OnLost: func(jobID, newOwner string) {
+			ast.Equal("other", newOwner)
+			lost = append(lost, jobID)
+		},
+	})
+	defer Register(KindImportTask, Handler{})
+
+	ast.NoError(db.CtxDB.Create(&db.JobLease{Kind: KindImportTask, JobID: "task1", Owner: instanceID, ExpireTime: time.Now().Add(time.Minute)}).Error)
+	// taken over after the lease expired
+	ast.NoError(db.CtxDB.Create(&db.JobLease{Kind: KindImportTask, JobID: "task2", Owner: "other", ExpireTime: time.Now().Add(time.Minute)}).Error)
+
+	heartbeat()
+
+	// the job without a lease has just finished
+	ast.Equal([]string{"task2"}, lost)
+}
@@ -0,0 +1,76 @@
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir is the dir of the checkpoints in the dir of a job
+const Dir = "blocks"
+
+/*
+Block is the extraction of a block of a job, which is kept to skip the block when the job runs again.
+Hash is the hash of the prompt of the block, the block is extracted again when the text, the schema or the prompt changes.
+*/
+type Block struct {
+	Index    int    `json:"index"`
+	Hash     string `json:"hash"`
+	Response string `json:"response"`
+	// Result is the nodes and the edges parsed from the response
+	Result           json.RawMessage `json:"result"`
+	PromptTokens     int             `json:"promptTokens"`
+	CompletionTokens int             `json:"completionTokens"`
+}
+
+// Store keeps the checkpoints of a job in a dir, a file per block
+type Store struct {
+	dir string
+}
+
+// New returns the store of the checkpoints in the dir of a job
+func New(jobDir string) *Store {
+	return &Store{dir: filepath.Join(jobDir, Dir)}
+}
+
+// Hash returns the hash of a prompt
+func Hash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Store) path(index int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%d.json", index))
+}
+
+// Save writes the checkpoint of a block, a broken write leaves no checkpoint
+func (s *Store) Save(b *Block) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	tmp := s.path(b.Index) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(b.Index))
+}
+
+// Load reads the checkpoint of the block with the prompt of the hash, ok is false if there is no such checkpoint
+func (s *Store) Load(index int, hash string) (*Block, bool) {
+	data, err := os.ReadFile(s.path(index))
+	if err != nil {
+		return nil, false
+	}
+	b := &Block{}
+	if err := json.Unmarshal(data, b); err != nil || b.Index != index || b.Hash != hash {
+		return nil, false
+	}
+	return b, true
+}
@@ -0,0 +1,44 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	jobDir := t.TempDir()
+	s := New(jobDir)
+	if _, ok := s.Load(0, Hash("prompt")); ok {
+		t.Error("expect no checkpoint")
+	}
+	b := &Block{
+		Index:            3,
+		Hash:             Hash("prompt"),
+		Response:         "```json\n{}\n```",
+		Result:           json.RawMessage(`{"nodes":[{"name":"Tom","type":"person"}],"edges":[]}`),
+		PromptTokens:     100,
+		CompletionTokens: 20,
+	}
+	if err := s.Save(b); err != nil {
+		t.Fatal(err)
+	}
+	loaded, ok := s.Load(3, Hash("prompt"))
+	if !ok || !reflect.DeepEqual(loaded, b) {
+		t.Errorf("unexpected checkpoint: %+v", loaded)
+	}
+	// the prompt changes
+	if _, ok := s.Load(3, Hash("another prompt")); ok {
+		t.Error("expect no checkpoint of another prompt")
+	}
+	if _, err := os.Stat(filepath.Join(jobDir, Dir, "3.json.tmp")); !os.IsNotExist(err) {
+		t.Errorf("expect no temp file, got %v", err)
+	}
+	// a broken checkpoint is ignored
+	os.WriteFile(filepath.Join(jobDir, Dir, "4.json"), []byte("{"), 0644)
+	if _, ok := s.Load(4, Hash("prompt")); ok {
+		t.Error("expect the broken checkpoint ignored")
+	}
+}
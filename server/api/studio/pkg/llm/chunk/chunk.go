@@ -0,0 +1,246 @@
+package chunk
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// StrategyLines fills the blocks with the lines by the bytes of the text, which is the default of the jobs
+	StrategyLines = "lines"
+	// StrategyTokens splits the text by the tokens at the heading, paragraph and sentence boundaries
+	StrategyTokens = "tokens"
+
+	// MinSize is the least tokens of a chunk
+	MinSize = 64
+)
+
+var (
+	// the headings marked by the pdf reader, such as <H1>Title<H1>, and the markdown headings
+	pdfHeading = regexp.MustCompile(`^<H(\d)>.*<H\d>$`)
+	mdHeading  = regexp.MustCompile(`^(#{1,6})\s+\S`)
+)
+
+// Options are the tokens of a chunk and the tokens repeated from the end of the previous chunk
+type Options struct {
+	Size    int
+	Overlap int
+}
+
+// unit is a heading line, or a sentence of a paragraph which is split no more
+type unit struct {
+	text   string
+	tokens int
+	// level is the level of the heading, 0 for the text
+	level int
+	// context is the heading lines above the unit
+	context []string
+}
+
+type heading struct {
+	level int
+	line  string
+}
+
+func headingLevel(line string) int {
+	line = strings.TrimSpace(line)
+	if match := pdfHeading.FindStringSubmatch(line); match != nil {
+		if level, err := strconv.Atoi(match[1]); err == nil && level > 0 {
+			return level
+		}
+		return 1
+	}
+	if match := mdHeading.FindStringSubmatch(line); match != nil {
+		return len(match[1])
+	}
+	return 0
+}
+
+/*
+Split splits the text into the chunks of the tokens in the options.
+A chunk is filled with the whole sentences, and starts with the headings above its first sentence, so the llm knows the section of it.
+A new chunk starts at a heading when the chunk is half full, and the sentences at the end of the previous chunk
+in the same section are repeated up to the overlap tokens, so the entities across the chunks are extracted in one of them.
+A sentence longer than a chunk is cut at the spaces, or at the characters for the text without spaces.
+*/
+func Split(text string, t *Tokenizer, opts Options) []string {
+	size := opts.Size
+	if size < MinSize {
+		size = MinSize
+	}
+	overlap := opts.Overlap
+	if overlap > size/2 {
+		overlap = size / 2
+	}
+	units := parse(text, t, size*3/4)
+
+	chunks := make([]string, 0)
+	var current []unit
+	tokens := 0
+	hasText := false
+	flush := func() {
+		if hasText {
+			var b strings.Builder
+			for _, u := range current {
+				b.WriteString(u.text)
+			}
+			chunks = append(chunks, b.String())
+		}
+		current, tokens, hasText = nil, 0, false
+	}
+	for _, u := range units {
+		if len(current) > 0 && (tokens+u.tokens > size || (u.level > 0 && hasText && tokens >= size/2)) {
+			previous := current
+			flush()
+			for _, line := range u.context {
+				current = append(current, unit{text: line, tokens: t.Count(line), level: 1})
+				tokens += current[len(current)-1].tokens
+			}
+			if u.level == 0 && overlap > 0 {
+				repeated := overlapUnits(previous, u.context, overlap, size-tokens-u.tokens)
+				for _, r := range repeated {
+					current = append(current, r)
+					tokens += r.tokens
+				}
+			}
+		}
+		current = append(current, u)
+		tokens += u.tokens
+		if u.level == 0 {
+			hasText = true
+		}
+	}
+	flush()
+	return chunks
+}
+
+// overlapUnits returns the sentences at the end of the previous chunk in the same section, up to the overlap tokens and the room left
+func overlapUnits(previous []unit, context []string, overlap, room int) []unit {
+	if overlap > room {
+		overlap = room
+	}
+	section := strings.Join(context, "")
+	start, tokens := len(previous), 0
+	for start > 0 {
+		u := previous[start-1]
+		if u.level > 0 || strings.Join(u.context, "") != section || tokens+u.tokens > overlap {
+			break
+		}
+		tokens += u.tokens
+		start--
+	}
+	return previous[start:]
+}
+
+// parse splits the text into the heading lines and the sentences, the sentences longer than the limit are cut
+func parse(text string, t *Tokenizer, limit int) []unit {
+	units := make([]unit, 0)
+	var stack []heading
+	context := func() []string {
+		lines := make([]string, 0, len(stack))
+		for _, h := range stack {
+			lines = append(lines, h.line)
+		}
+		return lines
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			// the blank line between the paragraphs is kept with the previous sentence
+			if n := len(units); n > 0 && line != "" && units[n-1].level == 0 {
+				units[n-1].text += "\n"
+			}
+			continue
+		}
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		if level := headingLevel(line); level > 0 {
+			for len(stack) > 0 && stack[len(stack)-1].level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			units = append(units, unit{text: line, tokens: t.Count(line), level: level, context: context()})
+			stack = append(stack, heading{level: level, line: line})
+			continue
+		}
+		lineContext := context()
+		for _, sentence := range sentences(line) {
+			for _, piece := range cut(sentence, t, limit) {
+				units = append(units, unit{text: piece, tokens: t.Count(piece), context: lineContext})
+			}
+		}
+	}
+	return units
+}
+
+const (
+	// the ends of the sentences in CJK, which need no space after them
+	cjkEnds = "。！？；…"
+	// the ends of the sentences followed by a space
+	ends = ".!?;"
+	// the closing quotes and brackets kept with the end of a sentence
+	closings = "\"'”’」』)）]"
+)
+
+// sentences splits a line into the sentences, the spaces after a sentence are kept with it
+func sentences(line string) []string {
+	result := make([]string, 0)
+	start := 0
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		i += size
+		end := strings.ContainsRune(cjkEnds, r)
+		if !end && strings.ContainsRune(ends, r) {
+			next, _ := utf8.DecodeRuneInString(line[i:])
+			end = i == len(line) || unicode.IsSpace(next) || strings.ContainsRune(closings, next)
+		}
+		if !end {
+			continue
+		}
+		for i < len(line) {
+			next, size := utf8.DecodeRuneInString(line[i:])
+			if !unicode.IsSpace(next) && !strings.ContainsRune(closings, next) {
+				break
+			}
+			i += size
+		}
+		result = append(result, line[start:i])
+		start = i
+	}
+	if start < len(line) {
+		result = append(result, line[start:])
+	}
+	return result
+}
+
+// cut cuts a sentence longer than the limit, at the last space in the second half of a piece if any
+func cut(sentence string, t *Tokenizer, limit int) []string {
+	if t.Count(sentence) <= limit {
+		return []string{sentence}
+	}
+	pieces := make([]string, 0)
+	start, space := 0, -1
+	tokens := 0.0
+	for i, r := range sentence {
+		w := t.weight(r)
+		if tokens+w > float64(limit) && i > start {
+			end := i
+			if space > start+(i-start)/2 {
+				end = space
+			}
+			pieces = append(pieces, sentence[start:end])
+			start, space = end, -1
+			tokens = float64(t.Count(sentence[start:i]))
+		}
+		tokens += w
+		if unicode.IsSpace(r) {
+			space = i + utf8.RuneLen(r)
+		}
+	}
+	if start < len(sentence) {
+		pieces = append(pieces, sentence[start:])
+	}
+	return pieces
+}
@@ -0,0 +1,81 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestForModel(t *testing.T) {
+	cases := map[[2]string]*Tokenizer{
+		{"openai", "gpt-4o-mini"}:                    O200K,
+		{"openai", "gpt-4-turbo"}:                    CL100K,
+		{"ollama", "meta-llama/Llama-3-8B-Instruct"}: CL100K,
+		{"qwen", "qwen-max"}:                         Qwen,
+		{"qwen", ""}:                                 Qwen,
+		{"ollama", "mistral:7b"}:                     SentencePiece,
+		{"openai", "deepseek-chat"}:                  DeepSeek,
+		{"azure", ""}:                                Default,
+	}
+	for args, expected := range cases {
+		if got := ForModel(args[0], args[1]); got != expected {
+			t.Errorf("ForModel(%q, %q) = %s, expected %s", args[0], args[1], got.Family, expected.Family)
+		}
+	}
+	if n := O200K.Count("abcdefgh"); n != 2 {
+		t.Errorf("expect 2 tokens, got %d", n)
+	}
+	if Qwen.Count("北京欢迎你") >= CL100K.Count("北京欢迎你") {
+		t.Error("expect qwen to count less tokens of the Chinese text")
+	}
+}
+
+func TestSentences(t *testing.T) {
+	got := sentences("Tom lives in Paris. He likes \"art.\" 他喜欢画画。然后呢？end\n")
+	expected := []string{"Tom lives in Paris. ", "He likes \"art.\" ", "他喜欢画画。", "然后呢？", "end\n"}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("unexpected sentences %q", got)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	text := "<H1>Report<H1>\n" +
+		"<H2>People<H2>\n" +
+		strings.Repeat("Tom works at Acme in the city of Paris. ", 20) + "\n\n" +
+		"<H2>Places<H2>\n" +
+		"Paris is the capital of France.\n"
+	chunks := Split(text, Default, Options{Size: 100, Overlap: 30})
+	if len(chunks) < 3 {
+		t.Fatalf("expect the text split into chunks, got %q", chunks)
+	}
+	for i, chunk := range chunks {
+		if n := Default.Count(chunk); n > 100 {
+			t.Errorf("chunk %d has %d tokens", i, n)
+		}
+		if !strings.HasPrefix(chunk, "<H1>Report<H1>\n<H2>") {
+			t.Errorf("expect chunk %d to start with the headings, got %q", i, chunk)
+		}
+		if !strings.HasSuffix(strings.TrimRight(chunk, "\n "), ".") {
+			t.Errorf("expect chunk %d to end with a sentence, got %q", i, chunk)
+		}
+	}
+	// the overlap repeats the last sentence of the previous chunk in the same section
+	if !strings.HasPrefix(chunks[1], "<H1>Report<H1>\n<H2>People<H2>\nTom works at Acme in the city of Paris. Tom") {
+		t.Errorf("expect the overlap in the second chunk, got %q", chunks[1])
+	}
+	last := chunks[len(chunks)-1]
+	if last != "<H1>Report<H1>\n<H2>Places<H2>\nParis is the capital of France.\n" {
+		t.Errorf("expect the new section starts a chunk without the overlap, got %q", last)
+	}
+
+	// the text without the boundaries is cut by the tokens
+	chunks = Split(strings.Repeat("北", 300), Default, Options{Size: 100})
+	if len(chunks) != 4 || strings.Join(chunks, "") != strings.Repeat("北", 300)+"\n" {
+		t.Errorf("unexpected chunks %q", chunks)
+	}
+
+	// the markdown headings
+	chunks = Split("# A\ntext a.\n## B\ntext b.\n", Default, Options{Size: 1000})
+	if len(chunks) != 1 || chunks[0] != "# A\ntext a.\n## B\ntext b.\n" {
+		t.Errorf("unexpected chunks %q", chunks)
+	}
+}
@@ -0,0 +1,92 @@
+package chunk
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+Tokenizer estimates the tokens of a text for a model family, by the tokens per character of the ascii, the CJK and the other characters.
+The rates follow the typical ratios of the tokenizers of the families, such as about 4 English characters per token,
+so the count is an estimate rather than the exact one, and the chunks are sized with a margin for it.
+*/
+type Tokenizer struct {
+	Family string
+	ascii  float64
+	cjk    float64
+	other  float64
+}
+
+var (
+	// O200K is the tokenizer of gpt-4o, gpt-4.1 and the o series
+	O200K = &Tokenizer{Family: "o200k", ascii: 0.25, cjk: 0.8, other: 0.45}
+	// CL100K is the tokenizer of gpt-4, gpt-3.5 and llama3
+	CL100K = &Tokenizer{Family: "cl100k", ascii: 0.26, cjk: 1.1, other: 0.6}
+	// Qwen is the tokenizer of the qwen models
+	Qwen = &Tokenizer{Family: "qwen", ascii: 0.26, cjk: 0.7, other: 0.5}
+	// DeepSeek is the tokenizer of the deepseek models
+	DeepSeek = &Tokenizer{Family: "deepseek", ascii: 0.3, cjk: 0.6, other: 0.5}
+	// SentencePiece is the tokenizer of llama2, mistral and the other models of the small vocabularies
+	SentencePiece = &Tokenizer{Family: "sentencepiece", ascii: 0.3, cjk: 1.5, other: 0.8}
+	// Default is the tokenizer of the unknown models, which overestimates the CJK text a bit
+	Default = &Tokenizer{Family: "default", ascii: 0.27, cjk: 1, other: 0.6}
+)
+
+// families are matched by the model name in order, the first match wins
+var families = []struct {
+	prefixes  []string
+	tokenizer *Tokenizer
+}{
+	{[]string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4", "chatgpt-4o"}, O200K},
+	{[]string{"gpt-4", "gpt-3.5", "llama3", "llama-3", "meta-llama-3", "llama3.1", "llama3.2"}, CL100K},
+	{[]string{"qwen", "qwq"}, Qwen},
+	{[]string{"deepseek"}, DeepSeek},
+	{[]string{"llama2", "llama-2", "mistral", "mixtral", "codellama"}, SentencePiece},
+}
+
+// ForModel returns the tokenizer of the model, the qwen api type defaults to the qwen family
+func ForModel(apiType, model string) *Tokenizer {
+	model = strings.ToLower(strings.TrimSpace(model))
+	// such as meta-llama/Llama-3-8B or library/qwen2:7b
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	for _, family := range families {
+		for _, prefix := range family.prefixes {
+			if strings.HasPrefix(model, prefix) {
+				return family.tokenizer
+			}
+		}
+	}
+	if apiType == "qwen" {
+		return Qwen
+	}
+	return Default
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// weight is the tokens of a character
+func (t *Tokenizer) weight(r rune) float64 {
+	switch {
+	case r < utf8.RuneSelf:
+		return t.ascii
+	case isCJK(r):
+		return t.cjk
+	}
+	return t.other
+}
+
+// Count estimates the tokens of the text
+func (t *Tokenizer) Count(text string) int {
+	tokens := 0.0
+	for _, r := range text {
+		tokens += t.weight(r)
+	}
+	return int(math.Ceil(tokens))
+}
@@ -0,0 +1,38 @@
+package llm
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
+	wsUtils "github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ws/utils"
+)
+
+// the max bytes of the log read for an event
+const eventLogLimit = 64 * 1024
+
+// jobEvents publishes the status, process and new logs of a job to the websocket subscribers
+type jobEvents struct {
+	tail *utils.FileTail
+}
+
+func newJobEvents(jobID string) *jobEvents {
+	logFile := filepath.Join(config.GetConfig().LLM.GQLPath, fmt.Sprintf("%s/all.log", jobID))
+	return &jobEvents{tail: utils.NewFileTail(logFile)}
+}
+
+func (e *jobEvents) publish(job *db.LLMJob) {
+	logs, _ := e.tail.Next(eventLogLimit)
+	event := &wsUtils.Event{
+		Topic:  wsUtils.Topic(wsUtils.TopicLLMJob, job.JobID),
+		Owner:  wsUtils.Owner(job.Host, job.UserName),
+		Status: string(job.Status),
+		Logs:   logs,
+	}
+	if len(job.Process) > 0 {
+		event.Stats = job.Process
+	}
+	wsUtils.Publish(event)
+}
@@ -30,13 +30,11 @@ func FetchWithLLMConfig(config *db.LLMConfig, req map[string]any, callback func(
 			logx.Error(fmt.Sprintf("panic: %v", err))
 		}
 	}()
-	// Convert the request parameters to a JSON string
-	var transform transformer.Handler
-	if config.APIType == "openai" {
-		transform = &transformer.OpenAI{}
-	} else if config.APIType == "qwen" {
-		transform = &transformer.Qwen{}
+	transform, err := transformer.New(config.APIType)
+	if err != nil {
+		return nil, err
 	}
+	// Convert the request parameters to a JSON string
 	httpReq, err := transform.HandleRequest(req, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %v", err)
@@ -51,6 +49,9 @@ func FetchWithLLMConfig(config *db.LLMConfig, req map[string]any, callback func(
 	}
 	defer resp.Body.Close()
 	defer client.CloseIdleConnections()
+	if err := transformer.CheckStatus(resp); err != nil {
+		return nil, err
+	}
 	return transform.HandleResponse(resp, callback)
 }
 
@@ -1,17 +1,19 @@
 package llm
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
-	"path"
 	"path/filepath"
-	"regexp"
 	"runtime/debug"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	nebula_go "github.com/vesoft-inc/nebula-go/v3"
@@ -20,7 +22,15 @@ import (
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/base"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/client"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/checkpoint"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/chunk"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/literal"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/ratelimit"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/resolve"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/structured"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/transformer"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/pdf"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
 	"gorm.io/datatypes"
 )
 
@@ -56,6 +66,15 @@ type ImportJob struct {
 	Schema            Schema
 	SchemaMap         map[string]map[string]Field
 	SpaceSchemaString string
+	// mu guards the caches and the process updated by the workers querying the blocks
+	mu      sync.Mutex
+	limiter *ratelimit.Limiter
+	// structured is the response format and the repairs of the answers, responseFormat is nil if the llm rejects the format
+	structured     structured.Options
+	responseFormat map[string]any
+	// aliases are the alias list of the job, and names are the first names seen of the normalized names
+	aliases *resolve.Aliases
+	names   map[string]string
 }
 
 func RunFileJob(job *db.LLMJob) {
@@ -76,6 +95,10 @@ func RunFileJob(job *db.LLMJob) {
 			llmJob.WriteLogFile(fmt.Sprintf("panic: %v , stack: %v", err, string(debug.Stack())), "error")
 			llmJob.SetJobFailed(err)
 		}
+		if IsRunningJobLost(job.JobID) {
+			llmJob.WriteLogFile("the job is taken over by another instance, stop it", "error")
+			return
+		}
 		processJson, err := json.Marshal(llmJob.Process)
 		if err != nil {
 			llmJob.WriteLogFile(fmt.Sprintf("marshal process error: %v", err), "error")
@@ -86,8 +109,8 @@ func RunFileJob(job *db.LLMJob) {
 		}).Error
 		if err != nil {
 			llmJob.WriteLogFile(fmt.Sprintf("update process error: %v", err), "error")
-			return
 		}
+		notifyJob(job, llmJob.Process)
 	}()
 	err := llmJob.AddLogFile()
 	if err != nil {
@@ -142,7 +165,14 @@ func RunFileJob(job *db.LLMJob) {
 
 	llmJob.WriteLogFile(fmt.Sprintf("start run file job, file path: %s", job.File), "info")
 
-	filePath := path.Join(config.GetConfig().File.UploadDir, llmJob.LLMJob.File)
+	// the file is uploaded into the namespace of the job owner
+	uploadDir := utils.UserUploadDir(config.GetConfig().File.UploadDir, llmJob.LLMJob.Host, llmJob.LLMJob.UserName)
+	filePath, err := utils.ResolveUploadPath(uploadDir, llmJob.LLMJob.File)
+	if err != nil {
+		llmJob.WriteLogFile(fmt.Sprintf("read file error: %v", err), "error")
+		llmJob.SetJobFailed(err)
+		return
+	}
 	text, err := llmJob.ReadFile(filePath)
 	if err != nil {
 		llmJob.WriteLogFile(fmt.Sprintf("read file error: %v", err), "error")
@@ -163,6 +193,7 @@ func RunFileJob(job *db.LLMJob) {
 		llmJob.SetJobFailed(err)
 		return
 	}
+	llmJob.ResolveEntities()
 	llmJob.Process.Ratio = 0.8
 
 	gqlPath := filepath.Join(config.GetConfig().LLM.GQLPath, fmt.Sprintf("%s/%s.ngql", llmJob.LLMJob.JobID, llmJob.LLMJob.File))
@@ -183,17 +214,27 @@ func RunFileJob(job *db.LLMJob) {
 }
 
 func (i *ImportJob) SyncProcess(job *db.LLMJob) {
-	for {
-		// stop
-		if job.Status != base.LLMStatusRunning {
-			return
-		}
+	events := newJobEvents(job.JobID)
+	for tick := 0; ; tick++ {
 		jsonStr, err := json.Marshal(i.Process)
 		if err != nil {
 			i.WriteLogFile(fmt.Sprintf("marshal process error: %v", err), "error")
 			continue
 		}
 		job.Process = datatypes.JSON(jsonStr)
+		events.publish(job)
+		// stop
+		if job.Status != base.LLMStatusRunning {
+			return
+		}
+		// persist the process for the other instances, which can not read it from memory
+		if tick%5 == 0 {
+			err = db.CtxDB.Model(&db.LLMJob{}).Where("job_id = ? AND status = ?", job.JobID, base.LLMStatusRunning).
+				Update("process", job.Process).Error
+			if err != nil {
+				i.WriteLogFile(fmt.Sprintf("update process error: %v", err), "error")
+			}
+		}
 		time.Sleep(time.Second)
 	}
 }
@@ -264,6 +305,9 @@ func (i *ImportJob) ReadFile(filePath string) (string, error) {
 }
 
 func (i *ImportJob) SplitText(str string) (blocks []string, err error) {
+	if i.LLMJob.ChunkStrategy == chunk.StrategyTokens {
+		return i.splitTokens(str)
+	}
 	// split text to each blocks for llm context length limit
 	blocks = make([]string, 0)
 	lines := strings.Split(str, "\n")
@@ -286,6 +330,36 @@ func (i *ImportJob) SplitText(str string) (blocks []string, err error) {
 	return blocks, nil
 }
 
+// tokenizer estimates the tokens for the model of the llm config, for both the chunks and the rate limits
+func (i *ImportJob) tokenizer() *chunk.Tokenizer {
+	var extra struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal([]byte(i.LLMConfig.Config), &extra)
+	return chunk.ForModel(string(i.LLMConfig.APIType), extra.Model)
+}
+
+/*
+splitTokens splits the text by the tokens estimated for the model of the llm config. The chunk size of the job defaults to
+half of the context length limit left by the prompt, and the other half is left to the answer.
+*/
+func (i *ImportJob) splitTokens(str string) ([]string, error) {
+	tokenizer := i.tokenizer()
+	size := i.LLMJob.ChunkSize
+	if size <= 0 {
+		promptTokens := tokenizer.Count(i.GetPrompt(""))
+		size = (i.LLMConfig.ContextLengthLimit - promptTokens) / 2
+		if size < chunk.MinSize {
+			return nil, fmt.Errorf("the context length limit %d leaves no room for the text after the prompt of about %d tokens",
+				i.LLMConfig.ContextLengthLimit, promptTokens)
+		}
+	}
+	blocks := chunk.Split(str, tokenizer, chunk.Options{Size: size, Overlap: i.LLMJob.ChunkOverlap})
+	i.WriteLogFile(fmt.Sprintf("split text success, tokenizer: %s, chunk size: %d, overlap: %d, blocks length: %d",
+		tokenizer.Family, size, i.LLMJob.ChunkOverlap, len(blocks)), "info")
+	return blocks, nil
+}
+
 func (i *ImportJob) ParseSchema(text string) error {
 	schema := Schema{}
 	err := json.Unmarshal([]byte(text), &schema)
@@ -334,57 +408,284 @@ type Node struct {
 }
 
 type Edge struct {
-	Src      string         `json:"src"`
-	Dst      string         `json:"dst"`
+	Src string `json:"src"`
+	Dst string `json:"dst"`
+	// the node types of the src and the dst, empty if the answer leaves them out
+	SrcType  string         `json:"srcType,omitempty"`
+	DstType  string         `json:"dstType,omitempty"`
 	EdgeType string         `json:"edgeType"`
 	Props    map[string]any `json:"props"`
 }
 
-func (i *ImportJob) ParseText(text string) {
-	// remove ```json and ``` in text
-	text = strings.ReplaceAll(text, "```json", "")
-	text = strings.ReplaceAll(text, "```", "")
-	text = strings.ReplaceAll(text, "\n", "")
+// spec is the schema of the space the answers are validated against
+func (i *ImportJob) spec() *structured.Spec {
+	spec := &structured.Spec{}
+	convert := func(name string, fields []Field) structured.Type {
+		t := structured.Type{Name: name}
+		for _, field := range fields {
+			t.Props = append(t.Props, structured.Prop{Name: field.Name, DataType: field.DataType, Nullable: field.Nullable})
+		}
+		return t
+	}
+	for _, tag := range i.Schema.NodeTypes {
+		spec.NodeTypes = append(spec.NodeTypes, convert(tag.Type, tag.Props))
+	}
+	for _, edge := range i.Schema.EdgeTypes {
+		spec.EdgeTypes = append(spec.EdgeTypes, convert(edge.Type, edge.Props))
+	}
+	return spec
+}
 
-	jsonObj := LLMResult{}
-	err := json.Unmarshal([]byte(text), &jsonObj)
+/*
+parseAnswer parses the nodes and the edges answered by the llm, and validates them against the schema of the space.
+The error is returned when the answer is not json, and the problems when the json does not fit the schema.
+*/
+func (i *ImportJob) parseAnswer(text string) (*LLMResult, []string, error) {
+	data := []byte(structured.Extract(text))
+	problems, err := i.spec().Validate(data)
 	if err != nil {
-		i.WriteLogFile(fmt.Sprintf("parse text error: %v, str:%s", err, text), "error")
-		return
+		return nil, nil, fmt.Errorf("parse text error: %v, str:%s", err, text)
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+	jsonObj := LLMResult{}
+	if err = json.Unmarshal(data, &jsonObj); err != nil {
+		return nil, nil, fmt.Errorf("parse text error: %v, str:%s", err, text)
+	}
+	return &jsonObj, nil, nil
+}
+
+/*
+mergeResult merges the nodes and the edges of a block into the caches. A node is keyed by its type and normalized name,
+so the nodes of the same name and different types are kept apart, and the names in the alias list of the job are merged
+into the canonical names. An edge is keyed by its src, dst, their node types and the edge type, so the edges of different
+types between the same nodes are all kept. The node type of an endpoint left out by the answer is the type of the node
+of the same name in the block, if the name is of one type only. The merges of different names are written to the log.
+*/
+func (i *ImportJob) mergeResult(jsonObj *LLMResult) {
+	blockTypes := make(map[string]map[string]bool)
+	for _, node := range jsonObj.Nodes {
+		normalized := resolve.Normalize(i.resolveName(node.Name))
+		if blockTypes[normalized] == nil {
+			blockTypes[normalized] = make(map[string]bool)
+		}
+		blockTypes[normalized][node.Type] = true
+	}
+	nodeType := func(name string) string {
+		if types := blockTypes[resolve.Normalize(name)]; len(types) == 1 {
+			for typ := range types {
+				return typ
+			}
+		}
+		return ""
 	}
 	for _, node := range jsonObj.Nodes {
-		nowNode, ok := i.CacheNodes[node.Name]
+		name := i.resolveName(node.Name)
+		key := resolve.Key(node.Type, name)
+		nowNode, ok := i.CacheNodes[key]
 		if !ok {
-			i.CacheNodes[node.Name] = node
+			if name != node.Name {
+				i.WriteLogFile(fmt.Sprintf("resolve node %q of %s to %q", node.Name, node.Type, name), "info")
+			}
+			node.Name = name
+			i.CacheNodes[key] = node
 			continue
 		}
+		if node.Name != nowNode.Name {
+			i.WriteLogFile(fmt.Sprintf("merge node %q into %q of %s", node.Name, nowNode.Name, node.Type), "info")
+		}
 		if nowNode.Props == nil {
 			nowNode.Props = make(map[string]any)
+			i.CacheNodes[key] = nowNode
 		}
 		for key, value := range node.Props {
 			nowNode.Props[key] = value
 		}
 	}
 	for _, edge := range jsonObj.Edges {
-		src := edge.Dst
-		dst := edge.Src
-		if src != "" && dst != "" {
-			if _, ok := i.CacheEdges[src]; !ok {
-				i.CacheEdges[src] = make(map[string]Edge)
+		if strings.TrimSpace(edge.Src) == "" || strings.TrimSpace(edge.Dst) == "" {
+			continue
+		}
+		edge.Src, edge.Dst = i.resolveName(edge.Src), i.resolveName(edge.Dst)
+		if edge.SrcType == "" {
+			edge.SrcType = nodeType(edge.Src)
+		}
+		if edge.DstType == "" {
+			edge.DstType = nodeType(edge.Dst)
+		}
+		i.mergeEdge(edge)
+	}
+}
+
+// resolveName returns the canonical name of a name, the alias in the list of the job, or the first name seen of the same normalized name
+func (i *ImportJob) resolveName(name string) string {
+	if canonical, ok := i.aliases.Resolve(name); ok {
+		return canonical
+	}
+	if i.names == nil {
+		i.names = make(map[string]string)
+	}
+	normalized := resolve.Normalize(name)
+	if display, ok := i.names[normalized]; ok {
+		return display
+	}
+	display := resolve.Display(name)
+	i.names[normalized] = display
+	return display
+}
+
+func edgeKey(edge Edge) string {
+	return resolve.Key(edge.SrcType, edge.Src) + "\x00" + resolve.Key(edge.DstType, edge.Dst)
+}
+
+func (i *ImportJob) mergeEdge(edge Edge) {
+	key := edgeKey(edge)
+	if _, ok := i.CacheEdges[key]; !ok {
+		i.CacheEdges[key] = make(map[string]Edge)
+	}
+	nowEdge, ok := i.CacheEdges[key][edge.EdgeType]
+	if !ok {
+		i.CacheEdges[key][edge.EdgeType] = edge
+		return
+	}
+	if nowEdge.Props == nil {
+		nowEdge.Props = make(map[string]any)
+		i.CacheEdges[key][edge.EdgeType] = nowEdge
+	}
+	for key, value := range edge.Props {
+		nowEdge.Props[key] = value
+	}
+}
+
+/*
+ResolveEntities asks the llm to cluster the names of each node type referring to the same entity, when the job asks for it.
+The nodes of a cluster are merged into the first one, which keeps its props and takes the missing ones from the others,
+and the edges are moved to it unless the merged name is still the name of a node of another type. The clustering is best effort,
+a failed request or answer skips the names of the request.
+*/
+func (i *ImportJob) ResolveEntities() {
+	if !i.LLMJob.ResolveEntities {
+		return
+	}
+	names := make(map[string][]string)
+	for _, node := range i.CacheNodes {
+		names[node.Type] = append(names[node.Type], node.Name)
+	}
+	types := make([]string, 0, len(names))
+	for typ := range names {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	// the merged names of each type, the edges are moved by them
+	renames := make(map[string]map[string]string)
+	for _, typ := range types {
+		typeNames := names[typ]
+		sort.Strings(typeNames)
+		for start := 0; start+1 < len(typeNames); start += resolve.ClusterBatchSize {
+			batch := typeNames[start:]
+			if len(batch) > resolve.ClusterBatchSize {
+				batch = batch[:resolve.ClusterBatchSize]
 			}
-			nowEdge, ok := i.CacheEdges[src][dst]
-			if !ok {
-				i.CacheEdges[src][dst] = edge
+			format := i.getResponseFormat()
+			if format != nil {
+				format = map[string]any{"type": structured.FormatJSONObject}
+			}
+			text, _, err := i.query([]map[string]any{{"role": "user", "content": resolve.ClusterPrompt(typ, batch)}}, format)
+			if err != nil {
+				i.WriteLogFile(fmt.Sprintf("cluster the names of %s error: %v", typ, err), "error")
 				continue
 			}
-			if nowEdge.Props == nil {
-				nowEdge.Props = make(map[string]any)
+			clusters, err := resolve.ParseClusters(text, batch)
+			if err != nil {
+				i.WriteLogFile(err.Error(), "error")
+				continue
 			}
-			for key, value := range edge.Props {
-				nowEdge.Props[key] = value
+			for _, cluster := range clusters {
+				i.mergeCluster(typ, cluster, renames)
 			}
 		}
 	}
+	if len(renames) > 0 {
+		i.moveEdges(renames)
+	}
+}
+
+func (i *ImportJob) mergeCluster(typ string, cluster []string, renames map[string]map[string]string) {
+	canonicalKey := resolve.Key(typ, cluster[0])
+	canonical, ok := i.CacheNodes[canonicalKey]
+	if !ok {
+		return
+	}
+	if canonical.Props == nil {
+		canonical.Props = make(map[string]any)
+		i.CacheNodes[canonicalKey] = canonical
+	}
+	for _, name := range cluster[1:] {
+		key := resolve.Key(typ, name)
+		node, ok := i.CacheNodes[key]
+		if !ok || key == canonicalKey {
+			continue
+		}
+		for prop, value := range node.Props {
+			if _, ok := canonical.Props[prop]; !ok {
+				canonical.Props[prop] = value
+			}
+		}
+		delete(i.CacheNodes, key)
+		normalized := resolve.Normalize(node.Name)
+		if renames[normalized] == nil {
+			renames[normalized] = make(map[string]string)
+		}
+		renames[normalized][typ] = canonical.Name
+		i.WriteLogFile(fmt.Sprintf("merge node %q into %q of %s, clustered by the llm", node.Name, canonical.Name, typ), "info")
+	}
+}
+
+/*
+moveEdges moves the edges of the merged names to the canonical names by the node types of their endpoints,
+an endpoint of unknown node type is kept if the name is still the name of a node of another type.
+*/
+func (i *ImportJob) moveEdges(renames map[string]map[string]string) {
+	remaining := make(map[string]bool, len(i.CacheNodes))
+	for _, node := range i.CacheNodes {
+		remaining[resolve.Normalize(node.Name)] = true
+	}
+	rename := func(name, typ string) string {
+		normalized := resolve.Normalize(name)
+		byType := renames[normalized]
+		if len(byType) == 0 {
+			return name
+		}
+		if typ != "" {
+			if canonical, ok := byType[typ]; ok {
+				return canonical
+			}
+			return name
+		}
+		if len(byType) > 1 || remaining[normalized] {
+			i.WriteLogFile(fmt.Sprintf("keep the edges of %q, which is the name of the nodes of several types", name), "info")
+			return name
+		}
+		for _, canonical := range byType {
+			return canonical
+		}
+		return name
+	}
+	edges := i.CacheEdges
+	i.CacheEdges = make(map[string]map[string]Edge, len(edges))
+	for _, byType := range edges {
+		for _, edge := range byType {
+			edge.Src, edge.Dst = rename(edge.Src, edge.SrcType), rename(edge.Dst, edge.DstType)
+			i.mergeEdge(edge)
+		}
+	}
+}
+
+func (i *ImportJob) getResponseFormat() map[string]any {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.responseFormat
 }
 
 func (i *ImportJob) GetPrompt(text string) string {
@@ -395,155 +696,318 @@ func (i *ImportJob) GetPrompt(text string) string {
 	return i.Prompt
 }
 
+type blockPrompt struct {
+	index  int
+	prompt string
+}
+
+/*
+QueryBlocks queries the blocks by the workers of the concurrency in the llm config, under the rate limits of the config.
+The answers are merged in the order of the blocks as they finish, so the nodes and the edges are the same as querying one by one.
+The extraction of each block is kept as a checkpoint in the job dir, and the blocks with the same prompts are not queried again
+when the job is rerun or resumed.
+*/
 func (i *ImportJob) QueryBlocks(blocks []string) error {
 	job := i.LLMJob
-	maxBlocks := config.GetConfig().LLM.MaxBlockSize
-	for index, block := range blocks {
-		if index >= maxBlocks {
-			break
+	if maxBlocks := config.GetConfig().LLM.MaxBlockSize; len(blocks) > maxBlocks {
+		blocks = blocks[:maxBlocks]
+	}
+	opts := ratelimit.ParseOptions(i.LLMConfig.Config)
+	i.limiter = ratelimit.For(i.LLMConfig.ID, opts)
+	i.structured = structured.ParseOptions(i.LLMConfig.Config)
+	i.responseFormat = i.spec().ResponseFormat(i.structured.Format)
+	aliases, err := resolve.ParseAliases(job.Aliases)
+	if err != nil {
+		return fmt.Errorf("parse aliases error: %v", err)
+	}
+	i.aliases = aliases
+	checkpoints := checkpoint.New(filepath.Join(config.GetConfig().LLM.GQLPath, job.JobID))
+	i.WriteLogFile(fmt.Sprintf("start query blocks, blocks length: %d, concurrency: %d, rpm: %d, tpm: %d", len(blocks), opts.Concurrency, opts.RPM, opts.TPM), "info")
+
+	results := make([]*LLMResult, len(blocks))
+	done := make([]bool, len(blocks))
+	merged, finished := 0, 0
+	complete := func(index int, result *LLMResult) {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		results[index], done[index] = result, true
+		for merged < len(blocks) && done[merged] {
+			if results[merged] != nil {
+				i.mergeResult(results[merged])
+				results[merged] = nil
+			}
+			merged++
 		}
+		// update process
+		finished++
+		ratio := float64(finished) / float64(len(blocks))
+		i.Process.Ratio = 0.1 + ratio*0.6
+		i.Process.CurrentSize = int((ratio * float64(i.Process.TotalSize)))
+	}
+
+	prompts := make(chan blockPrompt)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Concurrency && w < len(blocks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for block := range prompts {
+				complete(block.index, i.queryBlock(checkpoints, block))
+			}
+		}()
+	}
+	stopped := false
+	skipped := 0
+	for index, block := range blocks {
 		if IsRunningJobStopped(job.JobID) {
-			return fmt.Errorf("job stopped")
+			stopped = true
+			break
 		}
 		prompt := i.GetPrompt(block)
-		text, err := i.Query(prompt)
-		if err != nil {
-			i.WriteLogFile(fmt.Sprintf("query error: %v", err), "error")
+		if result, ok := i.restoreBlock(checkpoints, index, prompt); ok {
+			skipped++
+			complete(index, result)
 			continue
 		}
-		i.ParseText(text)
-		// update process
-		ratio := float64(index+1) / float64(len(blocks))
-		i.Process.Ratio = 0.1 + ratio*0.6
-		i.Process.CurrentSize = int((ratio * float64(i.Process.TotalSize)))
+		prompts <- blockPrompt{index: index, prompt: prompt}
+	}
+	close(prompts)
+	wg.Wait()
+	if skipped > 0 {
+		i.WriteLogFile(fmt.Sprintf("%d blocks are restored from the checkpoints", skipped), "info")
+	}
+	if stopped {
+		return fmt.Errorf("job stopped")
+	}
+	return nil
+}
+
+// restoreBlock reads the extraction of the block from its checkpoint, the tokens paid for it are counted in the process too
+func (i *ImportJob) restoreBlock(checkpoints *checkpoint.Store, index int, prompt string) (*LLMResult, bool) {
+	block, ok := checkpoints.Load(index, checkpoint.Hash(prompt))
+	if !ok {
+		return nil, false
+	}
+	result := &LLMResult{}
+	if err := json.Unmarshal(block.Result, result); err != nil {
+		return nil, false
+	}
+	i.mu.Lock()
+	i.Process.PromptTokens += block.PromptTokens
+	i.Process.CompletionTokens += block.CompletionTokens
+	i.mu.Unlock()
+	return result, true
+}
+
+/*
+queryBlock extracts the nodes and the edges of a block and keeps them as the checkpoint, the result is nil if it fails.
+The answer not parsed or not fitting the schema is sent back to the llm with the problems, until it is valid or the repairs run out.
+*/
+func (i *ImportJob) queryBlock(checkpoints *checkpoint.Store, block blockPrompt) *LLMResult {
+	messages := []map[string]any{{"role": "user", "content": block.prompt}}
+	total := transformer.Usage{}
+	for attempt := 0; attempt <= i.structured.Repairs; attempt++ {
+		text, usage, err := i.query(messages, i.getResponseFormat())
+		if err != nil {
+			i.WriteLogFile(fmt.Sprintf("query error: %v, block: %d", err, block.index), "error")
+			return nil
+		}
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+
+		result, problems, err := i.parseAnswer(text)
+		var repair string
+		i.mu.Lock()
+		if err != nil {
+			i.Process.ParseFailures++
+			repair = structured.ParseErrorPrompt(err)
+		} else if len(problems) > 0 {
+			i.Process.ValidationFailures++
+			repair = structured.RepairPrompt(problems)
+		}
+		i.mu.Unlock()
+		if repair == "" {
+			i.saveBlock(checkpoints, block, text, result, total)
+			return result
+		}
+		i.WriteLogFile(fmt.Sprintf("invalid answer, block: %d, attempt: %d, %s", block.index, attempt+1, repair), "error")
+		messages = append(messages,
+			map[string]any{"role": "assistant", "content": text},
+			map[string]any{"role": "user", "content": repair},
+		)
 	}
+	// the dropped block is not kept, which is queried again when the job runs again
+	i.mu.Lock()
+	i.Process.FailedBlocks++
+	i.mu.Unlock()
+	i.WriteLogFile(fmt.Sprintf("drop the block %d after %d repairs", block.index, i.structured.Repairs), "error")
 	return nil
 }
 
+func (i *ImportJob) saveBlock(checkpoints *checkpoint.Store, block blockPrompt, text string, result *LLMResult, usage transformer.Usage) {
+	data, err := json.Marshal(result)
+	if err == nil {
+		err = checkpoints.Save(&checkpoint.Block{
+			Index:            block.index,
+			Hash:             checkpoint.Hash(block.prompt),
+			Response:         text,
+			Result:           data,
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+		})
+	}
+	if err != nil {
+		i.WriteLogFile(fmt.Sprintf("save checkpoint error: %v, block: %d", err, block.index), "error")
+	}
+}
+
 func (i *ImportJob) Query(prompt string) (string, error) {
+	text, _, err := i.query([]map[string]any{{"role": "user", "content": prompt}}, i.getResponseFormat())
+	return text, err
+}
+
+/*
+query asks the llm under the rate limits, and retries with backoff when the llm is rate limited or fails.
+The answer is asked in the response format, which is dropped for the job if the llm rejects it.
+*/
+func (i *ImportJob) query(messages []map[string]any, format map[string]any) (string, transformer.Usage, error) {
+	prompt, _ := messages[len(messages)-1]["content"].(string)
 	i.WriteLogFile(fmt.Sprintf("start query, prompt: %s", prompt), "info")
-	messages := make([]map[string]any, 0)
-	messages = append(messages, map[string]any{
-		"role":    "user",
-		"content": prompt,
-	})
-	res, err := FetchWithLLMConfig(i.LLMConfig, map[string]any{
-		"stream":   false,
-		"messages": messages,
-	}, func(str string) {})
+	limiter := i.limiter
+	if limiter == nil {
+		limiter = ratelimit.NewLimiter(0, 0)
+	}
+	tokenizer, estimate := i.tokenizer(), 0
+	for _, message := range messages {
+		content, _ := message["content"].(string)
+		estimate += tokenizer.Count(content)
+	}
+	res, err := i.fetch(limiter, estimate, messages, format)
+	var statusErr *transformer.StatusError
+	if format != nil && errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusBadRequest {
+		i.WriteLogFile(fmt.Sprintf("the llm rejects the response format %v, query without it: %v", format["type"], err), "error")
+		i.mu.Lock()
+		i.responseFormat = nil
+		i.mu.Unlock()
+		res, err = i.fetch(limiter, estimate, messages, nil)
+	}
 	if err != nil {
-		return "", err
+		return "", transformer.Usage{}, err
 	}
 	i.WriteLogFile(fmt.Sprintf("query success, res: %v", res), "info")
-	text := res["choices"].([]any)[0].(map[string]any)["message"].(map[string]any)["content"].(string)
-	i.Process.PromptTokens += int(res["usage"].(map[string]any)["prompt_tokens"].(float64))
-	i.Process.CompletionTokens += int(res["usage"].(map[string]any)["completion_tokens"].(float64))
-	return text, nil
+	choices, _ := res["choices"].([]any)
+	if len(choices) == 0 {
+		return "", transformer.Usage{}, fmt.Errorf("no answer in the response: %v", res)
+	}
+	choice, _ := choices[0].(map[string]any)
+	message, _ := choice["message"].(map[string]any)
+	text, _ := message["content"].(string)
+	usage := transformer.UsageOf(res)
+	limiter.Consume(usage.PromptTokens + usage.CompletionTokens - estimate)
+	i.mu.Lock()
+	i.Process.PromptTokens += usage.PromptTokens
+	i.Process.CompletionTokens += usage.CompletionTokens
+	i.mu.Unlock()
+	return text, usage, nil
 }
 
+func (i *ImportJob) fetch(limiter *ratelimit.Limiter, estimate int, messages []map[string]any, format map[string]any) (map[string]any, error) {
+	var res map[string]any
+	attempt := 0
+	err := ratelimit.Retry(context.Background(), ratelimit.DefaultRetries, func() error {
+		if attempt++; attempt > 1 {
+			i.WriteLogFile(fmt.Sprintf("retry query, attempt: %d", attempt), "info")
+		}
+		if err := limiter.Wait(context.Background(), estimate); err != nil {
+			return err
+		}
+		req := map[string]any{
+			"stream":   false,
+			"messages": messages,
+		}
+		if format != nil {
+			req["response_format"] = format
+		}
+		var err error
+		res, err = FetchWithLLMConfig(i.LLMConfig, req, func(str string) {})
+		return err
+	})
+	return res, err
+}
+
+/*
+MakeGQLFile makes the insert statements of the nodes and the edges, a statement per line.
+The props are rendered by the data types in the schema, the values which cannot be converted are dropped and logged,
+so the statements always parse.
+*/
 func (i *ImportJob) MakeGQLFile(filePath string) ([]string, error) {
 	i.WriteLogFile(fmt.Sprintf("start make gql file, nodes length: %d, edges length: %d", len(i.CacheNodes), len(i.CacheEdges)), "info")
 	gqls := make([]string, 0)
-	spaceVIDType := i.Schema.VidType
-	// if spaceVIDType == "FIXED_STRING(32)" {
-	regex := regexp.MustCompile(`\((\d+)\)`)
-	match := regex.FindStringSubmatch(spaceVIDType)
-	vidLength := 128
-	if len(match) > 1 {
-		len, err := strconv.Atoi(match[1])
-		if err == nil {
-			vidLength = len
-		}
-	}
-	isVidString := strings.Contains(spaceVIDType, "STRING")
+	vidType := i.Schema.VidType
+	nodeFields := make(map[string][]Field, len(i.Schema.NodeTypes))
+	for _, tag := range i.Schema.NodeTypes {
+		nodeFields[tag.Type] = tag.Props
+	}
+	edgeFields := make(map[string][]Field, len(i.Schema.EdgeTypes))
+	for _, edge := range i.Schema.EdgeTypes {
+		edgeFields[edge.Type] = edge.Props
+	}
+
+	// the node types of each name, the vid of a name of several types is qualified by the type
+	nameTypes := make(map[string]map[string]bool)
 	for _, v := range i.CacheNodes {
-		typ := v.Type
-		name := v.Name
-		if isVidString {
-			if len(name) > vidLength {
-				name = name[:vidLength]
-			}
+		normalized := resolve.Normalize(v.Name)
+		if nameTypes[normalized] == nil {
+			nameTypes[normalized] = make(map[string]bool)
+		}
+		nameTypes[normalized][v.Type] = true
+	}
+	vid := func(typ, name string) (string, bool) {
+		types := nameTypes[resolve.Normalize(name)]
+		if len(types) <= 1 {
+			return literal.VID(vidType, name), true
+		}
+		if typ == "" {
+			return "", false
 		}
+		return literal.VID(vidType, typedName(typ, name)), true
+	}
 
-		typeSchema, ok := i.SchemaMap[typ]
+	for _, key := range sortedKeys(i.CacheNodes) {
+		v := i.CacheNodes[key]
+		fields, ok := nodeFields[v.Type]
 		if !ok {
 			continue
 		}
-		props := v.Props
-		propsStr := ""
-		valueStr := ""
-
-		for key, field := range typeSchema {
-			value, ok := props[key]
-			if !ok || value == nil {
-				if field.Nullable {
-					continue
-				} else {
-					value = ""
-				}
-			}
-
-			if propsStr != "" {
-				propsStr += ","
-			}
-			propsStr += fmt.Sprintf("`%s`", key)
-			if valueStr != "" {
-				valueStr += ","
-			}
-			if strings.Contains(strings.ToLower(field.DataType), "string") {
-				valueStr += fmt.Sprintf(`"%s"`, value)
-			} else {
-				if value == nil {
-					valueStr += "null"
-				} else {
-					valueStr += fmt.Sprintf(`%s`, value)
-				}
-			}
-		}
-
-		gql := fmt.Sprintf("INSERT VERTEX `%s` ({props}) VALUES \"%s\":({value});", typ, name)
-		gql = strings.ReplaceAll(gql, "{props}", propsStr)
-		gql = strings.ReplaceAll(gql, "{value}", valueStr)
+		props, values := i.renderProps(fields, v.Props, fmt.Sprintf("node %q of %s", v.Name, v.Type))
+		id, _ := vid(v.Type, v.Name)
+		gql := fmt.Sprintf("INSERT VERTEX %s (%s) VALUES %s:(%s);", literal.Name(v.Type), props, id, values)
 		gqls = append(gqls, gql)
 	}
 
-	for _, src := range i.CacheEdges {
-		for _, dst := range src {
-			propsName := ""
-			propsValue := ""
-			typeSchema, ok := i.SchemaMap[dst.EdgeType]
+	for _, key := range sortedKeys(i.CacheEdges) {
+		edges := i.CacheEdges[key]
+		for _, edgeType := range sortedKeys(edges) {
+			edge := edges[edgeType]
+			fields, ok := edgeFields[edge.EdgeType]
 			if !ok {
 				continue
 			}
-			for key, field := range typeSchema {
-				value, ok := dst.Props[key]
-				if !ok {
-					if field.Nullable {
-						continue
-					} else {
-						value = ""
-					}
-				}
-				if propsName != "" {
-					propsName += ","
-				}
-				propsName += fmt.Sprintf("`%s`", key)
-				if propsValue != "" {
-					propsValue += ","
-				}
-				if strings.Contains(strings.ToLower(field.DataType), "string") {
-					propsValue += fmt.Sprintf(`"%v"`, value)
-				} else {
-					propsValue += fmt.Sprintf(`%v`, value)
-				}
+			src, srcOK := vid(edge.SrcType, edge.Src)
+			dst, dstOK := vid(edge.DstType, edge.Dst)
+			if !srcOK || !dstOK {
+				i.WriteLogFile(fmt.Sprintf("skip edge %q->%q of %s, the node type of an endpoint of several node types is unknown",
+					edge.Src, edge.Dst, edge.EdgeType), "info")
+				continue
 			}
-			gql := fmt.Sprintf("INSERT EDGE `%s` (%s) VALUES \"%s\"->\"%s\":(%s);", dst.EdgeType, propsName, dst.Src, dst.Dst, propsValue)
+			props, values := i.renderProps(fields, edge.Props, fmt.Sprintf("edge %q->%q of %s", edge.Src, edge.Dst, edge.EdgeType))
+			gql := fmt.Sprintf("INSERT EDGE %s (%s) VALUES %s->%s:(%s);", literal.Name(edge.EdgeType), props, src, dst, values)
 			gqls = append(gqls, gql)
 		}
 	}
 
 	gqlStr := strings.Join(gqls, "\n")
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(filePath, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, err
 	}
@@ -556,6 +1020,11 @@ func (i *ImportJob) MakeGQLFile(filePath string) ([]string, error) {
 	return gqls, nil
 }
 
+// typedName is the vid of a name of several node types, such as "Apple (company)" and "Apple (fruit)"
+func typedName(typ, name string) string {
+	return name + " (" + typ + ")"
+}
+
 func (i *ImportJob) RunGQLFile(gqls []string) error {
 	i.WriteLogFile(fmt.Sprintf("start run gql, gqls length: %d", len(gqls)), "info")
 	batchSize := config.GetConfig().LLM.GQLBatchSize
@@ -592,6 +1061,46 @@ func (i *ImportJob) RunGQLFile(gqls []string) error {
 	return nil
 }
 
+/*
+renderProps renders the props of a node or an edge in the order of the schema.
+The missing string not nullable is the empty string as before, and the other missing ones are left to the default values.
+*/
+func (i *ImportJob) renderProps(fields []Field, props map[string]any, owner string) (string, string) {
+	names := make([]string, 0, len(fields))
+	values := make([]string, 0, len(fields))
+	for _, field := range fields {
+		value, ok := props[field.Name]
+		if !ok || value == nil {
+			if field.Nullable {
+				continue
+			}
+			dataType := strings.ToLower(field.DataType)
+			if dataType != "string" && !strings.HasPrefix(dataType, "fixed_string") {
+				i.WriteLogFile(fmt.Sprintf("missing the property %s of the %s, which is not nullable", field.Name, owner), "error")
+				continue
+			}
+			value = ""
+		}
+		rendered, err := literal.Render(field.DataType, value)
+		if err != nil {
+			i.WriteLogFile(fmt.Sprintf("drop the property %s of the %s: %v", field.Name, owner, err), "error")
+			continue
+		}
+		names = append(names, literal.Name(field.Name))
+		values = append(values, rendered)
+	}
+	return strings.Join(names, ","), strings.Join(values, ",")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func replaceBackslash(s string) string {
 	return strings.ReplaceAll(s, "\\", "\\\\")
 }
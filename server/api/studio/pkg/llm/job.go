@@ -1,25 +1,31 @@
 package llm
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/base"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/lease"
 	"github.com/zeromicro/go-zero/core/logx"
 	"gorm.io/gorm"
 )
 
 var (
 	RunningJobMap = make(map[string]*db.LLMJob)
-	mu            sync.Mutex
+	// the running jobs whose leases have been taken over by other instances
+	lostJobs = make(map[string]bool)
+	mu       sync.Mutex
 )
 
 func RunJobs(jobs []*db.LLMJob, JobRunnerMap map[string]func(job *db.LLMJob)) {
 	for _, job := range jobs {
-		job.Status = base.LLMStatusRunning
-		err := db.CtxDB.Save(job).Error
-		if err != nil {
-			logx.Errorf("failed to update job status: %v", err)
+		if !claimJob(job) {
+			continue
 		}
 		mu.Lock()
 		RunningJobMap[job.JobID] = job
@@ -28,7 +34,11 @@ func RunJobs(jobs []*db.LLMJob, JobRunnerMap map[string]func(job *db.LLMJob)) {
 			defer func() {
 				mu.Lock()
 				delete(RunningJobMap, job.JobID)
+				delete(lostJobs, job.JobID)
 				mu.Unlock()
+				if err := lease.Release(lease.KindLLMJob, job.JobID); err != nil {
+					logx.Errorf("failed to release job lease: %v", err)
+				}
 			}()
 			runner := JobRunnerMap[job.JobType]
 			if runner != nil {
@@ -38,6 +48,69 @@ func RunJobs(jobs []*db.LLMJob, JobRunnerMap map[string]func(job *db.LLMJob)) {
 	}
 }
 
+/*
+claimJob leases the pending job to this instance and turns it to running,
+it returns false when the job has been picked up by another instance.
+*/
+func claimJob(job *db.LLMJob) bool {
+	ok, err := lease.Claim(lease.KindLLMJob, job.JobID)
+	if err != nil {
+		logx.Errorf("failed to claim job %s: %v", job.JobID, err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	result := db.CtxDB.Model(&db.LLMJob{}).
+		Where("job_id = ? AND status = ?", job.JobID, base.LLMStatusPending).
+		Update("status", base.LLMStatusRunning)
+	if result.Error != nil || result.RowsAffected == 0 {
+		if result.Error != nil {
+			logx.Errorf("failed to update job status: %v", result.Error)
+		}
+		lease.Release(lease.KindLLMJob, job.JobID)
+		return false
+	}
+	job.Status = base.LLMStatusRunning
+	return true
+}
+
+/*
+CancelJob cancels the job on the instance running it,
+the job is stopped locally or through its lease when it runs on another instance.
+*/
+func CancelJob(jobID string) error {
+	if GetRunningJob(jobID) != nil {
+		ChangeRunningJobStatus(jobID, base.LLMStatusCancel)
+		return nil
+	}
+	_, err := lease.RequestStop(lease.KindLLMJob, jobID)
+	return err
+}
+
+/*
+DeleteJob deletes a job with its files, the job is cancelled first when it has not completed.
+The files are removed before the records, so a failed deletion can be retried.
+*/
+func DeleteJob(job *db.LLMJob) error {
+	if job.Status == base.LLMStatusRunning || job.Status == base.LLMStatusPending {
+		if err := CancelJob(job.JobID); err != nil {
+			return fmt.Errorf("cancel job error: %v", err)
+		}
+	}
+	jobPath := filepath.Join(config.GetConfig().LLM.GQLPath, job.JobID)
+	if err := os.RemoveAll(jobPath); err != nil {
+		return fmt.Errorf("remove job path error: %v", err)
+	}
+	if err := db.CtxDB.Delete(job).Error; err != nil {
+		return fmt.Errorf("delete job error: %v", err)
+	}
+	if err := lease.Remove(lease.KindLLMJob, job.JobID); err != nil {
+		return fmt.Errorf("delete job lease error: %v", err)
+	}
+	return db.CtxDB.Where("llm_job_id = ?", job.ID).Delete(&db.TaskInfo{}).Error
+}
+
 func IsRunningJobStopped(jobID string) bool {
 	mu.Lock()
 	defer mu.Unlock()
@@ -57,6 +130,26 @@ func ChangeRunningJobStatus(jobID string, status base.LLMStatus) {
 	}
 }
 
+/*
+IsRunningJobLost reports whether the lease of the running job has been taken over by another instance,
+the job is cancelled and must not write its status, which belongs to the new owner.
+*/
+func IsRunningJobLost(jobID string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return lostJobs[jobID]
+}
+
+func runningJobs() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	ids := make([]string, 0, len(RunningJobMap))
+	for id := range RunningJobMap {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func GetRunningJob(jobID string) *db.LLMJob {
 	mu.Lock()
 	defer mu.Unlock()
@@ -73,6 +166,43 @@ func GetPendingJobs() []*db.LLMJob {
 	if err != nil && err != gorm.ErrRecordNotFound {
 		logx.Errorf("failed to get pending jobs: %v", err)
 	}
+	if config.GetConfig().AppInstance != "multi" {
+		return jobs
+	}
+	// the connection of the job owner only lives on the instances the user logged in,
+	// leave the job to one of them.
+	runnable := make([]*db.LLMJob, 0, len(jobs))
+	for _, job := range jobs {
+		if _, ok := auth.CtxUserInfoMap[fmt.Sprintf("%s:%s", job.Host, job.UserName)]; ok {
+			runnable = append(runnable, job)
+		}
+	}
+	return runnable
+}
 
-	return jobs
+func registerLeaseHandler() {
+	lease.Register(lease.KindLLMJob, lease.Handler{
+		OnStop: func(jobID string) error {
+			ChangeRunningJobStatus(jobID, base.LLMStatusCancel)
+			return nil
+		},
+		OnTakeover: func(jobID, prevOwner string) {
+			// the instance running the job is gone, queue the job again
+			err := db.CtxDB.Model(&db.LLMJob{}).
+				Where("job_id = ? AND status = ?", jobID, base.LLMStatusRunning).
+				Update("status", base.LLMStatusPending).Error
+			if err != nil {
+				logx.Errorf("failed to requeue job %s of %s: %v", jobID, prevOwner, err)
+			}
+		},
+		Running: runningJobs,
+		OnLost: func(jobID, newOwner string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if job, ok := RunningJobMap[jobID]; ok {
+				lostJobs[jobID] = true
+				job.Status = base.LLMStatusCancel
+			}
+		},
+	})
 }
@@ -0,0 +1,356 @@
+package literal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	fixedLength = regexp.MustCompile(`\((\d+)\)`)
+	isoDuration = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+	wkt         = regexp.MustCompile(`^(?i)(POINT|LINESTRING|POLYGON)\s*\(`)
+)
+
+// the layouts of the dates and the times answered by the llm
+var (
+	dateLayouts     = []string{"2006-01-02", "2006/01/02", "2006.01.02", "20060102"}
+	timeLayouts     = []string{"15:04:05.999999999", "15:04:05", "15:04"}
+	datetimeLayouts = []string{
+		time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999",
+		"2006-01-02T15:04", "2006-01-02 15:04", "2006/01/02 15:04:05",
+	}
+)
+
+// Name quotes the name of a tag, an edge type or a property
+func Name(name string) string {
+	return "`" + strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(name) + "`"
+}
+
+// String quotes a string, the line breaks are escaped so a statement keeps in a line
+func String(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			// the other control characters break the statement
+			if r < 0x20 || r == 0x7f || r == utf8.RuneError {
+				b.WriteByte(' ')
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// truncate cuts the string to n bytes at most, without breaking a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// VID renders the vid of a name in the vid type of the space, the name is hashed in the INT64 space
+func VID(vidType, name string) string {
+	vidType = strings.ToUpper(vidType)
+	if strings.Contains(vidType, "INT") {
+		return fmt.Sprintf("hash(%s)", String(name))
+	}
+	if match := fixedLength.FindStringSubmatch(vidType); match != nil {
+		if n, err := strconv.Atoi(match[1]); err == nil && n > 0 {
+			name = truncate(name, n)
+		}
+	}
+	return String(name)
+}
+
+/*
+Render renders the value answered by the llm as the nGQL literal of the data type of a property.
+The value is coerced to the type when it can be, such as the number in a string, and the error tells why it cannot.
+A nil value is NULL.
+*/
+func Render(dataType string, value any) (string, error) {
+	if value == nil {
+		return "NULL", nil
+	}
+	if number, ok := value.(json.Number); ok {
+		value = string(number)
+	}
+	t := strings.ToLower(strings.TrimSpace(dataType))
+	switch {
+	case t == "string" || strings.HasPrefix(t, "fixed_string"):
+		s, err := toString(value)
+		if err != nil {
+			return "", err
+		}
+		return String(s), nil
+	case strings.HasPrefix(t, "int"):
+		bits := 64
+		if n, err := strconv.Atoi(strings.TrimPrefix(t, "int")); err == nil {
+			bits = n
+		}
+		n, err := toInt(value, bits)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(n, 10), nil
+	case t == "float" || t == "double":
+		f, err := toFloat(value)
+		if err != nil {
+			return "", err
+		}
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s, nil
+	case t == "bool":
+		b, err := toBool(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	case t == "date":
+		d, err := parseTime(value, append(dateLayouts, datetimeLayouts...))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("date(%s)", String(d.Format("2006-01-02"))), nil
+	case t == "time":
+		d, err := parseTime(value, timeLayouts)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("time(%s)", String(d.Format("15:04:05.000000"))), nil
+	case t == "datetime":
+		d, err := parseTime(value, append(datetimeLayouts, dateLayouts...))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("datetime(%s)", String(d.Format("2006-01-02T15:04:05.000000"))), nil
+	case t == "timestamp":
+		// the seconds since the epoch, or a datetime
+		if n, err := toInt(value, 64); err == nil {
+			if n < 0 {
+				return "", fmt.Errorf("%d is not a valid timestamp", n)
+			}
+			return strconv.FormatInt(n, 10), nil
+		}
+		d, err := parseTime(value, append(datetimeLayouts, dateLayouts...))
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(d.Unix(), 10), nil
+	case t == "duration":
+		return renderDuration(value)
+	case strings.HasPrefix(t, "geography"):
+		return renderGeography(t, value)
+	}
+	return "", fmt.Errorf("unsupported data type %s", dataType)
+}
+
+func toString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("%s is not a string", describe(value))
+}
+
+func toInt(value any, bits int) (int64, error) {
+	var n int64
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) >= 1<<63 {
+			return 0, fmt.Errorf("%v is not an integer", v)
+		}
+		n = int64(v)
+	case string:
+		var err error
+		n, err = strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not an integer", v)
+		}
+	default:
+		return 0, fmt.Errorf("%s is not an integer", describe(value))
+	}
+	if bits < 64 && (n < -(1<<(bits-1)) || n >= 1<<(bits-1)) {
+		return 0, fmt.Errorf("%d is out of the range of int%d", n, bits)
+	}
+	return n, nil
+}
+
+func toFloat(value any) (float64, error) {
+	var f float64
+	switch v := value.(type) {
+	case float64:
+		f = v
+	case string:
+		var err error
+		f, err = strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v)
+		}
+	default:
+		return 0, fmt.Errorf("%s is not a number", describe(value))
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%v is not a finite number", f)
+	}
+	return f, nil
+}
+
+func toBool(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "yes", "y", "1":
+			return true, nil
+		case "false", "no", "n", "0":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("%s is not a bool", describe(value))
+}
+
+// parseTime parses the value in the layouts, the time with a zone is converted to UTC
+func parseTime(value any, layouts []string) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%s is not a date or time string", describe(value))
+	}
+	s = strings.TrimSpace(s)
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a valid date or time", s)
+}
+
+// renderDuration renders the ISO 8601 duration such as P1DT2H, or the duration such as 1h30m, as the duration of the map
+func renderDuration(value any) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s is not a duration string", describe(value))
+	}
+	s = strings.ToUpper(strings.TrimSpace(s))
+	parts := make([]string, 0, 7)
+	add := func(key string, n int64) {
+		if n != 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", key, n))
+		}
+	}
+	if match := isoDuration.FindStringSubmatch(s); match != nil && s != "P" && !strings.HasSuffix(s, "T") {
+		n := func(i int) int64 {
+			v, _ := strconv.ParseInt(match[i], 10, 64)
+			return v
+		}
+		seconds, _ := strconv.ParseFloat(match[7], 64)
+		add("years", n(1))
+		add("months", n(2))
+		// the weeks are not a unit of the duration
+		add("days", n(3)*7+n(4))
+		add("hours", n(5))
+		add("minutes", n(6))
+		add("seconds", int64(seconds))
+		add("microseconds", int64(math.Round((seconds-math.Trunc(seconds))*1e6)))
+	} else if d, err := time.ParseDuration(strings.ToLower(s)); err == nil {
+		add("seconds", int64(d/time.Second))
+		add("microseconds", int64(d%time.Second)/int64(time.Microsecond))
+	} else {
+		return "", fmt.Errorf("%q is not a valid duration", s)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "seconds: 0")
+	}
+	return fmt.Sprintf("duration({%s})", strings.Join(parts, ", ")), nil
+}
+
+// renderGeography renders the WKT, or the point of [longitude, latitude] or {"longitude": .., "latitude": ..}
+func renderGeography(dataType string, value any) (string, error) {
+	// geography(point) accepts the points only
+	shape := ""
+	if i := strings.Index(dataType, "("); i >= 0 {
+		shape = strings.ToUpper(strings.Trim(dataType[i:], "() "))
+	}
+	var lng, lat any
+	switch v := value.(type) {
+	case string:
+		match := wkt.FindStringSubmatch(strings.TrimSpace(v))
+		if match == nil {
+			return "", fmt.Errorf("%q is not a valid WKT", v)
+		}
+		if shape != "" && strings.ToUpper(match[1]) != shape {
+			return "", fmt.Errorf("%q is not a %s", v, strings.ToLower(shape))
+		}
+		return fmt.Sprintf("ST_GeogFromText(%s)", String(strings.TrimSpace(v))), nil
+	case []any:
+		if len(v) != 2 {
+			return "", fmt.Errorf("%s is not a point of [longitude, latitude]", describe(value))
+		}
+		lng, lat = v[0], v[1]
+	case map[string]any:
+		lng, lat = v["longitude"], v["latitude"]
+		if lng == nil {
+			lng, lat = v["lng"], v["lat"]
+		}
+	default:
+		return "", fmt.Errorf("%s is not a geography", describe(value))
+	}
+	if shape != "" && shape != "POINT" {
+		return "", fmt.Errorf("%s is not a %s", describe(value), strings.ToLower(shape))
+	}
+	x, err := toFloat(lng)
+	if err != nil {
+		return "", err
+	}
+	y, err := toFloat(lat)
+	if err != nil {
+		return "", err
+	}
+	if x < -180 || x > 180 || y < -90 || y > 90 {
+		return "", fmt.Errorf("%s is out of the range of the longitude and the latitude", describe(value))
+	}
+	return fmt.Sprintf("ST_Point(%s, %s)", strconv.FormatFloat(x, 'f', -1, 64), strconv.FormatFloat(y, 'f', -1, 64)), nil
+}
+
+func describe(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
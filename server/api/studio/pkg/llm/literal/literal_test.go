@@ -0,0 +1,85 @@
+package literal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		dataType string
+		value    any
+		expected string
+	}{
+		{"string", `say "hi"\` + "\nbye", `"say \"hi\"\\\nbye"`},
+		{"fixed_string(32)", float64(1000000), `"1000000"`},
+		{"string", true, `"true"`},
+		{"int64", float64(42), "42"},
+		{"int64", " 42 ", "42"},
+		{"INT8", json.Number("-128"), "-128"},
+		{"double", float64(3), "3.0"},
+		{"float", "1.5e3", "1500.0"},
+		{"bool", "Yes", "true"},
+		{"bool", float64(0), "false"},
+		{"date", "2024-02-29", `date("2024-02-29")`},
+		{"date", "2024-02-29T10:00:00Z", `date("2024-02-29")`},
+		{"time", "09:30", `time("09:30:00.000000")`},
+		{"datetime", "2024-02-29 10:20:30", `datetime("2024-02-29T10:20:30.000000")`},
+		{"datetime", "2024-02-29T10:20:30+08:00", `datetime("2024-02-29T02:20:30.000000")`},
+		{"timestamp", float64(1700000000), "1700000000"},
+		{"timestamp", "1970-01-02T00:00:00Z", "86400"},
+		{"duration", "P1Y2W3DT4H5M6.5S", `duration({years: 1, days: 17, hours: 4, minutes: 5, seconds: 6, microseconds: 500000})`},
+		{"duration", "1h30m", `duration({seconds: 5400})`},
+		{"geography", "POINT(3 8)", `ST_GeogFromText("POINT(3 8)")`},
+		{"geography(point)", []any{float64(120.5), float64(30)}, `ST_Point(120.5, 30)`},
+		{"geography(point)", map[string]any{"longitude": "1", "latitude": float64(2)}, `ST_Point(1, 2)`},
+		{"int64", nil, "NULL"},
+	}
+	for _, c := range cases {
+		got, err := Render(c.dataType, c.value)
+		if err != nil || got != c.expected {
+			t.Errorf("Render(%s, %#v) = %s %v, expected %s", c.dataType, c.value, got, err, c.expected)
+		}
+	}
+
+	invalid := []struct {
+		dataType string
+		value    any
+	}{
+		{"int64", float64(1.5)},
+		{"int64", "forty-two"},
+		{"int8", float64(128)},
+		{"double", "NaN"},
+		{"bool", "maybe"},
+		{"string", map[string]any{"a": float64(1)}},
+		{"date", "29/02/2024"},
+		{"datetime", float64(1)},
+		{"timestamp", float64(-1)},
+		{"duration", "P"},
+		{"geography(point)", "LINESTRING(0 0, 1 1)"},
+		{"geography", []any{float64(200), float64(0)}},
+		{"list", "a"},
+	}
+	for _, c := range invalid {
+		if got, err := Render(c.dataType, c.value); err == nil {
+			t.Errorf("Render(%s, %#v) = %s, expect an error", c.dataType, c.value, got)
+		}
+	}
+}
+
+func TestVID(t *testing.T) {
+	cases := map[[2]string]string{
+		{"FIXED_STRING(32)", `Tom "T"`}: `"Tom \"T\""`,
+		{"FIXED_STRING(4)", "北京市"}:      `"北"`,
+		{"INT64", "Tom"}:                `hash("Tom")`,
+		{"FIXED_STRING(8)", "a\x00\nb"}: `"a \nb"`,
+	}
+	for args, expected := range cases {
+		if got := VID(args[0], args[1]); got != expected {
+			t.Errorf("VID(%q, %q) = %s, expected %s", args[0], args[1], got, expected)
+		}
+	}
+	if got := Name("a`b"); got != "`a\\`b`" {
+		t.Errorf("unexpected name %s", got)
+	}
+}
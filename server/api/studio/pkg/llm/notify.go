@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"fmt"
+	"net/url"
+
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/base"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/notify"
+)
+
+// notifyJob sends the final status of a job to the default notify channels of its owner
+func notifyJob(job *db.LLMJob, process *base.Process) {
+	var event string
+	switch job.Status {
+	case base.LLMStatusSuccess:
+		event = notify.EventFinished
+	case base.LLMStatusFailed:
+		event = notify.EventFailed
+	case base.LLMStatusCancel:
+		event = notify.EventStopped
+	default:
+		return
+	}
+	notify.Notify(job.Host, job.UserName, nil, &notify.Payload{
+		Event:   event,
+		Kind:    notify.KindLLMJob,
+		ID:      job.JobID,
+		Name:    job.File,
+		Space:   job.Space,
+		Status:  string(job.Status),
+		Message: process.FailedReason,
+		Stats:   process,
+		LogsURL: notify.LogsURL(fmt.Sprintf("/api/llm/import/job/log?jobId=%s", url.QueryEscape(job.JobID))),
+	})
+}
@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/transformer"
+	"golang.org/x/time/rate"
+)
+
+const (
+	DefaultConcurrency = 1
+	MaxConcurrency     = 32
+
+	DefaultRetries = 5
+	baseBackoff    = time.Second
+	maxBackoff     = time.Minute
+)
+
+// Options are the limits of a llm config, which are read from its extra config, such as {"concurrency": 4, "rpm": 60, "tpm": 90000}
+type Options struct {
+	// the blocks of a job queried at the same time
+	Concurrency int `json:"concurrency"`
+	// the requests and the tokens per minute of all the jobs of the config, 0 means no limit
+	RPM int `json:"rpm"`
+	TPM int `json:"tpm"`
+}
+
+// ParseOptions reads the limits from the extra config of a llm config
+func ParseOptions(extra string) Options {
+	var raw struct {
+		Concurrency float64 `json:"concurrency"`
+		RPM         float64 `json:"rpm"`
+		TPM         float64 `json:"tpm"`
+	}
+	json.Unmarshal([]byte(extra), &raw)
+	opts := Options{Concurrency: int(raw.Concurrency), RPM: int(raw.RPM), TPM: int(raw.TPM)}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.Concurrency > MaxConcurrency {
+		opts.Concurrency = MaxConcurrency
+	}
+	if opts.RPM < 0 {
+		opts.RPM = 0
+	}
+	if opts.TPM < 0 {
+		opts.TPM = 0
+	}
+	return opts
+}
+
+// Limiter limits the requests and the tokens per minute sent to a llm
+type Limiter struct {
+	requests *rate.Limiter
+	tokens   *rate.Limiter
+}
+
+func perMinute(n int) *rate.Limiter {
+	if n <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(float64(n)/60), n)
+}
+
+// NewLimiter creates a limiter of rpm requests and tpm tokens per minute, 0 means no limit
+func NewLimiter(rpm, tpm int) *Limiter {
+	return &Limiter{requests: perMinute(rpm), tokens: perMinute(tpm)}
+}
+
+// Wait blocks until a request of the estimated tokens is allowed
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	if l.requests != nil {
+		if err := l.requests.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if l.tokens != nil {
+		// a request larger than the limit waits for the full bucket
+		if burst := l.tokens.Burst(); tokens > burst {
+			tokens = burst
+		}
+		if err := l.tokens.WaitN(ctx, tokens); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Consume counts the tokens used beyond the estimate, which delays the next requests.
+The tokens are reserved in pieces of the burst, as a reservation larger than the burst is refused and counts nothing.
+*/
+func (l *Limiter) Consume(tokens int) {
+	if l.tokens == nil {
+		return
+	}
+	now := time.Now()
+	burst := l.tokens.Burst()
+	for tokens > 0 {
+		n := tokens
+		if n > burst {
+			n = burst
+		}
+		l.tokens.ReserveN(now, n)
+		tokens -= n
+	}
+}
+
+var (
+	mu       sync.Mutex
+	limiters = make(map[string]*Limiter)
+)
+
+// For returns the limiter shared by the jobs of a llm config, a new one is created when the limits change
+func For(id int, opts Options) *Limiter {
+	key := fmt.Sprintf("%d/%d/%d", id, opts.RPM, opts.TPM)
+	mu.Lock()
+	defer mu.Unlock()
+	l, ok := limiters[key]
+	if !ok {
+		l = NewLimiter(opts.RPM, opts.TPM)
+		limiters[key] = l
+	}
+	return l
+}
+
+// backoff is the wait before the retry of the attempt, doubled for each attempt with a jitter
+func backoff(attempt int) time.Duration {
+	d := baseBackoff << attempt
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Retryable reports whether the request can be retried, which is rate limited or failed by the server, and the wait the server asks
+func Retryable(err error) (bool, time.Duration) {
+	var statusErr *transformer.StatusError
+	if !errors.As(err, &statusErr) {
+		return false, 0
+	}
+	return statusErr.StatusCode == 429 || statusErr.StatusCode >= 500, statusErr.RetryAfter
+}
+
+// Retry calls fn until it succeeds, fails with an error not retryable, or the retries run out
+func Retry(ctx context.Context, retries int, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		retryable, wait := Retryable(err)
+		if !retryable || attempt >= retries {
+			return err
+		}
+		if wait <= 0 {
+			wait = backoff(attempt)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/transformer"
+)
+
+func TestParseOptions(t *testing.T) {
+	for extra, expected := range map[string]Options{
+		`{"model":"gpt-4","concurrency":4,"rpm":60,"tpm":90000}`: {Concurrency: 4, RPM: 60, TPM: 90000},
+		`{"concurrency":100,"rpm":-1}`:                           {Concurrency: MaxConcurrency},
+		"":                                                       {Concurrency: DefaultConcurrency},
+	} {
+		if opts := ParseOptions(extra); opts != expected {
+			t.Errorf("unexpected options of %s: got %v, want %v", extra, opts, expected)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	// 100 tokens per second
+	l := NewLimiter(0, 6000)
+	start := time.Now()
+	if err := l.Wait(ctx, 10000); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 50*time.Millisecond {
+		t.Errorf("the full bucket should be taken at once")
+	}
+	// the tokens used beyond the estimate delay the next request
+	l.Consume(10)
+	start = time.Now()
+	if err := l.Wait(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expect the wait for the consumed tokens, got %s", elapsed)
+	}
+
+	// the tokens used beyond the burst are all counted
+	l = NewLimiter(0, 6000)
+	if err := l.Wait(ctx, 6000); err != nil {
+		t.Fatal(err)
+	}
+	l.Consume(15000)
+	if delay := l.tokens.ReserveN(time.Now(), 1).Delay(); delay < 149*time.Second {
+		t.Errorf("expect the wait for all the consumed tokens, got %s", delay)
+	}
+
+	if For(1, Options{RPM: 10}) != For(1, Options{RPM: 10, Concurrency: 2}) || For(1, Options{RPM: 10}) == For(1, Options{RPM: 20}) {
+		t.Error("the limiter should be shared by the same limits of a config")
+	}
+	if err := NewLimiter(0, 0).Wait(ctx, 1<<30); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	err := Retry(ctx, 3, func() error {
+		if calls++; calls < 3 {
+			return &transformer.StatusError{StatusCode: 429 + (calls-1)*71, RetryAfter: time.Millisecond}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expect the success after the retries of 429 and 500, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = Retry(ctx, 3, func() error {
+		calls++
+		return &transformer.StatusError{StatusCode: 400}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expect no retry of 400, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = Retry(ctx, 2, func() error {
+		calls++
+		return fmt.Errorf("wrapped: %w", &transformer.StatusError{StatusCode: 503, RetryAfter: time.Millisecond})
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("expect the retries run out, got %v after %d calls", err, calls)
+	}
+	if d := backoff(10); d > maxBackoff || d < maxBackoff/2 {
+		t.Errorf("unexpected backoff: %s", d)
+	}
+}
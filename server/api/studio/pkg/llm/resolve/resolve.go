@@ -0,0 +1,143 @@
+package resolve
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/llm/structured"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ClusterBatchSize is the most names of a type sent to the llm to cluster at a time
+const ClusterBatchSize = 100
+
+var fold = cases.Fold()
+
+// Normalize returns the name compared to the others, in the unicode compatibility form, folded case and collapsed whitespace
+func Normalize(name string) string {
+	return strings.Join(strings.Fields(fold.String(norm.NFKC.String(name))), " ")
+}
+
+// Display returns the name written to the graph, with the whitespace collapsed
+func Display(name string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(name)), " ")
+}
+
+// Key is the key of a node, the nodes of different types are different entities even if they have the same name
+func Key(typ, name string) string {
+	return typ + "\x00" + Normalize(name)
+}
+
+// Aliases maps the names of an entity to its canonical name
+type Aliases struct {
+	canonical map[string]string
+}
+
+func NewAliases() *Aliases {
+	return &Aliases{canonical: make(map[string]string)}
+}
+
+/*
+ParseAliases parses the alias list of a job, a line per entity with the canonical name and its aliases, such as
+
+	Apple Inc.: Apple, AAPL, 苹果公司
+
+the empty lines and the lines starting with # are skipped.
+*/
+func ParseAliases(text string) (*Aliases, error) {
+	a := NewAliases()
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		canonical, aliases, ok := strings.Cut(line, ":")
+		if !ok {
+			canonical, aliases, ok = strings.Cut(line, "：")
+		}
+		canonical = Display(canonical)
+		if !ok || canonical == "" {
+			return nil, fmt.Errorf("line %d: expect the canonical name and the aliases separated by a colon, such as \"Apple Inc.: Apple, AAPL\"", n+1)
+		}
+		names := strings.FieldsFunc(aliases, func(r rune) bool { return r == ',' || r == '，' })
+		if err := a.Add(canonical, names...); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+	}
+	return a, nil
+}
+
+// Add merges the aliases into the canonical name, an alias belongs to one entity only
+func (a *Aliases) Add(canonical string, aliases ...string) error {
+	canonical = Display(canonical)
+	for _, name := range append([]string{canonical}, aliases...) {
+		key := Normalize(name)
+		if key == "" {
+			continue
+		}
+		if prev, ok := a.canonical[key]; ok && prev != canonical {
+			return fmt.Errorf("%q is the alias of both %q and %q", Display(name), prev, canonical)
+		}
+		a.canonical[key] = canonical
+	}
+	return nil
+}
+
+// Resolve returns the canonical name of the name, and whether it is in the list
+func (a *Aliases) Resolve(name string) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	canonical, ok := a.canonical[Normalize(name)]
+	return canonical, ok
+}
+
+const clusterTemplate = `The following names are the entities of the type "%s" extracted from a text:
+%s
+Group the names referring to the same real-world entity, such as the full name, the abbreviation, the nickname or the translation of it.
+Answer the groups of two or more names only, with the most complete and formal name first, using the names in the list exactly.
+Return the results directly, without explain and comment, in the following JSON format:
+{"clusters": [["canonical name", "alias", ...]]}`
+
+// ClusterPrompt asks the llm to group the names of the same entity
+func ClusterPrompt(typ string, names []string) string {
+	data, _ := json.Marshal(names)
+	return fmt.Sprintf(clusterTemplate, typ, data)
+}
+
+// ParseClusters parses the groups answered by the llm, the names not in the asked names and the groups of one name are dropped
+func ParseClusters(answer string, names []string) ([][]string, error) {
+	var result struct {
+		Clusters [][]string `json:"clusters"`
+	}
+	if err := json.Unmarshal([]byte(structured.Extract(answer)), &result); err != nil {
+		return nil, fmt.Errorf("parse clusters error: %v, str:%s", err, answer)
+	}
+	asked := make(map[string]string, len(names))
+	for _, name := range names {
+		asked[Normalize(name)] = name
+	}
+	clusters := make([][]string, 0, len(result.Clusters))
+	// a name is in the first group of two or more names only
+	grouped := make(map[string]bool)
+	for _, cluster := range result.Clusters {
+		group, keys := make([]string, 0, len(cluster)), make(map[string]bool, len(cluster))
+		for _, name := range cluster {
+			key := Normalize(name)
+			if original, ok := asked[key]; ok && !grouped[key] && !keys[key] {
+				keys[key] = true
+				group = append(group, original)
+			}
+		}
+		if len(group) < 2 {
+			continue
+		}
+		for key := range keys {
+			grouped[key] = true
+		}
+		clusters = append(clusters, group)
+	}
+	return clusters, nil
+}
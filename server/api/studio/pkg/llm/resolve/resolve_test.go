@@ -0,0 +1,67 @@
+package resolve
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"  Apple   Inc. ": "apple inc.",
+		"ＡＰＰＬＥ":           "apple",
+		"Straße":          "strasse",
+		"Ｔｏｍ\tSmith":      "tom smith",
+	}
+	for name, expected := range cases {
+		if got := Normalize(name); got != expected {
+			t.Errorf("Normalize(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+	if Key("company", "Apple") == Key("fruit", "Apple") {
+		t.Error("expect the keys of different types to differ")
+	}
+	if Key("company", "apple ") != Key("company", "APPLE") {
+		t.Error("expect the keys of the same normalized name to be equal")
+	}
+}
+
+func TestAliases(t *testing.T) {
+	a, err := ParseAliases(`
+# companies
+Apple Inc.: Apple, AAPL
+International Business Machines：IBM，Big Blue
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]string{"aapl": "Apple Inc.", " apple ": "Apple Inc.", "big blue": "International Business Machines", "apple inc.": "Apple Inc."} {
+		if got, ok := a.Resolve(name); !ok || got != expected {
+			t.Errorf("Resolve(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+	if _, ok := a.Resolve("Google"); ok {
+		t.Error("expect Google not in the aliases")
+	}
+	if _, err = ParseAliases("Apple Inc.: Apple\nApple Corps: Apple"); err == nil {
+		t.Error("expect the error of an alias of two entities")
+	}
+	if _, err = ParseAliases("Apple Inc."); err == nil {
+		t.Error("expect the error of a line without the colon")
+	}
+}
+
+func TestParseClusters(t *testing.T) {
+	names := []string{"Apple Inc.", "Apple", "AAPL", "Google", "Alphabet"}
+	answer := "```json\n" + `{"clusters": [["Apple Inc.", "apple", "AAPL"], ["Google"], ["Alphabet", "Google", "Unknown"], ["AAPL", "Alphabet"]]}` + "\n```"
+	clusters, err := ParseClusters(answer, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{"Apple Inc.", "Apple", "AAPL"}, {"Alphabet", "Google"}}
+	if !reflect.DeepEqual(clusters, expected) {
+		t.Errorf("unexpected clusters %v", clusters)
+	}
+	if _, err = ParseClusters("no clusters", names); err == nil {
+		t.Error("expect parse error")
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/lease"
 )
 
 type LLMJob = db.LLMJob
@@ -20,6 +21,8 @@ func InitSchedule() {
 			panic(err)
 		}
 	}
+	registerLeaseHandler()
+	lease.Start()
 	for {
 		jobs := GetPendingJobs()
 		RunJobs(jobs, map[string]func(job *db.LLMJob){
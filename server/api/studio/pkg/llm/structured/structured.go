@@ -0,0 +1,422 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FormatJSONSchema asks the llm to answer in the json schema of the space
+	FormatJSONSchema = "json_schema"
+	// FormatJSONObject asks the llm to answer a json object
+	FormatJSONObject = "json_object"
+	// FormatNone leaves the format to the prompt, for the llm without the structured output
+	FormatNone = "none"
+
+	DefaultRepairs = 2
+	MaxRepairs     = 5
+
+	// maxProblems limits the problems sent back to the llm
+	maxProblems = 20
+)
+
+// Options are the structured output of a llm config, which are read from its extra config, such as {"responseFormat": "json_schema", "repairs": 2}
+type Options struct {
+	Format string `json:"responseFormat"`
+	// the rounds to re-prompt with the problems of an invalid answer
+	Repairs int `json:"repairs"`
+}
+
+// ParseOptions reads the structured output from the extra config of a llm config
+func ParseOptions(extra string) Options {
+	var raw struct {
+		Format  string   `json:"responseFormat"`
+		Repairs *float64 `json:"repairs"`
+	}
+	json.Unmarshal([]byte(extra), &raw)
+	opts := Options{Format: raw.Format, Repairs: DefaultRepairs}
+	switch opts.Format {
+	case FormatJSONSchema, FormatJSONObject, FormatNone:
+	default:
+		opts.Format = FormatJSONObject
+	}
+	if raw.Repairs != nil {
+		opts.Repairs = int(*raw.Repairs)
+	}
+	if opts.Repairs < 0 {
+		opts.Repairs = 0
+	}
+	if opts.Repairs > MaxRepairs {
+		opts.Repairs = MaxRepairs
+	}
+	return opts
+}
+
+// Prop is a property of a node type or an edge type
+type Prop struct {
+	Name     string
+	DataType string
+	Nullable bool
+}
+
+// Type is a node type or an edge type of the space
+type Type struct {
+	Name  string
+	Props []Prop
+}
+
+// Spec is the schema of the space the answers are validated against
+type Spec struct {
+	NodeTypes []Type
+	EdgeTypes []Type
+}
+
+// ResponseFormat returns the response_format of the request in the format, nil for FormatNone
+func (s *Spec) ResponseFormat(format string) map[string]any {
+	switch format {
+	case FormatJSONSchema:
+		return map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   "graph",
+				"schema": s.JSONSchema(),
+			},
+		}
+	case FormatJSONObject:
+		return map[string]any{"type": "json_object"}
+	}
+	return nil
+}
+
+// the keys of the node types of the src and the dst of an edge, so that the same name of different node types is told apart
+var endpointTypeKeys = []string{"srcType", "dstType"}
+
+func (s *Spec) nodeTypeNames() []string {
+	names := make([]string, 0, len(s.NodeTypes))
+	for _, t := range s.NodeTypes {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// JSONSchema returns the json schema of the nodes and the edges of the space
+func (s *Spec) JSONSchema() map[string]any {
+	edges := itemsSchema(s.EdgeTypes, []string{"src", "dst"}, "edgeType")
+	if items, ok := edges["items"].(map[string]any); ok {
+		for _, item := range items["anyOf"].([]any) {
+			item := item.(map[string]any)
+			properties := item["properties"].(map[string]any)
+			for _, key := range endpointTypeKeys {
+				properties[key] = map[string]any{"type": "string", "enum": s.nodeTypeNames()}
+			}
+			item["required"] = append(item["required"].([]string), endpointTypeKeys...)
+		}
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"nodes": itemsSchema(s.NodeTypes, []string{"name"}, "type"),
+			"edges": edges,
+		},
+		"required":             []string{"nodes", "edges"},
+		"additionalProperties": false,
+	}
+}
+
+// itemsSchema is the array of the nodes or the edges, an item is one of the types
+func itemsSchema(types []Type, names []string, typeKey string) map[string]any {
+	if len(types) == 0 {
+		return map[string]any{"type": "array", "maxItems": 0}
+	}
+	items := make([]any, 0, len(types))
+	for _, t := range types {
+		props := make(map[string]any, len(t.Props))
+		for _, prop := range t.Props {
+			props[prop.Name] = propSchema(prop)
+		}
+		properties := map[string]any{
+			typeKey: map[string]any{"type": "string", "enum": []string{t.Name}},
+			"props": map[string]any{
+				"type":                 "object",
+				"properties":           props,
+				"additionalProperties": false,
+			},
+		}
+		required := []string{typeKey, "props"}
+		for _, key := range names {
+			properties[key] = map[string]any{"type": "string", "minLength": 1}
+			required = append(required, key)
+		}
+		items = append(items, map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		})
+	}
+	return map[string]any{"type": "array", "items": map[string]any{"anyOf": items}}
+}
+
+// kind is the json value of a data type of NebulaGraph
+type kind int
+
+const (
+	kindString kind = iota
+	kindInteger
+	kindNumber
+	kindBool
+	kindTimestamp
+	kindAny
+)
+
+func kindOf(dataType string) kind {
+	dataType = strings.ToLower(dataType)
+	switch {
+	case strings.Contains(dataType, "string"):
+		return kindString
+	case strings.HasPrefix(dataType, "int"):
+		return kindInteger
+	case dataType == "float" || dataType == "double":
+		return kindNumber
+	case dataType == "bool":
+		return kindBool
+	case dataType == "timestamp":
+		return kindTimestamp
+	case dataType == "date" || dataType == "time" || dataType == "datetime" || dataType == "duration" ||
+		strings.HasPrefix(dataType, "geography"):
+		return kindString
+	}
+	return kindAny
+}
+
+func propSchema(prop Prop) map[string]any {
+	var types []string
+	switch kindOf(prop.DataType) {
+	case kindString:
+		types = []string{"string"}
+	case kindInteger:
+		types = []string{"integer"}
+	case kindNumber:
+		types = []string{"number"}
+	case kindBool:
+		types = []string{"boolean"}
+	case kindTimestamp:
+		types = []string{"integer", "string"}
+	default:
+		types = []string{"string", "number", "boolean"}
+	}
+	if prop.Nullable {
+		types = append(types, "null")
+	}
+	if len(types) == 1 {
+		return map[string]any{"type": types[0]}
+	}
+	return map[string]any{"type": types}
+}
+
+var codeBlock = regexp.MustCompile("(?s)```[a-zA-Z-]*[ \\t]*\\n?(.*?)```")
+
+// Extract reads the json from the answer, in the first code block if any, or from the first { to the last }
+func Extract(answer string) string {
+	if match := codeBlock.FindStringSubmatch(answer); match != nil {
+		answer = match[1]
+	}
+	answer = strings.TrimSpace(answer)
+	if start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}"); start >= 0 && end > start {
+		answer = answer[start : end+1]
+	}
+	return answer
+}
+
+/*
+Validate checks the json answered by the llm against the schema of the space.
+The error is returned when the json cannot be parsed, and the problems of the parsed json are listed with their paths,
+such as nodes[0].props.age, which are sent back to the llm to fix the answer.
+*/
+func (s *Spec) Validate(data []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var answer any
+	if err := decoder.Decode(&answer); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the json object")
+	}
+	v := &validator{}
+	root, ok := answer.(map[string]any)
+	if !ok {
+		v.addf("the answer should be an object with the nodes and the edges")
+		return v.problems, nil
+	}
+	v.validateItems(root["nodes"], "nodes", s.NodeTypes, []string{"name"}, "type", "node type", nil)
+	v.validateItems(root["edges"], "edges", s.EdgeTypes, []string{"src", "dst"}, "edgeType", "edge type", s.NodeTypes)
+	return v.problems, nil
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...any) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+/*
+validateItems checks the nodes or the edges, the node types of the src and the dst of an edge are checked against nodeTypes.
+They can be left out, such as by the answer of a custom prompt not asking for them.
+*/
+func (v *validator) validateItems(value any, path string, types []Type, names []string, typeKey, typeName string, nodeTypes []Type) {
+	if value == nil {
+		return
+	}
+	items, ok := value.([]any)
+	if !ok {
+		v.addf("%s: should be an array", path)
+		return
+	}
+	typeMap := make(map[string]Type, len(types))
+	typeNames := make([]string, 0, len(types))
+	for _, t := range types {
+		typeMap[t.Name] = t
+		typeNames = append(typeNames, strconv.Quote(t.Name))
+	}
+	for index, value := range items {
+		itemPath := fmt.Sprintf("%s[%d]", path, index)
+		item, ok := value.(map[string]any)
+		if !ok {
+			v.addf("%s: should be an object", itemPath)
+			continue
+		}
+		for _, key := range names {
+			if name, _ := item[key].(string); strings.TrimSpace(name) == "" {
+				v.addf("%s.%s: should be a non-empty string", itemPath, key)
+			}
+		}
+		if nodeTypes != nil {
+			v.validateEndpointTypes(item, itemPath, nodeTypes)
+		}
+		typeValue, _ := item[typeKey].(string)
+		t, ok := typeMap[typeValue]
+		if !ok {
+			v.addf("%s.%s: unknown %s %q, the %ss are %s", itemPath, typeKey, typeName, typeValue, typeName, strings.Join(typeNames, ", "))
+			continue
+		}
+		v.validateProps(item["props"], itemPath+".props", t)
+	}
+}
+
+func (v *validator) validateEndpointTypes(item map[string]any, path string, nodeTypes []Type) {
+	names := make([]string, 0, len(nodeTypes))
+	for _, t := range nodeTypes {
+		names = append(names, strconv.Quote(t.Name))
+	}
+	for _, key := range endpointTypeKeys {
+		value, ok := item[key]
+		if !ok || value == nil {
+			continue
+		}
+		typeValue, _ := value.(string)
+		known := false
+		for _, t := range nodeTypes {
+			known = known || t.Name == typeValue
+		}
+		if !known {
+			v.addf("%s.%s: unknown node type %q, the node types are %s", path, key, typeValue, strings.Join(names, ", "))
+		}
+	}
+}
+
+func (v *validator) validateProps(value any, path string, t Type) {
+	if value == nil {
+		return
+	}
+	props, ok := value.(map[string]any)
+	if !ok {
+		v.addf("%s: should be an object", path)
+		return
+	}
+	propMap := make(map[string]Prop, len(t.Props))
+	for _, prop := range t.Props {
+		propMap[prop.Name] = prop
+	}
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := props[key]
+		prop, ok := propMap[key]
+		if !ok {
+			v.addf("%s.%s: %q has no property %q", path, key, t.Name, key)
+			continue
+		}
+		if value == nil {
+			if !prop.Nullable {
+				v.addf("%s.%s: the property is not nullable", path, key)
+			}
+			continue
+		}
+		if !matches(kindOf(prop.DataType), value) {
+			v.addf("%s.%s: %v is not a valid %s", path, key, describe(value), prop.DataType)
+		}
+	}
+}
+
+// matches reports whether the json value is valid for the kind, the numbers quoted as strings are accepted
+func matches(k kind, value any) bool {
+	switch value := value.(type) {
+	case map[string]any, []any:
+		return false
+	case string:
+		switch k {
+		case kindInteger:
+			_, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			return err == nil
+		case kindNumber:
+			_, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			return err == nil
+		case kindBool:
+			_, err := strconv.ParseBool(value)
+			return err == nil
+		}
+		return true
+	case json.Number:
+		switch k {
+		case kindInteger, kindTimestamp:
+			_, err := value.Int64()
+			return err == nil
+		case kindBool:
+			return false
+		}
+		return true
+	case bool:
+		return k == kindBool || k == kindString || k == kindAny
+	}
+	return false
+}
+
+func describe(value any) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// RepairPrompt asks the llm to fix the problems of the answer
+func RepairPrompt(problems []string) string {
+	if len(problems) > maxProblems {
+		problems = append(problems[:maxProblems:maxProblems], fmt.Sprintf("and %d more problems", len(problems)-maxProblems))
+	}
+	return "The answer is invalid for the schema of the graph:\n- " + strings.Join(problems, "\n- ") +
+		"\nPlease fix the problems and answer the whole JSON object again, without explain and comment."
+}
+
+// ParseErrorPrompt asks the llm to answer again when the answer is not a json object
+func ParseErrorPrompt(err error) string {
+	return fmt.Sprintf("The answer is not a valid JSON object: %v\nPlease answer the JSON object only, without explain and comment.", err)
+}
@@ -0,0 +1,126 @@
+package structured
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var spec = &Spec{
+	NodeTypes: []Type{
+		{Name: "person", Props: []Prop{{Name: "name", DataType: "string"}, {Name: "age", DataType: "int64", Nullable: true}}},
+		{Name: "city", Props: []Prop{{Name: "area", DataType: "double"}}},
+	},
+	EdgeTypes: []Type{
+		{Name: "live", Props: []Prop{{Name: "since", DataType: "date"}, {Name: "owner", DataType: "bool"}}},
+	},
+}
+
+func TestExtract(t *testing.T) {
+	cases := map[string]string{
+		"```json\n{\"nodes\":[]}\n```":                    `{"nodes":[]}`,
+		"Result:\n{\"nodes\":[]}\nDone.":                  `{"nodes":[]}`,
+		"{\"nodes\":[{\"name\":\"line\\nbreak\"}]}":       `{"nodes":[{"name":"line\nbreak"}]}`,
+		"```\n{\"edges\":[]}```":                          `{"edges":[]}`,
+		"no json":                                         "no json",
+		"```json\n{\"nodes\":[\"a\\nb\"]}\n```\ntrailing": `{"nodes":["a\nb"]}`,
+	}
+	for answer, expected := range cases {
+		if got := Extract(answer); got != expected {
+			t.Errorf("Extract(%q) = %q, expected %q", answer, got, expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	problems, err := spec.Validate([]byte(`{
+		"nodes": [
+			{"name": "Tom", "type": "person", "props": {"name": "Tom\nSmith", "age": 30}},
+			{"name": "Paris", "type": "city", "props": {"area": "105.4"}},
+			{"name": "Amy", "type": "person", "props": {"age": null}}
+		],
+		"edges": [
+			{"src": "Tom", "dst": "Paris", "edgeType": "live", "props": {"since": "2020-01-01", "owner": true}},
+			{"src": "Amy", "srcType": "person", "dst": "Paris", "dstType": "city", "edgeType": "live", "props": {"since": "2021-01-01", "owner": false}}
+		]
+	}`))
+	if err != nil || len(problems) != 0 {
+		t.Errorf("expect valid, got %v %v", problems, err)
+	}
+
+	problems, err = spec.Validate([]byte(`{
+		"nodes": [
+			{"name": "", "type": "person", "props": {"age": 30.5, "weight": 60}},
+			{"name": "Moon", "type": "planet"},
+			"Tom"
+		],
+		"edges": [{"src": "Tom", "srcType": "planet", "dst": "Paris", "dstType": 1, "edgeType": "live", "props": {"owner": "yes", "since": null}}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`nodes[0].name: should be a non-empty string`,
+		`nodes[0].props.age: 30.5 is not a valid int64`,
+		`nodes[0].props.weight: "person" has no property "weight"`,
+		`nodes[1].type: unknown node type "planet", the node types are "person", "city"`,
+		`nodes[2]: should be an object`,
+		`edges[0].srcType: unknown node type "planet", the node types are "person", "city"`,
+		`edges[0].dstType: unknown node type "", the node types are "person", "city"`,
+		`edges[0].props.owner: "yes" is not a valid bool`,
+		`edges[0].props.since: the property is not nullable`,
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("unexpected problems:\n%s", strings.Join(problems, "\n"))
+	}
+
+	if _, err = spec.Validate([]byte(`{"nodes": [`)); err == nil {
+		t.Error("expect parse error")
+	}
+	if problems, _ = spec.Validate([]byte(`[]`)); len(problems) != 1 {
+		t.Errorf("expect the answer to be an object, got %v", problems)
+	}
+}
+
+func TestResponseFormat(t *testing.T) {
+	if spec.ResponseFormat(FormatNone) != nil {
+		t.Error("expect no response format")
+	}
+	if format := spec.ResponseFormat(FormatJSONObject); format["type"] != "json_object" {
+		t.Errorf("unexpected format %v", format)
+	}
+	format := spec.ResponseFormat(FormatJSONSchema)
+	schema := format["json_schema"].(map[string]any)["schema"].(map[string]any)
+	nodes := schema["properties"].(map[string]any)["nodes"].(map[string]any)
+	items := nodes["items"].(map[string]any)["anyOf"].([]any)
+	if len(items) != 2 {
+		t.Fatalf("expect a schema per node type, got %v", items)
+	}
+	props := items[0].(map[string]any)["properties"].(map[string]any)["props"].(map[string]any)["properties"].(map[string]any)
+	if !reflect.DeepEqual(props["age"], map[string]any{"type": []string{"integer", "null"}}) {
+		t.Errorf("unexpected age schema %v", props["age"])
+	}
+	edges := schema["properties"].(map[string]any)["edges"].(map[string]any)
+	edge := edges["items"].(map[string]any)["anyOf"].([]any)[0].(map[string]any)
+	srcType := edge["properties"].(map[string]any)["srcType"]
+	if !reflect.DeepEqual(srcType, map[string]any{"type": "string", "enum": []string{"person", "city"}}) {
+		t.Errorf("unexpected srcType schema %v", srcType)
+	}
+	if required := edge["required"].([]string); !reflect.DeepEqual(required, []string{"edgeType", "props", "src", "dst", "srcType", "dstType"}) {
+		t.Errorf("unexpected required keys of the edge %v", required)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	cases := map[string]Options{
+		``:                                     {Format: FormatJSONObject, Repairs: DefaultRepairs},
+		`{"responseFormat":"json_schema"}`:     {Format: FormatJSONSchema, Repairs: DefaultRepairs},
+		`{"responseFormat":"xml","repairs":0}`: {Format: FormatJSONObject, Repairs: 0},
+		`{"repairs":9}`:                        {Format: FormatJSONObject, Repairs: MaxRepairs},
+	}
+	for extra, expected := range cases {
+		if got := ParseOptions(extra); got != expected {
+			t.Errorf("ParseOptions(%q) = %+v, expected %+v", extra, got, expected)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package text2ngql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultMaxRepairs = 3
+	MaxRepairs        = 5
+)
+
+const promptTemplate = `Assuming you are a NebulaGraph database AI assistant, your role is to translate the user's question into a NGQL query.
+The graph space is "{space}", the vid type of the space is {vidType}, and the schema of the space is:
+----
+{schema}
+----
+Use the node types, the edge types and the properties in the schema only. Notice the NebulaGraph dialect of Cypher
+needs the label to refer to the properties of a node, i.e. v.person.name instead of v.name.
+Answer the query only, marked with ` + "```ngql" + ` for the markdown code block, without any explanation.`
+
+const repairTemplate = `The query failed to validate with the error:
+%s
+Please fix the query for the question, and answer the fixed query only marked with ` + "```ngql" + `.`
+
+var codeBlock = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*\\n?(.*?)```")
+
+// Attempt is a query answered by the model and the validation error of it
+type Attempt struct {
+	Query string `json:"query"`
+	Error string `json:"error,omitempty"`
+}
+
+// Result is the final query, valid if it passes the validation, and all the attempts to reach it
+type Result struct {
+	Query            string    `json:"query"`
+	Valid            bool      `json:"valid"`
+	Attempts         []Attempt `json:"attempts"`
+	PromptTokens     int       `json:"promptTokens"`
+	CompletionTokens int       `json:"completionTokens"`
+	// Data is the result of the executed query, or ExecuteError tells why it is not executed or fails
+	Data         any    `json:"data,omitempty"`
+	ExecuteError string `json:"executeError,omitempty"`
+}
+
+// Options are the question and the callbacks to the model and the database
+type Options struct {
+	Question string
+	Space    string
+	VidType  string
+	Schema   string
+	// the repair rounds after the first answer
+	MaxRepairs int
+	// Ask sends the messages to the model, and returns the answer and the tokens consumed
+	Ask func(messages []map[string]any) (answer string, promptTokens, completionTokens int, err error)
+	// Validate checks the query in the database, queryErr is the syntax or the semantic error of the query,
+	// and err fails the generation, such as a broken session
+	Validate func(query string) (queryErr error, err error)
+}
+
+// Prompt builds the system prompt grounded by the schema of the space
+func Prompt(space, vidType, schema string) string {
+	return strings.NewReplacer("{space}", space, "{vidType}", vidType, "{schema}", schema).Replace(promptTemplate)
+}
+
+// ExtractQuery reads the query from the answer, in the first code block if any
+func ExtractQuery(answer string) string {
+	if match := codeBlock.FindStringSubmatch(answer); match != nil {
+		answer = match[1]
+	}
+	return strings.TrimRight(strings.TrimSpace(answer), "; \n\t")
+}
+
+// Explain returns the statement explaining the query without running it
+func Explain(query string) string {
+	if strings.Contains(query, ";") {
+		return "EXPLAIN {" + query + "}"
+	}
+	return "EXPLAIN " + query
+}
+
+/*
+Generate asks the model for the query of the question, and validates the answer in the database.
+The error of an invalid query is sent back to the model to fix it, until the query is valid or the repair rounds run out.
+*/
+func Generate(opts *Options) (*Result, error) {
+	repairs := opts.MaxRepairs
+	if repairs <= 0 {
+		repairs = DefaultMaxRepairs
+	}
+	if repairs > MaxRepairs {
+		repairs = MaxRepairs
+	}
+	messages := []map[string]any{
+		{"role": "system", "content": Prompt(opts.Space, opts.VidType, opts.Schema)},
+		{"role": "user", "content": opts.Question},
+	}
+	result := &Result{Attempts: make([]Attempt, 0, repairs+1)}
+	for round := 0; round <= repairs; round++ {
+		answer, promptTokens, completionTokens, err := opts.Ask(messages)
+		if err != nil {
+			return result, err
+		}
+		result.PromptTokens += promptTokens
+		result.CompletionTokens += completionTokens
+
+		attempt := Attempt{Query: ExtractQuery(answer)}
+		var queryErr error
+		if attempt.Query == "" {
+			queryErr = fmt.Errorf("no query is found in the answer")
+		} else if queryErr, err = opts.Validate(attempt.Query); err != nil {
+			return result, err
+		}
+		result.Query = attempt.Query
+		if queryErr == nil {
+			result.Attempts = append(result.Attempts, attempt)
+			result.Valid = true
+			return result, nil
+		}
+		attempt.Error = queryErr.Error()
+		result.Attempts = append(result.Attempts, attempt)
+		messages = append(messages,
+			map[string]any{"role": "assistant", "content": answer},
+			map[string]any{"role": "user", "content": fmt.Sprintf(repairTemplate, attempt.Error)},
+		)
+	}
+	return result, nil
+}
+
+// readKeywords start the statements and the piped clauses which only read the graph
+var readKeywords = map[string]bool{
+	"MATCH": true, "OPTIONAL": true, "GO": true, "FETCH": true, "LOOKUP": true, "FIND": true, "GET": true,
+	"SHOW": true, "DESCRIBE": true, "DESC": true, "YIELD": true, "UNWIND": true, "WITH": true, "RETURN": true,
+	"ORDER": true, "LIMIT": true, "GROUP": true, "SAMPLE": true,
+}
+
+/*
+IsReadOnly reports whether the query only reads the graph, which is safe to run for the result.
+Every statement and piped clause has to start with a reading keyword, the separators in the strings are counted too,
+which rejects some reading queries but never accepts a writing one.
+*/
+func IsReadOnly(query string) bool {
+	for _, statement := range strings.FieldsFunc(query, func(r rune) bool { return r == ';' || r == '|' }) {
+		statement = strings.TrimSpace(statement)
+		// the variable assignment, such as $a = GO FROM ...
+		if strings.HasPrefix(statement, "$") {
+			i := strings.Index(statement, "=")
+			if i < 0 {
+				return false
+			}
+			statement = statement[i+1:]
+		}
+		fields := strings.Fields(statement)
+		if len(fields) == 0 {
+			continue
+		}
+		keyword := strings.ToUpper(fields[0])
+		if i := strings.IndexAny(keyword, "(`"); i >= 0 {
+			keyword = keyword[:i]
+		}
+		if !readKeywords[keyword] {
+			return false
+		}
+	}
+	return true
+}
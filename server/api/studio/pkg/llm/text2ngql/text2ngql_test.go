@@ -0,0 +1,100 @@
+package text2ngql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractQuery(t *testing.T) {
+	for answer, expected := range map[string]string{
+		"```ngql\nMATCH (v:person) RETURN v LIMIT 10;\n```":                       "MATCH (v:person) RETURN v LIMIT 10",
+		"Here it is:\n```\nGO FROM \"a\" OVER follow YIELD dst(edge)\n```\nDone.": `GO FROM "a" OVER follow YIELD dst(edge)`,
+		"  SHOW TAGS;  ": "SHOW TAGS",
+		"":               "",
+	} {
+		if query := ExtractQuery(answer); query != expected {
+			t.Errorf("unexpected query of %q: got %q, want %q", answer, query, expected)
+		}
+	}
+	if explain := Explain("GO FROM 1 OVER e; SHOW TAGS"); explain != "EXPLAIN {GO FROM 1 OVER e; SHOW TAGS}" {
+		t.Errorf("unexpected explain: %s", explain)
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	for query, expected := range map[string]bool{
+		"MATCH (v:person) RETURN v":                                true,
+		"match (v) return v limit 1":                               true,
+		"GO FROM 'a' OVER follow YIELD dst(edge) AS id | LIMIT 10": true,
+		"$a = GO FROM 'a' OVER follow YIELD dst(edge) AS id; FETCH PROP ON person $a.id YIELD properties(vertex)": true,
+		"LOOKUP ON person YIELD id(vertex) | ORDER BY $-.id":                                                      true,
+		"INSERT VERTEX person(name) VALUES 'a':('a')":                                                             false,
+		"MATCH (v) RETURN v; DROP SPACE test":                                                                     false,
+		"GO FROM 'a' OVER follow YIELD dst(edge) AS id | DELETE VERTEX $-.id":                                     false,
+		"$a = DELETE VERTEX 'a'":                                                                                  false,
+		"$a DELETE":                                                                                               false,
+	} {
+		if IsReadOnly(query) != expected {
+			t.Errorf("unexpected read only of %q: want %v", query, expected)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	answers := []string{
+		"```ngql\nMATCH (v:person) RETURN v.name\n```",
+		"```ngql\n```",
+		"```ngql\nMATCH (v:person) RETURN v.person.name\n```",
+	}
+	var asked [][]map[string]any
+	opts := &Options{
+		Question: "who are the people",
+		Space:    "test",
+		VidType:  "FIXED_STRING(32)",
+		Schema:   "NodeType \"person\" (\"name\":string )",
+		Ask: func(messages []map[string]any) (string, int, int, error) {
+			asked = append(asked, messages)
+			return answers[len(asked)-1], 10, 2, nil
+		},
+		Validate: func(query string) (error, error) {
+			if strings.Contains(query, "v.name") {
+				return fmt.Errorf("SemanticError: To get the property of the vertex in `v.name', should use the format `var.tag.prop'"), nil
+			}
+			return nil, nil
+		},
+	}
+	result, err := Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Query != "MATCH (v:person) RETURN v.person.name" || len(result.Attempts) != 3 ||
+		result.PromptTokens != 30 || result.CompletionTokens != 6 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Attempts[1].Error != "no query is found in the answer" || !strings.Contains(result.Attempts[0].Error, "SemanticError") {
+		t.Errorf("unexpected attempts: %+v", result.Attempts)
+	}
+	// the errors are fed back with the history
+	last := asked[2]
+	if len(last) != 6 || !strings.Contains(last[0]["content"].(string), `NodeType "person"`) ||
+		!strings.Contains(last[3]["content"].(string), "SemanticError") || last[4]["content"] != answers[1] {
+		t.Errorf("unexpected messages: %v", last)
+	}
+
+	// the repair rounds are bounded
+	asked, answers = nil, []string{"```ngql\nMATCH (v) RETURN v.name\n```", "```ngql\nMATCH (v) RETURN v.name\n```"}
+	opts.MaxRepairs = 1
+	result, err = Generate(opts)
+	if err != nil || result.Valid || len(result.Attempts) != 2 || result.Query != "MATCH (v) RETURN v.name" {
+		t.Errorf("unexpected result: %+v %v", result, err)
+	}
+
+	// the broken session fails the generation
+	asked = nil
+	opts.Validate = func(string) (error, error) { return nil, fmt.Errorf("session expired") }
+	if _, err := Generate(opts); err == nil || !reflect.DeepEqual(len(asked), 1) {
+		t.Errorf("expect the session error, got %v", err)
+	}
+}
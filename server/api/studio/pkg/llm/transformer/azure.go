@@ -0,0 +1,112 @@
+package transformer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+)
+
+const (
+	AzureAPIType db.APIType = "azure"
+
+	defaultAzureAPIVersion = "2024-06-01"
+	azureDeploymentsPath   = "/openai/deployments/"
+)
+
+func init() {
+	Register(AzureAPIType, func() Handler { return &Azure{} })
+}
+
+/*
+Azure calls the chat completion api of an Azure OpenAI deployment. The url is the endpoint of the resource,
+such as https://example.openai.azure.com, and the request is routed to the deployment and the api-version in the config,
+or the url is the full url of the chat completion of a deployment.
+*/
+type Azure struct {
+	OpenAI
+}
+
+func (a *Azure) Validate(config *db.LLMConfig) error {
+	_, err := azureURL(config)
+	return err
+}
+
+// azureURL routes to the chat completion api of the deployment
+func azureURL(config *db.LLMConfig) (string, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid azure endpoint %q", config.URL)
+	}
+	configs := extraConfig(config)
+	if !strings.Contains(u.Path, azureDeploymentsPath) {
+		deployment, _ := configs["deployment"].(string)
+		if deployment == "" {
+			return "", fmt.Errorf("the deployment is required by azure")
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/") + azureDeploymentsPath + url.PathEscape(deployment) + "/chat/completions"
+	}
+	query := u.Query()
+	if version, _ := configs["apiVersion"].(string); version != "" {
+		query.Set("api-version", version)
+	} else if query.Get("api-version") == "" {
+		query.Set("api-version", defaultAzureAPIVersion)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func (a *Azure) HandleRequest(req map[string]any, config *db.LLMConfig) (*http.Request, error) {
+	endpoint, err := azureURL(config)
+	if err != nil {
+		return nil, err
+	}
+	// the deployment decides the model
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert request parameters to JSON: %v", err)
+	}
+	httpReq, err := http.NewRequest("POST", endpoint, strings.NewReader(string(reqJSON)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("api-key", config.Key)
+	return httpReq, nil
+}
+
+func (a *Azure) HandleResponse(resp *http.Response, callback func(str string)) (map[string]any, error) {
+	if !strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return a.OpenAI.HandleResponse(resp, callback)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		event := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if event == "[DONE]" {
+			break
+		}
+		// azure sends the content filter results in the chunks without choices first
+		chunk := make(map[string]any)
+		if err := json.Unmarshal([]byte(event), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse response data: %s %v", event, err)
+		}
+		if choices, _ := chunk["choices"].([]any); len(choices) == 0 {
+			continue
+		}
+		callback(event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	callback("[DONE]")
+	return nil, nil
+}
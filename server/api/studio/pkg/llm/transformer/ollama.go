@@ -0,0 +1,191 @@
+package transformer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+)
+
+const OllamaAPIType db.APIType = "ollama"
+
+func init() {
+	Register(OllamaAPIType, func() Handler { return &Ollama{} })
+}
+
+// ollamaOptions maps the OpenAI parameters to the options of Ollama
+var ollamaOptions = map[string]string{
+	"temperature":       "temperature",
+	"top_p":             "top_p",
+	"max_tokens":        "num_predict",
+	"stop":              "stop",
+	"seed":              "seed",
+	"presence_penalty":  "presence_penalty",
+	"frequency_penalty": "frequency_penalty",
+}
+
+/*
+Ollama calls the native chat api of Ollama, such as http://localhost:11434/api/chat.
+The streamed response is a json object per line instead of the server-sent events.
+*/
+type Ollama struct {
+}
+
+type ollamaResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+func (o *Ollama) Validate(config *db.LLMConfig) error {
+	if model, _ := extraConfig(config)["model"].(string); model == "" {
+		return fmt.Errorf("the model is required by ollama")
+	}
+	return nil
+}
+
+func (o *Ollama) HandleRequest(req map[string]any, config *db.LLMConfig) (*http.Request, error) {
+	ollamaReq := map[string]any{
+		"model": extraConfig(config)["model"],
+		// ollama streams by default
+		"stream": req["stream"] == true,
+	}
+	if messages, ok := req["messages"]; ok {
+		ollamaReq["messages"] = messages
+	} else {
+		ollamaReq["messages"] = []map[string]any{{"role": "user", "content": req["prompt"]}}
+	}
+	// the json mode or the json schema of the structured output
+	if format, ok := req["response_format"].(map[string]any); ok {
+		switch format["type"] {
+		case "json_object":
+			ollamaReq["format"] = "json"
+		case "json_schema":
+			if jsonSchema, ok := format["json_schema"].(map[string]any); ok && jsonSchema["schema"] != nil {
+				ollamaReq["format"] = jsonSchema["schema"]
+			}
+		}
+	}
+	options := make(map[string]any)
+	for k, v := range req {
+		if option, ok := ollamaOptions[k]; ok {
+			options[option] = v
+		}
+	}
+	if len(options) > 0 {
+		ollamaReq["options"] = options
+	}
+
+	reqJSON, err := json.Marshal(ollamaReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert request parameters to JSON: %v", err)
+	}
+	httpReq, err := http.NewRequest("POST", config.URL, strings.NewReader(string(reqJSON)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	// ollama behind a proxy may require a key
+	if config.Key != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+config.Key)
+	}
+	return httpReq, nil
+}
+
+func (r *ollamaResponse) usage() map[string]any {
+	return map[string]any{
+		"prompt_tokens":     r.PromptEvalCount,
+		"completion_tokens": r.EvalCount,
+		"total_tokens":      r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+func (r *ollamaResponse) finishReason() any {
+	if !r.Done {
+		return nil
+	}
+	if r.DoneReason == "" {
+		return "stop"
+	}
+	return r.DoneReason
+}
+
+// chunk converts a line of the streamed response to an OpenAI chunk, the last one carries the usage
+func (r *ollamaResponse) chunk() map[string]any {
+	chunk := map[string]any{
+		"object": "chat.completion.chunk",
+		"model":  r.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"delta":         map[string]any{"role": r.Message.Role, "content": r.Message.Content},
+			"finish_reason": r.finishReason(),
+		}},
+	}
+	if r.Done {
+		chunk["usage"] = r.usage()
+	}
+	return chunk
+}
+
+func (o *Ollama) HandleResponse(resp *http.Response, callback func(str string)) (map[string]any, error) {
+	if strings.Contains(resp.Header.Get("Content-Type"), "application/x-ndjson") {
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var event ollamaResponse
+			if err := json.Unmarshal([]byte(line), &event); err != nil {
+				return nil, fmt.Errorf("failed to parse response data: %s %v", line, err)
+			}
+			if event.Error != "" {
+				return nil, fmt.Errorf("ollama error: %s", event.Error)
+			}
+			chunk, _ := json.Marshal(event.chunk())
+			callback(string(chunk))
+			if event.Done {
+				break
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read response body: %v", err)
+		}
+		callback("[DONE]")
+		return nil, nil
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %s %v", string(bodyBytes), err)
+	}
+	var ollamaResp ollamaResponse
+	if err := json.Unmarshal(bodyBytes, &ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response data: %s %v", string(bodyBytes), err)
+	}
+	if ollamaResp.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", ollamaResp.Error)
+	}
+	return toMap(map[string]any{
+		"object": "chat.completion",
+		"model":  ollamaResp.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]any{"role": ollamaResp.Message.Role, "content": ollamaResp.Message.Content},
+			"finish_reason": ollamaResp.finishReason(),
+		}},
+		"usage": ollamaResp.usage(),
+	})
+}
@@ -11,11 +11,11 @@ import (
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
 )
 
-type Handler interface {
-	HandleRequest(req map[string]any, config *db.LLMConfig) (*http.Request, error)
-	HandleResponse(resp *http.Response, callback func(str string)) (map[string]any, error)
+func init() {
+	Register(db.OpenAI, func() Handler { return &OpenAI{} })
 }
 
+// OpenAI calls the OpenAI chat completion api, and the compatible apis of the local servers such as vLLM and Ollama
 type OpenAI struct {
 }
 
@@ -39,14 +39,17 @@ func (o *OpenAI) HandleRequest(req map[string]any, config *db.LLMConfig) (*http.
 
 	// Set the Content-Type and Authorization headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+config.Key)
-	httpReq.Header.Set("api-key", config.Key)
+	// the local servers may run without a key
+	if config.Key != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+config.Key)
+		httpReq.Header.Set("api-key", config.Key)
+	}
 	return httpReq, nil
 }
 
 func (o *OpenAI) HandleResponse(resp *http.Response, callback func(str string)) (map[string]any, error) {
 	// Check if the response is a server-sent event str
-	if resp.Header.Get("Content-Type") == "text/event-stream" {
+	if strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
 		scanner := bufio.NewScanner(resp.Body)
 		for scanner.Scan() {
 			line := scanner.Text()
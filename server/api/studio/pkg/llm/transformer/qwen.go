@@ -11,6 +11,12 @@ import (
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
 )
 
+const QwenAPIType db.APIType = "qwen"
+
+func init() {
+	Register(QwenAPIType, func() Handler { return &Qwen{} })
+}
+
 type Qwen struct {
 }
 
@@ -0,0 +1,138 @@
+package transformer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+)
+
+/*
+Handler converts the requests in the OpenAI chat completion format to the api of a provider, and converts the responses back.
+A response is converted to the OpenAI format with the choices and the usage, and a streamed event is passed to the callback
+as an OpenAI chunk, so the callers and the web pages read all the providers alike.
+*/
+type Handler interface {
+	HandleRequest(req map[string]any, config *db.LLMConfig) (*http.Request, error)
+	HandleResponse(resp *http.Response, callback func(str string)) (map[string]any, error)
+}
+
+// Validator checks the config of a provider when it is saved
+type Validator interface {
+	Validate(config *db.LLMConfig) error
+}
+
+var (
+	mu       sync.RWMutex
+	handlers = make(map[db.APIType]func() Handler)
+)
+
+// Register registers the handler of an api type, the handler is created for each request
+func Register(apiType db.APIType, newHandler func() Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[apiType] = newHandler
+}
+
+// New creates the handler of the api type
+func New(apiType db.APIType) (Handler, error) {
+	mu.RLock()
+	newHandler, ok := handlers[apiType]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported api type %q, the supported types are %s", apiType, strings.Join(Types(), ", "))
+	}
+	return newHandler(), nil
+}
+
+// Types lists the registered api types
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	types := make([]string, 0, len(handlers))
+	for apiType := range handlers {
+		types = append(types, string(apiType))
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Validate checks the api type of the config is registered and the config is valid for it
+func Validate(config *db.LLMConfig) error {
+	handler, err := New(config.APIType)
+	if err != nil {
+		return err
+	}
+	if config.URL == "" {
+		return fmt.Errorf("the url is required")
+	}
+	if validator, ok := handler.(Validator); ok {
+		return validator.Validate(config)
+	}
+	return nil
+}
+
+// Usage is the tokens consumed by a request
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// UsageOf reads the usage of a converted response, the missing counts are 0
+func UsageOf(resp map[string]any) Usage {
+	usage, _ := resp["usage"].(map[string]any)
+	count := func(key string) int {
+		n, _ := usage[key].(float64)
+		return int(n)
+	}
+	return Usage{PromptTokens: count("prompt_tokens"), CompletionTokens: count("completion_tokens")}
+}
+
+// extraConfig reads the extra config of the provider, which is a json object such as {"model": "gpt-4"}
+func extraConfig(config *db.LLMConfig) map[string]any {
+	configs := make(map[string]any)
+	json.Unmarshal([]byte(config.Config), &configs)
+	return configs
+}
+
+// toMap converts a response to the generic json values, which the callers read like a parsed response
+func toMap(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]any)
+	return m, json.Unmarshal(data, &m)
+}
+
+// StatusError is the response of a failed request, such as 429 for the rate limit
+type StatusError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the wait asked by the server
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// CheckStatus returns the StatusError of a failed response
+func CheckStatus(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	err := &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && seconds > 0 {
+		err.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return err
+}
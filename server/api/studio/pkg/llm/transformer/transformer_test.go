@@ -0,0 +1,193 @@
+package transformer
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+)
+
+// serve answers the requests with the body of the content type, and records the last request
+func serve(t *testing.T, contentType, body string) (*httptest.Server, *http.Request, *map[string]any) {
+	var last http.Request
+	received := make(map[string]any)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = *r
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &received)
+		w.Header().Set("Content-Type", contentType)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(s.Close)
+	return s, &last, &received
+}
+
+func fetch(t *testing.T, config *db.LLMConfig, req map[string]any) (map[string]any, []string) {
+	handler, err := New(config.APIType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq, err := handler.HandleRequest(req, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var events []string
+	data, err := handler.HandleResponse(resp, func(str string) { events = append(events, str) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, events
+}
+
+func TestRegistry(t *testing.T) {
+	if types := Types(); !reflect.DeepEqual(types, []string{"azure", "ollama", "openai", "qwen"}) {
+		t.Errorf("unexpected types: %v", types)
+	}
+	for _, c := range []struct {
+		config db.LLMConfig
+		err    string
+	}{
+		{db.LLMConfig{APIType: "openai", URL: "http://localhost:8000/v1/chat/completions"}, ""},
+		{db.LLMConfig{APIType: "unknown", URL: "http://localhost"}, "unsupported api type"},
+		{db.LLMConfig{APIType: "openai"}, "url is required"},
+		{db.LLMConfig{APIType: "ollama", URL: "http://localhost:11434/api/chat"}, "model is required"},
+		{db.LLMConfig{APIType: "ollama", URL: "http://localhost:11434/api/chat", Config: `{"model":"llama3"}`}, ""},
+		{db.LLMConfig{APIType: "azure", URL: "https://example.openai.azure.com"}, "deployment is required"},
+		{db.LLMConfig{APIType: "azure", URL: "example.openai.azure.com", Config: `{"deployment":"gpt-4o"}`}, "invalid azure endpoint"},
+	} {
+		err := Validate(&c.config)
+		if (c.err == "" && err != nil) || (c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err))) {
+			t.Errorf("unexpected validation of %v: got %v, want %q", c.config, err, c.err)
+		}
+	}
+	if usage := UsageOf(map[string]any{}); usage != (Usage{}) {
+		t.Errorf("unexpected usage: %v", usage)
+	}
+}
+
+func TestOllama(t *testing.T) {
+	s, last, received := serve(t, "application/json",
+		`{"model":"llama3","message":{"role":"assistant","content":"hi"},"done":true,"done_reason":"stop","prompt_eval_count":5,"eval_count":2}`)
+	config := &db.LLMConfig{APIType: OllamaAPIType, URL: s.URL + "/api/chat", Config: `{"model":"llama3"}`}
+	data, _ := fetch(t, config, map[string]any{"messages": []any{map[string]any{"role": "user", "content": "hello"}}, "max_tokens": 10})
+	if last.URL.Path != "/api/chat" || (*received)["stream"] != false || (*received)["model"] != "llama3" ||
+		!reflect.DeepEqual((*received)["options"], map[string]any{"num_predict": float64(10)}) {
+		t.Errorf("unexpected request: %s %v", last.URL, *received)
+	}
+	text := data["choices"].([]any)[0].(map[string]any)["message"].(map[string]any)["content"]
+	if usage := UsageOf(data); text != "hi" || usage != (Usage{PromptTokens: 5, CompletionTokens: 2}) {
+		t.Errorf("unexpected response: %v", data)
+	}
+
+	fetch(t, config, map[string]any{"prompt": "hello", "response_format": map[string]any{"type": "json_object"}})
+	if (*received)["format"] != "json" {
+		t.Errorf("expect the json mode, got %v", *received)
+	}
+	schema := map[string]any{"type": "object"}
+	fetch(t, config, map[string]any{"prompt": "hello", "response_format": map[string]any{
+		"type": "json_schema", "json_schema": map[string]any{"name": "graph", "schema": schema},
+	}})
+	if !reflect.DeepEqual((*received)["format"], schema) {
+		t.Errorf("expect the json schema, got %v", *received)
+	}
+
+	s, _, _ = serve(t, "application/x-ndjson", `{"model":"llama3","message":{"role":"assistant","content":"h"},"done":false}
+{"model":"llama3","message":{"role":"assistant","content":"i"},"done":false}
+{"model":"llama3","message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":5,"eval_count":2}
+`)
+	config.URL = s.URL
+	data, events := fetch(t, config, map[string]any{"prompt": "hello", "stream": true})
+	if data != nil || len(events) != 4 || events[3] != "[DONE]" {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	var text2 string
+	for _, event := range events[:3] {
+		chunk := make(map[string]any)
+		if err := json.Unmarshal([]byte(event), &chunk); err != nil {
+			t.Fatal(err)
+		}
+		text2 += chunk["choices"].([]any)[0].(map[string]any)["delta"].(map[string]any)["content"].(string)
+	}
+	if text2 != "hi" || !strings.Contains(events[2], `"finish_reason":"stop"`) || !strings.Contains(events[2], `"prompt_tokens":5`) {
+		t.Errorf("unexpected events: %v", events)
+	}
+
+	s, _, _ = serve(t, "application/json", `{"error":"model \"llama3\" not found"}`)
+	config.URL = s.URL
+	handler, _ := New(OllamaAPIType)
+	httpReq, _ := handler.HandleRequest(map[string]any{"prompt": "hello"}, config)
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := handler.HandleResponse(resp, func(string) {}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expect the ollama error, got %v", err)
+	}
+}
+
+func TestAzure(t *testing.T) {
+	for _, c := range []struct {
+		url, config, expected string
+	}{
+		{"https://example.openai.azure.com/", `{"deployment":"gpt-4o"}`,
+			"https://example.openai.azure.com/openai/deployments/gpt-4o/chat/completions?api-version=" + defaultAzureAPIVersion},
+		{"https://example.openai.azure.com", `{"deployment":"gpt-4o","apiVersion":"2024-02-01"}`,
+			"https://example.openai.azure.com/openai/deployments/gpt-4o/chat/completions?api-version=2024-02-01"},
+		{"https://example.openai.azure.com/openai/deployments/d1/chat/completions?api-version=2023-05-15", "",
+			"https://example.openai.azure.com/openai/deployments/d1/chat/completions?api-version=2023-05-15"},
+	} {
+		u, err := azureURL(&db.LLMConfig{URL: c.url, Config: c.config})
+		if err != nil || u != c.expected {
+			t.Errorf("unexpected url of %s: got %s %v, want %s", c.url, u, err, c.expected)
+		}
+	}
+
+	s, last, _ := serve(t, "text/event-stream; charset=utf-8", `data: {"choices":[],"prompt_filter_results":[]}
+
+data: {"choices":[{"index":0,"delta":{"content":"hi"}}]}
+
+data: [DONE]
+`)
+	config := &db.LLMConfig{APIType: AzureAPIType, URL: s.URL, Key: "secret", Config: `{"deployment":"gpt-4o"}`}
+	_, events := fetch(t, config, map[string]any{"stream": true, "messages": []any{}})
+	if last.URL.Path != "/openai/deployments/gpt-4o/chat/completions" || last.Header.Get("api-key") != "secret" {
+		t.Errorf("unexpected request: %s %v", last.URL, last.Header)
+	}
+	if !reflect.DeepEqual(events, []string{`{"choices":[{"index":0,"delta":{"content":"hi"}}]}`, "[DONE]"}) {
+		t.Errorf("unexpected events: %v", events)
+	}
+
+	s, _, _ = serve(t, "application/json", `{"choices":[{"message":{"content":"hi"}}],"usage":{"prompt_tokens":3,"completion_tokens":1}}`)
+	config.URL = s.URL
+	data, _ := fetch(t, config, map[string]any{"messages": []any{}})
+	if usage := UsageOf(data); usage != (Usage{PromptTokens: 3, CompletionTokens: 1}) {
+		t.Errorf("unexpected usage: %v", data)
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("Retry-After", "20")
+	recorder.WriteHeader(http.StatusTooManyRequests)
+	io.WriteString(recorder, `{"error":"rate limited"}`)
+	err := CheckStatus(recorder.Result())
+	statusErr, ok := err.(*StatusError)
+	if !ok || statusErr.StatusCode != 429 || statusErr.RetryAfter != 20*time.Second || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckStatus(httptest.NewRecorder().Result()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/lease"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/utils"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	TypeWebhook = "webhook"
+	TypeSMTP    = "smtp"
+
+	KindImportTask = "import_task"
+	KindLLMJob     = "llm_job"
+
+	EventFinished = "finished"
+	EventFailed   = "failed"
+	EventStopped  = "stopped"
+	EventTest     = "test"
+
+	DeliveryPending = "pending"
+	DeliverySuccess = "success"
+	DeliveryFailed  = "failed"
+)
+
+// the deliveries being sent by this instance
+var delivering sync.Map
+
+// Payload is the notification of a completed job, sent as the json body of a webhook or in an email
+type Payload struct {
+	Event string `json:"event"`
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Space string `json:"space,omitempty"`
+	// the final status of the job
+	Status string `json:"status"`
+	// the failure reason
+	Message string `json:"message,omitempty"`
+	Stats   any    `json:"stats,omitempty"`
+	LogsURL string `json:"logsUrl,omitempty"`
+	Time    int64  `json:"time"`
+}
+
+// Sender delivers a payload to one channel
+type Sender interface {
+	Send(p *Payload, body []byte) error
+}
+
+func NewSender(typ, cfg, secret string) (Sender, error) {
+	switch typ {
+	case TypeWebhook:
+		var c WebhookConfig
+		if err := json.Unmarshal([]byte(cfg), &c); err != nil {
+			return nil, fmt.Errorf("parse the webhook config error: %w", err)
+		}
+		return NewWebhookSender(&c, secret, timeout()), nil
+	case TypeSMTP:
+		var c SMTPConfig
+		if err := json.Unmarshal([]byte(cfg), &c); err != nil {
+			return nil, fmt.Errorf("parse the smtp config error: %w", err)
+		}
+		return NewSMTPSender(&c, secret, timeout()), nil
+	default:
+		return nil, fmt.Errorf("unsupported notify channel type: %s", typ)
+	}
+}
+
+func retries() int {
+	if c := config.GetConfig(); c != nil {
+		return c.Notify.Retries
+	}
+	return 3
+}
+
+func retryInterval() time.Duration {
+	if c := config.GetConfig(); c != nil && c.Notify.RetryInterval > 0 {
+		return time.Duration(c.Notify.RetryInterval) * time.Second
+	}
+	return 10 * time.Second
+}
+
+func timeout() time.Duration {
+	if c := config.GetConfig(); c != nil && c.Notify.Timeout > 0 {
+		return time.Duration(c.Notify.Timeout) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// LogsURL joins the path of the job logs to the configured base url, it is empty without a base url
+func LogsURL(path string) string {
+	c := config.GetConfig()
+	if c == nil || c.Notify.BaseURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(c.Notify.BaseURL, "/") + path
+}
+
+// SplitChannels parses the channel ids stored with a task
+func SplitChannels(channels string) []string {
+	var ids []string
+	for _, id := range strings.Split(channels, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+/*
+Notify sends the payload to the default channels of the user and the chosen channels,
+each delivery is logged and retried in the background.
+*/
+func Notify(host, username string, channelIDs []string, p *Payload) {
+	if db.CtxDB == nil {
+		return
+	}
+	query := db.CtxDB.Where("host = ? AND username = ?", host, username)
+	if len(channelIDs) > 0 {
+		query = query.Where("is_default = ? OR b_id IN (?)", true, channelIDs)
+	} else {
+		query = query.Where("is_default = ?", true)
+	}
+	var channels []db.NotifyChannel
+	if err := query.Find(&channels).Error; err != nil {
+		logx.Errorf("find the notify channels of %s %s error: %s", host, username, err)
+		return
+	}
+	if len(channels) == 0 {
+		return
+	}
+	if p.Time == 0 {
+		p.Time = time.Now().UnixMilli()
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		logx.Errorf("marshal the notification of %s %s error: %s", p.Kind, p.ID, err)
+		return
+	}
+	for i := range channels {
+		channel := &channels[i]
+		delivery := &db.NotifyDelivery{
+			ChannelID: channel.BID,
+			Host:      host,
+			Username:  username,
+			JobKind:   p.Kind,
+			JobID:     p.ID,
+			Event:     p.Event,
+			Status:    DeliveryPending,
+			Payload:   string(body),
+		}
+		if err := db.CtxDB.Create(delivery).Error; err != nil {
+			logx.Errorf("log the delivery to channel %s error: %s", channel.BID, err)
+			continue
+		}
+		if !claimDelivery(delivery) {
+			continue
+		}
+		sender, err := newChannelSender(channel)
+		if err != nil {
+			finishDelivery(delivery, err)
+			continue
+		}
+		go deliver(sender, delivery, p, body, retries(), retryInterval())
+	}
+}
+
+/*
+ResumeDeliveries sends again the deliveries left pending by the last run, with the retries they have left.
+Like the import tasks, only the deliveries without a live lease are resumed at once,
+the deliveries of a dead instance are resumed once their lease expires.
+*/
+func ResumeDeliveries() {
+	lease.Register(lease.KindNotifyDelivery, lease.Handler{
+		OnTakeover: func(jobID, prevOwner string) {
+			var delivery db.NotifyDelivery
+			if err := db.CtxDB.Where("id = ? AND status = ?", jobID, DeliveryPending).First(&delivery).Error; err != nil {
+				return
+			}
+			resumeDelivery(&delivery)
+		},
+		Running: func() []string {
+			var ids []string
+			delivering.Range(func(key, _ any) bool {
+				ids = append(ids, strconv.Itoa(key.(int)))
+				return true
+			})
+			return ids
+		},
+	})
+	var deliveries []db.NotifyDelivery
+	if err := db.CtxDB.Where("status = ?", DeliveryPending).Order("id").Find(&deliveries).Error; err != nil {
+		logx.Errorf("find the pending deliveries error: %s", err)
+		return
+	}
+	for i := range deliveries {
+		resumeDelivery(&deliveries[i])
+	}
+}
+
+// resumeDelivery rebuilds the sender and the payload of a pending delivery and sends it in background
+func resumeDelivery(delivery *db.NotifyDelivery) {
+	if !claimDelivery(delivery) {
+		return
+	}
+	var channel db.NotifyChannel
+	if err := db.CtxDB.Where("b_id = ?", delivery.ChannelID).First(&channel).Error; err != nil {
+		finishDelivery(delivery, fmt.Errorf("find the channel error: %w", err))
+		return
+	}
+	sender, err := newChannelSender(&channel)
+	if err != nil {
+		finishDelivery(delivery, err)
+		return
+	}
+	var p Payload
+	if err := json.Unmarshal([]byte(delivery.Payload), &p); err != nil {
+		finishDelivery(delivery, fmt.Errorf("parse the payload error: %w", err))
+		return
+	}
+	logx.Infof("resume the delivery %d of %s %s after %d attempts", delivery.ID, delivery.JobKind, delivery.JobID, delivery.Attempts)
+	go deliver(sender, delivery, &p, []byte(delivery.Payload), retries(), retryInterval())
+}
+
+// claimDelivery leases the delivery to this instance, it fails when the delivery is being sent here or by another instance
+func claimDelivery(delivery *db.NotifyDelivery) bool {
+	if _, loaded := delivering.LoadOrStore(delivery.ID, struct{}{}); loaded {
+		return false
+	}
+	ok, err := lease.Claim(lease.KindNotifyDelivery, strconv.Itoa(delivery.ID))
+	if err != nil {
+		logx.Errorf("claim the delivery %d error: %s", delivery.ID, err)
+	}
+	if !ok {
+		delivering.Delete(delivery.ID)
+	}
+	return ok
+}
+
+// Test sends a test notification to the channel once
+func Test(channel *db.NotifyChannel) error {
+	sender, err := newChannelSender(channel)
+	if err != nil {
+		return err
+	}
+	p := &Payload{
+		Event:   EventTest,
+		Kind:    "test",
+		Name:    channel.Name,
+		Status:  "test",
+		Message: "a test notification from nebula studio",
+		Time:    time.Now().UnixMilli(),
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return sender.Send(p, body)
+}
+
+func newChannelSender(channel *db.NotifyChannel) (Sender, error) {
+	var secret string
+	if channel.Secret != "" {
+		s, err := utils.Decrypt(channel.Secret, []byte(utils.CipherKey))
+		if err != nil {
+			return nil, fmt.Errorf("decrypt the channel secret error: %w", err)
+		}
+		secret = string(s)
+	}
+	return NewSender(channel.Type, channel.Config, secret)
+}
+
+// deliver sends the payload until it succeeds or the retries run out, the wait time doubles after each failure
+func deliver(sender Sender, delivery *db.NotifyDelivery, p *Payload, body []byte, retries int, interval time.Duration) {
+	defer func() {
+		if r := recover(); r != nil {
+			finishDelivery(delivery, fmt.Errorf("panic: %v", r))
+		}
+	}()
+	for {
+		err := sender.Send(p, body)
+		delivery.Attempts++
+		if err == nil || delivery.Attempts > retries {
+			finishDelivery(delivery, err)
+			return
+		}
+		delivery.LastError = err.Error()
+		if err := db.CtxDB.Model(delivery).Updates(map[string]any{
+			"attempts":   delivery.Attempts,
+			"last_error": delivery.LastError,
+		}).Error; err != nil {
+			logx.Errorf("update the delivery %d error: %s", delivery.ID, err)
+		}
+		time.Sleep(interval << (delivery.Attempts - 1))
+	}
+}
+
+func finishDelivery(delivery *db.NotifyDelivery, err error) {
+	delivery.Status = DeliverySuccess
+	if err != nil {
+		delivery.Status = DeliveryFailed
+		delivery.LastError = err.Error()
+		logx.Errorf("deliver the notification of %s %s to channel %s error: %s", delivery.JobKind, delivery.JobID, delivery.ChannelID, err)
+	}
+	if err := db.CtxDB.Model(delivery).Updates(map[string]any{
+		"status":     delivery.Status,
+		"attempts":   delivery.Attempts,
+		"last_error": delivery.LastError,
+	}).Error; err != nil {
+		logx.Errorf("update the delivery %d error: %s", delivery.ID, err)
+	}
+	if err := lease.Release(lease.KindNotifyDelivery, strconv.Itoa(delivery.ID)); err != nil {
+		logx.Errorf("release the delivery %d error: %s", delivery.ID, err)
+	}
+	delivering.Delete(delivery.ID)
+}
@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/lease"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDB(t *testing.T) {
+	d, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection opens a new memory database
+	sqlDB, err := d.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := d.AutoMigrate(&db.NotifyChannel{}, &db.NotifyDelivery{}, &db.JobLease{}); err != nil {
+		t.Fatal(err)
+	}
+	db.CtxDB = d
+}
+
+func TestWebhookSender(t *testing.T) {
+	ast := assert.New(t)
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer srv.Close()
+
+	p := &Payload{Event: EventFinished, Kind: KindImportTask, ID: "task1"}
+	sent := []byte(`{"id":"task1"}`)
+	sender := NewWebhookSender(&WebhookConfig{URL: srv.URL, Headers: map[string]string{"X-Token": "t"}}, "key", time.Second)
+	ast.NoError(sender.Send(p, sent))
+	ast.Equal(sent, body)
+	ast.Equal(Sign("key", sent), header.Get(HeaderSignature))
+	ast.Equal("sha256=", Sign("key", sent)[:7])
+	ast.Equal("import_task.finished", header.Get(HeaderEvent))
+	ast.Equal("t", header.Get("X-Token"))
+
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer fail.Close()
+	ast.Error(NewWebhookSender(&WebhookConfig{URL: fail.URL}, "", time.Second).Send(p, sent))
+}
+
+type flakySender struct {
+	failures int
+	calls    int
+}
+
+func (s *flakySender) Send(p *Payload, body []byte) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func TestDeliverRetry(t *testing.T) {
+	ast := assert.New(t)
+	setupDB(t)
+
+	newDelivery := func() *db.NotifyDelivery {
+		d := &db.NotifyDelivery{ChannelID: "c1", JobKind: KindImportTask, JobID: "task1", Event: EventFinished, Status: DeliveryPending}
+		ast.NoError(db.CtxDB.Create(d).Error)
+		return d
+	}
+
+	d := newDelivery()
+	deliver(&flakySender{failures: 2}, d, &Payload{}, nil, 3, time.Millisecond)
+	var saved db.NotifyDelivery
+	ast.NoError(db.CtxDB.First(&saved, d.ID).Error)
+	ast.Equal(DeliverySuccess, saved.Status)
+	ast.Equal(3, saved.Attempts)
+
+	d = newDelivery()
+	deliver(&flakySender{failures: 5}, d, &Payload{}, nil, 1, time.Millisecond)
+	saved = db.NotifyDelivery{}
+	ast.NoError(db.CtxDB.First(&saved, d.ID).Error)
+	ast.Equal(DeliveryFailed, saved.Status)
+	ast.Equal(2, saved.Attempts)
+	ast.Equal("unavailable", saved.LastError)
+}
+
+func TestResumeDeliveries(t *testing.T) {
+	ast := assert.New(t)
+	setupDB(t)
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+	}))
+	defer srv.Close()
+	channel := &db.NotifyChannel{BID: "c1", Name: "hook", Type: TypeWebhook, Config: `{"url":"` + srv.URL + `"}`}
+	ast.NoError(db.CtxDB.Create(channel).Error)
+
+	payload := `{"event":"finished","kind":"import_task","id":"task1","name":"players","status":"Success","time":1}`
+	newDelivery := func(channelID, status string) *db.NotifyDelivery {
+		d := &db.NotifyDelivery{ChannelID: channelID, JobKind: KindImportTask, JobID: "task1", Event: EventFinished, Status: status, Attempts: 1, Payload: payload}
+		ast.NoError(db.CtxDB.Create(d).Error)
+		return d
+	}
+	pending := newDelivery("c1", DeliveryPending)
+	sent := newDelivery("c1", DeliverySuccess)
+	deleted := newDelivery("c2", DeliveryPending)
+	// being sent by another instance
+	leased := newDelivery("c1", DeliveryPending)
+	ast.NoError(db.CtxDB.Create(&db.JobLease{Kind: lease.KindNotifyDelivery, JobID: strconv.Itoa(leased.ID), Owner: "other", ExpireTime: time.Now().Add(time.Minute)}).Error)
+
+	ResumeDeliveries()
+	status := func(d *db.NotifyDelivery) string {
+		var saved db.NotifyDelivery
+		ast.NoError(db.CtxDB.First(&saved, d.ID).Error)
+		return saved.Status
+	}
+	ast.Eventually(func() bool { return status(pending) == DeliverySuccess }, time.Second, 10*time.Millisecond)
+	ast.Equal([]string{payload}, received)
+	ast.Equal(DeliverySuccess, status(sent))
+	ast.Equal(DeliveryFailed, status(deleted))
+	ast.Equal(DeliveryPending, status(leased))
+
+	var saved db.NotifyDelivery
+	ast.NoError(db.CtxDB.First(&saved, pending.ID).Error)
+	ast.Equal(2, saved.Attempts)
+	l, err := lease.Get(lease.KindNotifyDelivery, strconv.Itoa(pending.ID))
+	ast.NoError(err)
+	ast.Nil(l)
+
+	// the delivery of a dead instance can be resumed once its lease has expired
+	ast.NoError(db.CtxDB.Model(&db.JobLease{}).Where("job_id = ?", strconv.Itoa(leased.ID)).Update("expire_time", time.Unix(0, 0)).Error)
+	resumeDelivery(leased)
+	ast.Eventually(func() bool { return status(leased) == DeliverySuccess }, time.Second, 10*time.Millisecond)
+	ast.Len(received, 2)
+}
@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+func (c *SMTPConfig) Validate() error {
+	if c.Host == "" || c.Port <= 0 {
+		return errors.New("the smtp host and port are required")
+	}
+	if c.From == "" {
+		return errors.New("the sender address is required")
+	}
+	if len(c.To) == 0 {
+		return errors.New("at least one recipient is required")
+	}
+	for _, addr := range append([]string{c.From}, c.To...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return fmt.Errorf("invalid email address: %q", addr)
+		}
+	}
+	return nil
+}
+
+/*
+SMTPSender mails the payload to the recipients,
+port 465 uses implicit TLS, the other ports upgrade with STARTTLS when the server supports it.
+*/
+type SMTPSender struct {
+	cfg      *SMTPConfig
+	password string
+	timeout  time.Duration
+}
+
+func NewSMTPSender(cfg *SMTPConfig, password string, timeout time.Duration) *SMTPSender {
+	return &SMTPSender{
+		cfg:      cfg,
+		password: password,
+		timeout:  timeout,
+	}
+}
+
+func (s *SMTPSender) Send(p *Payload, body []byte) error {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{Timeout: s.timeout}
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+	var conn net.Conn
+	var err error
+	if s.cfg.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(s.timeout))
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range s.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.message(p)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *SMTPSender) message(p *Payload) []byte {
+	var buf bytes.Buffer
+	subject := fmt.Sprintf("[Nebula Studio] %s %s %s", strings.ReplaceAll(p.Kind, "_", " "), p.Name, p.Event)
+	fmt.Fprintf(&buf, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(s.cfg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+
+	fmt.Fprintf(&buf, "Name: %s\r\n", p.Name)
+	fmt.Fprintf(&buf, "ID: %s\r\n", p.ID)
+	if p.Space != "" {
+		fmt.Fprintf(&buf, "Space: %s\r\n", p.Space)
+	}
+	fmt.Fprintf(&buf, "Status: %s\r\n", p.Status)
+	if p.Message != "" {
+		fmt.Fprintf(&buf, "Message: %s\r\n", p.Message)
+	}
+	if p.Stats != nil {
+		if stats, err := json.MarshalIndent(p.Stats, "", "  "); err == nil {
+			fmt.Fprintf(&buf, "Stats:\r\n%s\r\n", strings.ReplaceAll(string(stats), "\n", "\r\n"))
+		}
+	}
+	if p.LogsURL != "" {
+		fmt.Fprintf(&buf, "Logs: %s\r\n", p.LogsURL)
+	}
+	return buf.Bytes()
+}
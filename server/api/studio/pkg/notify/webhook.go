@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Studio-Event"
+	HeaderSignature = "X-Studio-Signature"
+)
+
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func (c *WebhookConfig) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("the webhook url must be http or https")
+	}
+	return nil
+}
+
+/*
+WebhookSender posts the json payload to the url,
+the body is signed with the secret as `sha256=<hex hmac>` in the X-Studio-Signature header.
+*/
+type WebhookSender struct {
+	cfg    *WebhookConfig
+	secret string
+	client *http.Client
+}
+
+func NewWebhookSender(cfg *WebhookConfig, secret string, timeout time.Duration) *WebhookSender {
+	return &WebhookSender{
+		cfg:    cfg,
+		secret: secret,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Sign returns the signature of the body, the receiver verifies it with the shared secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookSender) Send(p *Payload, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, p.Kind+"."+p.Event)
+	if s.secret != "" {
+		req.Header.Set(HeaderSignature, Sign(s.secret, body))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook responds %s: %s", resp.Status, msg)
+	}
+	return nil
+}
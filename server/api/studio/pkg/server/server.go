@@ -4,8 +4,11 @@ import (
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/config"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/handler"
 	db "github.com/vesoft-inc/nebula-studio/server/api/studio/internal/model"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service/importer"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/service/storage"
 	"github.com/vesoft-inc/nebula-studio/server/api/studio/internal/svc"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/notify"
 	"github.com/zeromicro/go-zero/rest"
 	"gorm.io/gorm"
 )
@@ -20,6 +23,9 @@ var NewServiceContext = svc.NewServiceContext
 func InitDB(c *config.Config, d *gorm.DB) {
 	db.InitDB(c, d)
 	importer.InitTaskStatus()
+	notify.ResumeDeliveries()
+	service.MigrateUploadNamespaces(c.File.UploadDir)
+	storage.StartJanitor()
 }
 
 func RegisterHandlers(server *rest.Server, studioSvcCtx *svc.ServiceContext) {
@@ -9,6 +9,10 @@ import (
 	"fmt"
 )
 
+// CipherKey is the AES key used to encrypt the secrets studio stores in its database.
+// TODO: make it configurable
+const CipherKey = "6b6579736f6d6574616c6b6579736f6d"
+
 // Encrypt encrypts plaintext using AES encryption with a given key.
 // It returns a base64-encoded ciphertext.
 func Encrypt(plaintext, key []byte) (string, error) {
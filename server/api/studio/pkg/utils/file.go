@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func CreateDir(dir string) error {
@@ -75,3 +77,82 @@ func ReadPartFile(path string) ([]string, error) {
 
 	return topLines, nil
 }
+
+// namespaceSegment escapes a host or a user name into a single path segment
+func namespaceSegment(name string) string {
+	segment := url.PathEscape(name)
+	if strings.HasPrefix(segment, ".") {
+		segment = "%2E" + segment[1:]
+	}
+	if segment == "" {
+		segment = "_"
+	}
+	return segment
+}
+
+// UserUploadDir returns the namespace of the files uploaded by a user of a nebula host in the upload dir
+func UserUploadDir(uploadDir, host, username string) string {
+	return filepath.Join(uploadDir, namespaceSegment(host), namespaceSegment(username))
+}
+
+// CleanUploadPath cleans a slash separated path in a namespace, which can not escape it or go through a hidden file
+func CleanUploadPath(p string) (string, error) {
+	var parts []string
+	for _, part := range strings.Split(strings.ReplaceAll(p, "\\", "/"), "/") {
+		if part == "" {
+			continue
+		}
+		// the parent, the current and the hidden entries
+		if strings.HasPrefix(part, ".") {
+			return "", fmt.Errorf("invalid path: %s", p)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("invalid path: %s", p)
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// ResolveUploadPath returns the file path of a path in the namespace dir
+func ResolveUploadPath(dir, p string) (string, error) {
+	cleaned, err := CleanUploadPath(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.FromSlash(cleaned)), nil
+}
+
+// UploadNamespace is the dir of the files uploaded by a user of a nebula host
+type UploadNamespace struct {
+	Host     string
+	Username string
+	Dir      string
+}
+
+// UploadNamespaces lists the namespaces in the upload dir
+func UploadNamespaces(uploadDir string) ([]UploadNamespace, error) {
+	hosts, err := os.ReadDir(uploadDir)
+	if err != nil {
+		return nil, err
+	}
+	var namespaces []UploadNamespace
+	for _, h := range hosts {
+		host, err := url.PathUnescape(h.Name())
+		if !h.IsDir() || strings.HasPrefix(h.Name(), ".") || err != nil {
+			continue
+		}
+		users, err := os.ReadDir(filepath.Join(uploadDir, h.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			username, err := url.PathUnescape(u.Name())
+			if !u.IsDir() || err != nil {
+				continue
+			}
+			namespaces = append(namespaces, UploadNamespace{Host: host, Username: username, Dir: filepath.Join(uploadDir, h.Name(), u.Name())})
+		}
+	}
+	return namespaces, nil
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUploadPath(t *testing.T) {
+	for p, expected := range map[string]string{
+		"a.csv":           "a.csv",
+		"/dir//a.csv":     "dir/a.csv",
+		"dir\\sub\\a.csv": "dir/sub/a.csv",
+		"../a.csv":        "",
+		"dir/../../a.csv": "",
+		"./a.csv":         "",
+		".uploads/x.part": "",
+		"/":               "",
+	} {
+		cleaned, err := CleanUploadPath(p)
+		if cleaned != expected || (err == nil) != (expected != "") {
+			t.Errorf("unexpected path of %s: %q %v", p, cleaned, err)
+		}
+	}
+
+	dir := UserUploadDir("/upload", "127.0.0.1:9669", "../root")
+	if dir != filepath.Join("/upload", "127.0.0.1:9669", "%2E.%2Froot") {
+		t.Errorf("unexpected namespace: %s", dir)
+	}
+	if dir := UserUploadDir("/upload", "127.0.0.1:9669", ".."); filepath.Dir(filepath.Dir(dir)) != "/upload" {
+		t.Errorf("the namespace escapes the upload dir: %s", dir)
+	}
+	if full, err := ResolveUploadPath("/upload/h/u", "dir/a.csv"); err != nil || full != "/upload/h/u/dir/a.csv" {
+		t.Errorf("unexpected file path: %s %v", full, err)
+	}
+
+	root := t.TempDir()
+	for _, user := range []string{"root", "../root", ".hidden"} {
+		if err := os.MkdirAll(UserUploadDir(root, "127.0.0.1:9669", user), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.MkdirAll(filepath.Join(root, ".uploads"), 0o755)
+	namespaces, err := UploadNamespaces(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := make(map[string]string)
+	for _, ns := range namespaces {
+		users[ns.Host+"|"+ns.Username] = ns.Dir
+	}
+	if len(users) != 3 || users["127.0.0.1:9669|../root"] != UserUploadDir(root, "127.0.0.1:9669", "../root") || users["127.0.0.1:9669|.hidden"] == "" {
+		t.Errorf("unexpected namespaces: %v", users)
+	}
+}
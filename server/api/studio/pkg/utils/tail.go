@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// FileTail reads the lines appended to a file since the last read
+type FileTail struct {
+	Path   string
+	offset int64
+	rest   []byte
+}
+
+func NewFileTail(path string) *FileTail {
+	return &FileTail{Path: path}
+}
+
+/*
+Next returns the complete lines appended since the last call, reading at most limit bytes,
+the unfinished last line is kept for the next call.
+*/
+func (t *FileTail) Next(limit int64) ([]string, error) {
+	f, err := os.Open(t.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// the file is truncated or recreated
+	if stat.Size() < t.offset {
+		t.offset, t.rest = 0, nil
+	}
+	if stat.Size() == t.offset {
+		return nil, nil
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf, err := io.ReadAll(io.LimitReader(f, limit))
+	if err != nil {
+		return nil, err
+	}
+	t.offset += int64(len(buf))
+	buf = append(t.rest, buf...)
+	end := bytes.LastIndexByte(buf, '\n')
+	if end < 0 {
+		// a line longer than the limit is returned in pieces
+		if int64(len(buf)) >= limit {
+			t.rest = nil
+			return []string{string(buf)}, nil
+		}
+		t.rest = buf
+		return nil, nil
+	}
+	t.rest = append([]byte(nil), buf[end+1:]...)
+	return toStrings(bytes.Split(buf[:end], []byte{'\n'})), nil
+}
+
+func toStrings(lines [][]byte) []string {
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, string(line))
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package event
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/base"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ws/utils"
+)
+
+var topicKinds = map[string]bool{
+	utils.TopicImportTask: true,
+	utils.TopicLLMJob:     true,
+}
+
+// Middleware subscribes and unsubscribes the topics of the job events, such as `import_task:<id>` or `llm_job`
+func Middleware(next utils.TNext) utils.TNext {
+	return func(msgReceived *utils.MessageReceive, c *utils.Client) *utils.MessagePost {
+		if next == nil || msgReceived == nil {
+			return nil
+		}
+		msgType := msgReceived.Body.MsgType
+		if msgType != utils.MsgTypeSubscribe && msgType != utils.MsgTypeUnsubscribe {
+			return next(msgReceived, c)
+		}
+
+		msgPost := utils.MessagePost{
+			Header: utils.MessagePostHeader{
+				MsgId:    msgReceived.Header.MsgId,
+				SendTime: time.Now().UnixMilli(),
+			},
+			Body: utils.MessagePostBody{
+				MsgType: msgType,
+			},
+		}
+		fail := func(err error) *utils.MessagePost {
+			msgPost.Body.Content = map[string]any{
+				"code":    base.Error,
+				"message": err.Error(),
+			}
+			return &msgPost
+		}
+
+		if clientInfo, ok := c.GetClientInfo().(*auth.AuthData); !ok || clientInfo == nil || clientInfo.Username == "" {
+			return fail(fmt.Errorf("invalid client info"))
+		}
+		topics, err := parseTopics(msgReceived.Body.Content["topics"])
+		if err != nil {
+			return fail(err)
+		}
+		if msgType == utils.MsgTypeSubscribe {
+			c.Hub.Subscribe(c, topics...)
+		} else {
+			c.Hub.Unsubscribe(c, topics...)
+		}
+		msgPost.Body.Content = map[string]any{
+			"code":    base.Success,
+			"data":    map[string]any{"topics": topics},
+			"message": "Success",
+		}
+		return &msgPost
+	}
+}
+
+func parseTopics(value any) ([]string, error) {
+	items, ok := value.([]any)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("topics are required")
+	}
+	topics := make([]string, 0, len(items))
+	for _, item := range items {
+		topic, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid topic %v", item)
+		}
+		if kind := strings.SplitN(topic, ":", 2)[0]; !topicKinds[kind] {
+			return nil, fmt.Errorf("unknown topic %s", topic)
+		}
+		topics = append(topics, topic)
+	}
+	return topics, nil
+}
@@ -0,0 +1,86 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/base"
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/ws/utils"
+)
+
+func TestParseTopics(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		// nil means an error
+		want []string
+	}{
+		{"topics", []any{"import_task:task1", "llm_job"}, []string{"import_task:task1", "llm_job"}},
+		{"no topics", []any{}, nil},
+		{"not a list", "import_task", nil},
+		{"not a string", []any{1}, nil},
+		{"unknown kind", []any{"import_task:task1", "query"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topics, err := parseTopics(tt.value)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("expect an error, got %v", topics)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(topics) != len(tt.want) || topics[0] != tt.want[0] || topics[1] != tt.want[1] {
+				t.Errorf("unexpected topics %v, want %v", topics, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	passed := false
+	next := func(msg *utils.MessageReceive, c *utils.Client) *utils.MessagePost {
+		passed = true
+		return nil
+	}
+	handle := Middleware(next)
+	message := func(msgType string, topics ...any) *utils.MessageReceive {
+		return &utils.MessageReceive{Body: utils.MessageReceiveBody{MsgType: msgType, Content: map[string]any{"topics": topics}}}
+	}
+	code := func(post *utils.MessagePost) any {
+		return post.Body.Content.(map[string]any)["code"]
+	}
+
+	h := utils.NewHub()
+	anonymous, err := utils.NewClient(h, nil, "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post := handle(message(utils.MsgTypeSubscribe, "llm_job"), anonymous); post == nil || code(post) != base.Error {
+		t.Errorf("expect the subscription of a client without info rejected, got %v", post)
+	}
+
+	c, err := utils.NewClient(h, nil, "test", &auth.AuthData{Address: "127.0.0.1", Port: 9669, Username: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post := handle(message(utils.MsgTypeSubscribe, "query"), c); post == nil || code(post) != base.Error {
+		t.Errorf("expect the unknown topic rejected, got %v", post)
+	}
+	if post := handle(message(utils.MsgTypeSubscribe, "llm_job"), c); post == nil || code(post) != base.Success {
+		t.Errorf("unexpected response of the subscription: %v", post)
+	}
+	if post := handle(message(utils.MsgTypeUnsubscribe, "llm_job"), c); post == nil || code(post) != base.Success {
+		t.Errorf("unexpected response of the unsubscription: %v", post)
+	}
+	if passed {
+		t.Error("unexpected subscription passed to the next middleware")
+	}
+	handle(message("ngql"), c)
+	if !passed {
+		t.Error("expect the other messages passed to the next middleware")
+	}
+}
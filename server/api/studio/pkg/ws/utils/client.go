@@ -105,6 +105,23 @@ func (c *Client) SendMessage(msgSend []byte) (closed bool) {
 	return false
 }
 
+// TrySendMessage drops the message instead of waiting when the send buffer is full
+func (c *Client) TrySendMessage(msgSend []byte) (sent bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			// the send channel is closed
+			sent = false
+		}
+	}()
+	select {
+	case c.send <- msgSend:
+		return true
+	default:
+		logx.Infof("[WebSocket TrySendMessage]: send buffer is full, drop the message, ID: %s", c.ID)
+		return false
+	}
+}
+
 func (c *Client) Serve() {
 	go c.writePump()
 	go c.readPump()
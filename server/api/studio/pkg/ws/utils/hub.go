@@ -18,6 +18,10 @@ type Hub struct {
 
 	// Unregister requests from clients.
 	unregister chan *Client
+
+	topicMu sync.RWMutex
+	// Subscribed clients of each topic.
+	topics map[string]map[string]*Client
 }
 
 func NewHub() *Hub {
@@ -26,10 +30,12 @@ func NewHub() *Hub {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		clients:    make(map[string]*Client, 0),
+		topics:     make(map[string]map[string]*Client),
 	}
 }
 
 func (h *Hub) Run() {
+	setRunningHub(h)
 	for {
 		select {
 		case client := <-h.register:
@@ -40,6 +46,7 @@ func (h *Hub) Run() {
 			h.mu.Lock()
 			if _, ok := h.clients[client.ID]; ok {
 				delete(h.clients, client.ID)
+				h.unsubscribeAll(client)
 				close(client.send)
 				afterDestroy := client.AfterDestroy
 				if afterDestroy != nil {
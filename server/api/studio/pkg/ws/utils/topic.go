@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	MsgTypeSubscribe   = "subscribe"
+	MsgTypeUnsubscribe = "unsubscribe"
+	MsgTypeEvent       = "event"
+
+	TopicImportTask = "import_task"
+	TopicLLMJob     = "llm_job"
+)
+
+/*
+Event is the progress of a job published to the subscribers of its topic.
+The topic is `<kind>:<id>`, a client subscribing `<kind>` receives the events of all its jobs of the kind.
+Owner is the `host:user` the job belongs to, the event is only sent to the clients of the owner.
+*/
+type Event struct {
+	Topic   string   `json:"topic"`
+	Owner   string   `json:"-"`
+	Status  string   `json:"status,omitempty"`
+	Message string   `json:"message,omitempty"`
+	Stats   any      `json:"stats,omitempty"`
+	Logs    []string `json:"logs,omitempty"`
+}
+
+var (
+	runningHub   *Hub
+	runningHubMu sync.RWMutex
+)
+
+func Topic(kind, id string) string {
+	return kind + ":" + id
+}
+
+func Owner(host, user string) string {
+	return host + ":" + user
+}
+
+// Publish sends the event through the running hub, it does nothing before the hub runs
+func Publish(event *Event) {
+	runningHubMu.RLock()
+	h := runningHub
+	runningHubMu.RUnlock()
+	if h != nil {
+		h.Publish(event)
+	}
+}
+
+func setRunningHub(h *Hub) {
+	runningHubMu.Lock()
+	defer runningHubMu.Unlock()
+	runningHub = h
+}
+
+func clientOwner(c *Client) (string, bool) {
+	info, ok := c.GetClientInfo().(*auth.AuthData)
+	if !ok || info == nil || info.Username == "" {
+		return "", false
+	}
+	return Owner(fmt.Sprintf("%s:%d", info.Address, info.Port), info.Username), true
+}
+
+func (h *Hub) Subscribe(c *Client, topics ...string) {
+	h.topicMu.Lock()
+	defer h.topicMu.Unlock()
+	for _, topic := range topics {
+		clients, ok := h.topics[topic]
+		if !ok {
+			clients = make(map[string]*Client)
+			h.topics[topic] = clients
+		}
+		clients[c.ID] = c
+	}
+}
+
+func (h *Hub) Unsubscribe(c *Client, topics ...string) {
+	h.topicMu.Lock()
+	defer h.topicMu.Unlock()
+	for _, topic := range topics {
+		if clients, ok := h.topics[topic]; ok {
+			delete(clients, c.ID)
+			if len(clients) == 0 {
+				delete(h.topics, topic)
+			}
+		}
+	}
+}
+
+func (h *Hub) unsubscribeAll(c *Client) {
+	h.topicMu.Lock()
+	defer h.topicMu.Unlock()
+	for topic, clients := range h.topics {
+		delete(clients, c.ID)
+		if len(clients) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
+
+// Publish sends the event to the clients of its owner which subscribe the topic or the kind of the topic
+func (h *Hub) Publish(event *Event) {
+	receivers := make(map[string]*Client)
+	h.topicMu.RLock()
+	for _, topic := range []string{event.Topic, strings.SplitN(event.Topic, ":", 2)[0]} {
+		for id, c := range h.topics[topic] {
+			receivers[id] = c
+		}
+	}
+	h.topicMu.RUnlock()
+	if len(receivers) == 0 {
+		return
+	}
+
+	msg, err := json.Marshal(MessagePost{
+		Header: MessagePostHeader{
+			SendTime: time.Now().UnixMilli(),
+		},
+		Body: MessagePostBody{
+			MsgType: MsgTypeEvent,
+			Content: event,
+		},
+	})
+	if err != nil {
+		logx.Errorf("[WebSocket Publish]: %v", err)
+		return
+	}
+	for _, c := range receivers {
+		if owner, ok := clientOwner(c); !ok || owner != event.Owner {
+			continue
+		}
+		c.TrySendMessage(msg)
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/vesoft-inc/nebula-studio/server/api/studio/pkg/auth"
+)
+
+const testHost = "127.0.0.1:9669"
+
+func newTestClient(t *testing.T, h *Hub, username string) *Client {
+	c, err := NewClient(h, nil, "test", &auth.AuthData{Address: "127.0.0.1", Port: 9669, Username: username})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// receivedTopics drains the send buffer of the client and returns the topics of the events
+func receivedTopics(t *testing.T, c *Client) []string {
+	var topics []string
+	for {
+		select {
+		case msg := <-c.send:
+			var post struct {
+				Body struct {
+					MsgType string `json:"msgType"`
+					Content Event  `json:"content"`
+				} `json:"body"`
+			}
+			if err := json.Unmarshal(msg, &post); err != nil {
+				t.Fatal(err)
+			}
+			if post.Body.MsgType != MsgTypeEvent {
+				t.Errorf("unexpected message type %s", post.Body.MsgType)
+			}
+			topics = append(topics, post.Body.Content.Topic)
+		default:
+			return topics
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	h := NewHub()
+	c1, c2 := newTestClient(t, h, "root"), newTestClient(t, h, "root")
+	task1 := Topic(TopicImportTask, "task1")
+
+	h.Subscribe(c1, task1, TopicLLMJob)
+	h.Subscribe(c2, task1)
+	if len(h.topics[task1]) != 2 || len(h.topics[TopicLLMJob]) != 1 {
+		t.Fatalf("unexpected topics: %v", h.topics)
+	}
+	// subscribe again
+	h.Subscribe(c1, task1)
+	if len(h.topics[task1]) != 2 {
+		t.Errorf("unexpected subscribers of %s: %v", task1, h.topics[task1])
+	}
+
+	h.Unsubscribe(c1, task1, "unknown")
+	if _, ok := h.topics[task1][c1.ID]; ok || len(h.topics[task1]) != 1 {
+		t.Errorf("unexpected subscribers of %s: %v", task1, h.topics[task1])
+	}
+	h.Unsubscribe(c2, task1)
+	if _, ok := h.topics[task1]; ok {
+		t.Errorf("expect the topic without subscribers removed: %v", h.topics)
+	}
+
+	h.Subscribe(c2, TopicLLMJob)
+	h.unsubscribeAll(c1)
+	if len(h.topics[TopicLLMJob]) != 1 {
+		t.Errorf("unexpected subscribers of %s: %v", TopicLLMJob, h.topics[TopicLLMJob])
+	}
+	h.unsubscribeAll(c2)
+	if len(h.topics) != 0 {
+		t.Errorf("unexpected topics: %v", h.topics)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	h := NewHub()
+	byTopic := newTestClient(t, h, "root")
+	byKind := newTestClient(t, h, "root")
+	both := newTestClient(t, h, "root")
+	other := newTestClient(t, h, "other")
+	otherKind := newTestClient(t, h, "root")
+	anonymous, err := NewClient(h, nil, "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task1 := Topic(TopicImportTask, "task1")
+	h.Subscribe(byTopic, task1)
+	h.Subscribe(byKind, TopicImportTask)
+	h.Subscribe(both, task1, TopicImportTask)
+	h.Subscribe(other, task1, TopicImportTask)
+	h.Subscribe(otherKind, TopicLLMJob)
+	h.Subscribe(anonymous, task1)
+
+	h.Publish(&Event{Topic: task1, Owner: Owner(testHost, "root"), Status: "Running"})
+	h.Publish(&Event{Topic: Topic(TopicImportTask, "task2"), Owner: Owner(testHost, "root")})
+	h.Publish(&Event{Topic: Topic(TopicImportTask, "task3"), Owner: Owner("127.0.0.1:9670", "root")})
+
+	tests := []struct {
+		name   string
+		client *Client
+		want   []string
+	}{
+		{"topic", byTopic, []string{task1}},
+		{"kind", byKind, []string{task1, Topic(TopicImportTask, "task2")}},
+		// the event is sent once to a client subscribing both the topic and the kind
+		{"topic and kind", both, []string{task1, Topic(TopicImportTask, "task2")}},
+		{"other user", other, nil},
+		{"other kind", otherKind, nil},
+		{"no client info", anonymous, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := receivedTopics(t, tt.client)
+			if len(got) != len(tt.want) {
+				t.Fatalf("unexpected events %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("unexpected events %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}